@@ -1,5 +1,3 @@
-import * as os from "node:os";
-import * as path from "node:path";
 import process from "node:process";
 import { serve } from "@hono/node-server";
 import { serveStatic } from "@hono/node-server/serve-static";
@@ -10,27 +8,58 @@ import { cors } from "hono/cors";
 import { environments } from "./routes/environments.js";
 import { files } from "./routes/files.js";
 import { git } from "./routes/git.js";
+import { terminals } from "./routes/terminals.js";
+import { handleCLIStream } from "./utils/cli-stream.js";
+import { ConfigError } from "./utils/config.js";
 import {
 	CLI_COMMANDS,
+	ENVIRONMENT_ID_PATTERN,
 	getDefaultCLIPath,
 	getDefaultWorkingDir,
 } from "./utils/constants.js";
-import { handleFileWatch, handleTerminal } from "./utils/terminal.js";
+import { handleFileSearch } from "./utils/file-search.js";
+import { handleFileWatch } from "./utils/file-watch.js";
+import { handleFolderWatch } from "./utils/folder-watch.js";
+import {
+	getEnvironmentPushOptions,
+	handleGitRemoteStream,
+} from "./utils/git-remote.js";
+import { resolveAllowedPath, resolveDirectory } from "./utils/path-access.js";
+import { handleTerminal } from "./utils/terminal.js";
+import {
+	resolveShellOptions,
+	type ShellRequest,
+} from "./utils/terminal-options.js";
 
 /**
- * Resolves a directory path, handling special cases like '.' and '~'
+ * Shorten a message to fit in a WebSocket close frame (123 bytes)
  */
-function resolveDirectory(dir: string): string {
-	if (dir === ".") {
-		return process.cwd();
-	}
-	if (dir.startsWith("~/")) {
-		return path.join(os.homedir(), dir.slice(2));
-	}
-	if (dir === "~") {
-		return os.homedir();
+function toCloseReason(message: string): string {
+	let reason = message;
+	while (Buffer.byteLength(reason) > 123) {
+		reason = reason.slice(0, -1);
 	}
-	return path.resolve(dir);
+	return reason;
+}
+
+/**
+ * Start a WebSocket handler once its folder is known to be inside the allowed
+ * roots, or send the error and close the socket
+ */
+function openInAllowedFolder(
+	socket: WebSocket,
+	folder: string,
+	open: () => void,
+): void {
+	resolveAllowedPath(folder).then(open, (error) => {
+		const message = error instanceof Error ? error.message : "Unknown error";
+		socket.send(JSON.stringify({ type: "error", error: message }));
+		// A broken config file is the server's fault
+		socket.close(
+			error instanceof ConfigError ? 1011 : 1008,
+			toCloseReason(message),
+		);
+	});
 }
 
 const app = new OpenAPIHono();
@@ -49,23 +78,56 @@ app.use(
 // WebSocket route for terminal
 app.get(
 	"/api/v1/terminal",
-	upgradeWebSocket((c) => ({
-		onOpen: (event, ws) => {
-			console.log(`Terminal WebSocket connection opened`);
-			if (ws.raw) {
-				handleTerminal(ws.raw);
-			}
-		},
-		onMessage: (event, ws) => {
-			// Message handling is done in handleTerminal
-		},
-		onClose: (event, ws) => {
-			console.log(`Terminal WebSocket connection closed`);
-		},
-		onError: (event, ws) => {
-			console.error("Terminal WebSocket error:", event);
-		},
-	})),
+	upgradeWebSocket((c) => {
+		// Optional folder to start the shell in, e.g. an environment worktree
+		const folder = c.req.query("folder");
+		// Shell options for a new session, checked against the config
+		const request: ShellRequest = {
+			shell: c.req.query("shell"),
+			args: c.req.queries("args"),
+			folder: folder ? resolveDirectory(folder) : undefined,
+			env: c.req.queries("env"),
+			cols: c.req.query("cols"),
+			rows: c.req.query("rows"),
+		};
+		// Optional session to reattach to after a reload or a dropped socket
+		const sessionId = c.req.query("session");
+		// Name shown to the other clients when the session is shared
+		const clientName = c.req.query("name");
+
+		return {
+			onOpen: (event, ws) => {
+				console.log(`Terminal WebSocket connection opened`);
+				const socket = ws.raw;
+				if (!socket) return;
+
+				resolveShellOptions(request)
+					.then((shell) =>
+						handleTerminal(socket, { shell, sessionId, clientName }),
+					)
+					.catch((error) => {
+						const message =
+							error instanceof Error ? error.message : "Unknown error";
+						console.error("Invalid terminal options:", message);
+						socket.send(JSON.stringify({ type: "error", error: message }));
+						// A broken config file is the server's fault
+						socket.close(
+							error instanceof ConfigError ? 1011 : 1008,
+							toCloseReason(message),
+						);
+					});
+			},
+			onMessage: (event, ws) => {
+				// Message handling is done in handleTerminal
+			},
+			onClose: (event, ws) => {
+				console.log(`Terminal WebSocket connection closed`);
+			},
+			onError: (event, ws) => {
+				console.error("Terminal WebSocket error:", event);
+			},
+		};
+	}),
 );
 
 // WebSocket route for environment-specific terminal
@@ -74,28 +136,33 @@ app.get(
 	upgradeWebSocket((c) => {
 		const environmentId = c.req.param("id");
 		const folder = c.req.query("folder");
-		const cli = c.req.query("cli");
 
 		// Get the folder parameter from query string, default to working directory
 		const workingDir = folder
 			? resolveDirectory(folder)
 			: getDefaultWorkingDir();
-		// Get the CLI command path from query string, default to constant
-		const cliPath = cli || getDefaultCLIPath();
+		// Session to reattach to, a new one is started if it isn't running
+		const sessionId = c.req.query("session");
+		// Name shown to the other clients when the session is shared
+		const clientName = c.req.query("name");
 
 		return {
 			onOpen: (event, ws) => {
 				console.log(
 					`Environment terminal WebSocket connection opened for environment: ${environmentId}`,
 				);
-				if (ws.raw) {
-					handleTerminal(ws.raw, {
+				const socket = ws.raw;
+				if (!socket) return;
+
+				openInAllowedFolder(socket, workingDir, () =>
+					handleTerminal(socket, {
 						command: CLI_COMMANDS.TERMINAL,
 						environmentId,
 						workingDir,
-						cliPath,
-					});
-				}
+						sessionId,
+						clientName,
+					}),
+				);
 			},
 			onMessage: (event, ws) => {
 				// Message handling is done in handleTerminal
@@ -120,25 +187,30 @@ app.get(
 	"/api/v1/environments/watch",
 	upgradeWebSocket((c) => {
 		const folder = c.req.query("folder");
-		const cli = c.req.query("cli");
 
 		// Get the folder parameter from query string, default to working directory
 		const workingDir = folder
 			? resolveDirectory(folder)
 			: getDefaultWorkingDir();
-		// Get the CLI command path from query string, default to constant
-		const cliPath = cli || getDefaultCLIPath();
+		// Session to reattach to, a new one is started if it isn't running
+		const sessionId = c.req.query("session");
+		// Name shown to the other clients when the session is shared
+		const clientName = c.req.query("name");
 
 		return {
 			onOpen: (event, ws) => {
 				console.log(`Watch WebSocket connection opened`);
-				if (ws.raw) {
-					handleTerminal(ws.raw, {
+				const socket = ws.raw;
+				if (!socket) return;
+
+				openInAllowedFolder(socket, workingDir, () =>
+					handleTerminal(socket, {
 						command: CLI_COMMANDS.WATCH,
 						workingDir,
-						cliPath,
-					});
-				}
+						sessionId,
+						clientName,
+					}),
+				);
 			},
 			onMessage: (event, ws) => {
 				// Message handling is done in handleTerminal
@@ -153,35 +225,258 @@ app.get(
 	}),
 );
 
-// WebSocket route for file watching
+// WebSocket route for watching files, one connection for any number of files
 app.get(
 	"/api/v1/files/watch",
+	upgradeWebSocket(() => {
+		return {
+			onOpen: (_event, ws) => {
+				console.log("File watch WebSocket connection opened");
+				const socket = ws.raw;
+				if (!socket) return;
+
+				// Files are added and removed with subscribe messages
+				handleFileWatch(socket);
+			},
+			onClose: (_event, _ws) => {
+				console.log("File watch WebSocket connection closed");
+			},
+			onError: (event, _ws) => {
+				console.error("File watch WebSocket error:", event);
+			},
+		};
+	}),
+);
+
+// WebSocket route for watching changes below a folder
+app.get(
+	"/api/v1/files/watch/folder",
 	upgradeWebSocket((c) => {
-		const filePath = c.req.query("path");
+		const folder = c.req.query("path") || getDefaultWorkingDir();
+		const ignore = c.req.query("ignore")?.split(",") ?? [];
+		const debounceMs = Number(c.req.query("debounce"));
+		const depth = Number(c.req.query("depth"));
+
+		return {
+			onOpen: (_event, ws) => {
+				const socket = ws.raw;
+				if (!socket) return;
 
-		if (!filePath) {
+				// Only watch folders inside the allowed roots
+				resolveAllowedPath(folder)
+					.then((resolvedFolder) =>
+						handleFolderWatch(socket, resolvedFolder, {
+							ignore,
+							...(Number.isInteger(debounceMs) && { debounceMs }),
+							...(Number.isInteger(depth) && depth >= 0 && { depth }),
+						}),
+					)
+					.catch((error) => {
+						socket.send(
+							JSON.stringify({
+								type: "error",
+								error: error instanceof Error ? error.message : "Unknown error",
+								timestamp: new Date().toISOString(),
+							}),
+						);
+						ws.close(1008, "Path is outside the allowed folders");
+					});
+			},
+			onError: (event, _ws) => {
+				console.error(`Folder watch WebSocket error for ${folder}:`, event);
+			},
+		};
+	}),
+);
+
+// WebSocket route for searching file names and contents
+app.get(
+	"/api/v1/files/search",
+	upgradeWebSocket((c) => {
+		const root = c.req.query("path") || getDefaultWorkingDir();
+		const query = c.req.query("query");
+		const mode = c.req.query("mode") ?? "name";
+
+		if (!query || (mode !== "name" && mode !== "content")) {
 			return {
 				onOpen: (_event, ws) => {
-					ws.close(1000, "File path is required");
+					ws.close(1008, "query and a valid mode are required");
 				},
 			};
 		}
 
+		const regex = c.req.query("regex") === "true";
+		const caseSensitive = c.req.query("caseSensitive") === "true";
+		const maxResults = Number(c.req.query("maxResults"));
+		const contextLines = Number(c.req.query("context"));
+
 		return {
 			onOpen: (_event, ws) => {
-				console.log(`File watch WebSocket connection opened for: ${filePath}`);
-				if (ws.raw) {
-					handleFileWatch(ws.raw, filePath);
-				}
+				const socket = ws.raw;
+				if (!socket) return;
+
+				// Only search inside the allowed roots
+				resolveAllowedPath(root)
+					.then((resolvedRoot) =>
+						handleFileSearch(socket, {
+							root: resolvedRoot,
+							query,
+							mode,
+							regex,
+							caseSensitive,
+							...(Number.isInteger(contextLines) && { contextLines }),
+							...(Number.isInteger(maxResults) &&
+								maxResults > 0 && { maxResults }),
+						}),
+					)
+					.catch((error) => {
+						socket.send(
+							JSON.stringify({
+								type: "error",
+								error: error instanceof Error ? error.message : "Unknown error",
+							}),
+						);
+						ws.close(1008, "Path is outside the allowed folders");
+					});
 			},
-			onMessage: (_event, _ws) => {
-				// File watching doesn't need to handle incoming messages
+			onError: (event, _ws) => {
+				console.error("File search WebSocket error:", event);
+			},
+		};
+	}),
+);
+
+// WebSocket routes streaming the log and diff of an environment
+for (const [name, command] of [
+	["logs", CLI_COMMANDS.LOG],
+	["diff", CLI_COMMANDS.DIFF],
+] as const) {
+	app.get(
+		`/api/v1/environments/:id/${name}/stream`,
+		upgradeWebSocket((c) => {
+			const environmentId = c.req.param("id");
+			const folder = c.req.query("folder");
+			const cli = c.req.query("cli");
+
+			// The ID is passed to the CLI as an argument
+			if (!ENVIRONMENT_ID_PATTERN.test(environmentId)) {
+				return {
+					onOpen: (_event, ws) => {
+						ws.close(1008, "Invalid environment ID");
+					},
+				};
+			}
+
+			const options = {
+				command,
+				environmentId,
+				workingDir: folder ? resolveDirectory(folder) : getDefaultWorkingDir(),
+				cliPath: cli || getDefaultCLIPath(),
+				// Keep sending changes, e.g. new log entries as the agent commits
+				follow: c.req.query("follow") === "true",
+			};
+
+			return {
+				onOpen: (_event, ws) => {
+					console.log(
+						`Environment ${name} stream opened for environment: ${environmentId}`,
+					);
+					const socket = ws.raw;
+					if (!socket) return;
+
+					openInAllowedFolder(socket, options.workingDir, () =>
+						handleCLIStream(socket, options),
+					);
+				},
+				onClose: (_event, _ws) => {
+					console.log(
+						`Environment ${name} stream closed for environment: ${environmentId}`,
+					);
+				},
+				onError: (event, _ws) => {
+					console.error(
+						`Environment ${name} stream error for environment ${environmentId}:`,
+						event,
+					);
+				},
+			};
+		}),
+	);
+}
+
+// WebSocket route for streaming git fetch/pull/push progress
+app.get(
+	"/api/v1/git/remote",
+	upgradeWebSocket((c) => {
+		const folder = c.req.query("folder");
+		const operation = c.req.query("operation");
+		const remote = c.req.query("remote");
+		const environmentId = c.req.query("environment");
+
+		// The ID is part of the pushed ref
+		if (
+			environmentId !== undefined &&
+			!ENVIRONMENT_ID_PATTERN.test(environmentId)
+		) {
+			return {
+				onOpen: (_event, ws) => {
+					ws.close(1008, "Invalid environment ID");
+				},
+			};
+		}
+
+		if (
+			!folder ||
+			!remote ||
+			(operation !== "fetch" && operation !== "pull" && operation !== "push")
+		) {
+			return {
+				onOpen: (_event, ws) => {
+					ws.close(
+						1008,
+						"folder, remote and a valid operation are required",
+					);
+				},
+			};
+		}
+
+		const workingDir = resolveDirectory(folder);
+		// Pushing an environment pushes its container-use branch for review
+		const options =
+			operation === "push" && environmentId
+				? getEnvironmentPushOptions(
+						workingDir,
+						environmentId,
+						remote,
+						c.req.query("remoteBranch"),
+					)
+				: {
+						operation,
+						folder: workingDir,
+						remote,
+						branch: c.req.query("branch"),
+						remoteBranch: c.req.query("remoteBranch"),
+						ffOnly: c.req.query("ffOnly") === "true",
+						prune: c.req.query("prune") === "true",
+						setUpstream: c.req.query("setUpstream") === "true",
+						force: c.req.query("force") === "true",
+					};
+
+		return {
+			onOpen: (_event, ws) => {
+				console.log(`Git ${operation} WebSocket connection opened`);
+				const socket = ws.raw;
+				if (!socket) return;
+
+				openInAllowedFolder(socket, workingDir, () =>
+					handleGitRemoteStream(socket, options),
+				);
 			},
 			onClose: (_event, _ws) => {
-				console.log(`File watch WebSocket connection closed for: ${filePath}`);
+				console.log(`Git ${operation} WebSocket connection closed`);
 			},
 			onError: (event, _ws) => {
-				console.error(`File watch WebSocket error for ${filePath}:`, event);
+				console.error(`Git ${operation} WebSocket error:`, event);
 			},
 		};
 	}),
@@ -202,6 +497,9 @@ apiApp.route("/", files);
 // Mount the git routes
 apiApp.route("/git", git);
 
+// Mount the terminal session routes
+apiApp.route("/terminal", terminals);
+
 // The OpenAPI documentation will be available at /api/v1/doc
 apiApp.doc("/doc", {
 	openapi: "3.1.0",
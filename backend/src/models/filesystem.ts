@@ -43,8 +43,452 @@ export const FolderListingSchema = z.object({
 	}),
 });
 
+/**
+ * Git status shown next to a tree entry, like the decorations of an IDE
+ *
+ * Folders get the most significant status of the files inside them.
+ */
+export const FileGitStatusSchema = z
+	.enum([
+		"modified",
+		"added",
+		"deleted",
+		"renamed",
+		"untracked",
+		"conflicted",
+		"ignored",
+	])
+	.openapi({
+		description: "Git status of the file, or of the changes inside a folder",
+		example: "modified",
+	});
+
+/**
+ * Entry of a file tree, listed flat with the folder it belongs to
+ */
+export const FileTreeEntrySchema = FileEntrySchema.extend({
+	parent: z.string().openapi({
+		description: "Full path of the folder containing the entry",
+		example: "/Users/john/hello/src",
+	}),
+	depth: z.number().int().openapi({
+		description: "Level below the requested folder, 1 for its direct entries",
+		example: 2,
+	}),
+	symlink: z.boolean().openapi({
+		description: "Whether the entry is a symbolic link",
+		example: false,
+	}),
+	ignored: z.boolean().openapi({
+		description: "Whether git ignores the entry",
+		example: false,
+	}),
+	gitStatus: FileGitStatusSchema.optional(),
+	childrenLoaded: z.boolean().optional().openapi({
+		description:
+			"For folders, whether their entries are part of this response. Request the folder itself to load them",
+		example: true,
+	}),
+});
+
+/**
+ * Several levels of a folder, loaded lazily one subtree at a time
+ */
+export const FileTreeSchema = z
+	.object({
+		path: z.string().openapi({
+			description: "Full path of the requested folder",
+			example: "/Users/john/hello",
+		}),
+		parent: z.string().nullable().openapi({
+			description: "Parent folder path, null if at root or outside the roots",
+			example: "/Users/john",
+		}),
+		depth: z.number().int().openapi({
+			description: "Number of levels included",
+			example: 2,
+		}),
+		repositoryRoot: z.string().nullable().openapi({
+			description: "Root of the git repository, null outside of one",
+			example: "/Users/john/hello",
+		}),
+		entries: z.array(FileTreeEntrySchema).openapi({
+			description:
+				"Entries of all included levels, folders first and by name within each folder",
+		}),
+		truncated: z.boolean().openapi({
+			description:
+				"Whether the entry limit was reached, leaving some folders unloaded",
+			example: false,
+		}),
+	})
+	.openapi("FileTree");
+
+/**
+ * File content response
+ */
+export const FileContentSchema = z
+	.object({
+		path: z.string().openapi({
+			description: "Full path to the file",
+			example: "/Users/john/hello/README.md",
+		}),
+		size: z.number().openapi({
+			description: "Total size of the file in bytes",
+			example: 1024,
+		}),
+		mimeType: z.string().openapi({
+			description: "MIME type guessed from the file extension",
+			example: "text/markdown",
+		}),
+		binary: z.boolean().openapi({
+			description: "Whether the file content looks binary",
+			example: false,
+		}),
+		encoding: z.enum(["utf-8", "base64"]).openapi({
+			description: "Encoding of content, base64 for binary files",
+			example: "utf-8",
+		}),
+		content: z.string().openapi({
+			description: "Content of the requested byte range",
+			example: "# Hello",
+		}),
+		offset: z.number().openapi({
+			description: "Byte offset the content starts at",
+			example: 0,
+		}),
+		length: z.number().openapi({
+			description: "Number of bytes returned",
+			example: 1024,
+		}),
+		truncated: z.boolean().openapi({
+			description: "Whether the file continues past the returned range",
+			example: false,
+		}),
+		maxSize: z.number().openapi({
+			description: "Maximum number of bytes returned per request",
+			example: 5242880,
+		}),
+		modified: z.string().openapi({
+			description: "Last modified timestamp",
+			example: "2023-01-01T00:00:00Z",
+		}),
+		etag: z.string().openapi({
+			description: "Entity tag of this version of the file",
+			example: 'W/"400-18c9a5e1f80"',
+		}),
+		hash: z.string().optional().openapi({
+			description:
+				"SHA-256 of the content, only set when the whole file is read",
+			example:
+				"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		}),
+	})
+	.openapi("FileContent");
+
+/**
+ * Version of a file on disk, used to detect conflicting saves
+ */
+export const FileVersionSchema = z
+	.object({
+		size: z.number().openapi({
+			description: "Size of the file in bytes",
+			example: 1024,
+		}),
+		modified: z.string().openapi({
+			description: "Last modified timestamp",
+			example: "2023-01-01T00:00:00.000Z",
+		}),
+		etag: z.string().openapi({
+			description: "Entity tag of this version of the file",
+			example: 'W/"400-18c9a5e1f80"',
+		}),
+		hash: z.string().openapi({
+			description: "SHA-256 of the content",
+			example:
+				"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		}),
+	})
+	.openapi("FileVersion");
+
+/**
+ * File save request
+ */
+export const FileSaveRequestSchema = z.object({
+	path: z.string().min(1).openapi({
+		description: "File path to write",
+		example: "~/hello/README.md",
+	}),
+	content: z.string().openapi({
+		description: "New content of the file as UTF-8 text",
+		example: "# Hello",
+	}),
+	expectedModified: z.string().optional().openapi({
+		description:
+			"Last modified timestamp the client loaded. The save is rejected if the file changed",
+		example: "2023-01-01T00:00:00.000Z",
+	}),
+	expectedHash: z.string().optional().openapi({
+		description:
+			"SHA-256 of the content the client loaded. The save is rejected if the file changed",
+		example:
+			"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	}),
+});
+
+/**
+ * File save result
+ */
+export const FileSaveResultSchema = z.object({
+	success: z.boolean().openapi({
+		description: "Whether the file was saved",
+		example: true,
+	}),
+	message: z.string().openapi({
+		description: "Success or error message",
+		example: "Saved README.md",
+	}),
+	data: FileVersionSchema,
+});
+
+/**
+ * Save conflict, returned when the file changed since it was loaded
+ */
+export const FileSaveConflictSchema = z.object({
+	error: z.string().openapi({
+		description: "Error message",
+		example: "File changed on disk since it was loaded",
+	}),
+	path: z.string().openapi({
+		description: "Full path to the file",
+		example: "/Users/john/hello/README.md",
+	}),
+	current: FileVersionSchema.nullable().openapi({
+		description: "Current version on disk, null if the file was deleted",
+	}),
+});
+
+/**
+ * Request to create a file or folder
+ */
+export const FileCreateRequestSchema = z.object({
+	path: z.string().min(1).openapi({
+		description: "Path of the new file or folder",
+		example: "~/hello/notes.md",
+	}),
+	type: z.enum(["file", "folder"]).openapi({
+		description: "Whether to create a file or a folder",
+		example: "file",
+	}),
+	content: z.string().optional().openapi({
+		description: "Initial content of a new file",
+		example: "# Notes",
+	}),
+});
+
+/**
+ * Request to move or copy a file or folder
+ */
+export const FileTransferRequestSchema = z.object({
+	source: z.string().min(1).openapi({
+		description: "Path of the file or folder to move or copy",
+		example: "~/hello/notes.md",
+	}),
+	destination: z.string().min(1).openapi({
+		description: "New path, including the file or folder name",
+		example: "~/hello/docs/notes.md",
+	}),
+	overwrite: z.boolean().optional().openapi({
+		description: "Move an existing destination to the trash instead of failing",
+		example: false,
+	}),
+});
+
+/**
+ * Request to move a file or folder to the trash
+ */
+export const FileTrashRequestSchema = z.object({
+	path: z.string().min(1).openapi({
+		description: "Path of the file or folder to delete",
+		example: "~/hello/notes.md",
+	}),
+});
+
+/**
+ * Request to restore an item from the trash
+ */
+export const FileRestoreRequestSchema = z.object({
+	id: z.string().min(1).openapi({
+		description: "ID of the trashed item",
+		example: "1704067200000-1a2b3c4d",
+	}),
+	overwrite: z.boolean().optional().openapi({
+		description:
+			"Move a file now at the original path to the trash instead of failing",
+		example: false,
+	}),
+});
+
+/**
+ * Outcome of a file operation
+ */
+export const FileOperationSchema = z
+	.object({
+		operation: z
+			.enum(["create", "mkdir", "move", "copy", "trash", "restore"])
+			.openapi({
+				description: "Operation that was performed",
+				example: "move",
+			}),
+		type: z.enum(["file", "folder"]).openapi({
+			description: "Type of the entry",
+			example: "file",
+		}),
+		source: z.string().optional().openapi({
+			description: "Original path for move and copy",
+			example: "/Users/john/hello/notes.md",
+		}),
+		path: z.string().openapi({
+			description: "Path of the resulting file or folder",
+			example: "/Users/john/hello/docs/notes.md",
+		}),
+		trashId: z.string().optional().openapi({
+			description: "ID of the item in the trash, for trash and restore",
+			example: "1704067200000-1a2b3c4d",
+		}),
+	})
+	.openapi("FileOperation");
+
+export const FileOperationResultSchema = z.object({
+	success: z.boolean().openapi({
+		description: "Whether the operation was successful",
+		example: true,
+	}),
+	message: z.string().openapi({
+		description: "Success or error message",
+		example: "Moved notes.md to docs/notes.md",
+	}),
+	data: FileOperationSchema,
+});
+
+/**
+ * Multipart upload of files into a folder
+ */
+export const FileUploadRequestSchema = z.object({
+	path: z.string().min(1).openapi({
+		description: "Folder to upload into",
+		example: "~/hello/fixtures",
+	}),
+	overwrite: z.enum(["true", "false"]).optional().openapi({
+		description: "Move existing files to the trash instead of failing",
+		example: "false",
+	}),
+	files: z.any().openapi({
+		type: "array",
+		items: { type: "string", format: "binary" },
+		description:
+			"Files to upload. File names may include folders, e.g. data/users.json",
+	}),
+});
+
+/**
+ * Files written by an upload
+ */
+export const FileUploadSchema = z
+	.object({
+		path: z.string().openapi({
+			description: "Folder the files were uploaded into",
+			example: "/Users/john/hello/fixtures",
+		}),
+		files: z
+			.array(
+				z.object({
+					path: z.string().openapi({
+						description: "Full path of the written file",
+						example: "/Users/john/hello/fixtures/data/users.json",
+					}),
+					size: z.number().openapi({
+						description: "Size of the file in bytes",
+						example: 1024,
+					}),
+				}),
+			)
+			.openapi({
+				description: "Uploaded files, in the order they were sent",
+			}),
+	})
+	.openapi("FileUpload");
+
+export const FileUploadResultSchema = z.object({
+	success: z.boolean().openapi({
+		description: "Whether all files were uploaded",
+		example: true,
+	}),
+	message: z.string().openapi({
+		description: "Success or error message",
+		example: "Uploaded 2 files to fixtures",
+	}),
+	data: FileUploadSchema,
+});
+
+/**
+ * Item in the trash
+ */
+export const TrashEntrySchema = z.object({
+	id: z.string().openapi({
+		description: "ID of the trashed item",
+		example: "1704067200000-1a2b3c4d",
+	}),
+	originalPath: z.string().openapi({
+		description: "Path the item was deleted from",
+		example: "/Users/john/hello/notes.md",
+	}),
+	type: z.enum(["file", "folder"]).openapi({
+		description: "Type of the entry",
+		example: "file",
+	}),
+	deletedAt: z.string().openapi({
+		description: "When the item was moved to the trash",
+		example: "2024-01-01T00:00:00.000Z",
+	}),
+});
+
+export const TrashListingSchema = z.object({
+	items: z.array(TrashEntrySchema).openapi({
+		description: "Trashed items, most recently deleted first",
+	}),
+});
+
+/**
+ * Error for paths outside the folders the file system API may access
+ */
+export const PathForbiddenSchema = z
+	.object({
+		error: z.string().openapi({
+			description: "Error message",
+			example: "Path is outside the allowed folders",
+		}),
+		path: z.string().openapi({
+			description: "Requested path, with symlinks resolved",
+			example: "/Users/john/.ssh/id_ed25519",
+		}),
+		allowedRoots: z.array(z.string()).openapi({
+			description: "Folders the file system API may access",
+			example: ["/Users/john/hello"],
+		}),
+	})
+	.openapi("PathForbidden");
+
 /**
  * TypeScript types for file system operations
  */
 export type FileEntry = z.infer<typeof FileEntrySchema>;
 export type FolderListing = z.infer<typeof FolderListingSchema>;
+export type FileGitStatus = z.infer<typeof FileGitStatusSchema>;
+export type FileTreeEntry = z.infer<typeof FileTreeEntrySchema>;
+export type FileTree = z.infer<typeof FileTreeSchema>;
+export type FileContent = z.infer<typeof FileContentSchema>;
+export type FileVersion = z.infer<typeof FileVersionSchema>;
+export type FileOperation = z.infer<typeof FileOperationSchema>;
+export type FileUpload = z.infer<typeof FileUploadSchema>;
+export type TrashEntry = z.infer<typeof TrashEntrySchema>;
@@ -76,6 +76,18 @@ export const GitCheckoutSchema = z.object({
 		example: "Successfully checked out branch: main",
 		description: "Success or error message",
 	}),
+	refType: z.enum(["local", "remote", "tag", "commit"]).optional().openapi({
+		example: "remote",
+		description: "Kind of ref that was checked out, as resolved by git",
+	}),
+	branch: z.string().optional().openapi({
+		example: "feature/login",
+		description: "Local branch now checked out, absent when HEAD is detached",
+	}),
+	detached: z.boolean().optional().openapi({
+		example: false,
+		description: "Whether HEAD is now detached",
+	}),
 	data: GitStatusSchema.optional().openapi({
 		description: "Updated git repository information",
 	}),
@@ -104,19 +116,127 @@ export const GitLogEntrySchema = z.object({
 	}),
 });
 
-export const GitStatusFileEntrySchema = z.object({
-	status: z.string().openapi({
+export const GitConflictTypeSchema = z
+	.enum([
+		"both-modified",
+		"both-added",
+		"deleted-by-us",
+		"deleted-by-them",
+		"added-by-us",
+		"added-by-them",
+		"both-deleted",
+	])
+	.openapi({
+		example: "both-modified",
+		description: "How the two sides conflict",
+	});
+
+export const GitFileStateSchema = z
+	.enum([".", "M", "T", "A", "D", "R", "C", "U", "?", "!"])
+	.openapi({
 		example: "M",
 		description:
-			"Git status code (M=modified, A=added, D=deleted, R=renamed, etc.)",
+			"Porcelain v2 state letter (.=unmodified, T=type changed, U=unmerged, ?=untracked, !=ignored)",
+	});
+
+export const GitSubmoduleStateSchema = z.object({
+	commitChanged: z.boolean().openapi({
+		example: false,
+		description: "Whether the submodule points at a different commit",
 	}),
-	path: z.string().openapi({
-		example: "src/index.ts",
-		description: "File path relative to repository root",
+	trackedChanges: z.boolean().openapi({
+		example: true,
+		description: "Whether the submodule has modified tracked files",
 	}),
-	description: z.string().openapi({
-		example: "modified",
-		description: "Human-readable description of the change",
+	untrackedChanges: z.boolean().openapi({
+		example: false,
+		description: "Whether the submodule has untracked files",
+	}),
+});
+
+export const GitStatusFileEntrySchema = z
+	.object({
+		status: z.string().openapi({
+			example: "M",
+			description:
+				"Git status code (M=modified, A=added, D=deleted, R=renamed, etc.)",
+		}),
+		path: z.string().openapi({
+			example: "src/index.ts",
+			description: "File path relative to repository root",
+		}),
+		description: z.string().openapi({
+			example: "modified",
+			description: "Human-readable description of the change",
+		}),
+		kind: z
+			.enum([
+				"changed",
+				"renamed",
+				"copied",
+				"unmerged",
+				"untracked",
+				"ignored",
+			])
+			.openapi({
+				example: "changed",
+				description: "Kind of porcelain v2 entry",
+			}),
+		indexStatus: GitFileStateSchema,
+		worktreeStatus: GitFileStateSchema,
+		staged: z.boolean().openapi({
+			example: false,
+			description: "Whether the file has changes in the index",
+		}),
+		unstaged: z.boolean().openapi({
+			example: true,
+			description: "Whether the file has changes in the working tree",
+		}),
+		originalPath: z.string().optional().openapi({
+			example: "src/main.ts",
+			description: "Path the file was renamed or copied from",
+		}),
+		similarity: z.number().optional().openapi({
+			example: 95,
+			description: "Rename or copy similarity score in percent",
+		}),
+		conflictType: GitConflictTypeSchema.optional(),
+		submodule: GitSubmoduleStateSchema.optional(),
+		insertions: z.number().optional().openapi({
+			example: 12,
+			description: "Lines added across staged and unstaged changes",
+		}),
+		deletions: z.number().optional().openapi({
+			example: 3,
+			description: "Lines deleted across staged and unstaged changes",
+		}),
+		binary: z.boolean().openapi({
+			example: false,
+			description: "Whether git treats the file as binary",
+		}),
+	})
+	.openapi("GitStatusFileEntry");
+
+export const GitBranchStatusSchema = z.object({
+	head: z.string().openapi({
+		example: "main",
+		description: "Current branch, or (detached)",
+	}),
+	oid: z.string().openapi({
+		example: "a1b2c3d",
+		description: "Short commit hash of HEAD, or (initial)",
+	}),
+	upstream: z.string().optional().openapi({
+		example: "origin/main",
+		description: "Upstream branch",
+	}),
+	ahead: z.number().optional().openapi({
+		example: 2,
+		description: "Commits ahead of upstream",
+	}),
+	behind: z.number().optional().openapi({
+		example: 0,
+		description: "Commits behind upstream",
 	}),
 });
 
@@ -131,6 +251,7 @@ export const GitStatusDetailSchema = z.object({
 				example: true,
 				description: "Whether there are any uncommitted changes",
 			}),
+			branch: GitBranchStatusSchema,
 			files: z.array(GitStatusFileEntrySchema).openapi({
 				description: "List of files with changes",
 			}),
@@ -140,6 +261,39 @@ export const GitStatusDetailSchema = z.object({
 		}),
 });
 
+export const GitFileDiffSchema = z.object({
+	success: z.boolean().openapi({
+		example: true,
+		description: "Whether the operation was successful",
+	}),
+	data: z.object({
+		path: z.string().openapi({
+			example: "src/index.ts",
+			description: "File path relative to repository root",
+		}),
+		originalPath: z.string().optional().openapi({
+			example: "src/main.ts",
+			description: "Path the file was renamed or copied from",
+		}),
+		staged: z.boolean().openapi({
+			example: false,
+			description: "Whether the diff is of the index instead of the worktree",
+		}),
+		untracked: z.boolean().openapi({
+			example: false,
+			description: "Whether the file is untracked and diffed as new",
+		}),
+		binary: z.boolean().openapi({
+			example: false,
+			description: "Whether git treats the file as binary",
+		}),
+		diff: z.string().openapi({
+			example: "diff --git a/src/index.ts b/src/index.ts\n...",
+			description: "Unified diff of the file",
+		}),
+	}),
+});
+
 export const GitLogSchema = z.object({
 	success: z.boolean().openapi({
 		example: true,
@@ -160,6 +314,320 @@ export const GitLogSchema = z.object({
 		}),
 });
 
+export const GitRemoteSchema = z.object({
+	name: z.string().openapi({
+		example: "origin",
+		description: "Remote name",
+	}),
+	fetchUrl: z.string().openapi({
+		example: "git@github.com:user/repo.git",
+		description: "URL used for fetching",
+	}),
+	pushUrl: z.string().openapi({
+		example: "git@github.com:user/repo.git",
+		description: "URL used for pushing",
+	}),
+});
+
+export const GitRemoteListSchema = z.object({
+	success: z.boolean().openapi({
+		example: true,
+		description: "Whether the operation was successful",
+	}),
+	data: z.array(GitRemoteSchema).openapi({
+		description: "Configured remotes",
+	}),
+});
+
+export const GitRefUpdateSchema = z
+	.object({
+		status: z
+			.enum([
+				"fast-forward",
+				"forced",
+				"new",
+				"deleted",
+				"rejected",
+				"up-to-date",
+				"tag-update",
+			])
+			.openapi({
+				example: "fast-forward",
+				description: "How the ref was updated",
+			}),
+		from: z.string().openapi({
+			example: "main",
+			description: "Source ref",
+		}),
+		to: z.string().openapi({
+			example: "origin/main",
+			description: "Destination ref",
+		}),
+		summary: z.string().openapi({
+			example: "1a2b3c4..5d6e7f8",
+			description: "Summary of the update as reported by git",
+		}),
+		reason: z.string().optional().openapi({
+			example: "non-fast-forward",
+			description: "Reason reported by git, e.g. for rejected refs",
+		}),
+	})
+	.openapi("GitRefUpdate");
+
+export const GitRemoteResultSchema = z
+	.object({
+		success: z.boolean().openapi({
+			example: true,
+			description: "Whether the operation was successful",
+		}),
+		operation: z.enum(["fetch", "pull", "push"]).openapi({
+			example: "fetch",
+			description: "Remote operation that was run",
+		}),
+		remote: z.string().openapi({
+			example: "origin",
+			description: "Remote the operation ran against",
+		}),
+		updatedRefs: z.array(GitRefUpdateSchema).openapi({
+			description: "Refs that were created, updated or deleted",
+		}),
+		rejectedRefs: z.array(GitRefUpdateSchema).openapi({
+			description: "Refs that git refused to update",
+		}),
+		output: z.string().openapi({
+			example: "Fast-forward\n README.md | 2 ++\n",
+			description: "Combined git output without progress lines",
+		}),
+		exitCode: z.number().openapi({
+			example: 0,
+			description: "Git exit code",
+		}),
+	})
+	.openapi("GitRemoteResult");
+
+export const GitFetchRequestSchema = z
+	.object({
+		remote: z.string().min(1).openapi({
+			example: "origin",
+			description: "Remote to fetch from",
+		}),
+		prune: z.boolean().optional().openapi({
+			example: false,
+			description:
+				"Remove remote-tracking refs that no longer exist on the remote",
+		}),
+	})
+	.openapi("GitFetchRequest");
+
+export const GitPullRequestSchema = z
+	.object({
+		remote: z.string().min(1).openapi({
+			example: "origin",
+			description: "Remote to pull from",
+		}),
+		branch: z.string().min(1).optional().openapi({
+			example: "main",
+			description:
+				"Remote branch to pull. Defaults to the configured upstream",
+		}),
+		ffOnly: z.boolean().optional().openapi({
+			example: true,
+			description:
+				"Refuse to merge and only update if a fast-forward is possible",
+		}),
+	})
+	.openapi("GitPullRequest");
+
+export const GitPushRequestSchema = z
+	.object({
+		remote: z.string().min(1).openapi({
+			example: "origin",
+			description: "Remote to push to",
+		}),
+		branch: z.string().min(1).optional().openapi({
+			example: "main",
+			description:
+				"Local branch or ref to push. Defaults to the current branch",
+		}),
+		remoteBranch: z.string().min(1).optional().openapi({
+			example: "main",
+			description:
+				"Branch name on the remote. Defaults to the local branch name",
+		}),
+		setUpstream: z.boolean().optional().openapi({
+			example: false,
+			description: "Set the pushed branch as upstream of the local branch",
+		}),
+		force: z.boolean().optional().openapi({
+			example: false,
+			description: "Force push using --force-with-lease",
+		}),
+	})
+	.openapi("GitPushRequest");
+
+export const GitConflictOperationSchema = z
+	.enum(["merge", "cherry-pick", "rebase"])
+	.openapi({
+		example: "merge",
+		description: "Operation that stopped on conflicts",
+	});
+
+export const GitConflictFileSchema = z.object({
+	path: z.string().openapi({
+		example: "src/index.ts",
+		description: "File path relative to the repository root",
+	}),
+	conflictType: GitConflictTypeSchema,
+});
+
+export const GitConflictStatusSchema = z
+	.object({
+		inProgress: z.boolean().openapi({
+			example: true,
+			description: "Whether a merge, cherry-pick or rebase is in progress",
+		}),
+		operation: GitConflictOperationSchema.optional(),
+		root: z.string().openapi({
+			example: "/home/user/hello",
+			description: "Absolute path of the repository root",
+		}),
+		head: z.string().optional().openapi({
+			example: "main",
+			description: "Branch (or HEAD) the changes are applied to",
+		}),
+		incoming: z.string().optional().openapi({
+			example: "container-use/sharing-loon",
+			description: "Commit or branch being merged, picked or replayed",
+		}),
+		files: z.array(GitConflictFileSchema).openapi({
+			description: "Files that still have unresolved conflicts",
+		}),
+	})
+	.openapi("GitConflictStatus");
+
+export const GitConflictStatusResponseSchema = z.object({
+	success: z.boolean().openapi({
+		example: true,
+		description: "Whether the operation was successful",
+	}),
+	data: GitConflictStatusSchema,
+});
+
+export const GitConflictVersionsSchema = z.object({
+	success: z.boolean().openapi({
+		example: true,
+		description: "Whether the operation was successful",
+	}),
+	data: z.object({
+		path: z.string().openapi({
+			example: "src/index.ts",
+			description: "File path relative to the repository root",
+		}),
+		base: z.string().optional().openapi({
+			description: "Common ancestor version, absent if the file was added",
+		}),
+		ours: z.string().optional().openapi({
+			description: "Version on the current branch, absent if deleted there",
+		}),
+		theirs: z.string().optional().openapi({
+			description: "Incoming version, absent if deleted there",
+		}),
+		merged: z.string().optional().openapi({
+			description: "Working tree version including conflict markers",
+		}),
+	}),
+});
+
+export const GitConflictResolveRequestSchema = z.object({
+	path: z.string().min(1).openapi({
+		example: "src/index.ts",
+		description: "Conflicted file path relative to the repository root",
+	}),
+	resolution: z.enum(["ours", "theirs", "content"]).openapi({
+		example: "content",
+		description: "Pick one side, or use the provided content",
+	}),
+	content: z.string().optional().openapi({
+		example: "export const answer = 42;\n",
+		description: "Resolved file content, required for content resolutions",
+	}),
+});
+
+export const GitConflictActionSchema = z.object({
+	success: z.boolean().openapi({
+		example: true,
+		description: "Whether the operation was successful",
+	}),
+	message: z.string().openapi({
+		example: "Merge continued",
+		description: "Success or error message",
+	}),
+	output: z.string().openapi({
+		example: "[main 1a2b3c4] Merge branch 'feature'",
+		description: "Git output",
+	}),
+	data: GitConflictStatusSchema,
+});
+
+export const GitWorktreeSchema = z
+	.object({
+		path: z.string().openapi({
+			example: "/home/user/.cuweb/worktrees/hello-1a2b3c4d/sharing-loon",
+			description: "Absolute path of the worktree",
+		}),
+		head: z.string().openapi({
+			example: "a1b2c3d",
+			description: "Short hash of the checked out commit",
+		}),
+		branch: z.string().optional().openapi({
+			example: "cu-sharing-loon",
+			description: "Checked out branch, absent when HEAD is detached",
+		}),
+		main: z.boolean().openapi({
+			example: false,
+			description: "Whether this is the main worktree of the repository",
+		}),
+		managed: z.boolean().openapi({
+			example: true,
+			description: "Whether the worktree was created by cuweb",
+		}),
+		environmentId: z.string().optional().openapi({
+			example: "sharing-loon",
+			description: "Environment the worktree was created for",
+		}),
+		locked: z.boolean().openapi({
+			example: false,
+			description: "Whether the worktree is locked against removal",
+		}),
+		prunable: z.boolean().openapi({
+			example: false,
+			description: "Whether the worktree folder is missing and can be pruned",
+		}),
+	})
+	.openapi("GitWorktree");
+
+export const GitWorktreeListSchema = z.object({
+	success: z.boolean().openapi({
+		example: true,
+		description: "Whether the operation was successful",
+	}),
+	data: z.array(GitWorktreeSchema).openapi({
+		description: "Worktrees of the repository, main worktree first",
+	}),
+});
+
+export const GitWorktreeResultSchema = z.object({
+	success: z.boolean().openapi({
+		example: true,
+		description: "Whether the operation was successful",
+	}),
+	message: z.string().openapi({
+		example: "Created worktree for sharing-loon",
+		description: "Success or error message",
+	}),
+	data: GitWorktreeSchema.optional(),
+});
+
 export type GitBranch = z.infer<typeof GitBranchSchema>;
 export type GitStatus = z.infer<typeof GitStatusSchema>;
 export type GitInfo = z.infer<typeof GitInfoSchema>;
@@ -168,3 +636,17 @@ export type GitLogEntry = z.infer<typeof GitLogEntrySchema>;
 export type GitLog = z.infer<typeof GitLogSchema>;
 export type GitStatusFileEntry = z.infer<typeof GitStatusFileEntrySchema>;
 export type GitStatusDetail = z.infer<typeof GitStatusDetailSchema>;
+export type GitFileState = z.infer<typeof GitFileStateSchema>;
+export type GitBranchStatus = z.infer<typeof GitBranchStatusSchema>;
+export type GitFileDiff = z.infer<typeof GitFileDiffSchema>["data"];
+export type GitRemote = z.infer<typeof GitRemoteSchema>;
+export type GitRefUpdate = z.infer<typeof GitRefUpdateSchema>;
+export type GitRemoteResult = z.infer<typeof GitRemoteResultSchema>;
+export type GitConflictOperation = z.infer<typeof GitConflictOperationSchema>;
+export type GitConflictType = z.infer<typeof GitConflictTypeSchema>;
+export type GitConflictFile = z.infer<typeof GitConflictFileSchema>;
+export type GitConflictStatus = z.infer<typeof GitConflictStatusSchema>;
+export type GitConflictVersions = z.infer<
+	typeof GitConflictVersionsSchema
+>["data"];
+export type GitWorktree = z.infer<typeof GitWorktreeSchema>;
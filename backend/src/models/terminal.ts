@@ -0,0 +1,263 @@
+import { z } from "@hono/zod-openapi";
+
+export const TerminalParticipantSchema = z
+	.object({
+		id: z.string().openapi({
+			example: "6d0e1b7a-9c4f-4d52-8b1e-2f7a3c5d9e10",
+			description: "ID of the connection, new for every reconnect",
+		}),
+		name: z.string().openapi({
+			example: "Ada",
+			description: "Name the client joined with",
+		}),
+		role: z.enum(["driver", "viewer"]).openapi({
+			example: "driver",
+			description:
+				"The driver types into the terminal, viewers only see its output",
+		}),
+		joinedAt: z.string().openapi({
+			example: "2025-08-01T12:00:00.000Z",
+			description: "When the client attached",
+		}),
+	})
+	.openapi("TerminalParticipant");
+
+export const TerminalSessionSchema = z
+	.object({
+		id: z.string().openapi({
+			example: "0b6c2f4e-3f1a-4c1e-9a57-5d2f8b1e7c90",
+			description: "Session ID, used to reattach to the session",
+		}),
+		kind: z.enum(["shell", "environment", "watch"]).openapi({
+			example: "environment",
+			description:
+				"Plain shell, container-use terminal of an environment, or container-use watch",
+		}),
+		environmentId: z.string().optional().openapi({
+			example: "sharing-loon",
+			description: "Environment the terminal is opened in",
+		}),
+		workingDir: z.string().openapi({
+			example: "/Users/john/hello",
+			description: "Folder the session was started in",
+		}),
+		pid: z.number().int().openapi({
+			example: 48213,
+			description: "Process ID of the session's shell",
+		}),
+		process: z.string().openapi({
+			example: "container-use",
+			description: "Name of the process in the foreground of the terminal",
+		}),
+		startedAt: z.string().openapi({
+			example: "2025-08-01T12:00:00.000Z",
+			description: "When the session was started",
+		}),
+		clients: z.number().int().openapi({
+			example: 1,
+			description:
+				"Number of attached browser connections, sessions without any are closed after the idle timeout",
+		}),
+		participants: z.array(TerminalParticipantSchema).openapi({
+			description: "Attached clients, in the order they joined",
+		}),
+		lastActivity: z.string().openapi({
+			example: "2025-08-01T12:05:30.000Z",
+			description: "Last input or output of the terminal",
+		}),
+		recordingId: z.string().optional().openapi({
+			example: "20250801-120000-sharing-loon-k3x9qa",
+			description: "Recording the session output is written to, if any",
+		}),
+	})
+	.openapi("TerminalSession");
+
+export const TerminalSessionListSchema = z
+	.object({
+		sessions: z.array(TerminalSessionSchema).openapi({
+			description: "Running sessions, oldest first",
+		}),
+	})
+	.openapi("TerminalSessionList");
+
+export const TerminalSignalRequestSchema = z
+	.object({
+		signal: z
+			.enum([
+				"SIGINT",
+				"SIGTERM",
+				"SIGKILL",
+				"SIGHUP",
+				"SIGQUIT",
+				"SIGTSTP",
+				"SIGCONT",
+				"SIGUSR1",
+				"SIGUSR2",
+			])
+			.openapi({
+				example: "SIGINT",
+				description: "Signal to send to the foreground process",
+			}),
+		token: z.string().optional().openapi({
+			example: "5e0c7a1d-8b2f-4f6e-a3c9-1d7b2e4f6a80",
+			description:
+				"Token from the caller's presence messages, only the session's driver can signal it",
+		}),
+	})
+	.openapi("TerminalSignalRequest");
+
+export const TerminalSessionActionSchema = z
+	.object({
+		success: z.boolean().openapi({
+			example: true,
+			description: "Whether the action succeeded",
+		}),
+		message: z.string().openapi({
+			example: "Sent SIGINT to session 0b6c2f4e-3f1a-4c1e-9a57-5d2f8b1e7c90",
+			description: "Success or error message",
+		}),
+	})
+	.openapi("TerminalSessionAction");
+
+export const TerminalRecordingSchema = z
+	.object({
+		id: z.string().openapi({
+			example: "20250801-120000-sharing-loon-k3x9qa",
+			description: "Recording ID, the name of its .cast file",
+		}),
+		title: z.string().optional().openapi({
+			example: "container-use terminal sharing-loon",
+			description: "What was recorded",
+		}),
+		width: z.number().int().openapi({
+			example: 120,
+			description: "Terminal columns when the recording started",
+		}),
+		height: z.number().int().openapi({
+			example: 30,
+			description: "Terminal rows when the recording started",
+		}),
+		startedAt: z.string().openapi({
+			example: "2025-08-01T12:00:00.000Z",
+			description: "When the recording started",
+		}),
+		duration: z.number().openapi({
+			example: 95.2,
+			description: "Length of the recording in seconds",
+		}),
+		size: z.number().int().openapi({
+			example: 48213,
+			description: "Size of the .cast file in bytes",
+		}),
+		active: z.boolean().openapi({
+			example: false,
+			description: "Whether the session is still being recorded",
+		}),
+	})
+	.openapi("TerminalRecording");
+
+export const TerminalRecordingListSchema = z
+	.object({
+		recordings: z.array(TerminalRecordingSchema).openapi({
+			description: "Recordings, newest first",
+		}),
+	})
+	.openapi("TerminalRecordingList");
+
+export const TerminalSnippetSchema = z
+	.object({
+		name: z.string().openapi({
+			example: "test",
+			description: "Name of the snippet",
+		}),
+		command: z.string().openapi({
+			example: "go test ./... -run {{pattern}}",
+			description: "Command template, {{name}} placeholders are variables",
+		}),
+		description: z.string().optional().openapi({
+			example: "Run the tests matching a pattern",
+			description: "What the snippet does",
+		}),
+		source: z.enum(["repository", "global"]).openapi({
+			example: "repository",
+			description:
+				"Whether the snippet comes from the repository's .cuweb.json or the global config",
+		}),
+		variables: z.array(z.string()).openapi({
+			example: ["pattern"],
+			description:
+				"Variables to ask for, environmentId and folder are filled in from the terminal",
+		}),
+	})
+	.openapi("TerminalSnippet");
+
+export const TerminalSnippetListSchema = z
+	.object({
+		snippets: z.array(TerminalSnippetSchema).openapi({
+			description: "Snippets of the repository and global ones, by name",
+		}),
+	})
+	.openapi("TerminalSnippetList");
+
+export const TerminalSnippetRunRequestSchema = z
+	.object({
+		name: z.string().min(1).openapi({
+			example: "test",
+			description: "Name of the snippet to run",
+		}),
+		sessionId: z.string().optional().openapi({
+			example: "0b6c2f4e-3f1a-4c1e-9a57-5d2f8b1e7c90",
+			description:
+				"Session to type the command into, a new session is started without it",
+		}),
+		token: z.string().optional().openapi({
+			example: "5e0c7a1d-8b2f-4f6e-a3c9-1d7b2e4f6a80",
+			description:
+				"Token from the caller's presence messages, required with a session as only its driver can type into it",
+		}),
+		environmentId: z.string().optional().openapi({
+			example: "sharing-loon",
+			description:
+				"Environment to open the new session in, a plain shell without it",
+		}),
+		variables: z.record(z.string(), z.string()).optional().openapi({
+			example: { pattern: "TestLogin" },
+			description: "Values of the snippet's variables",
+		}),
+	})
+	.openapi("TerminalSnippetRunRequest");
+
+export const TerminalSnippetRunSchema = z
+	.object({
+		sessionId: z.string().openapi({
+			example: "0b6c2f4e-3f1a-4c1e-9a57-5d2f8b1e7c90",
+			description: "Session the command was typed into",
+		}),
+		command: z.string().openapi({
+			example: "go test ./... -run TestLogin",
+			description: "Command with the variables filled in",
+		}),
+		created: z.boolean().openapi({
+			example: false,
+			description: "Whether the session was started for the snippet",
+		}),
+	})
+	.openapi("TerminalSnippetRun");
+
+export type TerminalParticipant = z.infer<typeof TerminalParticipantSchema>;
+export type TerminalSession = z.infer<typeof TerminalSessionSchema>;
+export type TerminalSessionList = z.infer<typeof TerminalSessionListSchema>;
+export type TerminalSignalRequest = z.infer<typeof TerminalSignalRequestSchema>;
+export type TerminalSessionAction = z.infer<
+	typeof TerminalSessionActionSchema
+>;
+export type TerminalRecording = z.infer<typeof TerminalRecordingSchema>;
+export type TerminalRecordingList = z.infer<
+	typeof TerminalRecordingListSchema
+>;
+export type TerminalSnippet = z.infer<typeof TerminalSnippetSchema>;
+export type TerminalSnippetList = z.infer<typeof TerminalSnippetListSchema>;
+export type TerminalSnippetRunRequest = z.infer<
+	typeof TerminalSnippetRunRequestSchema
+>;
+export type TerminalSnippetRun = z.infer<typeof TerminalSnippetRunSchema>;
@@ -1,5 +1,3 @@
-import * as os from "node:os";
-import * as path from "node:path";
 import { createRoute, OpenAPIHono, z } from "@hono/zod-openapi";
 import {
 	EnvironmentApplySchema,
@@ -10,6 +8,11 @@ import {
 	EnvironmentMergeSchema,
 	ErrorSchema,
 } from "../models/environment.js";
+import { PathForbiddenSchema } from "../models/filesystem.js";
+import {
+	GitRemoteResultSchema,
+	GitWorktreeResultSchema,
+} from "../models/git.js";
 import {
 	createCLIErrorResponse,
 	executeCLICommand,
@@ -20,23 +23,23 @@ import {
 	getDefaultCLIPath,
 	getDefaultWorkingDir,
 } from "../utils/constants.js";
+import { getConflictedFiles } from "../utils/git-conflicts.js";
+import {
+	GitRemoteError,
+	getEnvironmentPushOptions,
+	runGitRemoteOperation,
+} from "../utils/git-remote.js";
+import {
+	createEnvironmentWorktree,
+	GitWorktreeError,
+} from "../utils/git-worktree.js";
 import { parseEnvironmentList } from "../utils/parser.js";
-
-/**
- * Resolves a directory path, handling special cases like '.' and '~'
- */
-function resolveDirectory(dir: string): string {
-	if (dir === ".") {
-		return process.cwd();
-	}
-	if (dir.startsWith("~/")) {
-		return path.join(os.homedir(), dir.slice(2));
-	}
-	if (dir === "~") {
-		return os.homedir();
-	}
-	return path.resolve(dir);
-}
+import {
+	PathAccessError,
+	pathForbiddenResponse,
+	resolveAllowedPath,
+	resolveDirectory,
+} from "../utils/path-access.js";
 
 // Helper function to get git repository information
 async function getGitInfo(workingDir: string) {
@@ -115,6 +118,14 @@ export const environmentListRoute = createRoute({
 			},
 			description: "List of environments with git repository information",
 		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
 		500: {
 			content: {
 				"application/json": {
@@ -175,6 +186,14 @@ export const environmentLogsRoute = createRoute({
 			},
 			description: "Environment logs",
 		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
 		500: {
 			content: {
 				"application/json": {
@@ -235,6 +254,14 @@ export const environmentDiffRoute = createRoute({
 			},
 			description: "Environment diff",
 		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
 		500: {
 			content: {
 				"application/json": {
@@ -295,6 +322,22 @@ export const environmentApplyRoute = createRoute({
 			},
 			description: "Environment applied successfully",
 		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Stopped on conflicts that must be resolved or aborted",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
 		500: {
 			content: {
 				"application/json": {
@@ -355,6 +398,22 @@ export const environmentMergeRoute = createRoute({
 			},
 			description: "Environment merged successfully",
 		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Stopped on conflicts that must be resolved or aborted",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
 		500: {
 			content: {
 				"application/json": {
@@ -415,6 +474,162 @@ export const environmentCheckoutRoute = createRoute({
 			},
 			description: "Environment checked out successfully",
 		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to push an environment branch to a remote for review
+export const environmentPushRoute = createRoute({
+	method: "post",
+	path: "/environments/{id}/push",
+	request: {
+		params: z.object({
+			id: z.string().openapi({
+				param: {
+					name: "id",
+					in: "path",
+				},
+				example: "sharing-loon",
+				description: "Environment ID",
+			}),
+		}),
+		query: z.object({
+			folder: z
+				.string()
+				.optional()
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Working folder for the CLI command",
+				}),
+		}),
+		body: {
+			content: {
+				"application/json": {
+					schema: z.object({
+						remote: z.string().min(1).openapi({
+							example: "origin",
+							description: "Remote to push the environment branch to",
+						}),
+						remoteBranch: z.string().min(1).optional().openapi({
+							example: "cu-sharing-loon",
+							description:
+								"Branch name on the remote. Defaults to cu-<environment id>",
+						}),
+					}),
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitRemoteResultSchema,
+				},
+			},
+			description: "Push result, including updated and rejected refs",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (unknown remote or invalid branch name)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to open an environment in a separate git worktree
+export const environmentWorktreeRoute = createRoute({
+	method: "post",
+	path: "/environments/{id}/worktree",
+	request: {
+		params: z.object({
+			id: z.string().openapi({
+				param: {
+					name: "id",
+					in: "path",
+				},
+				example: "sharing-loon",
+				description: "Environment ID",
+			}),
+		}),
+		query: z.object({
+			folder: z
+				.string()
+				.optional()
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Working folder for the CLI command",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitWorktreeResultSchema,
+				},
+			},
+			description: "Worktree for the environment, created if needed",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description:
+				"Bad request (invalid environment or branch checked out elsewhere)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
 		500: {
 			content: {
 				"application/json": {
@@ -438,6 +653,9 @@ environments.openapi(environmentListRoute, async (c) => {
 	const cliPath = cli || getDefaultCLIPath();
 
 	try {
+		// Only run in folders inside the allowed roots
+		await resolveAllowedPath(workingDir);
+
 		// Get git repository information
 		const gitInfo = await getGitInfo(workingDir);
 
@@ -479,6 +697,9 @@ environments.openapi(environmentListRoute, async (c) => {
 			200,
 		);
 	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
 		console.error("CLI command failed:", error);
 		const errorResponse = createCLIErrorResponse(
 			"Failed to fetch environments",
@@ -502,6 +723,9 @@ environments.openapi(environmentLogsRoute, async (c) => {
 	const cliPath = cli || getDefaultCLIPath();
 
 	try {
+		// Only run in folders inside the allowed roots
+		await resolveAllowedPath(workingDir);
+
 		const result = await executeCLICommand({
 			command: CLI_COMMANDS.LOG,
 			args: [id],
@@ -544,6 +768,9 @@ environments.openapi(environmentLogsRoute, async (c) => {
 			200,
 		);
 	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
 		console.error("CLI log command failed:", error);
 		const errorResponse = createCLIErrorResponse(
 			"Failed to fetch environment logs",
@@ -567,6 +794,9 @@ environments.openapi(environmentDiffRoute, async (c) => {
 	const cliPath = cli || getDefaultCLIPath();
 
 	try {
+		// Only run in folders inside the allowed roots
+		await resolveAllowedPath(workingDir);
+
 		const result = await executeCLICommand({
 			command: CLI_COMMANDS.DIFF,
 			args: [id],
@@ -609,6 +839,9 @@ environments.openapi(environmentDiffRoute, async (c) => {
 			200,
 		);
 	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
 		console.error("CLI diff command failed:", error);
 		const errorResponse = createCLIErrorResponse(
 			"Failed to fetch environment diff",
@@ -632,6 +865,9 @@ environments.openapi(environmentApplyRoute, async (c) => {
 	const cliPath = cli || getDefaultCLIPath();
 
 	try {
+		// Only run in folders inside the allowed roots
+		await resolveAllowedPath(workingDir);
+
 		const result = await executeCLICommand({
 			command: CLI_COMMANDS.APPLY,
 			args: [id],
@@ -656,6 +892,16 @@ environments.openapi(environmentApplyRoute, async (c) => {
 					200,
 				);
 			}
+			// The repository is left mid-merge so the conflicts can be resolved
+			if ((await getConflictedFiles(workingDir)).length > 0) {
+				const errorResponse = createCLIErrorResponse(
+					"Stopped on conflicts, resolve them or abort",
+					result,
+					`${cliPath} ${CLI_COMMANDS.APPLY}`,
+					workingDir,
+				);
+				return c.json(errorResponse, 409);
+			}
 			console.error("CLI apply command failed:", result.stderr);
 			const errorResponse = createCLIErrorResponse(
 				"Failed to apply environment",
@@ -676,6 +922,9 @@ environments.openapi(environmentApplyRoute, async (c) => {
 			200,
 		);
 	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
 		console.error("CLI apply command failed:", error);
 		const errorResponse = createCLIErrorResponse(
 			"Failed to apply environment",
@@ -699,6 +948,9 @@ environments.openapi(environmentMergeRoute, async (c) => {
 	const cliPath = cli || getDefaultCLIPath();
 
 	try {
+		// Only run in folders inside the allowed roots
+		await resolveAllowedPath(workingDir);
+
 		const result = await executeCLICommand({
 			command: CLI_COMMANDS.MERGE,
 			args: [id],
@@ -723,6 +975,16 @@ environments.openapi(environmentMergeRoute, async (c) => {
 					200,
 				);
 			}
+			// The repository is left mid-merge so the conflicts can be resolved
+			if ((await getConflictedFiles(workingDir)).length > 0) {
+				const errorResponse = createCLIErrorResponse(
+					"Stopped on conflicts, resolve them or abort",
+					result,
+					`${cliPath} ${CLI_COMMANDS.MERGE}`,
+					workingDir,
+				);
+				return c.json(errorResponse, 409);
+			}
 			console.error("CLI merge command failed:", result.stderr);
 			const errorResponse = createCLIErrorResponse(
 				"Failed to merge environment",
@@ -743,6 +1005,9 @@ environments.openapi(environmentMergeRoute, async (c) => {
 			200,
 		);
 	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
 		console.error("CLI merge command failed:", error);
 		const errorResponse = createCLIErrorResponse(
 			"Failed to merge environment",
@@ -766,6 +1031,9 @@ environments.openapi(environmentCheckoutRoute, async (c) => {
 	const cliPath = cli || getDefaultCLIPath();
 
 	try {
+		// Only run in folders inside the allowed roots
+		await resolveAllowedPath(workingDir);
+
 		const result = await executeCLICommand({
 			command: CLI_COMMANDS.CHECKOUT,
 			args: [id],
@@ -810,6 +1078,9 @@ environments.openapi(environmentCheckoutRoute, async (c) => {
 			200,
 		);
 	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
 		console.error("CLI checkout command failed:", error);
 		const errorResponse = createCLIErrorResponse(
 			"Failed to checkout environment",
@@ -822,4 +1093,103 @@ environments.openapi(environmentCheckoutRoute, async (c) => {
 	}
 });
 
+// Mount the environment push route
+environments.openapi(environmentPushRoute, async (c) => {
+	const { id } = c.req.valid("param");
+	const { folder } = c.req.valid("query");
+	const { remote, remoteBranch } = c.req.valid("json");
+
+	// Get the folder parameter from query string, default to working directory
+	const workingDir = folder ? resolveDirectory(folder) : getDefaultWorkingDir();
+
+	try {
+		// Only run in folders inside the allowed roots
+		await resolveAllowedPath(workingDir);
+
+		const result = await runGitRemoteOperation(
+			getEnvironmentPushOptions(workingDir, id, remote, remoteBranch),
+		);
+
+		if (!result.success) {
+			console.error("Environment push failed:", result.output);
+		}
+
+		return c.json(result, 200);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof GitRemoteError) {
+			const errorResponse = createCLIErrorResponse(
+				error.message,
+				null,
+				"git push",
+				workingDir,
+			);
+			return c.json(errorResponse, 400);
+		}
+		console.error("Environment push failed:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to push environment",
+			null,
+			"git push",
+			workingDir,
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
+
+// Mount the environment worktree route
+environments.openapi(environmentWorktreeRoute, async (c) => {
+	const { id } = c.req.valid("param");
+	const { folder } = c.req.valid("query");
+
+	// Get the folder parameter from query string, default to working directory
+	const workingDir = folder ? resolveDirectory(folder) : getDefaultWorkingDir();
+
+	try {
+		// Only run in folders inside the allowed roots
+		await resolveAllowedPath(workingDir);
+
+		const { created, worktree } = await createEnvironmentWorktree(
+			workingDir,
+			id,
+		);
+
+		return c.json(
+			{
+				success: true,
+				message: created
+					? `Created worktree for ${id}`
+					: `Worktree for ${id} already exists`,
+				data: worktree,
+			},
+			200,
+		);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof GitWorktreeError) {
+			const errorResponse = createCLIErrorResponse(
+				error.message,
+				null,
+				"git worktree add",
+				workingDir,
+			);
+			return c.json(errorResponse, 400);
+		}
+		console.error("Environment worktree creation failed:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to create environment worktree",
+			null,
+			"git worktree add",
+			workingDir,
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
+
 export type AppType = typeof environments;
@@ -1,9 +1,64 @@
-import { promises as fs } from "node:fs";
-import * as os from "node:os";
+import { createReadStream, promises as fs } from "node:fs";
 import * as path from "node:path";
+import { Readable } from "node:stream";
 import { createRoute, OpenAPIHono, z } from "@hono/zod-openapi";
 import { ErrorSchema } from "../models/environment.js";
-import { FolderListingSchema } from "../models/filesystem.js";
+import {
+	FileContentSchema,
+	FileCreateRequestSchema,
+	FileOperationResultSchema,
+	FileRestoreRequestSchema,
+	FileSaveConflictSchema,
+	FileSaveRequestSchema,
+	FileSaveResultSchema,
+	FileTransferRequestSchema,
+	FileTrashRequestSchema,
+	FileTreeSchema,
+	type FileUpload,
+	FileUploadRequestSchema,
+	FileUploadResultSchema,
+	FolderListingSchema,
+	PathForbiddenSchema,
+	TrashListingSchema,
+} from "../models/filesystem.js";
+import { getDefaultWorkingDir } from "../utils/constants.js";
+import {
+	ARCHIVE_MIME_TYPES,
+	createFolderArchive,
+	getContentDisposition,
+} from "../utils/file-archive.js";
+import {
+	FileContentError,
+	FileVersionConflictError,
+	getFileETag,
+	getMimeType,
+	getRawContentType,
+	isFileNotModified,
+	readFileContent,
+	writeFileContent,
+} from "../utils/file-content.js";
+import {
+	copyEntry,
+	createEntry,
+	FileOperationError,
+	listTrash,
+	moveEntry,
+	resolveUploadPath,
+	restoreEntry,
+	trashEntry,
+	writeUploadedFile,
+} from "../utils/file-operations.js";
+import {
+	DEFAULT_TREE_DEPTH,
+	getFileTree,
+	MAX_TREE_DEPTH,
+} from "../utils/file-tree.js";
+import {
+	isPathAllowed,
+	PathAccessError,
+	pathForbiddenResponse,
+	resolveAllowedPath,
+} from "../utils/path-access.js";
 
 // Route to list folder contents
 export const folderListRoute = createRoute({
@@ -21,7 +76,7 @@ export const folderListRoute = createRoute({
 					},
 					example: "~/hello",
 					description:
-						"Folder path to list. Defaults to home folder if not provided",
+						"Folder path to list. Defaults to the working directory if not provided",
 				}),
 		}),
 	},
@@ -34,6 +89,807 @@ export const folderListRoute = createRoute({
 			},
 			description: "Folder listing",
 		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to list several levels of a folder with git status
+export const fileTreeRoute = createRoute({
+	method: "get",
+	path: "/files/tree",
+	request: {
+		query: z.object({
+			path: z
+				.string()
+				.optional()
+				.openapi({
+					param: {
+						name: "path",
+						in: "query",
+					},
+					example: "~/hello",
+					description:
+						"Folder to list. Defaults to the working directory if not provided",
+				}),
+			depth: z.coerce
+				.number()
+				.int()
+				.min(1)
+				.max(MAX_TREE_DEPTH)
+				.optional()
+				.openapi({
+					param: {
+						name: "depth",
+						in: "query",
+					},
+					example: DEFAULT_TREE_DEPTH,
+					description: "Number of levels to list",
+				}),
+			showIgnored: z
+				.enum(["true", "false"])
+				.optional()
+				.openapi({
+					param: {
+						name: "showIgnored",
+						in: "query",
+					},
+					example: "false",
+					description: "Also list files and folders ignored by git",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: FileTreeSchema,
+				},
+			},
+			description: "Folder tree",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Path is not a folder",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Folder not found",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to read a file's content
+export const fileContentRoute = createRoute({
+	method: "get",
+	path: "/files/content",
+	request: {
+		query: z.object({
+			path: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "path",
+						in: "query",
+					},
+					example: "~/hello/README.md",
+					description: "File path to read",
+				}),
+			offset: z.coerce
+				.number()
+				.int()
+				.min(0)
+				.optional()
+				.openapi({
+					param: {
+						name: "offset",
+						in: "query",
+					},
+					example: 0,
+					description: "Byte offset to start reading at",
+				}),
+			length: z.coerce
+				.number()
+				.int()
+				.min(1)
+				.optional()
+				.openapi({
+					param: {
+						name: "length",
+						in: "query",
+					},
+					example: 65536,
+					description:
+						"Number of bytes to read. Defaults to and is capped at the maximum file size",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: FileContentSchema,
+				},
+			},
+			description: "File content",
+		},
+		304: {
+			description: "File has not changed since the given ETag or date",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (path is a folder or offset is out of range)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "File is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "File not found",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to read a file's raw bytes, e.g. for images in previews
+export const fileRawRoute = createRoute({
+	method: "get",
+	path: "/files/raw",
+	request: {
+		query: z.object({
+			path: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "path",
+						in: "query",
+					},
+					example: "~/hello/docs/chart.png",
+					description: "File path to read",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/octet-stream": {
+					schema: z.string().openapi({ format: "binary" }),
+				},
+			},
+			description: "File content, with the Content-Type of the file",
+		},
+		304: {
+			description: "File has not changed since the given ETag or date",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (path is a folder)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "File is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "File not found",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to save a file's content
+export const fileSaveRoute = createRoute({
+	method: "put",
+	path: "/files/content",
+	request: {
+		body: {
+			content: {
+				"application/json": {
+					schema: FileSaveRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: FileSaveResultSchema,
+				},
+			},
+			description: "File saved",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (path is a folder)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "File is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Parent folder not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: FileSaveConflictSchema,
+				},
+			},
+			description: "File changed on disk since the expected version",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to create a file or folder
+export const fileCreateRoute = createRoute({
+	method: "post",
+	path: "/files/create",
+	request: {
+		body: {
+			content: {
+				"application/json": {
+					schema: FileCreateRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: FileOperationResultSchema,
+				},
+			},
+			description: "File or folder created",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (invalid path)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Path is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Parent path not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Path already exists",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to rename or move a file or folder
+export const fileMoveRoute = createRoute({
+	method: "post",
+	path: "/files/move",
+	request: {
+		body: {
+			content: {
+				"application/json": {
+					schema: FileTransferRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: FileOperationResultSchema,
+				},
+			},
+			description: "File or folder moved",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (e.g. moving a folder into itself)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Source or destination is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Source not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Destination already exists",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to copy a file or folder
+export const fileCopyRoute = createRoute({
+	method: "post",
+	path: "/files/copy",
+	request: {
+		body: {
+			content: {
+				"application/json": {
+					schema: FileTransferRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: FileOperationResultSchema,
+				},
+			},
+			description: "File or folder copied",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (e.g. copying a folder into itself)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Source or destination is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Source not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Destination already exists",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to move a file or folder to the trash
+export const fileTrashRoute = createRoute({
+	method: "post",
+	path: "/files/trash",
+	request: {
+		body: {
+			content: {
+				"application/json": {
+					schema: FileTrashRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: FileOperationResultSchema,
+				},
+			},
+			description: "File or folder moved to the trash",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (path cannot be deleted)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Path is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Path not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Conflict with an existing path",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to list the trash
+export const trashListRoute = createRoute({
+	method: "get",
+	path: "/files/trash",
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: TrashListingSchema,
+				},
+			},
+			description: "Trashed files and folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to restore a file or folder from the trash
+export const fileRestoreRoute = createRoute({
+	method: "post",
+	path: "/files/trash/restore",
+	request: {
+		body: {
+			content: {
+				"application/json": {
+					schema: FileRestoreRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: FileOperationResultSchema,
+				},
+			},
+			description: "File or folder restored",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (invalid trash ID)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Original path is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Trash item not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Original path is taken",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to download a file, or a folder as an archive
+export const fileDownloadRoute = createRoute({
+	method: "get",
+	path: "/files/download",
+	request: {
+		query: z.object({
+			path: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "path",
+						in: "query",
+					},
+					example: "~/hello/dist",
+					description: "File or folder to download",
+				}),
+			format: z
+				.enum(["zip", "tar.gz"])
+				.optional()
+				.openapi({
+					param: {
+						name: "format",
+						in: "query",
+					},
+					example: "zip",
+					description: "Archive format for folders. Defaults to zip",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/octet-stream": {
+					schema: z.string().openapi({ format: "binary" }),
+				},
+			},
+			description: "File content, or the folder as a zip or tar.gz archive",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Path is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "File or folder not found",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to upload files into a folder
+export const fileUploadRoute = createRoute({
+	method: "post",
+	path: "/files/upload",
+	request: {
+		body: {
+			content: {
+				"multipart/form-data": {
+					schema: FileUploadRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: FileUploadResultSchema,
+				},
+			},
+			description: "Files uploaded",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (no files, invalid file name or not a folder)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder or a file in it is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Folder not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "A file already exists",
+		},
 		500: {
 			content: {
 				"application/json": {
@@ -47,14 +903,74 @@ export const folderListRoute = createRoute({
 
 export const files = new OpenAPIHono();
 
+/**
+ * Error response in the same shape as CLI errors, for file system failures
+ */
+function fileErrorResponse(
+	error: string,
+	stderr: string,
+	command: string,
+	cwd: string,
+) {
+	return {
+		error,
+		details: {
+			exitCode: 1,
+			stderr,
+			command,
+			cwd,
+		},
+	};
+}
+
+/**
+ * Map a failed file operation to an error response and status
+ */
+function fileOperationErrorResponse(
+	err: unknown,
+	command: string,
+	cwd: string,
+) {
+	const errorMessage = err instanceof Error ? err.message : "Unknown error";
+	if (err instanceof FileOperationError) {
+		return {
+			body: fileErrorResponse(errorMessage, errorMessage, command, cwd),
+			status: err.status,
+		};
+	}
+	if ((err as NodeJS.ErrnoException).code === "ENOENT") {
+		return {
+			body: fileErrorResponse("Path not found", errorMessage, command, cwd),
+			status: 404 as const,
+		};
+	}
+	console.error(`File operation error (${command}):`, err);
+	return {
+		body: fileErrorResponse(
+			"File operation failed",
+			errorMessage,
+			command,
+			cwd,
+		),
+		status: 500 as const,
+	};
+}
+
+/**
+ * Describe a path for messages, relative to the folder of the operation
+ */
+function describePath(from: string, to: string): string {
+	return path.relative(path.dirname(from), to) || path.basename(to);
+}
+
 // Mount the folder list route
 files.openapi(folderListRoute, async (c) => {
 	try {
 		const { path: requestedPath } = c.req.valid("query");
-		const targetPath = requestedPath || os.homedir();
+		const targetPath = requestedPath || getDefaultWorkingDir();
 
-		// Resolve and normalize the path
-		const resolvedPath = path.resolve(targetPath);
+		// Resolve and normalize the path, refusing anything outside the roots
+		const resolvedPath = await resolveAllowedPath(targetPath);
 
 		// Check if path exists and is a folder
 		const stats = await fs.stat(resolvedPath);
@@ -107,15 +1023,22 @@ files.openapi(folderListRoute, async (c) => {
 			return a.name.localeCompare(b.name);
 		});
 
+		// Don't offer to navigate up out of the allowed roots
 		const parent = path.dirname(resolvedPath);
 		const response = {
 			path: resolvedPath,
 			items,
-			parent: parent === resolvedPath ? null : parent,
+			parent:
+				parent === resolvedPath || !(await isPathAllowed(parent))
+					? null
+					: parent,
 		};
 
 		return c.json(response, 200);
 	} catch (err) {
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
 		console.error("Folder listing error:", err);
 		const errorMessage = err instanceof Error ? err.message : "Unknown error";
 		return c.json(
@@ -132,3 +1055,581 @@ files.openapi(folderListRoute, async (c) => {
 		);
 	}
 });
+
+// Mount the folder tree route
+files.openapi(fileTreeRoute, async (c) => {
+	const { path: requestedPath, depth, showIgnored } = c.req.valid("query");
+	const resolvedPath = path.resolve(requestedPath || getDefaultWorkingDir());
+
+	try {
+		await resolveAllowedPath(resolvedPath);
+		const stats = await fs.stat(resolvedPath);
+		if (!stats.isDirectory()) {
+			return c.json(
+				fileErrorResponse(
+					"Path is not a folder",
+					`Path ${resolvedPath} is not a folder`,
+					"fs:stat",
+					resolvedPath,
+				),
+				400,
+			);
+		}
+
+		const tree = await getFileTree(resolvedPath, {
+			depth,
+			showIgnored: showIgnored === "true",
+		});
+		// Don't offer to navigate up out of the allowed roots
+		const parent = path.dirname(resolvedPath);
+		return c.json(
+			{
+				...tree,
+				parent:
+					parent === resolvedPath || !(await isPathAllowed(parent))
+						? null
+						: parent,
+			},
+			200,
+		);
+	} catch (err) {
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
+		const errorMessage = err instanceof Error ? err.message : "Unknown error";
+		if ((err as NodeJS.ErrnoException).code === "ENOENT") {
+			return c.json(
+				fileErrorResponse(
+					"Folder not found",
+					errorMessage,
+					"fs:stat",
+					resolvedPath,
+				),
+				404,
+			);
+		}
+		console.error("Folder tree error:", err);
+		return c.json(
+			fileErrorResponse(
+				"Folder could not be listed",
+				errorMessage,
+				"fs:readdir",
+				resolvedPath,
+			),
+			500,
+		);
+	}
+});
+
+// Mount the file content route
+files.openapi(fileContentRoute, async (c) => {
+	const { path: requestedPath, offset, length } = c.req.valid("query");
+	const resolvedPath = path.resolve(requestedPath);
+
+	try {
+		await resolveAllowedPath(resolvedPath);
+
+		// Let the browser revalidate instead of downloading an unchanged file
+		const stats = await fs.stat(resolvedPath);
+		const notModified = isFileNotModified(
+			stats,
+			c.req.header("If-None-Match"),
+			c.req.header("If-Modified-Since"),
+		);
+
+		c.header("ETag", getFileETag(stats));
+		c.header("Last-Modified", stats.mtime.toUTCString());
+		c.header("Cache-Control", "no-cache");
+		if (notModified && stats.isFile()) {
+			return c.body(null, 304);
+		}
+
+		const content = await readFileContent(resolvedPath, { offset, length });
+		return c.json(content, 200);
+	} catch (err) {
+		const errorMessage = err instanceof Error ? err.message : "Unknown error";
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
+		if (err instanceof FileContentError) {
+			return c.json(
+				fileErrorResponse(errorMessage, errorMessage, "fs:read", resolvedPath),
+				400,
+			);
+		}
+		if ((err as NodeJS.ErrnoException).code === "ENOENT") {
+			return c.json(
+				fileErrorResponse(
+					"File not found",
+					errorMessage,
+					"fs:stat",
+					resolvedPath,
+				),
+				404,
+			);
+		}
+		console.error("File content error:", err);
+		return c.json(
+			fileErrorResponse(
+				"File could not be read",
+				errorMessage,
+				"fs:read",
+				resolvedPath,
+			),
+			500,
+		);
+	}
+});
+
+// Mount the raw file route
+files.openapi(fileRawRoute, async (c) => {
+	const { path: requestedPath } = c.req.valid("query");
+	const resolvedPath = path.resolve(requestedPath);
+
+	try {
+		await resolveAllowedPath(resolvedPath);
+		const stats = await fs.stat(resolvedPath);
+		if (stats.isDirectory()) {
+			return c.json(
+				fileErrorResponse(
+					"Path is a folder",
+					`Path ${resolvedPath} is a folder`,
+					"fs:stat",
+					resolvedPath,
+				),
+				400,
+			);
+		}
+
+		c.header("ETag", getFileETag(stats));
+		c.header("Last-Modified", stats.mtime.toUTCString());
+		c.header("Cache-Control", "no-cache");
+		const notModified = isFileNotModified(
+			stats,
+			c.req.header("If-None-Match"),
+			c.req.header("If-Modified-Since"),
+		);
+		if (notModified) {
+			return c.body(null, 304);
+		}
+
+		c.header("Content-Type", await getRawContentType(resolvedPath));
+		c.header("Content-Length", String(stats.size));
+		c.header(
+			"Content-Disposition",
+			getContentDisposition(path.basename(resolvedPath), "inline"),
+		);
+		// Files are shown as they are, so an opened SVG or HTML file must not
+		// run scripts with the API's origin
+		c.header("X-Content-Type-Options", "nosniff");
+		c.header(
+			"Content-Security-Policy",
+			"default-src 'none'; img-src data:; style-src 'unsafe-inline'; sandbox",
+		);
+		const file = createReadStream(resolvedPath);
+		return c.body(Readable.toWeb(file) as ReadableStream, 200);
+	} catch (err) {
+		const errorMessage = err instanceof Error ? err.message : "Unknown error";
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
+		if ((err as NodeJS.ErrnoException).code === "ENOENT") {
+			return c.json(
+				fileErrorResponse(
+					"File not found",
+					errorMessage,
+					"fs:stat",
+					resolvedPath,
+				),
+				404,
+			);
+		}
+		console.error("Raw file error:", err);
+		return c.json(
+			fileErrorResponse(
+				"File could not be read",
+				errorMessage,
+				"fs:read",
+				resolvedPath,
+			),
+			500,
+		);
+	}
+});
+
+// Mount the file save route
+files.openapi(fileSaveRoute, async (c) => {
+	const { path: requestedPath, content, expectedModified, expectedHash } =
+		c.req.valid("json");
+	const resolvedPath = path.resolve(requestedPath);
+
+	try {
+		await resolveAllowedPath(resolvedPath);
+		const version = await writeFileContent(resolvedPath, content, {
+			modified: expectedModified,
+			hash: expectedHash,
+		});
+
+		return c.json(
+			{
+				success: true,
+				message: `Saved ${path.basename(resolvedPath)}`,
+				data: version,
+			},
+			200,
+		);
+	} catch (err) {
+		const errorMessage = err instanceof Error ? err.message : "Unknown error";
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
+		if (err instanceof FileVersionConflictError) {
+			return c.json(
+				{
+					error: errorMessage,
+					path: resolvedPath,
+					current: err.current,
+				},
+				409,
+			);
+		}
+		if (err instanceof FileContentError) {
+			return c.json(
+				fileErrorResponse(errorMessage, errorMessage, "fs:write", resolvedPath),
+				400,
+			);
+		}
+		if ((err as NodeJS.ErrnoException).code === "ENOENT") {
+			return c.json(
+				fileErrorResponse(
+					"Parent folder not found",
+					errorMessage,
+					"fs:write",
+					resolvedPath,
+				),
+				404,
+			);
+		}
+		console.error("File save error:", err);
+		return c.json(
+			fileErrorResponse(
+				"File could not be saved",
+				errorMessage,
+				"fs:write",
+				resolvedPath,
+			),
+			500,
+		);
+	}
+});
+
+// Mount the file create route
+files.openapi(fileCreateRoute, async (c) => {
+	const { path: requestedPath, type, content } = c.req.valid("json");
+	const resolvedPath = path.resolve(requestedPath);
+
+	try {
+		await resolveAllowedPath(resolvedPath);
+		const result = await createEntry(resolvedPath, type, content);
+		return c.json(
+			{
+				success: true,
+				message: `Created ${type} ${path.basename(resolvedPath)}`,
+				data: result,
+			},
+			200,
+		);
+	} catch (err) {
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
+		const { body, status } = fileOperationErrorResponse(
+			err,
+			type === "folder" ? "fs:mkdir" : "fs:create",
+			resolvedPath,
+		);
+		return c.json(body, status);
+	}
+});
+
+// Mount the file move route
+files.openapi(fileMoveRoute, async (c) => {
+	const { source, destination, overwrite } = c.req.valid("json");
+	const sourcePath = path.resolve(source);
+	const destinationPath = path.resolve(destination);
+
+	try {
+		await resolveAllowedPath(sourcePath);
+		await resolveAllowedPath(destinationPath);
+		const result = await moveEntry(sourcePath, destinationPath, overwrite);
+		return c.json(
+			{
+				success: true,
+				message: `Moved ${path.basename(sourcePath)} to ${describePath(
+					sourcePath,
+					destinationPath,
+				)}`,
+				data: result,
+			},
+			200,
+		);
+	} catch (err) {
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
+		const { body, status } = fileOperationErrorResponse(
+			err,
+			"fs:move",
+			sourcePath,
+		);
+		return c.json(body, status);
+	}
+});
+
+// Mount the file copy route
+files.openapi(fileCopyRoute, async (c) => {
+	const { source, destination, overwrite } = c.req.valid("json");
+	const sourcePath = path.resolve(source);
+	const destinationPath = path.resolve(destination);
+
+	try {
+		await resolveAllowedPath(sourcePath);
+		await resolveAllowedPath(destinationPath);
+		const result = await copyEntry(sourcePath, destinationPath, overwrite);
+		return c.json(
+			{
+				success: true,
+				message: `Copied ${path.basename(sourcePath)} to ${describePath(
+					sourcePath,
+					destinationPath,
+				)}`,
+				data: result,
+			},
+			200,
+		);
+	} catch (err) {
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
+		const { body, status } = fileOperationErrorResponse(
+			err,
+			"fs:copy",
+			sourcePath,
+		);
+		return c.json(body, status);
+	}
+});
+
+// Mount the file trash route
+files.openapi(fileTrashRoute, async (c) => {
+	const { path: requestedPath } = c.req.valid("json");
+	const resolvedPath = path.resolve(requestedPath);
+
+	try {
+		await resolveAllowedPath(resolvedPath);
+		const result = await trashEntry(resolvedPath);
+		return c.json(
+			{
+				success: true,
+				message: `Moved ${path.basename(resolvedPath)} to the trash`,
+				data: result,
+			},
+			200,
+		);
+	} catch (err) {
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
+		const { body, status } = fileOperationErrorResponse(
+			err,
+			"fs:trash",
+			resolvedPath,
+		);
+		return c.json(body, status);
+	}
+});
+
+// Mount the trash list route
+files.openapi(trashListRoute, async (c) => {
+	try {
+		// Only list items that could be restored
+		const items = [];
+		for (const item of await listTrash()) {
+			if (await isPathAllowed(item.originalPath)) {
+				items.push(item);
+			}
+		}
+		return c.json({ items }, 200);
+	} catch (err) {
+		console.error("Trash listing error:", err);
+		const errorMessage = err instanceof Error ? err.message : "Unknown error";
+		return c.json(
+			fileErrorResponse(
+				"Trash could not be listed",
+				errorMessage,
+				"fs:readdir",
+				"unknown",
+			),
+			500,
+		);
+	}
+});
+
+// Mount the file restore route
+files.openapi(fileRestoreRoute, async (c) => {
+	const { id, overwrite } = c.req.valid("json");
+
+	try {
+		// The item goes back to where it was deleted from, which must be allowed
+		const entry = (await listTrash()).find((item) => item.id === id);
+		if (entry) {
+			await resolveAllowedPath(entry.originalPath);
+		}
+		const result = await restoreEntry(id, overwrite);
+		return c.json(
+			{
+				success: true,
+				message: `Restored ${result.path}`,
+				data: result,
+			},
+			200,
+		);
+	} catch (err) {
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
+		const { body, status } = fileOperationErrorResponse(
+			err,
+			"fs:restore",
+			"unknown",
+		);
+		return c.json(body, status);
+	}
+});
+
+// Mount the file download route
+files.openapi(fileDownloadRoute, async (c) => {
+	const { path: requestedPath, format = "zip" } = c.req.valid("query");
+	const resolvedPath = path.resolve(requestedPath);
+
+	try {
+		await resolveAllowedPath(resolvedPath);
+		const stats = await fs.stat(resolvedPath);
+
+		if (stats.isDirectory()) {
+			const name = path.basename(resolvedPath) || "root";
+			c.header("Content-Type", ARCHIVE_MIME_TYPES[format]);
+			c.header(
+				"Content-Disposition",
+				getContentDisposition(`${name}.${format}`),
+			);
+			const archive = createFolderArchive(resolvedPath, format);
+			return c.body(Readable.toWeb(archive) as ReadableStream, 200);
+		}
+
+		c.header("Content-Type", getMimeType(resolvedPath, true));
+		c.header("Content-Length", String(stats.size));
+		c.header(
+			"Content-Disposition",
+			getContentDisposition(path.basename(resolvedPath)),
+		);
+		c.header("ETag", getFileETag(stats));
+		c.header("Last-Modified", stats.mtime.toUTCString());
+		const file = createReadStream(resolvedPath);
+		return c.body(Readable.toWeb(file) as ReadableStream, 200);
+	} catch (err) {
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
+		const errorMessage = err instanceof Error ? err.message : "Unknown error";
+		if ((err as NodeJS.ErrnoException).code === "ENOENT") {
+			return c.json(
+				fileErrorResponse(
+					"File or folder not found",
+					errorMessage,
+					"fs:stat",
+					resolvedPath,
+				),
+				404,
+			);
+		}
+		console.error("File download error:", err);
+		return c.json(
+			fileErrorResponse(
+				"File could not be downloaded",
+				errorMessage,
+				"fs:read",
+				resolvedPath,
+			),
+			500,
+		);
+	}
+});
+
+// Mount the file upload route
+files.openapi(fileUploadRoute, async (c) => {
+	const { path: folder, overwrite, files: fields } = c.req.valid("form");
+	const folderPath = path.resolve(folder);
+	// A single file arrives as a value, several as an array
+	const uploads = [fields]
+		.flat()
+		.filter((field): field is File => field instanceof File);
+
+	try {
+		await resolveAllowedPath(folderPath);
+		if (!(await fs.stat(folderPath)).isDirectory()) {
+			throw new FileOperationError(`Path is not a folder: ${folderPath}`);
+		}
+		if (uploads.length === 0) {
+			throw new FileOperationError("No files to upload");
+		}
+
+		// Check every destination before writing any file, so a conflict
+		// doesn't leave half of the upload behind
+		const destinations = await Promise.all(
+			uploads.map(async (file) => {
+				const destination = resolveUploadPath(folderPath, file.name);
+				await resolveAllowedPath(destination);
+				const existing = await fs.lstat(destination).catch(() => null);
+				if (existing && overwrite !== "true") {
+					throw new FileOperationError(
+						`Destination already exists: ${destination}`,
+						409,
+					);
+				}
+				return destination;
+			}),
+		);
+		const written: FileUpload["files"] = [];
+		for (const [index, file] of uploads.entries()) {
+			written.push(
+				await writeUploadedFile(
+					destinations[index],
+					file,
+					overwrite === "true",
+				),
+			);
+		}
+
+		return c.json(
+			{
+				success: true,
+				message: `Uploaded ${written.length} ${
+					written.length === 1 ? "file" : "files"
+				} to ${path.basename(folderPath)}`,
+				data: { path: folderPath, files: written },
+			},
+			200,
+		);
+	} catch (err) {
+		if (err instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(err), 403);
+		}
+		const { body, status } = fileOperationErrorResponse(
+			err,
+			"fs:upload",
+			folderPath,
+		);
+		return c.json(body, status);
+	}
+});
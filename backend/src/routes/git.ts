@@ -1,17 +1,60 @@
 import * as fs from "node:fs/promises";
-import * as path from "node:path";
 import { createRoute, OpenAPIHono, z } from "@hono/zod-openapi";
 import { ErrorSchema } from "../models/environment.js";
+import { PathForbiddenSchema } from "../models/filesystem.js";
 import {
 	GitCheckoutSchema,
+	GitConflictActionSchema,
+	GitConflictResolveRequestSchema,
+	GitConflictStatusResponseSchema,
+	GitConflictVersionsSchema,
+	GitFetchRequestSchema,
+	GitFileDiffSchema,
 	GitInfoSchema,
 	GitLogSchema,
+	GitPullRequestSchema,
+	GitPushRequestSchema,
+	GitRemoteListSchema,
+	GitRemoteResultSchema,
 	GitStatusDetailSchema,
+	GitWorktreeListSchema,
+	GitWorktreeResultSchema,
 } from "../models/git.js";
 import {
 	createCLIErrorResponse,
 	executeGenericCommand,
 } from "../utils/cli-executor.js";
+import {
+	finishConflictOperation,
+	GitConflictError,
+	getConflictStatus,
+	getConflictVersions,
+	resolveConflict,
+} from "../utils/git-conflicts.js";
+import {
+	GitRemoteError,
+	getRemoteNames,
+	getRemotes,
+	isSafeGitArgument,
+	runGitRemoteOperation,
+} from "../utils/git-remote.js";
+import {
+	GitStatusError,
+	getFileDiff,
+	getWorkingTreeStatus,
+	isGitRepository,
+	refExists,
+} from "../utils/git-status.js";
+import {
+	GitWorktreeError,
+	listWorktrees,
+	removeWorktree,
+} from "../utils/git-worktree.js";
+import {
+	PathAccessError,
+	pathForbiddenResponse,
+	resolveAllowedPath,
+} from "../utils/path-access.js";
 
 // Route to get git information
 export const gitInfoRoute = createRoute({
@@ -49,6 +92,14 @@ export const gitInfoRoute = createRoute({
 			},
 			description: "Folder not found",
 		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
 		500: {
 			content: {
 				"application/json": {
@@ -82,9 +133,15 @@ export const gitCheckoutRoute = createRoute({
 			content: {
 				"application/json": {
 					schema: z.object({
-						branch: z.string().openapi({
-							example: "main",
-							description: "Branch name to checkout",
+						branch: z.string().min(1).openapi({
+							example: "origin/feature/login",
+							description:
+								"Local branch, remote branch, tag or commit to checkout",
+						}),
+						detach: z.boolean().optional().openapi({
+							example: false,
+							description:
+								"Checkout in detached HEAD state. Tags and commits are always detached",
 						}),
 					}),
 				},
@@ -108,6 +165,14 @@ export const gitCheckoutRoute = createRoute({
 			},
 			description: "Bad request (not a git repository or uncommitted changes)",
 		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
 		500: {
 			content: {
 				"application/json": {
@@ -177,6 +242,14 @@ export const gitLogRoute = createRoute({
 			},
 			description: "Bad request (not a git repository or branch not found)",
 		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
 		500: {
 			content: {
 				"application/json": {
@@ -205,6 +278,17 @@ export const gitStatusRoute = createRoute({
 					example: "~/hello",
 					description: "Folder path for git operations",
 				}),
+			ignored: z
+				.enum(["true", "false"])
+				.optional()
+				.openapi({
+					param: {
+						name: "ignored",
+						in: "query",
+					},
+					example: "false",
+					description: "Also list ignored files",
+				}),
 		}),
 	},
 	responses: {
@@ -224,6 +308,14 @@ export const gitStatusRoute = createRoute({
 			},
 			description: "Bad request (not a git repository)",
 		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
 		500: {
 			content: {
 				"application/json": {
@@ -235,435 +327,1836 @@ export const gitStatusRoute = createRoute({
 	},
 });
 
-export const git = new OpenAPIHono();
-
-// Types for internal use
-interface GitBranch {
-	name: string;
-	current: boolean;
-	remote: boolean;
-	upstream?: string;
-	ahead?: number;
-	behind?: number;
-	commitHash?: string;
-	commitMessage?: string;
-	trackingStatus?: string;
-}
-
-interface GitStatus {
-	currentBranch: string;
-	isRepository: boolean;
-	hasUncommittedChanges: boolean;
-	branches: GitBranch[];
-}
-
-/**
- * Check if a directory is a git repository
- */
-async function isGitRepository(folder: string): Promise<boolean> {
-	try {
-		const gitDir = path.join(folder, ".git");
-		const stat = await fs.stat(gitDir);
-		return stat.isDirectory();
-	} catch {
-		return false;
-	}
-}
-
-/**
- * Get current branch name
- */
-async function getCurrentBranch(folder: string): Promise<string | null> {
-	try {
-		const result = await executeGenericCommand({
-			command: "git",
-			args: ["rev-parse", "--abbrev-ref", "HEAD"],
-			workingDir: folder,
-		});
-
-		if (result.code === 0) {
-			return result.stdout.trim();
-		}
-		return null;
-	} catch {
-		return null;
-	}
-}
-
-/**
- * Get all branches (local and remote) with detailed information
- */
-async function getAllBranches(folder: string): Promise<GitBranch[]> {
-	try {
-		// Use git branch -av to get all branches with verbose information
-		const result = await executeGenericCommand({
-			command: "git",
-			args: ["branch", "-av"],
-			workingDir: folder,
-		});
+// Route to get the diff of a single changed file
+export const gitFileDiffRoute = createRoute({
+	method: "get",
+	path: "/status/diff",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+			path: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "path",
+						in: "query",
+					},
+					example: "src/index.ts",
+					description: "File path relative to the repository root",
+				}),
+			staged: z
+				.enum(["true", "false"])
+				.optional()
+				.openapi({
+					param: {
+						name: "staged",
+						in: "query",
+					},
+					example: "false",
+					description: "Diff the index against HEAD instead of the worktree",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitFileDiffSchema,
+				},
+			},
+			description: "Diff of the file",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (not a git repository or file has no changes)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
 
-		const branches: GitBranch[] = [];
+// Route to list configured remotes
+export const gitRemotesRoute = createRoute({
+	method: "get",
+	path: "/remotes",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitRemoteListSchema,
+				},
+			},
+			description: "Configured remotes",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (not a git repository)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
 
-		if (result.code === 0) {
-			const lines = result.stdout
-				.split("\n")
-				.filter((line: string) => line.trim());
+// Route to fetch from a remote
+export const gitFetchRoute = createRoute({
+	method: "post",
+	path: "/fetch",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+		}),
+		body: {
+			content: {
+				"application/json": {
+					schema: GitFetchRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitRemoteResultSchema,
+				},
+			},
+			description: "Fetch result, including updated and rejected refs",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (not a git repository or unknown remote)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
 
-			for (const line of lines) {
-				const trimmed = line.trim();
-				if (!trimmed) continue;
+// Route to pull from a remote
+export const gitPullRoute = createRoute({
+	method: "post",
+	path: "/pull",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+		}),
+		body: {
+			content: {
+				"application/json": {
+					schema: GitPullRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitRemoteResultSchema,
+				},
+			},
+			description: "Pull result, including updated and rejected refs",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (not a git repository or unknown remote)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to push to a remote
+export const gitPushRoute = createRoute({
+	method: "post",
+	path: "/push",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+		}),
+		body: {
+			content: {
+				"application/json": {
+					schema: GitPushRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitRemoteResultSchema,
+				},
+			},
+			description: "Push result, including updated and rejected refs",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (not a git repository or unknown remote)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to get the in-progress merge, cherry-pick or rebase and its conflicts
+export const gitConflictsRoute = createRoute({
+	method: "get",
+	path: "/conflicts",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitConflictStatusResponseSchema,
+				},
+			},
+			description: "Conflict state of the repository",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (not a git repository)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to get the base, ours and theirs versions of a conflicted file
+export const gitConflictFileRoute = createRoute({
+	method: "get",
+	path: "/conflicts/file",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+			path: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "path",
+						in: "query",
+					},
+					example: "src/index.ts",
+					description: "Conflicted file path relative to the repository root",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitConflictVersionsSchema,
+				},
+			},
+			description: "Versions of the conflicted file",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (not a git repository or file is not conflicted)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to resolve a conflicted file
+export const gitConflictResolveRoute = createRoute({
+	method: "post",
+	path: "/conflicts/resolve",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+		}),
+		body: {
+			content: {
+				"application/json": {
+					schema: GitConflictResolveRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitConflictActionSchema,
+				},
+			},
+			description: "Conflict resolved and staged",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (not a git repository or file is not conflicted)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to continue the in-progress operation once conflicts are resolved
+export const gitConflictContinueRoute = createRoute({
+	method: "post",
+	path: "/conflicts/continue",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitConflictActionSchema,
+				},
+			},
+			description: "Result of continuing the operation",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (nothing in progress or unresolved conflicts)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to abort the in-progress operation
+export const gitConflictAbortRoute = createRoute({
+	method: "post",
+	path: "/conflicts/abort",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitConflictActionSchema,
+				},
+			},
+			description: "Result of aborting the operation",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (nothing in progress)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to list the worktrees of a repository
+export const gitWorktreesRoute = createRoute({
+	method: "get",
+	path: "/worktrees",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitWorktreeListSchema,
+				},
+			},
+			description: "Worktrees of the repository",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (not a git repository)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+// Route to remove a worktree created by cuweb
+export const gitWorktreeRemoveRoute = createRoute({
+	method: "delete",
+	path: "/worktrees",
+	request: {
+		query: z.object({
+			folder: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "folder",
+						in: "query",
+					},
+					example: "~/hello",
+					description: "Folder path for git operations",
+				}),
+			path: z
+				.string()
+				.min(1)
+				.openapi({
+					param: {
+						name: "path",
+						in: "query",
+					},
+					example: "/home/user/.cuweb/worktrees/hello-1a2b3c4d/sharing-loon",
+					description: "Absolute path of the worktree to remove",
+				}),
+			force: z
+				.enum(["true", "false"])
+				.optional()
+				.openapi({
+					param: {
+						name: "force",
+						in: "query",
+					},
+					example: "false",
+					description: "Remove even if the worktree has uncommitted changes",
+				}),
+		}),
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: GitWorktreeResultSchema,
+				},
+			},
+			description: "Worktree removed",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Bad request (not a worktree created by cuweb, or it has changes)",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Internal server error",
+		},
+	},
+});
+
+export const git = new OpenAPIHono();
+
+// Types for internal use
+interface GitBranch {
+	name: string;
+	current: boolean;
+	remote: boolean;
+	upstream?: string;
+	ahead?: number;
+	behind?: number;
+	commitHash?: string;
+	commitMessage?: string;
+	trackingStatus?: string;
+}
+
+interface GitStatus {
+	currentBranch: string;
+	isRepository: boolean;
+	hasUncommittedChanges: boolean;
+	branches: GitBranch[];
+}
+
+/**
+ * Get current branch name
+ */
+async function getCurrentBranch(folder: string): Promise<string | null> {
+	try {
+		const result = await executeGenericCommand({
+			command: "git",
+			args: ["rev-parse", "--abbrev-ref", "HEAD"],
+			workingDir: folder,
+		});
+
+		if (result.code === 0) {
+			return result.stdout.trim();
+		}
+		return null;
+	} catch {
+		return null;
+	}
+}
+
+/**
+ * Get all branches (local and remote) with detailed information
+ */
+async function getAllBranches(folder: string): Promise<GitBranch[]> {
+	try {
+		// Use git branch -av to get all branches with verbose information
+		const result = await executeGenericCommand({
+			command: "git",
+			args: ["branch", "-av"],
+			workingDir: folder,
+		});
+
+		const branches: GitBranch[] = [];
+
+		if (result.code === 0) {
+			const lines = result.stdout
+				.split("\n")
+				.filter((line: string) => line.trim());
+
+			for (const line of lines) {
+				const trimmed = line.trim();
+				if (!trimmed) continue;
+
+				// Skip HEAD references (like "remotes/origin/HEAD -> origin/main")
+				if (trimmed.includes("->")) continue;
+
+				const isCurrent = trimmed.startsWith("*");
+				const branchInfo = isCurrent ? trimmed.substring(1).trim() : trimmed;
+
+				// Parse the branch line format:
+				// branch-name    commit-hash [tracking-info] commit message
+				// remotes/origin/branch-name    commit-hash commit message
+				const match = branchInfo.match(/^(\S+)\s+([a-f0-9]+)\s*(.*)$/);
+
+				if (!match) continue;
+
+				const [, fullName, commitHash, rest] = match;
+				let commitMessage = rest;
+				let trackingStatus: string | undefined;
+				let upstream: string | undefined;
+
+				// Check for tracking information in brackets like [gone], [ahead 2], [behind 1], etc.
+				const trackingMatch = rest.match(/^\[([^\]]+)\]\s*(.*)$/);
+				if (trackingMatch) {
+					const [, tracking, message] = trackingMatch;
+					trackingStatus = tracking;
+					commitMessage = message;
+
+					// Extract upstream information if available
+					if (tracking.includes("gone")) {
+						trackingStatus = "gone";
+					} else if (tracking.includes(":")) {
+						// Format like "origin/main: ahead 1"
+						const parts = tracking.split(":");
+						if (parts.length > 0) {
+							upstream = parts[0].trim();
+							if (parts.length > 1) {
+								trackingStatus = parts[1].trim();
+							}
+						}
+					}
+				}
+
+				// Determine if it's a remote branch
+				const isRemote = fullName.startsWith("remotes/");
+				let branchName = fullName;
+
+				if (isRemote) {
+					// For remote branches, keep the remote/branch format (e.g., "container-use/social-tapir")
+					// but strip the "remotes/" prefix
+					const remoteParts = fullName.split("/");
+					if (remoteParts.length >= 3) {
+						branchName = remoteParts.slice(1).join("/"); // Keep "container-use/social-tapir"
+						if (!upstream) {
+							upstream = fullName;
+						}
+					}
+				}
+
+				// For local branches, try to get tracking information if not already parsed
+				if (!isRemote && !trackingStatus) {
+					try {
+						const trackingResult = await executeGenericCommand({
+							command: "git",
+							args: ["status", "-b", "--porcelain=v1"],
+							workingDir: folder,
+						});
+
+						if (trackingResult.code === 0) {
+							const statusLines = trackingResult.stdout.split("\n");
+							const branchLine = statusLines.find((line) =>
+								line.startsWith("##"),
+							);
+							if (branchLine?.includes(branchName)) {
+								// Parse tracking info from status
+								if (branchLine.includes("[gone]")) {
+									trackingStatus = "gone";
+								} else if (branchLine.includes("[ahead")) {
+									const aheadMatch = branchLine.match(/\[ahead (\d+)\]/);
+									if (aheadMatch) {
+										trackingStatus = `ahead ${aheadMatch[1]}`;
+									}
+								} else if (branchLine.includes("[behind")) {
+									const behindMatch = branchLine.match(/\[behind (\d+)\]/);
+									if (behindMatch) {
+										trackingStatus = `behind ${behindMatch[1]}`;
+									}
+								}
+
+								// Extract upstream
+								const upstreamMatch = branchLine.match(/\.\.\.([^\s\]]+)/);
+								if (upstreamMatch) {
+									upstream = upstreamMatch[1];
+								}
+							}
+						}
+					} catch {
+						// Ignore errors getting tracking info for individual branches
+					}
+				}
+
+				branches.push({
+					name: branchName,
+					current: isCurrent,
+					remote: isRemote,
+					upstream,
+					commitHash: commitHash.substring(0, 7), // Short hash
+					commitMessage:
+						commitMessage.trim().length > 100
+							? `${commitMessage.trim().substring(0, 97)}...`
+							: commitMessage.trim() || undefined,
+					trackingStatus,
+				});
+			}
+		}
+
+		return branches;
+	} catch {
+		return [];
+	}
+}
+
+/**
+ * Check if there are uncommitted changes
+ */
+async function hasUncommittedChanges(folder: string): Promise<boolean> {
+	try {
+		const result = await executeGenericCommand({
+			command: "git",
+			args: ["status", "--porcelain"],
+			workingDir: folder,
+		});
+
+		return result.code === 0 && result.stdout.trim().length > 0;
+	} catch {
+		return false;
+	}
+}
+
+type CheckoutRefType = "local" | "remote" | "tag" | "commit";
+
+/**
+ * Ask git what kind of ref a checkout target is instead of guessing from its name
+ *
+ * Local branches take precedence, then remote branches, tags and commits.
+ */
+async function resolveCheckoutRef(
+	folder: string,
+	name: string,
+): Promise<CheckoutRefType | null> {
+	if (await refExists(folder, `refs/heads/${name}`)) {
+		return "local";
+	}
+	if (await refExists(folder, `refs/remotes/${name}`)) {
+		return "remote";
+	}
+	if (await refExists(folder, `refs/tags/${name}`)) {
+		return "tag";
+	}
+
+	const result = await executeGenericCommand({
+		command: "git",
+		args: ["rev-parse", "--verify", "--quiet", `${name}^{commit}`],
+		workingDir: folder,
+	});
+	return result.code === 0 ? "commit" : null;
+}
+
+/**
+ * Split a remote branch like "origin/feature/login" into remote and branch
+ *
+ * Only the remote name is stripped. Remote names may contain slashes too,
+ * so the longest configured remote that prefixes the name wins.
+ */
+async function splitRemoteBranch(
+	folder: string,
+	name: string,
+): Promise<{ remote: string; branch: string } | null> {
+	const remote = (await getRemoteNames(folder))
+		.filter((remote) => name.startsWith(`${remote}/`))
+		.sort((a, b) => b.length - a.length)[0];
+	if (!remote) {
+		return null;
+	}
+	return { remote, branch: name.slice(remote.length + 1) };
+}
+
+/**
+ * Find a local branch whose upstream is the given remote-tracking ref
+ */
+async function findTrackingBranch(
+	folder: string,
+	remoteRef: string,
+): Promise<string | null> {
+	const result = await executeGenericCommand({
+		command: "git",
+		args: [
+			"for-each-ref",
+			"--format=%(refname:short)%09%(upstream)",
+			"refs/heads",
+		],
+		workingDir: folder,
+	});
+	if (result.code !== 0) {
+		return null;
+	}
+
+	for (const line of result.stdout.split("\n")) {
+		const [branch, upstream] = line.split("\t");
+		if (branch && upstream === remoteRef) {
+			return branch;
+		}
+	}
+	return null;
+}
+
+/**
+ * Get git log for a specific branch
+ */
+async function getGitLog(
+	folder: string,
+	branch: string,
+	limit = 10,
+): Promise<{
+	branch: string;
+	commits: Array<{
+		hash: string;
+		message: string;
+		author: string;
+		date: string;
+		relative: string;
+	}>;
+}> {
+	try {
+		// Use git log with format to get structured data
+		const result = await executeGenericCommand({
+			command: "git",
+			args: [
+				"log",
+				branch,
+				"--oneline",
+				"--format=%H|%s|%an|%ai|%ar",
+				`-${limit}`,
+			],
+			workingDir: folder,
+		});
+
+		const commits = [];
+
+		if (result.code === 0 && result.stdout.trim()) {
+			const lines = result.stdout.split("\n").filter((line) => line.trim());
+
+			for (const line of lines) {
+				const parts = line.split("|");
+				if (parts.length >= 5) {
+					commits.push({
+						hash: parts[0].substring(0, 7), // Short hash
+						message: parts[1],
+						author: parts[2],
+						date: parts[3],
+						relative: parts[4],
+					});
+				}
+			}
+		}
+
+		return {
+			branch,
+			commits,
+		};
+	} catch (error) {
+		console.error("Error getting git log:", error);
+		return {
+			branch,
+			commits: [],
+		};
+	}
+}
+
+/**
+ * Get git status for a folder
+ */
+async function getGitStatus(folder: string): Promise<GitStatus> {
+	const isRepo = await isGitRepository(folder);
+
+	if (!isRepo) {
+		return {
+			currentBranch: "",
+			isRepository: false,
+			hasUncommittedChanges: false,
+			branches: [],
+		};
+	}
+
+	const [currentBranch, branches, uncommittedChanges] = await Promise.all([
+		getCurrentBranch(folder),
+		getAllBranches(folder),
+		hasUncommittedChanges(folder),
+	]);
+
+	return {
+		currentBranch: currentBranch || "",
+		isRepository: true,
+		hasUncommittedChanges: uncommittedChanges,
+		branches,
+	};
+}
+
+// Mount the git info route
+git.openapi(gitInfoRoute, async (c) => {
+	try {
+		const { folder } = c.req.valid("query");
 
-				// Skip HEAD references (like "remotes/origin/HEAD -> origin/main")
-				if (trimmed.includes("->")) continue;
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
 
-				const isCurrent = trimmed.startsWith("*");
-				const branchInfo = isCurrent ? trimmed.substring(1).trim() : trimmed;
+		// Check if folder exists
+		try {
+			await fs.access(absolutePath);
+		} catch {
+			const errorResponse = createCLIErrorResponse(
+				"Folder not found",
+				null,
+				"git",
+				absolutePath,
+			);
+			return c.json(errorResponse, 404);
+		}
 
-				// Parse the branch line format:
-				// branch-name    commit-hash [tracking-info] commit message
-				// remotes/origin/branch-name    commit-hash commit message
-				const match = branchInfo.match(/^(\S+)\s+([a-f0-9]+)\s*(.*)$/);
+		const gitStatus = await getGitStatus(absolutePath);
 
-				if (!match) continue;
+		return c.json(
+			{
+				success: true,
+				data: gitStatus,
+			},
+			200,
+		);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		console.error("Error getting git info:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to get git information",
+			null,
+			"git",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
 
-				const [, fullName, commitHash, rest] = match;
-				let commitMessage = rest;
-				let trackingStatus: string | undefined;
-				let upstream: string | undefined;
+// Mount the git checkout route
+git.openapi(gitCheckoutRoute, async (c) => {
+	try {
+		const { folder } = c.req.valid("query");
+		const { branch, detach } = c.req.valid("json");
 
-				// Check for tracking information in brackets like [gone], [ahead 2], [behind 1], etc.
-				const trackingMatch = rest.match(/^\[([^\]]+)\]\s*(.*)$/);
-				if (trackingMatch) {
-					const [, tracking, message] = trackingMatch;
-					trackingStatus = tracking;
-					commitMessage = message;
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
 
-					// Extract upstream information if available
-					if (tracking.includes("gone")) {
-						trackingStatus = "gone";
-					} else if (tracking.includes(":")) {
-						// Format like "origin/main: ahead 1"
-						const parts = tracking.split(":");
-						if (parts.length > 0) {
-							upstream = parts[0].trim();
-							if (parts.length > 1) {
-								trackingStatus = parts[1].trim();
-							}
-						}
-					}
-				}
+		if (!isSafeGitArgument(branch)) {
+			const errorResponse = createCLIErrorResponse(
+				`Invalid branch name: ${branch}`,
+				null,
+				"git checkout",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
 
-				// Determine if it's a remote branch
-				const isRemote = fullName.startsWith("remotes/");
-				let branchName = fullName;
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
+			const errorResponse = createCLIErrorResponse(
+				"Not a git repository",
+				null,
+				"git",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
+
+		// Check for uncommitted changes
+		const hasChanges = await hasUncommittedChanges(absolutePath);
+		if (hasChanges) {
+			const errorResponse = createCLIErrorResponse(
+				"Cannot checkout: uncommitted changes detected",
+				null,
+				"git checkout",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
+
+		const refType = await resolveCheckoutRef(absolutePath, branch);
+		if (!refType) {
+			const errorResponse = createCLIErrorResponse(
+				`Unknown branch, tag or commit: ${branch}`,
+				null,
+				"git checkout",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
+
+		// Tags and commits can only be checked out with a detached HEAD
+		const detached =
+			detach === true || refType === "tag" || refType === "commit";
+		let checkoutArgs: string[];
+		let localBranch: string | undefined;
+
+		if (detached) {
+			const target = refType === "tag" ? `refs/tags/${branch}` : branch;
+			checkoutArgs = ["checkout", "--detach", target, "--"];
+		} else if (refType === "remote") {
+			const remoteBranch = await splitRemoteBranch(absolutePath, branch);
+			if (!remoteBranch?.branch) {
+				const errorResponse = createCLIErrorResponse(
+					"Invalid remote branch name",
+					null,
+					"git checkout",
+					absolutePath,
+				);
+				return c.json(errorResponse, 400);
+			}
+
+			// Reuse a local branch that already tracks this remote branch
+			const trackingBranch = await findTrackingBranch(
+				absolutePath,
+				`refs/remotes/${branch}`,
+			);
+			if (trackingBranch) {
+				localBranch = trackingBranch;
+				checkoutArgs = ["checkout", trackingBranch, "--"];
+			} else if (
+				await refExists(absolutePath, `refs/heads/${remoteBranch.branch}`)
+			) {
+				const errorResponse = createCLIErrorResponse(
+					`Cannot checkout: local branch ${remoteBranch.branch} exists but does not track ${branch}`,
+					null,
+					"git checkout",
+					absolutePath,
+				);
+				return c.json(errorResponse, 400);
+			} else {
+				// Create a local tracking branch named after the remote branch
+				localBranch = remoteBranch.branch;
+				checkoutArgs = ["checkout", "--track", "-b", localBranch, branch, "--"];
+			}
+		} else {
+			localBranch = branch;
+			// "--" keeps a branch named like a file from being read as a path
+			checkoutArgs = ["checkout", branch, "--"];
+		}
+
+		const result = await executeGenericCommand({
+			command: "git",
+			args: checkoutArgs,
+			workingDir: absolutePath,
+		});
+
+		if (result.code !== 0) {
+			const errorResponse = createCLIErrorResponse(
+				"Failed to checkout branch",
+				result,
+				"git checkout",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
+
+		// Get updated git status
+		const gitStatus = await getGitStatus(absolutePath);
+
+		return c.json(
+			{
+				success: true,
+				message: detached
+					? `Successfully checked out ${branch} (detached HEAD)`
+					: `Successfully checked out branch: ${localBranch}`,
+				refType,
+				...(localBranch && { branch: localBranch }),
+				detached,
+				data: gitStatus,
+			},
+			200,
+		);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		console.error("Error checking out branch:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to checkout branch",
+			null,
+			"git checkout",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
+
+// Mount the git log route
+git.openapi(gitLogRoute, async (c) => {
+	try {
+		const { folder, branch, limit } = c.req.valid("query");
+
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
+
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
+			const errorResponse = createCLIErrorResponse(
+				"Not a git repository",
+				null,
+				"git log",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
+
+		// Get git log for the specified branch
+		const logData = await getGitLog(
+			absolutePath,
+			branch,
+			limit ? parseInt(limit, 10) : 10,
+		);
+
+		return c.json(
+			{
+				success: true,
+				data: logData,
+			},
+			200,
+		);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		console.error("Error getting git log:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to get git log",
+			null,
+			"git log",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
+
+// Mount the git status route
+git.openapi(gitStatusRoute, async (c) => {
+	try {
+		const { folder, ignored } = c.req.valid("query");
+
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
+
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
+			const errorResponse = createCLIErrorResponse(
+				"Not a git repository",
+				null,
+				"git status",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
+
+		// Get detailed git status
+		const { branch, files } = await getWorkingTreeStatus(absolutePath, {
+			ignored: ignored === "true",
+		});
+
+		return c.json(
+			{
+				success: true,
+				data: {
+					hasChanges: files.some((file) => file.kind !== "ignored"),
+					branch,
+					files,
+				},
+			},
+			200,
+		);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		console.error("Error getting git status:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to get git status",
+			null,
+			"git status",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
 
-				if (isRemote) {
-					// For remote branches, keep the remote/branch format (e.g., "container-use/social-tapir")
-					// but strip the "remotes/" prefix
-					const remoteParts = fullName.split("/");
-					if (remoteParts.length >= 3) {
-						branchName = remoteParts.slice(1).join("/"); // Keep "container-use/social-tapir"
-						if (!upstream) {
-							upstream = fullName;
-						}
-					}
-				}
+// Mount the file diff route
+git.openapi(gitFileDiffRoute, async (c) => {
+	try {
+		const { folder, path: filePath, staged } = c.req.valid("query");
 
-				// For local branches, try to get tracking information if not already parsed
-				if (!isRemote && !trackingStatus) {
-					try {
-						const trackingResult = await executeGenericCommand({
-							command: "git",
-							args: ["status", "-b", "--porcelain=v1"],
-							workingDir: folder,
-						});
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
 
-						if (trackingResult.code === 0) {
-							const statusLines = trackingResult.stdout.split("\n");
-							const branchLine = statusLines.find((line) =>
-								line.startsWith("##"),
-							);
-							if (branchLine?.includes(branchName)) {
-								// Parse tracking info from status
-								if (branchLine.includes("[gone]")) {
-									trackingStatus = "gone";
-								} else if (branchLine.includes("[ahead")) {
-									const aheadMatch = branchLine.match(/\[ahead (\d+)\]/);
-									if (aheadMatch) {
-										trackingStatus = `ahead ${aheadMatch[1]}`;
-									}
-								} else if (branchLine.includes("[behind")) {
-									const behindMatch = branchLine.match(/\[behind (\d+)\]/);
-									if (behindMatch) {
-										trackingStatus = `behind ${behindMatch[1]}`;
-									}
-								}
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
+			const errorResponse = createCLIErrorResponse(
+				"Not a git repository",
+				null,
+				"git diff",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
 
-								// Extract upstream
-								const upstreamMatch = branchLine.match(/\.\.\.([^\s\]]+)/);
-								if (upstreamMatch) {
-									upstream = upstreamMatch[1];
-								}
-							}
-						}
-					} catch {
-						// Ignore errors getting tracking info for individual branches
-					}
-				}
+		const diff = await getFileDiff(
+			absolutePath,
+			filePath,
+			staged === "true",
+		);
 
-				branches.push({
-					name: branchName,
-					current: isCurrent,
-					remote: isRemote,
-					upstream,
-					commitHash: commitHash.substring(0, 7), // Short hash
-					commitMessage:
-						commitMessage.trim().length > 100
-							? `${commitMessage.trim().substring(0, 97)}...`
-							: commitMessage.trim() || undefined,
-					trackingStatus,
-				});
-			}
+		return c.json({ success: true, data: diff }, 200);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
 		}
-
-		return branches;
-	} catch {
-		return [];
+		if (error instanceof GitStatusError) {
+			const errorResponse = createCLIErrorResponse(
+				error.message,
+				null,
+				"git diff",
+				"unknown",
+			);
+			return c.json(errorResponse, 400);
+		}
+		console.error("Error getting git file diff:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to get git file diff",
+			null,
+			"git diff",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
 	}
-}
+});
 
-/**
- * Get detailed git status with file changes
- */
-async function getDetailedGitStatus(folder: string): Promise<{
-	hasChanges: boolean;
-	files: Array<{
-		status: string;
-		path: string;
-		description: string;
-	}>;
-}> {
+// Mount the git remotes route
+git.openapi(gitRemotesRoute, async (c) => {
 	try {
-		const result = await executeGenericCommand({
-			command: "git",
-			args: ["status", "--porcelain"],
-			workingDir: folder,
-		});
+		const { folder } = c.req.valid("query");
 
-		const files: Array<{
-			status: string;
-			path: string;
-			description: string;
-		}> = [];
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
 
-		if (result.code === 0 && result.stdout.trim()) {
-			const lines = result.stdout.split("\n").filter((line) => line.trim());
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
+			const errorResponse = createCLIErrorResponse(
+				"Not a git repository",
+				null,
+				"git remote",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
 
-			for (const line of lines) {
-				if (line.length >= 3) {
-					const status = line.substring(0, 2);
-					const path = line.substring(3);
-
-					// Parse git status codes and create human-readable descriptions
-					let description = "unknown change";
-
-					// Index status (first character)
-					const indexStatus = status[0];
-					// Working tree status (second character)
-					const workingStatus = status[1];
-
-					if (indexStatus === "M" || workingStatus === "M") {
-						description = "modified";
-					} else if (indexStatus === "A" || workingStatus === "A") {
-						description = "added";
-					} else if (indexStatus === "D" || workingStatus === "D") {
-						description = "deleted";
-					} else if (indexStatus === "R" || workingStatus === "R") {
-						description = "renamed";
-					} else if (indexStatus === "C" || workingStatus === "C") {
-						description = "copied";
-					} else if (indexStatus === "U" || workingStatus === "U") {
-						description = "unmerged";
-					} else if (status === "??") {
-						description = "untracked";
-					} else if (status === "!!") {
-						description = "ignored";
-					}
+		const remotes = await getRemotes(absolutePath);
 
-					// Add more specific descriptions for index vs working tree
-					if (indexStatus !== " " && workingStatus !== " ") {
-						description = `staged & ${description}`;
-					} else if (indexStatus !== " ") {
-						description = `staged ${description}`;
-					}
+		return c.json(
+			{
+				success: true,
+				data: remotes,
+			},
+			200,
+		);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		console.error("Error listing git remotes:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to list git remotes",
+			null,
+			"git remote",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
 
-					files.push({
-						status: status.trim(),
-						path,
-						description,
-					});
-				}
-			}
+// Mount the git fetch route
+git.openapi(gitFetchRoute, async (c) => {
+	try {
+		const { folder } = c.req.valid("query");
+		const { remote, prune } = c.req.valid("json");
+
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
+
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
+			const errorResponse = createCLIErrorResponse(
+				"Not a git repository",
+				null,
+				"git fetch",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
 		}
 
-		return {
-			hasChanges: files.length > 0,
-			files,
-		};
+		// A completed git run is always returned with its structured result,
+		// so that rejected refs reach the client even when git fails
+		const result = await runGitRemoteOperation({
+			operation: "fetch",
+			folder: absolutePath,
+			remote,
+			prune,
+		});
+
+		return c.json(result, 200);
 	} catch (error) {
-		console.error("Error getting detailed git status:", error);
-		return {
-			hasChanges: false,
-			files: [],
-		};
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof GitRemoteError) {
+			const errorResponse = createCLIErrorResponse(
+				error.message,
+				null,
+				"git fetch",
+				"unknown",
+			);
+			return c.json(errorResponse, 400);
+		}
+		console.error("Error running git fetch:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to run git fetch",
+			null,
+			"git fetch",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
 	}
-}
+});
 
-/**
- * Check if there are uncommitted changes
- */
-async function hasUncommittedChanges(folder: string): Promise<boolean> {
+// Mount the git pull route
+git.openapi(gitPullRoute, async (c) => {
 	try {
-		const result = await executeGenericCommand({
-			command: "git",
-			args: ["status", "--porcelain"],
-			workingDir: folder,
+		const { folder } = c.req.valid("query");
+		const { remote, branch, ffOnly } = c.req.valid("json");
+
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
+
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
+			const errorResponse = createCLIErrorResponse(
+				"Not a git repository",
+				null,
+				"git pull",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
+
+		// A completed git run is always returned with its structured result,
+		// so that rejected refs reach the client even when git fails
+		const result = await runGitRemoteOperation({
+			operation: "pull",
+			folder: absolutePath,
+			remote,
+			branch,
+			ffOnly,
 		});
 
-		return result.code === 0 && result.stdout.trim().length > 0;
-	} catch {
-		return false;
+		return c.json(result, 200);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof GitRemoteError) {
+			const errorResponse = createCLIErrorResponse(
+				error.message,
+				null,
+				"git pull",
+				"unknown",
+			);
+			return c.json(errorResponse, 400);
+		}
+		console.error("Error running git pull:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to run git pull",
+			null,
+			"git pull",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
 	}
-}
+});
 
-/**
- * Get git log for a specific branch
- */
-async function getGitLog(
-	folder: string,
-	branch: string,
-	limit = 10,
-): Promise<{
-	branch: string;
-	commits: Array<{
-		hash: string;
-		message: string;
-		author: string;
-		date: string;
-		relative: string;
-	}>;
-}> {
+// Mount the git push route
+git.openapi(gitPushRoute, async (c) => {
 	try {
-		// Use git log with format to get structured data
-		const result = await executeGenericCommand({
-			command: "git",
-			args: [
-				"log",
-				branch,
-				"--oneline",
-				"--format=%H|%s|%an|%ai|%ar",
-				`-${limit}`,
-			],
-			workingDir: folder,
+		const { folder } = c.req.valid("query");
+		const { remote, branch, remoteBranch, setUpstream, force } =
+			c.req.valid("json");
+
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
+
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
+			const errorResponse = createCLIErrorResponse(
+				"Not a git repository",
+				null,
+				"git push",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
+
+		// A completed git run is always returned with its structured result,
+		// so that rejected refs reach the client even when git fails
+		const result = await runGitRemoteOperation({
+			operation: "push",
+			folder: absolutePath,
+			remote,
+			branch,
+			remoteBranch,
+			setUpstream,
+			force,
 		});
 
-		const commits = [];
+		return c.json(result, 200);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof GitRemoteError) {
+			const errorResponse = createCLIErrorResponse(
+				error.message,
+				null,
+				"git push",
+				"unknown",
+			);
+			return c.json(errorResponse, 400);
+		}
+		console.error("Error running git push:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to run git push",
+			null,
+			"git push",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
 
-		if (result.code === 0 && result.stdout.trim()) {
-			const lines = result.stdout.split("\n").filter((line) => line.trim());
+// Mount the conflict status route
+git.openapi(gitConflictsRoute, async (c) => {
+	try {
+		const { folder } = c.req.valid("query");
 
-			for (const line of lines) {
-				const parts = line.split("|");
-				if (parts.length >= 5) {
-					commits.push({
-						hash: parts[0].substring(0, 7), // Short hash
-						message: parts[1],
-						author: parts[2],
-						date: parts[3],
-						relative: parts[4],
-					});
-				}
-			}
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
+
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
+			const errorResponse = createCLIErrorResponse(
+				"Not a git repository",
+				null,
+				"git status",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
 		}
 
-		return {
-			branch,
-			commits,
-		};
+		const status = await getConflictStatus(absolutePath);
+
+		return c.json({ success: true, data: status }, 200);
 	} catch (error) {
-		console.error("Error getting git log:", error);
-		return {
-			branch,
-			commits: [],
-		};
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof GitConflictError) {
+			const errorResponse = createCLIErrorResponse(
+				error.message,
+				null,
+				"git conflict status",
+				"unknown",
+			);
+			return c.json(errorResponse, 400);
+		}
+		console.error("Error running git conflict status:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to run git conflict status",
+			null,
+			"git conflict status",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
 	}
-}
+});
 
-/**
- * Get git status for a folder
- */
-async function getGitStatus(folder: string): Promise<GitStatus> {
-	const isRepo = await isGitRepository(folder);
+// Mount the conflict file route
+git.openapi(gitConflictFileRoute, async (c) => {
+	try {
+		const { folder, path: filePath } = c.req.valid("query");
 
-	if (!isRepo) {
-		return {
-			currentBranch: "",
-			isRepository: false,
-			hasUncommittedChanges: false,
-			branches: [],
-		};
-	}
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
 
-	const [currentBranch, branches, uncommittedChanges] = await Promise.all([
-		getCurrentBranch(folder),
-		getAllBranches(folder),
-		hasUncommittedChanges(folder),
-	]);
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
+			const errorResponse = createCLIErrorResponse(
+				"Not a git repository",
+				null,
+				"git show",
+				absolutePath,
+			);
+			return c.json(errorResponse, 400);
+		}
 
-	return {
-		currentBranch: currentBranch || "",
-		isRepository: true,
-		hasUncommittedChanges: uncommittedChanges,
-		branches,
-	};
-}
+		const versions = await getConflictVersions(absolutePath, filePath);
 
-// Mount the git info route
-git.openapi(gitInfoRoute, async (c) => {
+		return c.json({ success: true, data: versions }, 200);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof GitConflictError) {
+			const errorResponse = createCLIErrorResponse(
+				error.message,
+				null,
+				"git conflict file",
+				"unknown",
+			);
+			return c.json(errorResponse, 400);
+		}
+		console.error("Error running git conflict file:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to run git conflict file",
+			null,
+			"git conflict file",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
+
+// Mount the conflict resolve route
+git.openapi(gitConflictResolveRoute, async (c) => {
 	try {
 		const { folder } = c.req.valid("query");
+		const { path: filePath, resolution, content } = c.req.valid("json");
 
-		// Resolve absolute path
-		const absolutePath = path.resolve(folder);
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
 
-		// Check if folder exists
-		try {
-			await fs.access(absolutePath);
-		} catch {
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
 			const errorResponse = createCLIErrorResponse(
-				"Folder not found",
+				"Not a git repository",
 				null,
-				"git",
+				"git add",
 				absolutePath,
 			);
-			return c.json(errorResponse, 404);
+			return c.json(errorResponse, 400);
 		}
 
-		const gitStatus = await getGitStatus(absolutePath);
+		await resolveConflict(absolutePath, filePath, resolution, content);
+		const status = await getConflictStatus(absolutePath);
 
 		return c.json(
 			{
 				success: true,
-				data: gitStatus,
+				message: `Resolved ${filePath} using ${resolution}`,
+				output: "",
+				data: status,
 			},
 			200,
 		);
 	} catch (error) {
-		console.error("Error getting git info:", error);
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof GitConflictError) {
+			const errorResponse = createCLIErrorResponse(
+				error.message,
+				null,
+				"git conflict resolve",
+				"unknown",
+			);
+			return c.json(errorResponse, 400);
+		}
+		console.error("Error running git conflict resolve:", error);
 		const errorResponse = createCLIErrorResponse(
-			"Failed to get git information",
+			"Failed to run git conflict resolve",
 			null,
-			"git",
+			"git conflict resolve",
 			"unknown",
 			error instanceof Error ? error : undefined,
 		);
@@ -671,14 +2164,13 @@ git.openapi(gitInfoRoute, async (c) => {
 	}
 });
 
-// Mount the git checkout route
-git.openapi(gitCheckoutRoute, async (c) => {
+// Mount the conflict continue route
+git.openapi(gitConflictContinueRoute, async (c) => {
 	try {
 		const { folder } = c.req.valid("query");
-		const { branch } = c.req.valid("json");
 
-		// Resolve absolute path
-		const absolutePath = path.resolve(folder);
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
 
 		// Check if folder exists and is a git repository
 		const isRepo = await isGitRepository(absolutePath);
@@ -686,82 +2178,113 @@ git.openapi(gitCheckoutRoute, async (c) => {
 			const errorResponse = createCLIErrorResponse(
 				"Not a git repository",
 				null,
-				"git",
+				"git continue",
 				absolutePath,
 			);
 			return c.json(errorResponse, 400);
 		}
 
-		// Check for uncommitted changes
-		const hasChanges = await hasUncommittedChanges(absolutePath);
-		if (hasChanges) {
+		const { operation, result } = await finishConflictOperation(
+			absolutePath,
+			"continue",
+		);
+		// A rebase may stop again on the next commit, so report the new state
+		const status = await getConflictStatus(absolutePath);
+
+		return c.json(
+			{
+				success: result.code === 0,
+				message:
+					result.code === 0
+						? `${operation} continued`
+						: `Failed to continue ${operation}`,
+				output: `${result.stdout}${result.stderr}`.trim(),
+				data: status,
+			},
+			200,
+		);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof GitConflictError) {
 			const errorResponse = createCLIErrorResponse(
-				"Cannot checkout: uncommitted changes detected",
+				error.message,
 				null,
-				"git checkout",
-				absolutePath,
+				"git conflict continue",
+				"unknown",
 			);
 			return c.json(errorResponse, 400);
 		}
+		console.error("Error running git conflict continue:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to run git conflict continue",
+			null,
+			"git conflict continue",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
 
-		// Perform checkout
-		let checkoutArgs: string[];
-
-		// Check if it's a remote branch (contains '/')
-		if (branch.includes("/")) {
-			// For remote branches, create a local tracking branch
-			// Extract the branch name (last part after '/')
-			const localBranchName = branch.split("/").pop();
-			if (!localBranchName) {
-				const errorResponse = createCLIErrorResponse(
-					"Invalid remote branch name",
-					null,
-					"git checkout",
-					absolutePath,
-				);
-				return c.json(errorResponse, 400);
-			}
-
-			// Use git checkout -b localName remoteName to create tracking branch
-			checkoutArgs = ["checkout", "-b", localBranchName, branch];
-		} else {
-			// For local branches, use regular checkout
-			checkoutArgs = ["checkout", branch];
-		}
+// Mount the conflict abort route
+git.openapi(gitConflictAbortRoute, async (c) => {
+	try {
+		const { folder } = c.req.valid("query");
 
-		const result = await executeGenericCommand({
-			command: "git",
-			args: checkoutArgs,
-			workingDir: absolutePath,
-		});
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
 
-		if (result.code !== 0) {
+		// Check if folder exists and is a git repository
+		const isRepo = await isGitRepository(absolutePath);
+		if (!isRepo) {
 			const errorResponse = createCLIErrorResponse(
-				"Failed to checkout branch",
-				result,
-				"git checkout",
+				"Not a git repository",
+				null,
+				"git abort",
 				absolutePath,
 			);
 			return c.json(errorResponse, 400);
 		}
 
-		// Get updated git status
-		const gitStatus = await getGitStatus(absolutePath);
+		const { operation, result } = await finishConflictOperation(
+			absolutePath,
+			"abort",
+		);
+		// Report the state after the abort, e.g. conflicts it failed to clear
+		const status = await getConflictStatus(absolutePath);
 
 		return c.json(
 			{
-				success: true,
-				message: `Successfully checked out branch: ${branch}`,
-				data: gitStatus,
+				success: result.code === 0,
+				message:
+					result.code === 0
+						? `${operation} aborted`
+						: `Failed to abort ${operation}`,
+				output: `${result.stdout}${result.stderr}`.trim(),
+				data: status,
 			},
 			200,
 		);
 	} catch (error) {
-		console.error("Error checking out branch:", error);
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof GitConflictError) {
+			const errorResponse = createCLIErrorResponse(
+				error.message,
+				null,
+				"git conflict abort",
+				"unknown",
+			);
+			return c.json(errorResponse, 400);
+		}
+		console.error("Error running git conflict abort:", error);
 		const errorResponse = createCLIErrorResponse(
-			"Failed to checkout branch",
+			"Failed to run git conflict abort",
 			null,
-			"git checkout",
+			"git conflict abort",
 			"unknown",
 			error instanceof Error ? error : undefined,
 		);
@@ -769,13 +2292,13 @@ git.openapi(gitCheckoutRoute, async (c) => {
 	}
 });
 
-// Mount the git log route
-git.openapi(gitLogRoute, async (c) => {
+// Mount the git worktree list route
+git.openapi(gitWorktreesRoute, async (c) => {
 	try {
-		const { folder, branch, limit } = c.req.valid("query");
+		const { folder } = c.req.valid("query");
 
-		// Resolve absolute path
-		const absolutePath = path.resolve(folder);
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
 
 		// Check if folder exists and is a git repository
 		const isRepo = await isGitRepository(absolutePath);
@@ -783,32 +2306,24 @@ git.openapi(gitLogRoute, async (c) => {
 			const errorResponse = createCLIErrorResponse(
 				"Not a git repository",
 				null,
-				"git log",
+				"git worktree list",
 				absolutePath,
 			);
 			return c.json(errorResponse, 400);
 		}
 
-		// Get git log for the specified branch
-		const logData = await getGitLog(
-			absolutePath,
-			branch,
-			limit ? parseInt(limit, 10) : 10,
-		);
+		const worktrees = await listWorktrees(absolutePath);
 
-		return c.json(
-			{
-				success: true,
-				data: logData,
-			},
-			200,
-		);
+		return c.json({ success: true, data: worktrees }, 200);
 	} catch (error) {
-		console.error("Error getting git log:", error);
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		console.error("Error listing git worktrees:", error);
 		const errorResponse = createCLIErrorResponse(
-			"Failed to get git log",
+			"Failed to list git worktrees",
 			null,
-			"git log",
+			"git worktree list",
 			"unknown",
 			error instanceof Error ? error : undefined,
 		);
@@ -816,13 +2331,13 @@ git.openapi(gitLogRoute, async (c) => {
 	}
 });
 
-// Mount the git status route
-git.openapi(gitStatusRoute, async (c) => {
+// Mount the git worktree remove route
+git.openapi(gitWorktreeRemoveRoute, async (c) => {
 	try {
-		const { folder } = c.req.valid("query");
+		const { folder, path: worktreePath, force } = c.req.valid("query");
 
-		// Resolve absolute path
-		const absolutePath = path.resolve(folder);
+		// Resolve absolute path, only inside the allowed folders
+		const absolutePath = await resolveAllowedPath(folder);
 
 		// Check if folder exists and is a git repository
 		const isRepo = await isGitRepository(absolutePath);
@@ -830,28 +2345,39 @@ git.openapi(gitStatusRoute, async (c) => {
 			const errorResponse = createCLIErrorResponse(
 				"Not a git repository",
 				null,
-				"git status",
+				"git worktree remove",
 				absolutePath,
 			);
 			return c.json(errorResponse, 400);
 		}
 
-		// Get detailed git status
-		const statusData = await getDetailedGitStatus(absolutePath);
+		await removeWorktree(absolutePath, worktreePath, force === "true");
 
 		return c.json(
 			{
 				success: true,
-				data: statusData,
+				message: `Removed worktree ${worktreePath}`,
 			},
 			200,
 		);
 	} catch (error) {
-		console.error("Error getting git status:", error);
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof GitWorktreeError) {
+			const errorResponse = createCLIErrorResponse(
+				error.message,
+				null,
+				"git worktree remove",
+				"unknown",
+			);
+			return c.json(errorResponse, 400);
+		}
+		console.error("Error removing git worktree:", error);
 		const errorResponse = createCLIErrorResponse(
-			"Failed to get git status",
+			"Failed to remove git worktree",
 			null,
-			"git status",
+			"git worktree remove",
 			"unknown",
 			error instanceof Error ? error : undefined,
 		);
@@ -0,0 +1,882 @@
+import { createReadStream } from "node:fs";
+import { Readable } from "node:stream";
+import { createRoute, OpenAPIHono, z } from "@hono/zod-openapi";
+import { ErrorSchema } from "../models/environment.js";
+import { PathForbiddenSchema } from "../models/filesystem.js";
+import {
+	TerminalRecordingListSchema,
+	TerminalRecordingSchema,
+	TerminalSessionActionSchema,
+	TerminalSessionListSchema,
+	TerminalSignalRequestSchema,
+	TerminalSnippetListSchema,
+	TerminalSnippetRunRequestSchema,
+	TerminalSnippetRunSchema,
+} from "../models/terminal.js";
+import { createCLIErrorResponse } from "../utils/cli-executor.js";
+import { ConfigError } from "../utils/config.js";
+import { CLI_COMMANDS, getDefaultWorkingDir } from "../utils/constants.js";
+import { getContentDisposition } from "../utils/file-archive.js";
+import {
+	PathAccessError,
+	pathForbiddenResponse,
+	resolveAllowedPath,
+	resolveDirectory,
+} from "../utils/path-access.js";
+import {
+	getTerminalSession,
+	killTerminalSession,
+	listTerminalSessions,
+	signalTerminalSession,
+	startTerminalRecording,
+	startTerminalSession,
+	stopTerminalRecording,
+	TerminalDriverError,
+	TerminalSessionError,
+	writeTerminalSession,
+} from "../utils/terminal.js";
+import {
+	resolveShellOptions,
+	TerminalOptionsError,
+} from "../utils/terminal-options.js";
+import {
+	deleteRecording,
+	getRecordingPath,
+	listRecordings,
+	TerminalRecordingError,
+} from "../utils/terminal-recording.js";
+import {
+	listSnippets,
+	renderSnippet,
+	TerminalSnippetError,
+} from "../utils/terminal-snippets.js";
+
+const SessionParamsSchema = z.object({
+	id: z.string().openapi({
+		param: {
+			name: "id",
+			in: "path",
+		},
+		example: "0b6c2f4e-3f1a-4c1e-9a57-5d2f8b1e7c90",
+		description: "Terminal session ID",
+	}),
+});
+
+// Route to list the running terminal sessions
+export const terminalSessionListRoute = createRoute({
+	method: "get",
+	path: "/sessions",
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: TerminalSessionListSchema,
+				},
+			},
+			description: "Running terminal sessions",
+		},
+	},
+});
+
+const SessionTokenQuerySchema = z.object({
+	token: z
+		.string()
+		.optional()
+		.openapi({
+			param: {
+				name: "token",
+				in: "query",
+			},
+			example: "5e0c7a1d-8b2f-4f6e-a3c9-1d7b2e4f6a80",
+			description:
+				"Token from the caller's presence messages, only the session's driver can kill it",
+		}),
+});
+
+// Route to kill a terminal session
+export const terminalSessionKillRoute = createRoute({
+	method: "delete",
+	path: "/sessions/{id}",
+	request: {
+		params: SessionParamsSchema,
+		query: SessionTokenQuerySchema,
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: TerminalSessionActionSchema,
+				},
+			},
+			description: "Session killed",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Session not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "The caller is not the session's driver",
+		},
+	},
+});
+
+// Route to send a signal to the foreground process of a terminal session
+export const terminalSessionSignalRoute = createRoute({
+	method: "post",
+	path: "/sessions/{id}/signal",
+	request: {
+		params: SessionParamsSchema,
+		body: {
+			content: {
+				"application/json": {
+					schema: TerminalSignalRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: TerminalSessionActionSchema,
+				},
+			},
+			description: "Signal sent",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Session not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "The caller is not the session's driver",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "The signal could not be sent",
+		},
+	},
+});
+
+const RecordingParamsSchema = z.object({
+	id: z.string().openapi({
+		param: {
+			name: "id",
+			in: "path",
+		},
+		example: "20250801-120000-sharing-loon-k3x9qa",
+		description: "Recording ID",
+	}),
+});
+
+// Route to start recording a terminal session
+export const terminalRecordingStartRoute = createRoute({
+	method: "post",
+	path: "/sessions/{id}/recording",
+	request: {
+		params: SessionParamsSchema,
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: TerminalRecordingSchema,
+				},
+			},
+			description: "Recording started",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Session not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "The session is already being recorded",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "The recording could not be created",
+		},
+	},
+});
+
+// Route to stop recording a terminal session
+export const terminalRecordingStopRoute = createRoute({
+	method: "delete",
+	path: "/sessions/{id}/recording",
+	request: {
+		params: SessionParamsSchema,
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: TerminalRecordingSchema,
+				},
+			},
+			description: "Recording stopped",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Session not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "The session is not being recorded",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "The recording could not be saved",
+		},
+	},
+});
+
+// Route to list terminal recordings
+export const terminalRecordingListRoute = createRoute({
+	method: "get",
+	path: "/recordings",
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: TerminalRecordingListSchema,
+				},
+			},
+			description: "Saved recordings",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "The recordings folder could not be read",
+		},
+	},
+});
+
+// Route to download a terminal recording
+export const terminalRecordingDownloadRoute = createRoute({
+	method: "get",
+	path: "/recordings/{id}",
+	request: {
+		params: RecordingParamsSchema,
+	},
+	responses: {
+		200: {
+			content: {
+				"application/x-asciicast": {
+					schema: z.string().openapi({ format: "binary" }),
+				},
+			},
+			description: "Recording in asciicast v2 format",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Invalid recording ID",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Recording not found",
+		},
+	},
+});
+
+// Route to delete a terminal recording
+export const terminalRecordingDeleteRoute = createRoute({
+	method: "delete",
+	path: "/recordings/{id}",
+	request: {
+		params: RecordingParamsSchema,
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: TerminalSessionActionSchema,
+				},
+			},
+			description: "Recording deleted",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Invalid recording ID",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Recording not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "The recording is still running",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "The recording could not be deleted",
+		},
+	},
+});
+
+const SnippetQuerySchema = z.object({
+	folder: z
+		.string()
+		.optional()
+		.openapi({
+			param: {
+				name: "folder",
+				in: "query",
+			},
+			example: "~/hello",
+			description:
+				"Folder whose repository's snippets are used, and new sessions start in",
+		}),
+});
+
+// Route to list the snippets of a repository and the global ones
+export const terminalSnippetListRoute = createRoute({
+	method: "get",
+	path: "/snippets",
+	request: {
+		query: SnippetQuerySchema,
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: TerminalSnippetListSchema,
+				},
+			},
+			description: "Available snippets",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "A config file could not be read",
+		},
+	},
+});
+
+// Route to run a snippet in a terminal session
+export const terminalSnippetRunRoute = createRoute({
+	method: "post",
+	path: "/snippets/run",
+	request: {
+		query: SnippetQuerySchema,
+		body: {
+			content: {
+				"application/json": {
+					schema: TerminalSnippetRunRequestSchema,
+				},
+			},
+		},
+	},
+	responses: {
+		200: {
+			content: {
+				"application/json": {
+					schema: TerminalSnippetRunSchema,
+				},
+			},
+			description: "Command typed into the session, a new one once it is ready",
+		},
+		400: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "A variable is missing or the session can't be started",
+		},
+		403: {
+			content: {
+				"application/json": {
+					schema: PathForbiddenSchema,
+				},
+			},
+			description: "Folder is outside the allowed folders",
+		},
+		404: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "Snippet or session not found",
+		},
+		409: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "The caller isn't the driver of the session",
+		},
+		500: {
+			content: {
+				"application/json": {
+					schema: ErrorSchema,
+				},
+			},
+			description: "A config file could not be read",
+		},
+	},
+});
+
+export const terminals = new OpenAPIHono();
+
+const sessionNotFoundResponse = (id: string, command: string) =>
+	createCLIErrorResponse(
+		`Terminal session ${id} not found`,
+		null,
+		command,
+		"unknown",
+	);
+
+// Mount the session list route
+terminals.openapi(terminalSessionListRoute, (c) => {
+	return c.json({ sessions: listTerminalSessions() }, 200);
+});
+
+// Mount the session kill route
+terminals.openapi(terminalSessionKillRoute, (c) => {
+	const { id } = c.req.valid("param");
+	const { token } = c.req.valid("query");
+
+	try {
+		if (!killTerminalSession(id, token)) {
+			return c.json(sessionNotFoundResponse(id, "kill"), 404);
+		}
+	} catch (error) {
+		if (error instanceof TerminalDriverError) {
+			return c.json(
+				createCLIErrorResponse(error.message, null, "kill", "unknown"),
+				409,
+			);
+		}
+		throw error;
+	}
+	return c.json(
+		{
+			success: true,
+			message: `Killed session ${id}`,
+		},
+		200,
+	);
+});
+
+// Mount the session signal route
+terminals.openapi(terminalSessionSignalRoute, async (c) => {
+	const { id } = c.req.valid("param");
+	const { signal, token } = c.req.valid("json");
+
+	try {
+		if (!(await signalTerminalSession(id, signal, token))) {
+			return c.json(sessionNotFoundResponse(id, `kill -${signal}`), 404);
+		}
+		return c.json(
+			{
+				success: true,
+				message: `Sent ${signal} to session ${id}`,
+			},
+			200,
+		);
+	} catch (error) {
+		if (error instanceof TerminalDriverError) {
+			return c.json(
+				createCLIErrorResponse(
+					error.message,
+					null,
+					`kill -${signal}`,
+					"unknown",
+				),
+				409,
+			);
+		}
+		if (!(error instanceof TerminalSessionError)) {
+			console.error("Error signaling terminal session:", error);
+		}
+		const errorResponse = createCLIErrorResponse(
+			error instanceof TerminalSessionError
+				? error.message
+				: "Failed to send signal",
+			null,
+			`kill -${signal}`,
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
+
+// Mount the recording start route
+terminals.openapi(terminalRecordingStartRoute, async (c) => {
+	const { id } = c.req.valid("param");
+
+	try {
+		const recording = await startTerminalRecording(id);
+		if (!recording) {
+			return c.json(sessionNotFoundResponse(id, "record"), 404);
+		}
+		return c.json(recording, 200);
+	} catch (error) {
+		const command = `record ${id}`;
+		if (error instanceof TerminalSessionError) {
+			return c.json(
+				createCLIErrorResponse(error.message, null, command, "unknown"),
+				409,
+			);
+		}
+		console.error("Error starting terminal recording:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to start recording",
+			null,
+			command,
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
+
+// Mount the recording stop route
+terminals.openapi(terminalRecordingStopRoute, async (c) => {
+	const { id } = c.req.valid("param");
+
+	try {
+		const recording = await stopTerminalRecording(id);
+		if (!recording) {
+			return c.json(sessionNotFoundResponse(id, "stop recording"), 404);
+		}
+		return c.json(recording, 200);
+	} catch (error) {
+		const command = `stop recording ${id}`;
+		if (error instanceof TerminalSessionError) {
+			return c.json(
+				createCLIErrorResponse(error.message, null, command, "unknown"),
+				409,
+			);
+		}
+		console.error("Error stopping terminal recording:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to save recording",
+			null,
+			command,
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
+
+// Mount the recording list route
+terminals.openapi(terminalRecordingListRoute, async (c) => {
+	try {
+		return c.json({ recordings: await listRecordings() }, 200);
+	} catch (error) {
+		console.error("Error listing terminal recordings:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to list recordings",
+			null,
+			"fs:readdir",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
+
+// Mount the recording download route
+terminals.openapi(terminalRecordingDownloadRoute, async (c) => {
+	const { id } = c.req.valid("param");
+
+	let filePath: string;
+	try {
+		filePath = getRecordingPath(id);
+	} catch (error) {
+		const message = error instanceof Error ? error.message : "Unknown error";
+		return c.json(
+			createCLIErrorResponse(message, null, "fs:read", "unknown"),
+			400,
+		);
+	}
+
+	// Open before answering so a missing file is a 404, not a broken stream
+	const file = createReadStream(filePath);
+	try {
+		await new Promise<void>((resolve, reject) => {
+			file.once("open", () => resolve());
+			file.once("error", reject);
+		});
+	} catch (error) {
+		return c.json(
+			createCLIErrorResponse(
+				`Recording ${id} not found`,
+				null,
+				"fs:read",
+				filePath,
+				error instanceof Error ? error : undefined,
+			),
+			404,
+		);
+	}
+
+	c.header("Content-Type", "application/x-asciicast");
+	c.header("Content-Disposition", getContentDisposition(`${id}.cast`));
+	return c.body(Readable.toWeb(file) as ReadableStream, 200);
+});
+
+// Mount the recording delete route
+terminals.openapi(terminalRecordingDeleteRoute, async (c) => {
+	const { id } = c.req.valid("param");
+
+	try {
+		getRecordingPath(id);
+	} catch (error) {
+		const message = error instanceof Error ? error.message : "Unknown error";
+		return c.json(
+			createCLIErrorResponse(message, null, "fs:unlink", "unknown"),
+			400,
+		);
+	}
+
+	try {
+		await deleteRecording(id);
+		return c.json(
+			{
+				success: true,
+				message: `Deleted recording ${id}`,
+			},
+			200,
+		);
+	} catch (error) {
+		if (error instanceof TerminalRecordingError) {
+			return c.json(
+				createCLIErrorResponse(error.message, null, "fs:unlink", "unknown"),
+				409,
+			);
+		}
+		if ((error as NodeJS.ErrnoException).code === "ENOENT") {
+			return c.json(
+				createCLIErrorResponse(
+					`Recording ${id} not found`,
+					null,
+					"fs:unlink",
+					"unknown",
+				),
+				404,
+			);
+		}
+		console.error("Error deleting terminal recording:", error);
+		const errorResponse = createCLIErrorResponse(
+			"Failed to delete recording",
+			null,
+			"fs:unlink",
+			"unknown",
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
+
+// Mount the snippet list route
+terminals.openapi(terminalSnippetListRoute, async (c) => {
+	const { folder } = c.req.valid("query");
+	const workingDir = folder ? resolveDirectory(folder) : getDefaultWorkingDir();
+
+	try {
+		// Only read config files inside the allowed folders
+		await resolveAllowedPath(workingDir);
+		return c.json({ snippets: await listSnippets(workingDir) }, 200);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (!(error instanceof ConfigError)) {
+			console.error("Error listing terminal snippets:", error);
+		}
+		const errorResponse = createCLIErrorResponse(
+			error instanceof ConfigError ? error.message : "Failed to list snippets",
+			null,
+			"snippets",
+			workingDir,
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
+
+// Mount the snippet run route
+terminals.openapi(terminalSnippetRunRoute, async (c) => {
+	const { folder } = c.req.valid("query");
+	const { name, sessionId, token, environmentId, variables } =
+		c.req.valid("json");
+	const workingDir = folder ? resolveDirectory(folder) : getDefaultWorkingDir();
+	const command = `snippet ${name}`;
+
+	try {
+		// Only read config files and start sessions inside the allowed folders
+		await resolveAllowedPath(workingDir);
+		const snippet = (await listSnippets(workingDir)).find(
+			(candidate) => candidate.name === name,
+		);
+		if (!snippet) {
+			return c.json(
+				createCLIErrorResponse(
+					`Snippet ${name} not found`,
+					null,
+					command,
+					workingDir,
+				),
+				404,
+			);
+		}
+
+		const existing = sessionId ? getTerminalSession(sessionId) : null;
+		if (sessionId && !existing) {
+			return c.json(sessionNotFoundResponse(sessionId, command), 404);
+		}
+		// The terminal's environment and folder win over the request's
+		const rendered = renderSnippet(snippet, {
+			...variables,
+			environmentId: existing ? existing.environmentId : environmentId,
+			folder: existing ? existing.workingDir : workingDir,
+		});
+
+		// Typed like a pasted command, lines end with a carriage return
+		const input = `${rendered.replace(/\r?\n/g, "\r")}\r`;
+		let session = existing;
+		if (session) {
+			writeTerminalSession(session.id, input, token);
+		} else {
+			// A new session gets the command once its shell is ready
+			session = environmentId
+				? startTerminalSession(
+						{ command: CLI_COMMANDS.TERMINAL, environmentId, workingDir },
+						input,
+					)
+				: startTerminalSession(
+						{ shell: await resolveShellOptions({ folder: workingDir }) },
+						input,
+					);
+		}
+		return c.json(
+			{
+				sessionId: session.id,
+				command: rendered,
+				created: !existing,
+			},
+			200,
+		);
+	} catch (error) {
+		if (error instanceof PathAccessError) {
+			return c.json(pathForbiddenResponse(error), 403);
+		}
+		if (error instanceof TerminalDriverError) {
+			return c.json(
+				createCLIErrorResponse(error.message, null, command, workingDir),
+				409,
+			);
+		}
+		if (
+			error instanceof TerminalSnippetError ||
+			error instanceof TerminalOptionsError ||
+			error instanceof TerminalSessionError
+		) {
+			return c.json(
+				createCLIErrorResponse(error.message, null, command, workingDir),
+				400,
+			);
+		}
+		if (!(error instanceof ConfigError)) {
+			console.error("Error running terminal snippet:", error);
+		}
+		const errorResponse = createCLIErrorResponse(
+			error instanceof ConfigError ? error.message : "Failed to run snippet",
+			null,
+			command,
+			workingDir,
+			error instanceof Error ? error : undefined,
+		);
+		return c.json(errorResponse, 500);
+	}
+});
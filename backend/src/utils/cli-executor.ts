@@ -16,6 +16,10 @@ export interface GenericCommandOptions {
 	workingDir?: string;
 	environment?: Record<string, string>;
 	forceColor?: boolean;
+	// Called with every chunk of output as it is produced, for streaming progress
+	onData?: (chunk: string, stream: "stdout" | "stderr") => void;
+	// Written to stdin, which is closed afterwards
+	input?: string;
 }
 
 export interface CLIExecutionResult {
@@ -101,6 +105,8 @@ export async function executeGenericCommand(
 		workingDir = process.cwd(),
 		environment = {},
 		forceColor = true,
+		onData,
+		input,
 	} = options;
 
 	return new Promise<CLIExecutionResult>((resolve, reject) => {
@@ -123,12 +129,20 @@ export async function executeGenericCommand(
 		let stdout = "";
 		let stderr = "";
 
+		if (input !== undefined) {
+			child.stdin?.end(input);
+		}
+
 		child.stdout?.on("data", (data) => {
-			stdout += data.toString();
+			const chunk = data.toString();
+			stdout += chunk;
+			onData?.(chunk, "stdout");
 		});
 
 		child.stderr?.on("data", (data) => {
-			stderr += data.toString();
+			const chunk = data.toString();
+			stderr += chunk;
+			onData?.(chunk, "stderr");
 		});
 
 		child.on("close", (code) => {
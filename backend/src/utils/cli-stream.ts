@@ -0,0 +1,233 @@
+import * as path from "node:path";
+import chokidar from "chokidar";
+import { executeGenericCommand } from "./cli-executor.js";
+import type { CLI_COMMANDS } from "./constants.js";
+
+export interface CLIStreamOptions {
+	command: typeof CLI_COMMANDS.LOG | typeof CLI_COMMANDS.DIFF;
+	environmentId: string;
+	workingDir: string;
+	cliPath: string;
+	// Run the command again whenever the environment changes
+	follow?: boolean;
+}
+
+type CLIStreamMessage =
+	| { type: "output"; data: string }
+	| { type: "update"; data: string; at: "start" | "end" }
+	| { type: "reset"; data: string }
+	| { type: "end"; code: number; timestamp: string }
+	| { type: "error"; error: string };
+
+// How often a followed environment is checked when the repository holding
+// it can't be watched, in milliseconds
+const FOLLOW_POLL_INTERVAL = 10000;
+
+/**
+ * Describe how the output of a command changed since its last run
+ *
+ * Logs list the newest commits first, so new entries are added at the
+ * start. Output that changed in any other way is sent whole.
+ */
+const describeChange = (
+	previous: string,
+	current: string,
+): CLIStreamMessage | null => {
+	if (current === previous) {
+		return null;
+	}
+	if (current.endsWith(previous)) {
+		return {
+			type: "update",
+			data: current.slice(0, current.length - previous.length),
+			at: "start",
+		};
+	}
+	if (current.startsWith(previous)) {
+		return {
+			type: "update",
+			data: current.slice(previous.length),
+			at: "end",
+		};
+	}
+	return { type: "reset", data: current };
+};
+
+/**
+ * Find the repository container-use keeps the environments in
+ *
+ * It's the "container-use" remote of the project, a local bare repository
+ * the agents commit to.
+ */
+const getEnvironmentRepository = async (
+	workingDir: string,
+): Promise<string | null> => {
+	const result = await executeGenericCommand({
+		command: "git",
+		args: ["remote", "get-url", "container-use"],
+		workingDir,
+		forceColor: false,
+	});
+	const url = result.stdout.trim();
+	return result.code === 0 && path.isAbsolute(url) ? url : null;
+};
+
+/**
+ * Call onChange whenever the branch of an environment moves, e.g. when the
+ * agent commits
+ *
+ * Falls back to polling when the repository isn't a local folder. Returns a
+ * function that stops watching.
+ */
+const watchEnvironment = async (
+	workingDir: string,
+	environmentId: string,
+	onChange: () => void,
+): Promise<() => void> => {
+	const repository = await getEnvironmentRepository(workingDir);
+	if (!repository) {
+		const timer = setInterval(onChange, FOLLOW_POLL_INTERVAL);
+		return () => clearInterval(timer);
+	}
+
+	// Branches are files under refs/heads until git packs them
+	const ref = path.join(repository, "refs", "heads", environmentId);
+	const packedRefs = path.join(repository, "packed-refs");
+	const watcher = chokidar.watch([path.dirname(ref), packedRefs], {
+		ignoreInitial: true,
+		depth: 0,
+	});
+	watcher.on("all", (_event, changedPath) => {
+		if (changedPath === ref || changedPath === packedRefs) {
+			onChange();
+		}
+	});
+	return () => {
+		watcher.close();
+	};
+};
+
+/**
+ * WebSocket handler streaming the log or diff of an environment
+ *
+ * The output is sent as the CLI produces it. In follow mode the command
+ * runs again whenever the environment's branch moves, and only what changed
+ * is sent.
+ *
+ * Messages sent to the client:
+ * - { type: "output", data } output of the first run, as it is produced
+ * - { type: "update", data, at } output added at the "start" or the "end"
+ *   since the last run, e.g. new log entries
+ * - { type: "reset", data } the whole output, when it changed otherwise
+ * - { type: "end", code, timestamp } after each run that sent output
+ * - { type: "error", error } when the command fails
+ * Without follow mode the socket is closed after the first run.
+ */
+export const handleCLIStream = (
+	ws: WebSocket,
+	options: CLIStreamOptions,
+): void => {
+	const { command, environmentId, workingDir, cliPath, follow } = options;
+	let closed = false;
+	let stopWatching: (() => void) | null = null;
+	ws.addEventListener("close", () => {
+		closed = true;
+		stopWatching?.();
+	});
+
+	const send = (message: CLIStreamMessage) => {
+		if (!closed) {
+			ws.send(JSON.stringify(message));
+		}
+	};
+
+	// Output of the last successful run, later runs are compared to it
+	let previous: string | null = null;
+
+	const run = async () => {
+		const isFirst = previous === null;
+		const result = await executeGenericCommand({
+			command: cliPath,
+			args: [command, environmentId],
+			workingDir,
+			onData: isFirst
+				? (chunk, stream) => {
+						if (stream === "stdout") {
+							send({ type: "output", data: chunk });
+						}
+					}
+				: undefined,
+		});
+
+		if (result.code !== 0) {
+			send({
+				type: "error",
+				error:
+					result.stderr.trim() ||
+					`${cliPath} ${command} exited with code ${result.code}`,
+			});
+			return;
+		}
+		if (!isFirst) {
+			const change = describeChange(previous ?? "", result.stdout);
+			if (!change) {
+				return;
+			}
+			send(change);
+		}
+		previous = result.stdout;
+		send({
+			type: "end",
+			code: result.code,
+			timestamp: new Date().toISOString(),
+		});
+	};
+
+	// Runs don't overlap, a change during a run triggers one more
+	let running = false;
+	let pending = false;
+	const refresh = () => {
+		if (closed) {
+			return;
+		}
+		if (running) {
+			pending = true;
+			return;
+		}
+		running = true;
+		run()
+			.catch((error) => {
+				send({
+					type: "error",
+					error: error instanceof Error ? error.message : "Unknown error",
+				});
+			})
+			.finally(() => {
+				running = false;
+				if (!follow) {
+					ws.close(1000, "Done");
+				} else if (pending) {
+					pending = false;
+					refresh();
+				}
+			});
+	};
+
+	refresh();
+	if (follow) {
+		watchEnvironment(workingDir, environmentId, refresh)
+			.then((stop) => {
+				if (closed) {
+					stop();
+				} else {
+					stopWatching = stop;
+				}
+			})
+			.catch((error) => {
+				send({
+					type: "error",
+					error: error instanceof Error ? error.message : "Unknown error",
+				});
+			});
+	}
+};
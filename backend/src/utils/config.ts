@@ -0,0 +1,40 @@
+import { promises as fs } from "node:fs";
+import { getConfigFile } from "./constants.js";
+
+/**
+ * Error raised for a config file that can't be read or isn't valid
+ */
+export class ConfigError extends Error {}
+
+/**
+ * Read a cuweb config file, the global one by default
+ *
+ * A missing file is an empty config. The file is read again on every call,
+ * so edits apply without restarting the server.
+ */
+export const readConfig = async (
+	file: string = getConfigFile(),
+): Promise<Record<string, unknown>> => {
+	let content: string;
+	try {
+		content = await fs.readFile(file, "utf-8");
+	} catch (error) {
+		if ((error as NodeJS.ErrnoException).code === "ENOENT") {
+			return {};
+		}
+		throw new ConfigError(
+			`Failed to read ${file}: ${error instanceof Error ? error.message : "Unknown error"}`,
+		);
+	}
+
+	let config: unknown;
+	try {
+		config = JSON.parse(content);
+	} catch {
+		throw new ConfigError(`${file} is not valid JSON`);
+	}
+	if (typeof config !== "object" || config === null || Array.isArray(config)) {
+		throw new ConfigError(`${file} must hold a JSON object`);
+	}
+	return config as Record<string, unknown>;
+};
@@ -1,3 +1,6 @@
+import * as os from "node:os";
+import * as path from "node:path";
+
 /**
  * Application constants and configuration values
  */
@@ -21,6 +24,10 @@ export const CLI_COMMANDS = {
 // CLI command type
 export type CLICommand = (typeof CLI_COMMANDS)[keyof typeof CLI_COMMANDS];
 
+// Environment IDs are generated names like "sharing-loon". They never start
+// with a dash, so they can't be mistaken for an option on a command line.
+export const ENVIRONMENT_ID_PATTERN = /^[A-Za-z0-9][A-Za-z0-9._-]*$/;
+
 // Query parameter names
 export const QUERY_PARAMS = {
 	FOLDER: "folder",
@@ -45,3 +52,181 @@ export function getDefaultWorkingDir(): string {
 export function getDefaultCLIPath(): string {
 	return process.env.CUWEB_CLI_BINARY || DEFAULT_CLI_PATH;
 }
+
+/**
+ * Get the folder where cuweb creates git worktrees for environments
+ */
+export function getWorktreesDir(): string {
+	return (
+		process.env.CUWEB_WORKTREES_DIR ||
+		path.join(os.homedir(), ".cuweb", "worktrees")
+	);
+}
+
+/**
+ * Get the folder deleted files are moved to, so they can be restored
+ */
+export function getTrashDir(): string {
+	return (
+		process.env.CUWEB_TRASH_DIR || path.join(os.homedir(), ".cuweb", "trash")
+	);
+}
+
+/**
+ * Get the folder terminal recordings are saved to
+ */
+export function getRecordingsDir(): string {
+	return (
+		process.env.CUWEB_RECORDINGS_DIR ||
+		path.join(os.homedir(), ".cuweb", "recordings")
+	);
+}
+
+/**
+ * Get the folders the file system API may access
+ *
+ * These are the working directory, the cuweb worktrees folder and any extra
+ * folders registered through CUWEB_ALLOWED_ROOTS, separated like PATH.
+ */
+export function getAllowedRoots(): string[] {
+	const extraRoots = (process.env.CUWEB_ALLOWED_ROOTS || "")
+		.split(path.delimiter)
+		.filter(Boolean);
+	return [getDefaultWorkingDir(), getWorktreesDir(), ...extraRoots].map(
+		(root) => path.resolve(root),
+	);
+}
+
+// Largest file the files API reads in one request (5 MiB)
+export const DEFAULT_MAX_FILE_SIZE = 5 * 1024 * 1024;
+
+/**
+ * Get the maximum file size to read from environment variable or default
+ */
+export function getMaxFileSize(): number {
+	const maxFileSize = Number(process.env.CUWEB_MAX_FILE_SIZE);
+	return Number.isInteger(maxFileSize) && maxFileSize > 0
+		? maxFileSize
+		: DEFAULT_MAX_FILE_SIZE;
+}
+
+// Terminal output kept per session and replayed on reconnect (256 KiB)
+export const DEFAULT_TERMINAL_SCROLLBACK = 256 * 1024;
+
+// Seconds a terminal session lives without any client attached
+export const DEFAULT_TERMINAL_IDLE_TIMEOUT = 10 * 60;
+
+/**
+ * Get how many characters of output a terminal session keeps
+ */
+export function getTerminalScrollback(): number {
+	const scrollback = Number(process.env.CUWEB_TERMINAL_SCROLLBACK);
+	return Number.isInteger(scrollback) && scrollback > 0
+		? scrollback
+		: DEFAULT_TERMINAL_SCROLLBACK;
+}
+
+/**
+ * Get how long a detached terminal session is kept, in milliseconds
+ */
+export function getTerminalIdleTimeout(): number {
+	const idleTimeout = Number(process.env.CUWEB_TERMINAL_IDLE_TIMEOUT);
+	return (
+		(Number.isInteger(idleTimeout) && idleTimeout > 0
+			? idleTimeout
+			: DEFAULT_TERMINAL_IDLE_TIMEOUT) * 1000
+	);
+}
+
+// Characters of output a terminal session sends per second (1 MiB)
+export const DEFAULT_TERMINAL_OUTPUT_RATE = 1024 * 1024;
+
+// Seconds between pings checking that a terminal's browser is still there
+export const DEFAULT_TERMINAL_PING_INTERVAL = 30;
+
+/**
+ * Get how many characters of output a terminal session sends per second,
+ * 0 for no limit
+ */
+export function getTerminalOutputRate(): number {
+	const outputRate = Number(process.env.CUWEB_TERMINAL_OUTPUT_RATE || NaN);
+	return Number.isInteger(outputRate) && outputRate >= 0
+		? outputRate
+		: DEFAULT_TERMINAL_OUTPUT_RATE;
+}
+
+/**
+ * Get the time between pings of terminal clients, in milliseconds
+ *
+ * Clients that haven't answered a ping by the next one are dropped.
+ */
+export function getTerminalPingInterval(): number {
+	const pingInterval = Number(process.env.CUWEB_TERMINAL_PING_INTERVAL);
+	return (
+		(Number.isInteger(pingInterval) && pingInterval > 0
+			? pingInterval
+			: DEFAULT_TERMINAL_PING_INTERVAL) * 1000
+	);
+}
+
+/**
+ * Check whether container-use commands in terminals run through a login
+ * shell, which loads the user's profile first
+ */
+export function getTerminalLoginShell(): boolean {
+	const loginShell = process.env.CUWEB_TERMINAL_LOGIN_SHELL;
+	return loginShell === "true" || loginShell === "1";
+}
+
+/**
+ * Get the cuweb config file, a JSON file with e.g. the terminal settings
+ */
+export function getConfigFile(): string {
+	return (
+		process.env.CUWEB_CONFIG || path.join(os.homedir(), ".cuweb", "config.json")
+	);
+}
+
+/**
+ * Get the shell plain terminals run, set with --terminal-shell
+ */
+export function getTerminalShell(): string | undefined {
+	return process.env.CUWEB_TERMINAL_SHELL || undefined;
+}
+
+/**
+ * Get the arguments of the shell of plain terminals, separated by spaces
+ */
+export function getTerminalShellArgs(): string[] | undefined {
+	const shellArgs = process.env.CUWEB_TERMINAL_SHELL_ARGS;
+	if (shellArgs === undefined) {
+		return undefined;
+	}
+	return shellArgs.split(/\s+/).filter(Boolean);
+}
+
+/**
+ * Get the extra environment variables of plain terminals
+ *
+ * CUWEB_TERMINAL_ENV holds them as a JSON object of strings.
+ */
+export function getTerminalEnv(): Record<string, string> {
+	try {
+		const env = JSON.parse(process.env.CUWEB_TERMINAL_ENV || "{}");
+		return Object.fromEntries(
+			Object.entries(env).filter(
+				(entry): entry is [string, string] => typeof entry[1] === "string",
+			),
+		);
+	} catch {
+		return {};
+	}
+}
+
+/**
+ * Get the size new plain terminals start with, set as "COLSxROWS"
+ */
+export function getTerminalSize(): { cols: number; rows: number } | undefined {
+	const match = /^(\d+)x(\d+)$/.exec(process.env.CUWEB_TERMINAL_SIZE || "");
+	return match ? { cols: Number(match[1]), rows: Number(match[2]) } : undefined;
+}
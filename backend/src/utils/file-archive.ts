@@ -0,0 +1,366 @@
+import { createReadStream, promises as fs } from "node:fs";
+import * as path from "node:path";
+import { pipeline, Readable } from "node:stream";
+import * as zlib from "node:zlib";
+
+export type ArchiveFormat = "zip" | "tar.gz";
+
+export const ARCHIVE_MIME_TYPES: Record<ArchiveFormat, string> = {
+	zip: "application/zip",
+	"tar.gz": "application/gzip",
+};
+
+// Never worth downloading, and large in busy repositories
+const ARCHIVE_SKIPPED = new Set([".git"]);
+
+interface ArchiveEntry {
+	// Path inside the archive, with forward slashes
+	name: string;
+	fullPath: string;
+	type: "file" | "folder";
+	size: number;
+	mode: number;
+	mtime: Date;
+}
+
+/**
+ * List a folder and everything below it, the folder itself first
+ *
+ * Symlinks are skipped, so an archive can't pull in files from outside the
+ * allowed roots.
+ */
+async function* walkFolder(folder: string): AsyncGenerator<ArchiveEntry> {
+	const pending = [{ fullPath: folder, name: path.basename(folder) }];
+	while (pending.length > 0) {
+		const current = pending.shift() as (typeof pending)[number];
+		const stats = await fs.lstat(current.fullPath);
+		yield {
+			name: `${current.name}/`,
+			fullPath: current.fullPath,
+			type: "folder",
+			size: 0,
+			mode: stats.mode & 0o7777,
+			mtime: stats.mtime,
+		};
+
+		const children = await fs.readdir(current.fullPath, {
+			withFileTypes: true,
+		});
+		children.sort((a, b) => a.name.localeCompare(b.name));
+		for (const child of children) {
+			if (ARCHIVE_SKIPPED.has(child.name)) continue;
+			const fullPath = path.join(current.fullPath, child.name);
+			const name = `${current.name}/${child.name}`;
+			if (child.isDirectory()) {
+				pending.push({ fullPath, name });
+			} else if (child.isFile()) {
+				const childStats = await fs.lstat(fullPath);
+				yield {
+					name,
+					fullPath,
+					type: "file",
+					size: childStats.size,
+					mode: childStats.mode & 0o7777,
+					mtime: childStats.mtime,
+				};
+			}
+		}
+	}
+}
+
+/**
+ * Read a file as exactly size bytes, in case it changes while being read
+ */
+async function* readExactly(
+	filePath: string,
+	size: number,
+): AsyncGenerator<Buffer> {
+	let remaining = size;
+	for await (const chunk of createReadStream(filePath)) {
+		if (remaining === 0) break;
+		const data = chunk as Buffer;
+		const part = data.length > remaining ? data.subarray(0, remaining) : data;
+		remaining -= part.length;
+		yield part;
+	}
+	if (remaining > 0) {
+		yield Buffer.alloc(remaining);
+	}
+}
+
+// tar
+
+const TAR_BLOCK = 512;
+
+function writeOctal(
+	header: Buffer,
+	value: number,
+	offset: number,
+	size: number,
+) {
+	header.write(
+		value.toString(8).padStart(size - 1, "0"),
+		offset,
+		size - 1,
+		"ascii",
+	);
+}
+
+function tarHeader(
+	name: string,
+	options: { size: number; mode: number; mtime: Date; typeflag: string },
+): Buffer {
+	const header = Buffer.alloc(TAR_BLOCK);
+	header.write(name, 0, 100, "utf-8");
+	writeOctal(header, options.mode, 100, 8);
+	writeOctal(header, 0, 108, 8);
+	writeOctal(header, 0, 116, 8);
+	writeOctal(header, options.size, 124, 12);
+	writeOctal(header, Math.floor(options.mtime.getTime() / 1000), 136, 12);
+	header.write(options.typeflag, 156, 1, "ascii");
+	header.write("ustar\0", 257, 6, "ascii");
+	header.write("00", 263, 2, "ascii");
+
+	// The checksum is computed with its own field filled with spaces
+	header.fill(" ", 148, 156);
+	let checksum = 0;
+	for (const byte of header) {
+		checksum += byte;
+	}
+	header.write(`${checksum.toString(8).padStart(6, "0")}\0 `, 148, 8, "ascii");
+	return header;
+}
+
+function tarPadding(size: number): Buffer {
+	return Buffer.alloc((TAR_BLOCK - (size % TAR_BLOCK)) % TAR_BLOCK);
+}
+
+/**
+ * PAX extended header carrying a path too long for the tar header
+ */
+function paxPathHeader(name: string, mtime: Date): Buffer[] {
+	const record = ` path=${name}\n`;
+	// The length prefix counts its own digits
+	const recordLength = Buffer.byteLength(record);
+	let length = recordLength + String(recordLength).length;
+	length = recordLength + String(length).length;
+	const data = Buffer.from(`${length}${record}`, "utf-8");
+	return [
+		tarHeader("././@PaxHeader", {
+			size: data.length,
+			mode: 0o644,
+			mtime,
+			typeflag: "x",
+		}),
+		data,
+		tarPadding(data.length),
+	];
+}
+
+async function* tarEntries(folder: string): AsyncGenerator<Buffer> {
+	for await (const entry of walkFolder(folder)) {
+		if (Buffer.byteLength(entry.name) > 100) {
+			yield* paxPathHeader(entry.name, entry.mtime);
+		}
+		yield tarHeader(entry.name, {
+			size: entry.size,
+			mode: entry.mode,
+			mtime: entry.mtime,
+			typeflag: entry.type === "folder" ? "5" : "0",
+		});
+		if (entry.type === "file") {
+			yield* readExactly(entry.fullPath, entry.size);
+			yield tarPadding(entry.size);
+		}
+	}
+	// An archive ends with two empty blocks
+	yield Buffer.alloc(TAR_BLOCK * 2);
+}
+
+// zip
+
+const CRC_TABLE = (() => {
+	const table = new Uint32Array(256);
+	for (let n = 0; n < 256; n++) {
+		let c = n;
+		for (let k = 0; k < 8; k++) {
+			c = c & 1 ? 0xedb88320 ^ (c >>> 1) : c >>> 1;
+		}
+		table[n] = c >>> 0;
+	}
+	return table;
+})();
+
+function crc32(data: Buffer, previous = 0): number {
+	let crc = previous ^ 0xffffffff;
+	for (const byte of data) {
+		crc = CRC_TABLE[(crc ^ byte) & 0xff] ^ (crc >>> 8);
+	}
+	return (crc ^ 0xffffffff) >>> 0;
+}
+
+function dosDateTime(date: Date): { time: number; date: number } {
+	// DOS dates start in 1980
+	const year = Math.max(date.getFullYear(), 1980);
+	return {
+		time:
+			(date.getHours() << 11) |
+			(date.getMinutes() << 5) |
+			Math.floor(date.getSeconds() / 2),
+		date: ((year - 1980) << 9) | ((date.getMonth() + 1) << 5) | date.getDate(),
+	};
+}
+
+interface ZipRecord {
+	name: Buffer;
+	method: number;
+	time: number;
+	date: number;
+	crc: number;
+	compressedSize: number;
+	size: number;
+	externalAttributes: number;
+	offset: number;
+}
+
+// Sizes are only known after compressing, so they follow the data
+const ZIP_FLAGS = 0x0008 | 0x0800; // data descriptor, UTF-8 names
+const ZIP_LIMIT = 0xffffffff;
+
+function zipLocalHeader(record: ZipRecord): Buffer {
+	const header = Buffer.alloc(30);
+	header.writeUInt32LE(0x04034b50, 0);
+	header.writeUInt16LE(20, 4);
+	header.writeUInt16LE(ZIP_FLAGS, 6);
+	header.writeUInt16LE(record.method, 8);
+	header.writeUInt16LE(record.time, 10);
+	header.writeUInt16LE(record.date, 12);
+	// CRC and sizes are left 0 and sent in the data descriptor
+	header.writeUInt16LE(record.name.length, 26);
+	return Buffer.concat([header, record.name]);
+}
+
+function zipDataDescriptor(record: ZipRecord): Buffer {
+	const descriptor = Buffer.alloc(16);
+	descriptor.writeUInt32LE(0x08074b50, 0);
+	descriptor.writeUInt32LE(record.crc, 4);
+	descriptor.writeUInt32LE(record.compressedSize, 8);
+	descriptor.writeUInt32LE(record.size, 12);
+	return descriptor;
+}
+
+function zipCentralHeader(record: ZipRecord): Buffer {
+	const header = Buffer.alloc(46);
+	header.writeUInt32LE(0x02014b50, 0);
+	header.writeUInt16LE((3 << 8) | 20, 4); // made on Unix
+	header.writeUInt16LE(20, 6);
+	header.writeUInt16LE(ZIP_FLAGS, 8);
+	header.writeUInt16LE(record.method, 10);
+	header.writeUInt16LE(record.time, 12);
+	header.writeUInt16LE(record.date, 14);
+	header.writeUInt32LE(record.crc, 16);
+	header.writeUInt32LE(record.compressedSize, 20);
+	header.writeUInt32LE(record.size, 24);
+	header.writeUInt16LE(record.name.length, 28);
+	header.writeUInt32LE(record.externalAttributes, 38);
+	header.writeUInt32LE(record.offset, 42);
+	return Buffer.concat([header, record.name]);
+}
+
+function zipEnd(records: ZipRecord[], offset: number, size: number): Buffer {
+	const end = Buffer.alloc(22);
+	end.writeUInt32LE(0x06054b50, 0);
+	end.writeUInt16LE(records.length, 8);
+	end.writeUInt16LE(records.length, 10);
+	end.writeUInt32LE(size, 12);
+	end.writeUInt32LE(offset, 16);
+	return end;
+}
+
+async function* zipEntries(folder: string): AsyncGenerator<Buffer> {
+	const records: ZipRecord[] = [];
+	let offset = 0;
+	const emit = function* (data: Buffer) {
+		offset += data.length;
+		yield data;
+	};
+
+	for await (const entry of walkFolder(folder)) {
+		if (entry.size > ZIP_LIMIT || offset > ZIP_LIMIT) {
+			throw new Error("Folder is too large for a zip archive, use tar.gz");
+		}
+		const isFolder = entry.type === "folder";
+		const record: ZipRecord = {
+			name: Buffer.from(entry.name, "utf-8"),
+			method: isFolder ? 0 : 8, // stored or deflated
+			...dosDateTime(entry.mtime),
+			crc: 0,
+			compressedSize: 0,
+			size: 0,
+			// Unix mode in the high bits, MS-DOS folder flag in the low ones
+			externalAttributes:
+				((((isFolder ? 0o040000 : 0o100000) | entry.mode) << 16) |
+					(isFolder ? 0x10 : 0)) >>>
+				0,
+			offset,
+		};
+		records.push(record);
+		yield* emit(zipLocalHeader(record));
+
+		if (!isFolder) {
+			const deflate = zlib.createDeflateRaw();
+			const input = Readable.from(readExactly(entry.fullPath, entry.size));
+			input.on("data", (chunk: Buffer) => {
+				record.crc = crc32(chunk, record.crc);
+				record.size += chunk.length;
+			});
+			// Read errors end the deflate stream with the same error
+			pipeline(input, deflate, () => {});
+			for await (const chunk of deflate) {
+				record.compressedSize += (chunk as Buffer).length;
+				yield* emit(chunk as Buffer);
+			}
+		}
+		yield* emit(zipDataDescriptor(record));
+	}
+
+	const centralOffset = offset;
+	for (const record of records) {
+		yield* emit(zipCentralHeader(record));
+	}
+	yield zipEnd(records, centralOffset, offset - centralOffset);
+}
+
+/**
+ * Stream a folder as a zip or gzipped tar archive
+ *
+ * Entries are read while the archive is sent, so large folders never sit
+ * in memory. The folder is the single top-level entry of the archive.
+ */
+export function createFolderArchive(
+	folder: string,
+	format: ArchiveFormat,
+): Readable {
+	if (format === "zip") {
+		return Readable.from(zipEntries(folder), { objectMode: false });
+	}
+	const gzip = zlib.createGzip();
+	const tar = Readable.from(tarEntries(folder), { objectMode: false });
+	// Read errors end the gzip stream with the same error
+	pipeline(tar, gzip, () => {});
+	return gzip;
+}
+
+/**
+ * Content-Disposition header with a UTF-8 name fallback
+ *
+ * Attachments are downloaded, inline files are shown by the browser.
+ */
+export function getContentDisposition(
+	fileName: string,
+	type: "attachment" | "inline" = "attachment",
+): string {
+	const asciiName = fileName.replace(/[^\x20-\x7e]|["\\]/g, "_");
+	const utf8Name = encodeURIComponent(fileName);
+	return `${type}; filename="${asciiName}"; filename*=UTF-8''${utf8Name}`;
+}
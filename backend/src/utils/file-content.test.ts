@@ -0,0 +1,141 @@
+import assert from "node:assert/strict";
+import {
+	lstatSync,
+	mkdtempSync,
+	readdirSync,
+	readFileSync,
+	rmSync,
+	symlinkSync,
+	unlinkSync,
+	writeFileSync,
+} from "node:fs";
+import { tmpdir } from "node:os";
+import * as path from "node:path";
+import { after, before, describe, it } from "node:test";
+import {
+	FileVersionConflictError,
+	getFileVersion,
+	hashContent,
+	readFileContent,
+	writeFileContent,
+} from "./file-content.js";
+
+describe("file content", () => {
+	let root: string;
+
+	before(() => {
+		root = mkdtempSync(path.join(tmpdir(), "cuweb-file-content-"));
+	});
+
+	after(() => {
+		rmSync(root, { recursive: true, force: true });
+	});
+
+	describe("readFileContent", () => {
+		it("doesn't split a UTF-8 character at the end of a range", async () => {
+			const filePath = path.join(root, "euro.txt");
+			// "€" is 3 bytes and "😀" is 4 bytes in UTF-8
+			writeFileSync(filePath, "ab€cd😀e");
+
+			const first = await readFileContent(filePath, { length: 4 });
+			assert.equal(first.content, "ab");
+			assert.equal(first.length, 2);
+			assert.equal(first.truncated, true);
+
+			const second = await readFileContent(filePath, {
+				offset: first.length,
+				length: 8,
+			});
+			assert.equal(second.content, "€cd");
+			assert.equal(second.length, 5);
+
+			const last = await readFileContent(filePath, {
+				offset: first.length + second.length,
+			});
+			assert.equal(last.content, "😀e");
+			assert.equal(last.truncated, false);
+		});
+
+		it("only hashes the content of a complete read", async () => {
+			const filePath = path.join(root, "hash.txt");
+			writeFileSync(filePath, "hello world\n");
+
+			const complete = await readFileContent(filePath);
+			assert.equal(complete.hash, hashContent(readFileSync(filePath)));
+			assert.equal(
+				(await readFileContent(filePath, { length: 5 })).hash,
+				undefined,
+			);
+			assert.equal(
+				(await readFileContent(filePath, { offset: 6 })).hash,
+				undefined,
+			);
+		});
+	});
+
+	describe("writeFileContent", () => {
+		it("writes when the expected version matches", async () => {
+			const filePath = path.join(root, "save.txt");
+			writeFileSync(filePath, "one\n");
+			const loaded = await getFileVersion(filePath);
+			assert.ok(loaded);
+
+			const saved = await writeFileContent(filePath, "two\n", {
+				hash: loaded.hash,
+			});
+			assert.equal(readFileSync(filePath, "utf-8"), "two\n");
+			assert.equal(saved.hash, hashContent(Buffer.from("two\n")));
+		});
+
+		it("rejects a file that changed since it was loaded", async () => {
+			const filePath = path.join(root, "conflict.txt");
+			writeFileSync(filePath, "one\n");
+			const loaded = await getFileVersion(filePath);
+			assert.ok(loaded);
+			writeFileSync(filePath, "changed elsewhere\n");
+
+			await assert.rejects(
+				writeFileContent(filePath, "two\n", { hash: loaded.hash }),
+				(error: unknown) => {
+					assert.ok(error instanceof FileVersionConflictError);
+					assert.equal(
+						error.current?.hash,
+						hashContent(Buffer.from("changed elsewhere\n")),
+					);
+					return true;
+				},
+			);
+			assert.equal(readFileSync(filePath, "utf-8"), "changed elsewhere\n");
+		});
+
+		it("rejects a file that was deleted since it was loaded", async () => {
+			const filePath = path.join(root, "deleted.txt");
+			writeFileSync(filePath, "one\n");
+			const loaded = await getFileVersion(filePath);
+			assert.ok(loaded);
+			unlinkSync(filePath);
+
+			await assert.rejects(
+				writeFileContent(filePath, "two\n", { modified: loaded.modified }),
+				(error: unknown) =>
+					error instanceof FileVersionConflictError && error.current === null,
+			);
+		});
+
+		it("writes through a symlink and keeps the link", async () => {
+			const target = path.join(root, "target.txt");
+			const link = path.join(root, "link.txt");
+			writeFileSync(target, "one\n");
+			symlinkSync(target, link);
+
+			await writeFileContent(link, "two\n");
+			assert.equal(lstatSync(link).isSymbolicLink(), true);
+			assert.equal(readFileSync(target, "utf-8"), "two\n");
+			// No temporary file is left next to the target
+			assert.deepEqual(
+				readdirSync(root).filter((name) => name.includes(".cuweb-")),
+				[],
+			);
+		});
+	});
+});
@@ -0,0 +1,351 @@
+import { createHash } from "node:crypto";
+import { promises as fs } from "node:fs";
+import * as path from "node:path";
+import type { FileContent, FileVersion } from "../models/filesystem.js";
+import { getMaxFileSize } from "./constants.js";
+
+/**
+ * Error raised when a file cannot be read as requested
+ */
+export class FileContentError extends Error {}
+
+/**
+ * Error raised when a file changed on disk since the version a client saw
+ */
+export class FileVersionConflictError extends Error {
+	current: FileVersion | null;
+
+	constructor(message: string, current: FileVersion | null) {
+		super(message);
+		this.current = current;
+	}
+}
+
+// Like git, only the start of a file is inspected to decide if it is binary
+const BINARY_SNIFF_SIZE = 8000;
+
+const MIME_TYPES: Record<string, string> = {
+	txt: "text/plain",
+	md: "text/markdown",
+	markdown: "text/markdown",
+	html: "text/html",
+	htm: "text/html",
+	css: "text/css",
+	csv: "text/csv",
+	tsv: "text/tab-separated-values",
+	xml: "application/xml",
+	js: "text/javascript",
+	mjs: "text/javascript",
+	cjs: "text/javascript",
+	jsx: "text/javascript",
+	ts: "text/typescript",
+	tsx: "text/typescript",
+	json: "application/json",
+	yml: "application/yaml",
+	yaml: "application/yaml",
+	toml: "application/toml",
+	sh: "application/x-sh",
+	py: "text/x-python",
+	go: "text/x-go",
+	rs: "text/x-rust",
+	java: "text/x-java",
+	c: "text/x-c",
+	h: "text/x-c",
+	cpp: "text/x-c++",
+	svg: "image/svg+xml",
+	png: "image/png",
+	jpg: "image/jpeg",
+	jpeg: "image/jpeg",
+	gif: "image/gif",
+	webp: "image/webp",
+	ico: "image/x-icon",
+	bmp: "image/bmp",
+	pdf: "application/pdf",
+	zip: "application/zip",
+	gz: "application/gzip",
+	tar: "application/x-tar",
+	wasm: "application/wasm",
+	mp3: "audio/mpeg",
+	wav: "audio/wav",
+	mp4: "video/mp4",
+	webm: "video/webm",
+	woff: "font/woff",
+	woff2: "font/woff2",
+	ttf: "font/ttf",
+};
+
+/**
+ * Guess a file's MIME type from its extension
+ */
+export function getMimeType(filePath: string, binary: boolean): string {
+	const extension = path.extname(filePath).slice(1).toLowerCase();
+	return (
+		MIME_TYPES[extension] ??
+		(binary ? "application/octet-stream" : "text/plain")
+	);
+}
+
+/**
+ * Check whether a chunk of a file looks binary
+ *
+ * A NUL byte is a reliable sign; otherwise a high share of control
+ * characters (besides tabs and line breaks) is treated as binary as well.
+ */
+export function isBinaryContent(buffer: Buffer): boolean {
+	const sample = buffer.subarray(0, BINARY_SNIFF_SIZE);
+	if (sample.length === 0) {
+		return false;
+	}
+	if (sample.includes(0)) {
+		return true;
+	}
+
+	let control = 0;
+	for (const byte of sample) {
+		if (byte < 32 && byte !== 9 && byte !== 10 && byte !== 13 && byte !== 12) {
+			control++;
+		}
+	}
+	return control / sample.length > 0.1;
+}
+
+/**
+ * Weak ETag from size and modification time, like most static file servers
+ */
+export function getFileETag(stats: { size: number; mtimeMs: number }): string {
+	const mtime = Math.floor(stats.mtimeMs).toString(16);
+	return `W/"${stats.size.toString(16)}-${mtime}"`;
+}
+
+/**
+ * Check conditional request headers against a file's ETag and mtime
+ *
+ * If-None-Match wins over If-Modified-Since, as in RFC 9110.
+ */
+export function isFileNotModified(
+	stats: { size: number; mtimeMs: number },
+	ifNoneMatch: string | undefined,
+	ifModifiedSince: string | undefined,
+): boolean {
+	if (ifNoneMatch) {
+		const etag = getFileETag(stats);
+		return ifNoneMatch.split(",").some((tag) => tag.trim() === etag);
+	}
+	return (
+		ifModifiedSince !== undefined &&
+		Math.floor(stats.mtimeMs / 1000) * 1000 <= Date.parse(ifModifiedSince)
+	);
+}
+
+/**
+ * SHA-256 of file content, used as a version that survives touch and copies
+ *
+ * The raw bytes are hashed, decoding them first would give files that
+ * aren't valid UTF-8 a different hash.
+ */
+export function hashContent(content: Buffer): string {
+	return createHash("sha256").update(content).digest("hex");
+}
+
+async function readRange(
+	filePath: string,
+	offset: number,
+	length: number,
+): Promise<Buffer> {
+	const handle = await fs.open(filePath, "r");
+	try {
+		const buffer = Buffer.alloc(length);
+		const { bytesRead } = await handle.read(buffer, 0, length, offset);
+		return buffer.subarray(0, bytesRead);
+	} finally {
+		await handle.close();
+	}
+}
+
+/**
+ * Content-Type to serve a file's raw bytes with
+ *
+ * The start of the file decides between text and binary for unknown
+ * extensions, and text is always declared as UTF-8.
+ */
+export async function getRawContentType(filePath: string): Promise<string> {
+	const head = await readRange(filePath, 0, BINARY_SNIFF_SIZE);
+	const mimeType = getMimeType(filePath, isBinaryContent(head));
+	return mimeType.startsWith("text/") ? `${mimeType}; charset=utf-8` : mimeType;
+}
+
+/**
+ * Read a byte range of a file, at most the configured maximum size
+ *
+ * Without a length the file is read from offset up to the maximum size, and
+ * truncated is set if there is more. Text is returned as UTF-8 and binary
+ * content as base64.
+ */
+export async function readFileContent(
+	filePath: string,
+	options: { offset?: number; length?: number } = {},
+): Promise<FileContent> {
+	const stats = await fs.stat(filePath);
+	if (stats.isDirectory()) {
+		throw new FileContentError(`Path ${filePath} is a folder`);
+	}
+
+	const maxSize = getMaxFileSize();
+	const offset = options.offset ?? 0;
+	if (offset > stats.size) {
+		throw new FileContentError(
+			`Offset ${offset} is past the end of the file (${stats.size} bytes)`,
+		);
+	}
+	const length = Math.min(
+		options.length ?? maxSize,
+		maxSize,
+		stats.size - offset,
+	);
+
+	// Sniff from the start of the file so every range is classified the same
+	const head =
+		offset === 0 && length >= BINARY_SNIFF_SIZE
+			? null
+			: await readRange(filePath, 0, BINARY_SNIFF_SIZE);
+	let data = await readRange(filePath, offset, length);
+	const binary = isBinaryContent(head ?? data);
+
+	// Don't split a multi-byte UTF-8 character at the end of a partial read
+	if (!binary && offset + data.length < stats.size) {
+		let start = data.length - 1;
+		while (start > 0 && (data[start] & 0xc0) === 0x80) {
+			start--;
+		}
+		const lead = data[start];
+		const expected =
+			lead >= 0xf0 ? 4 : lead >= 0xe0 ? 3 : lead >= 0xc0 ? 2 : 1;
+		if (start >= 0 && data.length - start < expected) {
+			data = data.subarray(0, start);
+		}
+	}
+
+	const complete = offset === 0 && data.length === stats.size;
+
+	return {
+		path: filePath,
+		size: stats.size,
+		mimeType: getMimeType(filePath, binary),
+		binary,
+		encoding: binary ? "base64" : "utf-8",
+		content: data.toString(binary ? "base64" : "utf-8"),
+		offset,
+		length: data.length,
+		truncated: offset + data.length < stats.size,
+		maxSize,
+		modified: stats.mtime.toISOString(),
+		etag: getFileETag(stats),
+		...(complete && { hash: hashContent(data) }),
+	};
+}
+
+/**
+ * Get the current version of a file, or null if it does not exist
+ */
+export async function getFileVersion(
+	filePath: string,
+): Promise<FileVersion | null> {
+	try {
+		const stats = await fs.stat(filePath);
+		if (stats.isDirectory()) {
+			throw new FileContentError(`Path ${filePath} is a folder`);
+		}
+		const content = await fs.readFile(filePath);
+		return {
+			size: stats.size,
+			modified: stats.mtime.toISOString(),
+			etag: getFileETag(stats),
+			hash: hashContent(content),
+		};
+	} catch (error) {
+		if ((error as NodeJS.ErrnoException).code === "ENOENT") {
+			return null;
+		}
+		throw error;
+	}
+}
+
+const isSameVersion = (a: FileVersion | null, b: FileVersion | null) =>
+	a === null || b === null
+		? a === b
+		: a.modified === b.modified && a.hash === b.hash;
+
+/**
+ * Write a file if it is still at the version the client last saw
+ *
+ * The expected version is a modification time or content hash; without one
+ * the file is overwritten. The content is written to a temporary file and
+ * renamed over the original, so watchers never see a partial file. A
+ * symlink is written through, the file it points to is replaced.
+ *
+ * The version is checked again right before the rename. Without locking,
+ * a change landing between that check and the rename is still overwritten.
+ */
+export async function writeFileContent(
+	filePath: string,
+	content: string,
+	expected: { modified?: string; hash?: string } = {},
+): Promise<FileVersion> {
+	// Renaming over a symlink would replace the link with a regular file
+	const targetPath = await fs.realpath(filePath).catch((error) => {
+		if ((error as NodeJS.ErrnoException).code === "ENOENT") {
+			return filePath;
+		}
+		throw error;
+	});
+
+	const current = await getFileVersion(targetPath);
+	if (current === null && (expected.modified || expected.hash)) {
+		throw new FileVersionConflictError(
+			`File was deleted since it was loaded: ${filePath}`,
+			null,
+		);
+	}
+	if (
+		current &&
+		((expected.modified && expected.modified !== current.modified) ||
+			(expected.hash && expected.hash !== current.hash))
+	) {
+		throw new FileVersionConflictError(
+			`File changed on disk since it was loaded: ${filePath}`,
+			current,
+		);
+	}
+
+	const stats = current ? await fs.stat(targetPath) : null;
+
+	const tempPath = path.join(
+		path.dirname(targetPath),
+		`.${path.basename(targetPath)}.cuweb-${process.pid}-${Date.now()}`,
+	);
+	try {
+		await fs.writeFile(tempPath, content, {
+			encoding: "utf-8",
+			// Keep the permissions of the existing file, e.g. executable scripts
+			...(stats && { mode: stats.mode & 0o7777 }),
+		});
+		// The file may have changed while the new content was written
+		const latest = await getFileVersion(targetPath);
+		if (!isSameVersion(latest, current)) {
+			throw new FileVersionConflictError(
+				`File changed on disk while saving: ${filePath}`,
+				latest,
+			);
+		}
+		await fs.rename(tempPath, targetPath);
+	} catch (error) {
+		await fs.rm(tempPath, { force: true });
+		throw error;
+	}
+
+	const version = await getFileVersion(targetPath);
+	if (!version) {
+		throw new Error(`File disappeared after saving: ${filePath}`);
+	}
+	return version;
+}
@@ -0,0 +1,343 @@
+import { randomBytes } from "node:crypto";
+import { createWriteStream, promises as fs } from "node:fs";
+import * as os from "node:os";
+import * as path from "node:path";
+import { Readable } from "node:stream";
+import { pipeline } from "node:stream/promises";
+import type { ReadableStream as NodeReadableStream } from "node:stream/web";
+import type { FileOperation, TrashEntry } from "../models/filesystem.js";
+import { getTrashDir } from "./constants.js";
+
+/**
+ * Error raised when a file operation is rejected, with the status to report
+ */
+export class FileOperationError extends Error {
+	status: 400 | 404 | 409;
+
+	constructor(message: string, status: 400 | 404 | 409 = 400) {
+		super(message);
+		this.status = status;
+	}
+}
+
+// Stored next to each trashed item to know where it came from
+const TRASH_INFO_FILE = ".cuweb-trash.json";
+
+interface TrashInfo {
+	originalPath: string;
+	type: "file" | "folder";
+	deletedAt: string;
+}
+
+async function getType(entryPath: string): Promise<"file" | "folder" | null> {
+	try {
+		const stats = await fs.lstat(entryPath);
+		return stats.isDirectory() ? "folder" : "file";
+	} catch (error) {
+		if ((error as NodeJS.ErrnoException).code === "ENOENT") {
+			return null;
+		}
+		throw error;
+	}
+}
+
+async function requireType(entryPath: string): Promise<"file" | "folder"> {
+	const type = await getType(entryPath);
+	if (!type) {
+		throw new FileOperationError(`Path not found: ${entryPath}`, 404);
+	}
+	return type;
+}
+
+function isInside(parent: string, child: string): boolean {
+	const relative = path.relative(parent, child);
+	return (
+		relative === "" ||
+		(!relative.startsWith("..") && !path.isAbsolute(relative))
+	);
+}
+
+/**
+ * Refuse operations that would remove the file system root, the home folder
+ * or the trash itself
+ */
+function assertRemovable(entryPath: string) {
+	if (
+		entryPath === path.parse(entryPath).root ||
+		entryPath === os.homedir() ||
+		isInside(entryPath, getTrashDir())
+	) {
+		throw new FileOperationError(`Refusing to remove ${entryPath}`);
+	}
+}
+
+/**
+ * Rename across file systems by copying and removing when rename can't
+ */
+async function moveAcrossDevices(source: string, destination: string) {
+	try {
+		await fs.rename(source, destination);
+	} catch (error) {
+		if ((error as NodeJS.ErrnoException).code !== "EXDEV") {
+			throw error;
+		}
+		await fs.cp(source, destination, {
+			recursive: true,
+			preserveTimestamps: true,
+			verbatimSymlinks: true,
+		});
+		await fs.rm(source, { recursive: true, force: true });
+	}
+}
+
+/**
+ * Make room for a move or copy, moving an existing destination to the trash
+ * when overwriting
+ */
+async function prepareDestination(destination: string, overwrite: boolean) {
+	if (!(await getType(destination))) {
+		await fs.mkdir(path.dirname(destination), { recursive: true });
+		return;
+	}
+	if (!overwrite) {
+		throw new FileOperationError(
+			`Destination already exists: ${destination}`,
+			409,
+		);
+	}
+	await trashEntry(destination);
+}
+
+/**
+ * Create an empty folder, or a file with optional content
+ *
+ * Missing parent folders are created as well.
+ */
+export async function createEntry(
+	entryPath: string,
+	type: "file" | "folder",
+	content = "",
+): Promise<FileOperation> {
+	if (await getType(entryPath)) {
+		throw new FileOperationError(`Path already exists: ${entryPath}`, 409);
+	}
+
+	if (type === "folder") {
+		await fs.mkdir(entryPath, { recursive: true });
+	} else {
+		await fs.mkdir(path.dirname(entryPath), { recursive: true });
+		// "wx" fails instead of overwriting a file created in the meantime
+		await fs.writeFile(entryPath, content, { encoding: "utf-8", flag: "wx" });
+	}
+
+	return {
+		operation: type === "folder" ? "mkdir" : "create",
+		type,
+		path: entryPath,
+	};
+}
+
+/**
+ * Rename or move a file or folder
+ */
+export async function moveEntry(
+	source: string,
+	destination: string,
+	overwrite = false,
+): Promise<FileOperation> {
+	const type = await requireType(source);
+	if (source === destination) {
+		throw new FileOperationError("Source and destination are the same");
+	}
+	if (type === "folder" && isInside(source, destination)) {
+		throw new FileOperationError("Cannot move a folder into itself");
+	}
+	assertRemovable(source);
+
+	await prepareDestination(destination, overwrite);
+	await moveAcrossDevices(source, destination);
+
+	return { operation: "move", type, source, path: destination };
+}
+
+/**
+ * Copy a file or folder, including the contents of folders
+ */
+export async function copyEntry(
+	source: string,
+	destination: string,
+	overwrite = false,
+): Promise<FileOperation> {
+	const type = await requireType(source);
+	if (source === destination) {
+		throw new FileOperationError("Source and destination are the same");
+	}
+	if (type === "folder" && isInside(source, destination)) {
+		throw new FileOperationError("Cannot copy a folder into itself");
+	}
+
+	await prepareDestination(destination, overwrite);
+	await fs.cp(source, destination, {
+		recursive: true,
+		preserveTimestamps: true,
+		verbatimSymlinks: true,
+		errorOnExist: true,
+		force: false,
+	});
+
+	return { operation: "copy", type, source, path: destination };
+}
+
+/**
+ * Move a file or folder to the trash, from where it can be restored
+ *
+ * Each trashed item gets its own folder holding the item and a note of its
+ * original path.
+ */
+export async function trashEntry(entryPath: string): Promise<FileOperation> {
+	const type = await requireType(entryPath);
+	assertRemovable(entryPath);
+
+	const trashId = `${Date.now()}-${randomBytes(4).toString("hex")}`;
+	const trashFolder = path.join(getTrashDir(), trashId);
+	await fs.mkdir(trashFolder, { recursive: true });
+
+	const info: TrashInfo = {
+		originalPath: entryPath,
+		type,
+		deletedAt: new Date().toISOString(),
+	};
+	await fs.writeFile(
+		path.join(trashFolder, TRASH_INFO_FILE),
+		JSON.stringify(info, null, 2),
+	);
+
+	try {
+		await moveAcrossDevices(
+			entryPath,
+			path.join(trashFolder, path.basename(entryPath)),
+		);
+	} catch (error) {
+		await fs.rm(trashFolder, { recursive: true, force: true });
+		throw error;
+	}
+
+	return { operation: "trash", type, path: entryPath, trashId };
+}
+
+async function readTrashInfo(trashId: string): Promise<TrashInfo | null> {
+	try {
+		const info = await fs.readFile(
+			path.join(getTrashDir(), trashId, TRASH_INFO_FILE),
+			"utf-8",
+		);
+		return JSON.parse(info) as TrashInfo;
+	} catch {
+		return null;
+	}
+}
+
+/**
+ * List trashed items, most recently deleted first
+ */
+export async function listTrash(): Promise<TrashEntry[]> {
+	let trashIds: string[];
+	try {
+		trashIds = await fs.readdir(getTrashDir());
+	} catch {
+		return [];
+	}
+
+	const entries: TrashEntry[] = [];
+	for (const trashId of trashIds) {
+		const info = await readTrashInfo(trashId);
+		if (info) {
+			entries.push({ id: trashId, ...info });
+		}
+	}
+
+	return entries.sort((a, b) => b.deletedAt.localeCompare(a.deletedAt));
+}
+
+/**
+ * Move a trashed item back to its original path
+ */
+export async function restoreEntry(
+	trashId: string,
+	overwrite = false,
+): Promise<FileOperation> {
+	// IDs are folder names in the trash, never paths
+	if (path.basename(trashId) !== trashId || trashId.startsWith(".")) {
+		throw new FileOperationError(`Invalid trash ID: ${trashId}`);
+	}
+	const info = await readTrashInfo(trashId);
+	if (!info) {
+		throw new FileOperationError(`Trash item not found: ${trashId}`, 404);
+	}
+
+	const trashFolder = path.join(getTrashDir(), trashId);
+	await prepareDestination(info.originalPath, overwrite);
+	await moveAcrossDevices(
+		path.join(trashFolder, path.basename(info.originalPath)),
+		info.originalPath,
+	);
+	await fs.rm(trashFolder, { recursive: true, force: true });
+
+	return {
+		operation: "restore",
+		type: info.type,
+		path: info.originalPath,
+		trashId,
+	};
+}
+
+/**
+ * Path an uploaded file is written to, from the name the client sent
+ *
+ * Names may contain folders, e.g. from a dropped folder, but must stay
+ * inside the target folder.
+ */
+export function resolveUploadPath(folder: string, name: string): string {
+	const relative = path.normalize(name.replaceAll("\\", "/"));
+	if (
+		!name ||
+		path.isAbsolute(relative) ||
+		relative === "." ||
+		relative.split(path.sep).includes("..")
+	) {
+		throw new FileOperationError(`Invalid upload file name: ${name}`);
+	}
+	return path.join(folder, relative);
+}
+
+/**
+ * Write an uploaded file, streaming it to disk
+ *
+ * With overwrite an existing file is moved to the trash first, like other
+ * operations that replace files.
+ */
+export async function writeUploadedFile(
+	destination: string,
+	file: Blob,
+	overwrite = false,
+): Promise<{ path: string; size: number }> {
+	await prepareDestination(destination, overwrite);
+	try {
+		await pipeline(
+			Readable.fromWeb(file.stream() as NodeReadableStream),
+			// "wx" fails instead of overwriting a file created in the meantime
+			createWriteStream(destination, { flags: "wx" }),
+		);
+	} catch (error) {
+		if ((error as NodeJS.ErrnoException).code === "EEXIST") {
+			throw new FileOperationError(
+				`Destination already exists: ${destination}`,
+				409,
+			);
+		}
+		// Don't leave a partial file behind
+		await fs.rm(destination, { force: true });
+		throw error;
+	}
+	return { path: destination, size: file.size };
+}
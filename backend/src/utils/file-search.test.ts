@@ -0,0 +1,88 @@
+import assert from "node:assert/strict";
+import { mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import * as path from "node:path";
+import { after, before, describe, it } from "node:test";
+import {
+	type FileContentMatches,
+	FileSearchError,
+	type FileSearchEvent,
+	type FileSearchOptions,
+	REGEX_FILE_TIMEOUT_MS,
+	searchFiles,
+} from "./file-search.js";
+
+describe("content search", () => {
+	let root: string;
+
+	const collect = async (options: Omit<FileSearchOptions, "root">) => {
+		const events: FileSearchEvent[] = [];
+		for await (const event of searchFiles({ root, ...options })) {
+			events.push(event);
+		}
+		return events.filter(
+			(event): event is FileContentMatches => event.type === "content",
+		);
+	};
+
+	before(() => {
+		root = mkdtempSync(path.join(tmpdir(), "cuweb-file-search-"));
+		writeFileSync(
+			path.join(root, "notes.txt"),
+			"first line\ncall (a+b) here\nfoo42 and foo7\n",
+		);
+		writeFileSync(path.join(root, "slow.txt"), `${"a".repeat(40)}b\n`);
+	});
+
+	after(() => {
+		rmSync(root, { recursive: true, force: true });
+	});
+
+	it("matches a literal query with regex characters", async () => {
+		const results = await collect({ query: "(a+b)", mode: "content" });
+		assert.deepEqual(
+			results.map((result) => [
+				result.path,
+				result.matches.map((match) => [match.line, match.column]),
+			]),
+			[["notes.txt", [[2, 6]]]],
+		);
+	});
+
+	it("matches a regular expression", async () => {
+		const results = await collect({
+			query: "foo\\d+",
+			mode: "content",
+			regex: true,
+		});
+		assert.equal(results.length, 1);
+		assert.deepEqual(
+			results[0].matches.map((match) => [match.line, match.length]),
+			[[3, 5]],
+		);
+	});
+
+	it("rejects an invalid regular expression", async () => {
+		await assert.rejects(
+			collect({ query: "foo(", mode: "content", regex: true }),
+			FileSearchError,
+		);
+	});
+
+	it("stops a regular expression that backtracks forever", async () => {
+		const startedAt = Date.now();
+		let ticks = 0;
+		const timer = setInterval(() => ticks++, 100);
+		try {
+			await assert.rejects(
+				collect({ query: "(a+)+$", mode: "content", regex: true }),
+				/took too long/,
+			);
+		} finally {
+			clearInterval(timer);
+		}
+		assert.ok(Date.now() - startedAt < REGEX_FILE_TIMEOUT_MS + 2000);
+		// The event loop kept running while the pattern was matched
+		assert.ok(ticks > 0);
+	});
+});
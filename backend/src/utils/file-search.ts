@@ -0,0 +1,529 @@
+import { type Dirent, promises as fs } from "node:fs";
+import * as path from "node:path";
+import { Worker } from "node:worker_threads";
+import { executeGenericCommand } from "./cli-executor.js";
+import { getMaxFileSize } from "./constants.js";
+import { isBinaryContent } from "./file-content.js";
+
+/**
+ * Error raised when a search is rejected before it starts, e.g. a bad regex
+ */
+export class FileSearchError extends Error {}
+
+export type FileSearchMode = "name" | "content";
+
+export interface FileSearchOptions {
+	root: string;
+	query: string;
+	mode: FileSearchMode;
+	// Content search only: treat the query as a regular expression
+	regex?: boolean;
+	caseSensitive?: boolean;
+	// Content search only: lines shown before and after each match
+	contextLines?: number;
+	maxFiles?: number;
+	maxResults?: number;
+	signal?: AbortSignal;
+}
+
+export interface FileNameMatch {
+	type: "name";
+	path: string;
+	score: number;
+	// Positions in path of the matched query characters
+	indices: number[];
+}
+
+export interface FileContentMatch {
+	line: number;
+	column: number;
+	length: number;
+	text: string;
+	// Where text starts in the line, when a long line is clipped
+	textOffset: number;
+	before: string[];
+	after: string[];
+}
+
+export interface FileContentMatches {
+	type: "content";
+	path: string;
+	matches: FileContentMatch[];
+}
+
+export interface FileSearchSummary {
+	type: "done";
+	filesSearched: number;
+	results: number;
+	// Set when the file or result limit stopped the search early
+	truncated: boolean;
+	durationMs: number;
+}
+
+export type FileSearchEvent =
+	| FileNameMatch
+	| FileContentMatches
+	| FileSearchSummary;
+
+export const DEFAULT_SEARCH_MAX_FILES = 20000;
+export const DEFAULT_SEARCH_MAX_RESULTS = 500;
+export const MAX_CONTEXT_LINES = 10;
+
+// Skipped when walking a folder that is not a git repository
+const ALWAYS_IGNORED = new Set([".git", "node_modules"]);
+
+// Long lines, e.g. minified code, are cut around the match
+const MAX_LINE_LENGTH = 500;
+
+// How long a regular expression may take to match the lines of one file
+export const REGEX_FILE_TIMEOUT_MS = 2000;
+
+/**
+ * List the files under root, relative to it
+ *
+ * In a git repository this is what git would consider: tracked and untracked
+ * files, without ignored ones. Other folders are walked without following
+ * symlinks, skipping .git and node_modules.
+ */
+export async function listSearchableFiles(
+	root: string,
+	maxFiles: number,
+): Promise<{ files: string[]; truncated: boolean }> {
+	try {
+		const result = await executeGenericCommand({
+			command: "git",
+			args: [
+				"ls-files",
+				"-z",
+				"--cached",
+				"--others",
+				"--exclude-standard",
+			],
+			workingDir: root,
+			forceColor: false,
+		});
+		if (result.code === 0) {
+			// Unmerged files are listed once per stage
+			const files = [...new Set(result.stdout.split("\0").filter(Boolean))];
+			return {
+				files: files.slice(0, maxFiles),
+				truncated: files.length > maxFiles,
+			};
+		}
+	} catch {
+		// git is not installed, walk the folder instead
+	}
+
+	const files: string[] = [];
+	const pending = [""];
+	while (pending.length > 0) {
+		const relativeFolder = pending.shift() as string;
+		let entries: Dirent[];
+		try {
+			entries = await fs.readdir(path.join(root, relativeFolder), {
+				withFileTypes: true,
+			});
+		} catch {
+			continue;
+		}
+		entries.sort((a, b) => a.name.localeCompare(b.name));
+
+		for (const entry of entries) {
+			const relativePath = path.join(relativeFolder, entry.name);
+			if (entry.isDirectory()) {
+				if (!ALWAYS_IGNORED.has(entry.name)) {
+					pending.push(relativePath);
+				}
+			} else if (entry.isFile()) {
+				if (files.length >= maxFiles) {
+					return { files, truncated: true };
+				}
+				files.push(relativePath);
+			}
+		}
+	}
+	return { files, truncated: false };
+}
+
+function isWordStart(target: string, index: number): boolean {
+	if (index === 0) return true;
+	const previous = target[index - 1];
+	const current = target[index];
+	return (
+		"/\\_-. ".includes(previous) ||
+		(previous === previous.toLowerCase() && current !== current.toLowerCase())
+	);
+}
+
+/**
+ * Match query characters in order against a path, like editor quick open
+ *
+ * Matches in the file name, at word starts and in consecutive runs score
+ * higher; shorter paths win ties. Returns null if not all characters match.
+ */
+export function fuzzyMatch(
+	query: string,
+	target: string,
+): { score: number; indices: number[] } | null {
+	const needle = query.toLowerCase().replaceAll(" ", "");
+	if (!needle) return null;
+	const haystack = target.toLowerCase();
+
+	// Prefer matching inside the file name when the whole query fits there
+	const nameStart = target.lastIndexOf("/") + 1;
+	const attempt = (from: number) => {
+		const indices: number[] = [];
+		let position = from;
+		for (const char of needle) {
+			const found = haystack.indexOf(char, position);
+			if (found === -1) return null;
+			indices.push(found);
+			position = found + 1;
+		}
+		return indices;
+	};
+	const indices = attempt(nameStart) ?? attempt(0);
+	if (!indices) return null;
+
+	let score = 0;
+	for (let i = 0; i < indices.length; i++) {
+		const index = indices[i];
+		score += 1;
+		if (index >= nameStart) score += 2;
+		if (isWordStart(target, index)) score += 3;
+		if (i > 0 && indices[i - 1] === index - 1) score += 4;
+	}
+	score -= target.length / 100;
+
+	return { score: Math.round(score * 100) / 100, indices };
+}
+
+interface LineMatch {
+	line: number;
+	index: number;
+	length: number;
+}
+
+/**
+ * Find the first match in each line, up to limit matching lines
+ */
+type LineMatcher = {
+	match(lines: string[], limit: number): Promise<LineMatch[]>;
+	close(): void;
+};
+
+function matchLines(
+	pattern: RegExp,
+	lines: string[],
+	limit: number,
+): LineMatch[] {
+	const found: LineMatch[] = [];
+	for (let i = 0; i < lines.length && found.length < limit; i++) {
+		pattern.lastIndex = 0;
+		const match = pattern.exec(lines[i]);
+		if (match) {
+			found.push({ line: i, index: match.index, length: match[0].length });
+		}
+	}
+	return found;
+}
+
+// Run with eval by the worker, so it must be plain JavaScript
+const REGEX_WORKER_SOURCE = `
+const { parentPort, workerData } = require("node:worker_threads");
+const pattern = new RegExp(workerData.source, workerData.flags);
+parentPort.on("message", ({ lines, limit }) => {
+	const found = [];
+	for (let i = 0; i < lines.length && found.length < limit; i++) {
+		pattern.lastIndex = 0;
+		const match = pattern.exec(lines[i]);
+		if (match) {
+			found.push({ line: i, index: match.index, length: match[0].length });
+		}
+	}
+	parentPort.postMessage(found);
+});
+`;
+
+/**
+ * Match a client's regular expression in a worker thread
+ *
+ * Patterns like (a+)+$ can backtrack for minutes on some lines, which would
+ * block every other request if run here. The worker is terminated when a
+ * file takes longer than REGEX_FILE_TIMEOUT_MS.
+ */
+function createRegexMatcher(source: string, flags: string): LineMatcher {
+	const worker = new Worker(REGEX_WORKER_SOURCE, {
+		eval: true,
+		workerData: { source, flags },
+	});
+	let closed = false;
+	const close = () => {
+		closed = true;
+		void worker.terminate();
+	};
+
+	return {
+		match: (lines, limit) =>
+			new Promise((resolve, reject) => {
+				if (closed) {
+					reject(new FileSearchError("Search was stopped"));
+					return;
+				}
+				const cleanup = () => {
+					clearTimeout(timer);
+					worker.off("message", onMessage);
+					worker.off("error", onError);
+				};
+				const onMessage = (found: LineMatch[]) => {
+					cleanup();
+					resolve(found);
+				};
+				const onError = (error: Error) => {
+					cleanup();
+					close();
+					reject(new FileSearchError(error.message));
+				};
+				const timer = setTimeout(() => {
+					cleanup();
+					close();
+					reject(
+						new FileSearchError(
+							"Regular expression took too long, try a simpler one",
+						),
+					);
+				}, REGEX_FILE_TIMEOUT_MS);
+				worker.on("message", onMessage);
+				worker.on("error", onError);
+				worker.postMessage({ lines, limit });
+			}),
+		close,
+	};
+}
+
+function buildMatcher(options: FileSearchOptions): LineMatcher {
+	const source = options.regex
+		? options.query
+		: options.query.replace(/[.*+?^${}()|[\]\\]/g, "\\$&");
+	const flags = options.caseSensitive ? "g" : "gi";
+	let pattern: RegExp;
+	try {
+		pattern = new RegExp(source, flags);
+	} catch (error) {
+		throw new FileSearchError(
+			error instanceof Error
+				? error.message
+				: `Invalid regular expression: ${options.query}`,
+		);
+	}
+	// An escaped query can't backtrack, so it is matched here
+	if (options.regex) {
+		return createRegexMatcher(source, flags);
+	}
+	return {
+		match: async (lines, limit) => matchLines(pattern, lines, limit),
+		close: () => {},
+	};
+}
+
+function clipStart(line: string, index: number): number {
+	return line.length <= MAX_LINE_LENGTH
+		? 0
+		: Math.max(0, index - MAX_LINE_LENGTH / 2);
+}
+
+function clipLine(line: string, start = 0): string {
+	return line.slice(start, start + MAX_LINE_LENGTH);
+}
+
+/**
+ * Find the matching lines of a text file, with context lines around them
+ */
+async function searchFileContent(
+	filePath: string,
+	matcher: LineMatcher,
+	contextLines: number,
+	limit: number,
+): Promise<FileContentMatch[]> {
+	const stats = await fs.lstat(filePath);
+	// Symlinks are skipped so a search can't read outside the root
+	if (!stats.isFile() || stats.size > getMaxFileSize()) {
+		return [];
+	}
+
+	const buffer = await fs.readFile(filePath);
+	if (isBinaryContent(buffer)) {
+		return [];
+	}
+
+	const lines = buffer.toString("utf-8").split(/\r?\n/);
+	// A final newline doesn't start another line
+	if (lines.at(-1) === "") {
+		lines.pop();
+	}
+	const matches: FileContentMatch[] = [];
+	for (const { line: i, index, length } of await matcher.match(lines, limit)) {
+		const textOffset = clipStart(lines[i], index);
+		matches.push({
+			line: i + 1,
+			// 1-based like editors, counted in the full line
+			column: index + 1,
+			length,
+			text: clipLine(lines[i], textOffset),
+			textOffset,
+			before: lines
+				.slice(Math.max(0, i - contextLines), i)
+				.map((line) => clipLine(line)),
+			after: lines
+				.slice(i + 1, i + 1 + contextLines)
+				.map((line) => clipLine(line)),
+		});
+	}
+	return matches;
+}
+
+/**
+ * Search file names or contents under a root, yielding results as found
+ *
+ * File name results are ranked, so they are yielded once all names are
+ * scored. Content results are yielded per file while the search runs. The
+ * last event is always a summary.
+ */
+export async function* searchFiles(
+	options: FileSearchOptions,
+): AsyncGenerator<FileSearchEvent> {
+	const startedAt = Date.now();
+	const maxResults = options.maxResults ?? DEFAULT_SEARCH_MAX_RESULTS;
+	const contextLines = Math.min(
+		Math.max(options.contextLines ?? 2, 0),
+		MAX_CONTEXT_LINES,
+	);
+	if (!options.query) {
+		throw new FileSearchError("Search query is required");
+	}
+	const matcher = options.mode === "content" ? buildMatcher(options) : null;
+	try {
+		yield* runSearch(options, matcher, contextLines, maxResults, startedAt);
+	} finally {
+		matcher?.close();
+	}
+}
+
+async function* runSearch(
+	options: FileSearchOptions,
+	matcher: LineMatcher | null,
+	contextLines: number,
+	maxResults: number,
+	startedAt: number,
+): AsyncGenerator<FileSearchEvent> {
+	const { files, truncated: tooManyFiles } = await listSearchableFiles(
+		options.root,
+		options.maxFiles ?? DEFAULT_SEARCH_MAX_FILES,
+	);
+
+	let results = 0;
+	let filesSearched = 0;
+	let truncated = tooManyFiles;
+
+	if (!matcher) {
+		const ranked: FileNameMatch[] = [];
+		for (const file of files) {
+			filesSearched++;
+			const match = fuzzyMatch(options.query, file);
+			if (match) {
+				ranked.push({ type: "name", path: file, ...match });
+			}
+		}
+		ranked.sort((a, b) => b.score - a.score || a.path.localeCompare(b.path));
+		truncated ||= ranked.length > maxResults;
+		for (const match of ranked.slice(0, maxResults)) {
+			if (options.signal?.aborted) return;
+			results++;
+			yield match;
+		}
+	} else {
+		for (const file of files) {
+			if (options.signal?.aborted) return;
+			if (results >= maxResults) {
+				truncated = true;
+				break;
+			}
+			filesSearched++;
+
+			let matches: FileContentMatch[];
+			try {
+				matches = await searchFileContent(
+					path.join(options.root, file),
+					matcher,
+					contextLines,
+					maxResults - results,
+				);
+			} catch (error) {
+				if (error instanceof FileSearchError) {
+					throw error;
+				}
+				// Deleted since listing or unreadable
+				continue;
+			}
+			if (matches.length > 0) {
+				results += matches.length;
+				yield { type: "content", path: file, matches };
+			}
+		}
+	}
+
+	yield {
+		type: "done",
+		filesSearched,
+		results,
+		truncated,
+		durationMs: Date.now() - startedAt,
+	};
+}
+
+/**
+ * WebSocket handler that runs a search and streams its results
+ *
+ * Messages sent to the client:
+ * - { type: "name", path, score, indices }
+ * - { type: "content", path, matches: [{ line, column, length, text, ... }] }
+ * - { type: "done", filesSearched, results, truncated, durationMs }
+ * - { type: "error", error }
+ * The search stops when the socket closes, and the socket is closed once the
+ * search finishes.
+ */
+export const handleFileSearch = (
+	ws: WebSocket,
+	options: Omit<FileSearchOptions, "signal">,
+): void => {
+	const controller = new AbortController();
+	ws.addEventListener("close", () => {
+		controller.abort();
+	});
+
+	const send = (message: object) => {
+		if (!controller.signal.aborted) {
+			ws.send(JSON.stringify(message));
+		}
+	};
+
+	(async () => {
+		for await (const event of searchFiles({
+			...options,
+			signal: controller.signal,
+		})) {
+			send(event);
+		}
+	})()
+		.catch((error) => {
+			send({
+				type: "error",
+				error: error instanceof Error ? error.message : "Unknown error",
+			});
+		})
+		.finally(() => {
+			if (!controller.signal.aborted) {
+				ws.close(1000, "Search finished");
+			}
+		});
+};
@@ -0,0 +1,231 @@
+import { type Dirent, promises as fs } from "node:fs";
+import * as path from "node:path";
+import type { GitStatusFileEntry } from "../models/git.js";
+import type {
+	FileGitStatus,
+	FileTree,
+	FileTreeEntry,
+} from "../models/filesystem.js";
+import { getFolderStatus, getIgnoredPaths } from "./git-status.js";
+
+export const DEFAULT_TREE_DEPTH = 2;
+export const MAX_TREE_DEPTH = 5;
+
+// Large folders such as node_modules are cut off instead of listed in full
+export const MAX_TREE_ENTRIES = 5000;
+
+// Order in which the changes inside a folder decide its decoration
+const FOLDER_STATUS_PRIORITY: FileGitStatus[] = [
+	"conflicted",
+	"modified",
+	"added",
+	"untracked",
+];
+
+/**
+ * Map a porcelain status entry to the decoration of its file
+ */
+export function getFileGitStatus(entry: GitStatusFileEntry): FileGitStatus {
+	switch (entry.kind) {
+		case "unmerged":
+			return "conflicted";
+		case "untracked":
+			return "untracked";
+		case "ignored":
+			return "ignored";
+		case "renamed":
+		case "copied":
+			return "renamed";
+	}
+	if (entry.indexStatus === "A") return "added";
+	if (entry.indexStatus === "D" || entry.worktreeStatus === "D") {
+		return "deleted";
+	}
+	return "modified";
+}
+
+/**
+ * Combine the status of a file into the decoration of a folder containing it
+ */
+function combineFolderStatus(
+	current: FileGitStatus | undefined,
+	status: FileGitStatus,
+): FileGitStatus {
+	// Deleted and renamed files change the folder like modified ones
+	const folderStatus =
+		status === "deleted" || status === "renamed" ? "modified" : status;
+	if (!current) return folderStatus;
+	return FOLDER_STATUS_PRIORITY.indexOf(folderStatus) <
+		FOLDER_STATUS_PRIORITY.indexOf(current)
+		? folderStatus
+		: current;
+}
+
+/**
+ * Decorations for the files and folders under a folder, by full path
+ */
+async function getGitDecorations(
+	folder: string,
+): Promise<{ root: string; statuses: Map<string, FileGitStatus> } | null> {
+	const status = await getFolderStatus(folder);
+	if (!status) {
+		return null;
+	}
+
+	// git reports paths from the real repository root, which differs from
+	// the requested folder when it is reached through a symlink
+	const realFolder = await fs.realpath(folder);
+	const statuses = new Map<string, FileGitStatus>();
+	for (const entry of status.files) {
+		const relative = path.relative(
+			realFolder,
+			path.join(status.root, entry.path),
+		);
+		if (relative.startsWith("..") || path.isAbsolute(relative)) continue;
+
+		const fileStatus = getFileGitStatus(entry);
+		const filePath = path.join(folder, relative);
+		statuses.set(filePath, fileStatus);
+		for (
+			let parent = path.dirname(filePath);
+			parent.length > folder.length;
+			parent = path.dirname(parent)
+		) {
+			statuses.set(
+				parent,
+				combineFolderStatus(statuses.get(parent), fileStatus),
+			);
+		}
+	}
+	return { root: status.root, statuses };
+}
+
+async function readFolder(folder: string): Promise<Dirent[]> {
+	try {
+		const entries = await fs.readdir(folder, { withFileTypes: true });
+		return entries.sort((a, b) => a.name.localeCompare(b.name));
+	} catch {
+		// Unreadable folders are shown empty
+		return [];
+	}
+}
+
+/**
+ * List several levels of a folder, with git status and ignore information
+ *
+ * Levels are read breadth-first, so a large folder uses up the entry limit
+ * only after the levels above it are complete. Ignored folders and symlinks
+ * are listed but not descended into; ignored entries are left out unless
+ * showIgnored is set.
+ */
+export async function getFileTree(
+	folder: string,
+	options: { depth?: number; showIgnored?: boolean } = {},
+): Promise<Omit<FileTree, "parent">> {
+	const depth = Math.min(
+		Math.max(options.depth ?? DEFAULT_TREE_DEPTH, 1),
+		MAX_TREE_DEPTH,
+	);
+	const git = await getGitDecorations(folder);
+
+	const entries: FileTreeEntry[] = [];
+	let truncated = false;
+	let folders = [folder];
+
+	for (let level = 1; level <= depth && folders.length > 0; level++) {
+		const listed = await Promise.all(
+			folders.map(async (parent) => ({
+				parent,
+				children: await readFolder(parent),
+			})),
+		);
+		const levelEntries = listed.flatMap(({ parent, children }) =>
+			children.map((child) => ({
+				parent,
+				child,
+				fullPath: path.join(parent, child.name),
+			})),
+		);
+
+		// One check-ignore call per level, with paths relative to folder
+		const ignored = git
+			? await getIgnoredPaths(
+					folder,
+					levelEntries.map(({ fullPath }) => path.relative(folder, fullPath)),
+				)
+			: new Set<string>();
+
+		const toItem = async ({
+			parent,
+			child,
+			fullPath,
+		}: (typeof levelEntries)[number]): Promise<FileTreeEntry | null> => {
+			const isIgnored =
+				ignored.has(path.relative(folder, fullPath)) ||
+				(git !== null && child.name === ".git");
+			if (isIgnored && !options.showIgnored) {
+				return null;
+			}
+
+			let stats: Awaited<ReturnType<typeof fs.stat>> | null = null;
+			try {
+				// Follows symlinks, so links to folders show as folders
+				stats = await fs.stat(fullPath);
+			} catch {
+				// Broken symlinks are shown as files
+			}
+			return {
+				name: child.name,
+				path: fullPath,
+				type: stats?.isDirectory() ? "folder" : "file",
+				size: stats?.isFile() ? stats.size : undefined,
+				modified: stats?.mtime.toISOString(),
+				parent,
+				depth: level,
+				symlink: child.isSymbolicLink(),
+				ignored: isIgnored,
+				gitStatus: isIgnored ? "ignored" : git?.statuses.get(fullPath),
+			};
+		};
+
+		// Keep entries grouped by folder and sorted by name, folders first
+		const folderOrder = new Map(folders.map((parent, i) => [parent, i]));
+		const levelItems = (await Promise.all(levelEntries.map(toItem)))
+			.filter((item): item is FileTreeEntry => item !== null)
+			.sort(
+				(a, b) =>
+					(folderOrder.get(a.parent) ?? 0) - (folderOrder.get(b.parent) ?? 0) ||
+					Number(b.type === "folder") - Number(a.type === "folder"),
+			);
+
+		const candidates: FileTreeEntry[] = [];
+		for (const item of levelItems) {
+			if (entries.length >= MAX_TREE_ENTRIES) {
+				truncated = true;
+				break;
+			}
+			entries.push(item);
+			if (item.type === "folder") {
+				item.childrenLoaded = false;
+				if (level < depth && !item.symlink && !item.ignored) {
+					candidates.push(item);
+				}
+			}
+		}
+
+		// Folders whose entries didn't fit are left for a later request
+		if (truncated) break;
+		for (const item of candidates) {
+			item.childrenLoaded = true;
+		}
+		folders = candidates.map((item) => item.path);
+	}
+
+	return {
+		path: folder,
+		depth,
+		repositoryRoot: git?.root ?? null,
+		entries,
+		truncated,
+	};
+}
@@ -0,0 +1,71 @@
+import assert from "node:assert/strict";
+import { describe, it } from "node:test";
+import { diffText, type TextPatch } from "./file-watch.js";
+
+const applyPatch = (text: string, patch: TextPatch): string =>
+	text.slice(0, patch.offset) +
+	patch.text +
+	text.slice(patch.offset + patch.deleteCount);
+
+describe("diffText", () => {
+	it("only sends what was appended", () => {
+		assert.deepEqual(diffText("line 1\n", "line 1\nline 2\n"), {
+			offset: 7,
+			deleteCount: 0,
+			text: "line 2\n",
+		});
+	});
+
+	it("finds an edit in the middle", () => {
+		assert.deepEqual(diffText("const a = 1;", "const abc = 1;"), {
+			offset: 7,
+			deleteCount: 0,
+			text: "bc",
+		});
+		assert.deepEqual(diffText("hello big world", "hello world"), {
+			offset: 6,
+			deleteCount: 4,
+			text: "",
+		});
+		assert.deepEqual(diffText("x = old;", "x = new;"), {
+			offset: 4,
+			deleteCount: 3,
+			text: "new",
+		});
+	});
+
+	it("returns an empty patch for the same text", () => {
+		assert.deepEqual(diffText("same", "same"), {
+			offset: 4,
+			deleteCount: 0,
+			text: "",
+		});
+	});
+
+	it("doesn't overlap the prefix and suffix in repeated text", () => {
+		// "aa" is both a prefix and a suffix of "aaa"
+		assert.deepEqual(diffText("aa", "aaa"), {
+			offset: 2,
+			deleteCount: 0,
+			text: "a",
+		});
+		assert.deepEqual(diffText("abab", "ab"), {
+			offset: 2,
+			deleteCount: 2,
+			text: "",
+		});
+	});
+
+	it("produces patches that turn the previous text into the next", () => {
+		const cases: [string, string][] = [
+			["", "new file\n"],
+			["old file\n", ""],
+			["héllo 😀 wörld", "héllo 😃 wörld"],
+			["a\r\nb\r\n", "a\nb\n"],
+			["abcabc", "abcXabc"],
+		];
+		for (const [previous, next] of cases) {
+			assert.equal(applyPatch(previous, diffText(previous, next)), next);
+		}
+	});
+});
@@ -0,0 +1,307 @@
+import { readFile, stat } from "node:fs/promises";
+import chokidar, { type FSWatcher } from "chokidar";
+import { getMaxFileSize } from "./constants.js";
+import { hashContent } from "./file-content.js";
+import { resolveAllowedPath } from "./path-access.js";
+
+/**
+ * Single edit turning the previous content of a file into the new one
+ *
+ * Offsets count UTF-16 code units, like JavaScript string indexes.
+ */
+export interface TextPatch {
+	offset: number;
+	deleteCount: number;
+	text: string;
+}
+
+interface FileSnapshot {
+	content: string;
+	modified: string;
+	hash: string;
+}
+
+type FileWatchEvent =
+	| ({ type: "content" } & FileSnapshot)
+	| ({ type: "patch"; baseHash: string; patch: TextPatch } & Omit<
+			FileSnapshot,
+			"content"
+	  >)
+	| { type: "deleted" }
+	| { type: "error"; error: string };
+
+type FileWatchListener = (event: FileWatchEvent) => void;
+
+/**
+ * Watcher of one file, shared by every client subscribed to it
+ */
+interface SharedFileWatcher {
+	watcher: FSWatcher;
+	listeners: Set<FileWatchListener>;
+	// Last content read, patches are computed against it
+	snapshot: FileSnapshot | null;
+	// Reads run one at a time so patches apply in order
+	reading: Promise<void>;
+}
+
+const sharedWatchers = new Map<string, SharedFileWatcher>();
+
+/**
+ * Compute the edit between two versions of a text
+ *
+ * Only the changed middle is sent, which keeps appends to logs and small
+ * edits in large files cheap.
+ */
+export function diffText(previous: string, next: string): TextPatch {
+	const maxPrefix = Math.min(previous.length, next.length);
+	let prefix = 0;
+	while (prefix < maxPrefix && previous[prefix] === next[prefix]) {
+		prefix++;
+	}
+	const maxSuffix = maxPrefix - prefix;
+	let suffix = 0;
+	while (
+		suffix < maxSuffix &&
+		previous[previous.length - 1 - suffix] === next[next.length - 1 - suffix]
+	) {
+		suffix++;
+	}
+	return {
+		offset: prefix,
+		deleteCount: previous.length - prefix - suffix,
+		text: next.slice(prefix, next.length - suffix),
+	};
+}
+
+async function readSnapshot(filePath: string): Promise<FileSnapshot> {
+	// Large files are read in ranges through /files/content instead
+	const { size } = await stat(filePath);
+	if (size > getMaxFileSize()) {
+		throw new Error(
+			`File is too large to watch (${size} bytes, limit ${getMaxFileSize()})`,
+		);
+	}
+
+	// Hashed as raw bytes like /files/content, so the versions match
+	const data = await readFile(filePath);
+	const { mtime } = await stat(filePath);
+	return {
+		content: data.toString("utf-8"),
+		modified: mtime.toISOString(),
+		hash: hashContent(data),
+	};
+}
+
+function broadcast(shared: SharedFileWatcher, event: FileWatchEvent) {
+	for (const listener of shared.listeners) {
+		listener(event);
+	}
+}
+
+/**
+ * Re-read a watched file and send its changes to the subscribers
+ */
+function refresh(shared: SharedFileWatcher, filePath: string) {
+	shared.reading = shared.reading.then(async () => {
+		try {
+			const snapshot = await readSnapshot(filePath);
+			const previous = shared.snapshot;
+			shared.snapshot = snapshot;
+			if (!previous) {
+				broadcast(shared, { type: "content", ...snapshot });
+			} else if (previous.hash !== snapshot.hash) {
+				broadcast(shared, {
+					type: "patch",
+					baseHash: previous.hash,
+					patch: diffText(previous.content, snapshot.content),
+					modified: snapshot.modified,
+					hash: snapshot.hash,
+				});
+			}
+		} catch (error) {
+			shared.snapshot = null;
+			broadcast(shared, {
+				type: "error",
+				error: error instanceof Error ? error.message : "Unknown error",
+			});
+		}
+	});
+}
+
+function createSharedWatcher(filePath: string): SharedFileWatcher {
+	const watcher = chokidar.watch(filePath, {
+		persistent: true,
+		ignoreInitial: true, // The first read happens on subscribe
+		awaitWriteFinish: {
+			stabilityThreshold: 100,
+			pollInterval: 50,
+		},
+	});
+	const shared: SharedFileWatcher = {
+		watcher,
+		listeners: new Set(),
+		snapshot: null,
+		reading: Promise.resolve(),
+	};
+
+	// A file created again after a delete is sent whole
+	watcher.on("add", () => refresh(shared, filePath));
+	watcher.on("change", () => refresh(shared, filePath));
+	watcher.on("unlink", () => {
+		shared.reading = shared.reading.then(() => {
+			shared.snapshot = null;
+			broadcast(shared, { type: "deleted" });
+		});
+	});
+	watcher.on("error", (error: unknown) => {
+		broadcast(shared, {
+			type: "error",
+			error: error instanceof Error ? error.message : "Unknown error",
+		});
+	});
+	return shared;
+}
+
+/**
+ * Start listening to a file, sharing the watcher with other listeners
+ *
+ * The listener first gets the current content, then patches. Returns a
+ * function that stops listening; the watcher closes with its last listener.
+ */
+function subscribeToFile(
+	filePath: string,
+	listener: FileWatchListener,
+): () => void {
+	let shared = sharedWatchers.get(filePath);
+	if (!shared) {
+		shared = createSharedWatcher(filePath);
+		sharedWatchers.set(filePath, shared);
+	}
+	const current = shared;
+	current.listeners.add(listener);
+
+	current.reading = current.reading.then(async () => {
+		if (!current.listeners.has(listener)) return;
+		try {
+			current.snapshot ??= await readSnapshot(filePath);
+			listener({ type: "content", ...current.snapshot });
+		} catch (error) {
+			listener({
+				type: "error",
+				error: error instanceof Error ? error.message : "Unknown error",
+			});
+		}
+	});
+
+	return () => {
+		current.listeners.delete(listener);
+		if (current.listeners.size === 0) {
+			current.watcher.close();
+			sharedWatchers.delete(filePath);
+		}
+	};
+}
+
+/**
+ * WebSocket handler multiplexing the watches of many files
+ *
+ * Messages from the client:
+ * - { type: "subscribe", path } starts watching a file
+ * - { type: "unsubscribe", path } stops watching it
+ * - { type: "resync", path } asks for the whole content again, e.g. when a
+ *   patch doesn't apply
+ * Messages sent to the client, all with the path as subscribed:
+ * - { type: "content", path, content, modified, hash } on subscribe, resync
+ *   and when a deleted file is created again
+ * - { type: "patch", path, baseHash, patch: { offset, deleteCount, text },
+ *   modified, hash } for changes to the content with hash baseHash
+ * - { type: "deleted", path }
+ * - { type: "error", path?, error }
+ * Watchers are shared between all connections and closed with their last
+ * subscriber.
+ *
+ * @param ws WebSocket connection from @hono/node-ws
+ */
+export const handleFileWatch = (ws: WebSocket): void => {
+	// Unsubscribe functions by the path the client subscribed with
+	const subscriptions = new Map<string, Promise<(() => void) | null>>();
+
+	const send = (message: object) => {
+		if (ws.readyState === ws.OPEN) {
+			ws.send(
+				JSON.stringify({ ...message, timestamp: new Date().toISOString() }),
+			);
+		}
+	};
+
+	const subscribe = (requestedPath: string) => {
+		if (subscriptions.has(requestedPath)) return;
+		subscriptions.set(
+			requestedPath,
+			// Only watch files inside the allowed roots
+			resolveAllowedPath(requestedPath)
+				.then((filePath) =>
+					subscribeToFile(filePath, (event) =>
+						send({ ...event, path: requestedPath }),
+					),
+				)
+				.catch((error) => {
+					send({
+						type: "error",
+						path: requestedPath,
+						error: error instanceof Error ? error.message : "Unknown error",
+					});
+					subscriptions.delete(requestedPath);
+					return null;
+				}),
+		);
+	};
+
+	const unsubscribe = (requestedPath: string) => {
+		const subscription = subscriptions.get(requestedPath);
+		subscriptions.delete(requestedPath);
+		subscription?.then((stop) => stop?.());
+	};
+
+	ws.addEventListener("message", (event: MessageEvent) => {
+		let message: { type?: string; path?: unknown };
+		try {
+			message = JSON.parse(String(event.data));
+		} catch {
+			send({ type: "error", error: "Messages must be JSON" });
+			return;
+		}
+		if (typeof message.path !== "string" || !message.path) {
+			send({ type: "error", error: "path is required" });
+			return;
+		}
+
+		switch (message.type) {
+			case "subscribe":
+				subscribe(message.path);
+				break;
+			case "unsubscribe":
+				unsubscribe(message.path);
+				break;
+			case "resync":
+				unsubscribe(message.path);
+				subscribe(message.path);
+				break;
+			default:
+				send({
+					type: "error",
+					path: message.path,
+					error: `Unknown message type: ${message.type}`,
+				});
+		}
+	});
+
+	// Release this connection's subscriptions when it closes
+	const close = () => {
+		for (const requestedPath of [...subscriptions.keys()]) {
+			unsubscribe(requestedPath);
+		}
+	};
+	ws.addEventListener("close", close);
+	ws.addEventListener("error", close);
+};
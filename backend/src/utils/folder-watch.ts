@@ -0,0 +1,210 @@
+import type { Stats } from "node:fs";
+import * as path from "node:path";
+import chokidar, { type FSWatcher } from "chokidar";
+import { getIgnoredPaths } from "./git-status.js";
+
+export type FolderChangeType =
+	| "add"
+	| "change"
+	| "unlink"
+	| "addDir"
+	| "unlinkDir";
+
+export interface FolderChange {
+	type: FolderChangeType;
+	// Full path of the file or folder that changed
+	path: string;
+	size?: number;
+	modified?: string;
+	// Whether git ignores the path, so clients hiding ignored files can skip it
+	ignored: boolean;
+}
+
+export interface FolderWatchOptions {
+	// Glob patterns relative to the folder, e.g. "dist" or "**/*.log"
+	ignore?: string[];
+	// Time to collect changes before sending them, in milliseconds
+	debounceMs?: number;
+	// Levels below the folder to watch, unlimited if not set
+	depth?: number;
+}
+
+export const DEFAULT_WATCH_DEBOUNCE_MS = 150;
+export const MAX_WATCH_DEBOUNCE_MS = 5000;
+
+// Watching these is expensive and rarely useful in a file tree
+export const DEFAULT_WATCH_IGNORE = [".git", "node_modules"];
+
+/**
+ * Convert a glob pattern to a regular expression matching relative paths
+ *
+ * Supports *, ** and ?. Like .gitignore, a pattern without a slash matches
+ * at any depth, and a pattern matching a folder also matches its contents.
+ */
+export function globToRegExp(pattern: string): RegExp {
+	const glob = pattern.replace(/^\/|\/$/g, "");
+	let source = "";
+	for (let i = 0; i < glob.length; i++) {
+		const char = glob[i];
+		if (char === "*" && glob[i + 1] === "*") {
+			// "**/" matches any number of folders, including none
+			if (glob[i + 2] === "/") {
+				source += "(?:.*/)?";
+				i += 2;
+			} else {
+				source += ".*";
+				i++;
+			}
+		} else if (char === "*") {
+			source += "[^/]*";
+		} else if (char === "?") {
+			source += "[^/]";
+		} else {
+			source += char.replace(/[.+^${}()|[\]\\]/g, "\\$&");
+		}
+	}
+	const anyDepth = pattern.includes("/") ? "" : "(?:.*/)?";
+	return new RegExp(`^${anyDepth}${source}(?:/.*)?$`);
+}
+
+/**
+ * Merge a new change into the pending change of the same path
+ *
+ * Returns null when the changes cancel out, e.g. a file created and deleted
+ * before the batch was sent.
+ */
+function mergeChange(
+	pending: FolderChangeType | undefined,
+	next: FolderChangeType,
+): FolderChangeType | null {
+	if (pending === "add" && next === "change") return "add";
+	if (pending === "add" && next === "unlink") return null;
+	if (pending === "addDir" && next === "unlinkDir") return null;
+	if (pending === "unlink" && next === "add") return "change";
+	return next;
+}
+
+/**
+ * WebSocket handler that streams changes below a folder
+ *
+ * Messages sent to the client:
+ * - { type: "ready", folder } once the initial scan finished
+ * - { type: "changes", folder, changes: [{ type, path, ... }], timestamp }
+ * - { type: "error", error, timestamp }
+ * Changes are collected for debounceMs and sent in batches, with at most one
+ * change per path. Symlinks are not followed.
+ *
+ * @param ws WebSocket connection from @hono/node-ws
+ * @param folder Absolute path to the folder to watch
+ */
+export const handleFolderWatch = (
+	ws: WebSocket,
+	folder: string,
+	options: FolderWatchOptions = {},
+): void => {
+	const debounceMs = Math.min(
+		Math.max(options.debounceMs ?? DEFAULT_WATCH_DEBOUNCE_MS, 0),
+		MAX_WATCH_DEBOUNCE_MS,
+	);
+	const ignorePatterns = [...DEFAULT_WATCH_IGNORE, ...(options.ignore ?? [])]
+		.filter(Boolean)
+		.map(globToRegExp);
+
+	let watcher: FSWatcher | null = null;
+	let timer: NodeJS.Timeout | null = null;
+	const pending = new Map<string, { type: FolderChangeType; stats?: Stats }>();
+
+	const send = (message: object) => {
+		if (ws.readyState === ws.OPEN) {
+			ws.send(JSON.stringify(message));
+		}
+	};
+
+	const flush = async () => {
+		timer = null;
+		const batch = [...pending.entries()];
+		pending.clear();
+		if (batch.length === 0) return;
+
+		let ignored = new Set<string>();
+		try {
+			ignored = await getIgnoredPaths(
+				folder,
+				batch.map(([changedPath]) => path.relative(folder, changedPath)),
+			);
+		} catch {
+			// git is not installed, nothing is ignored
+		}
+
+		const changes: FolderChange[] = batch.map(
+			([changedPath, { type, stats }]) => ({
+				type,
+				path: changedPath,
+				size: stats?.isFile() ? stats.size : undefined,
+				modified: stats?.mtime.toISOString(),
+				ignored: ignored.has(path.relative(folder, changedPath)),
+			}),
+		);
+		send({
+			type: "changes",
+			folder,
+			changes,
+			timestamp: new Date().toISOString(),
+		});
+	};
+
+	watcher = chokidar.watch(folder, {
+		persistent: true,
+		ignoreInitial: true, // Clients list the folder themselves
+		followSymlinks: false,
+		alwaysStat: true,
+		depth: options.depth,
+		ignored: (watchedPath: string) => {
+			const relative = path.relative(folder, watchedPath);
+			return (
+				relative !== "" &&
+				ignorePatterns.some((pattern) => pattern.test(relative))
+			);
+		},
+	});
+
+	watcher.on("all", (type, changedPath, stats) => {
+		if (changedPath === folder) return;
+		const merged = mergeChange(pending.get(changedPath)?.type, type);
+		if (merged) {
+			pending.set(changedPath, { type: merged, stats });
+		} else {
+			pending.delete(changedPath);
+		}
+		if (!timer) {
+			timer = setTimeout(flush, debounceMs);
+		}
+	});
+
+	watcher.on("ready", () => {
+		send({ type: "ready", folder });
+	});
+
+	watcher.on("error", (error: unknown) => {
+		send({
+			type: "error",
+			error: error instanceof Error ? error.message : "Unknown error",
+			timestamp: new Date().toISOString(),
+		});
+	});
+
+	const close = () => {
+		if (timer) {
+			clearTimeout(timer);
+			timer = null;
+		}
+		if (watcher) {
+			watcher.close();
+			watcher = null;
+		}
+	};
+
+	// Clean up when the WebSocket closes
+	ws.addEventListener("close", close);
+	ws.addEventListener("error", close);
+};
@@ -0,0 +1,310 @@
+import * as fs from "node:fs/promises";
+import * as path from "node:path";
+import type {
+	GitConflictFile,
+	GitConflictOperation,
+	GitConflictStatus,
+	GitConflictVersions,
+} from "../models/git.js";
+import { executeGenericCommand } from "./cli-executor.js";
+
+/**
+ * Error raised when a conflict action is not possible in the current state
+ */
+export class GitConflictError extends Error {}
+
+// Continuing a merge or rebase must not open an editor for the commit message
+const NON_INTERACTIVE_ENV = {
+	GIT_EDITOR: "true",
+	GIT_SEQUENCE_EDITOR: "true",
+};
+
+// Marker files git leaves in the git directory while an operation is stopped
+const OPERATION_MARKERS: Array<[GitConflictOperation, string]> = [
+	["rebase", "rebase-merge"],
+	["rebase", "rebase-apply"],
+	["cherry-pick", "CHERRY_PICK_HEAD"],
+	["merge", "MERGE_HEAD"],
+];
+
+// Conflict types by the index stages present (1 = base, 2 = ours, 3 = theirs)
+const CONFLICT_TYPES: Record<string, GitConflictFile["conflictType"]> = {
+	"123": "both-modified",
+	"23": "both-added",
+	"13": "deleted-by-us",
+	"12": "deleted-by-them",
+	"2": "added-by-us",
+	"3": "added-by-them",
+	"1": "both-deleted",
+};
+
+/**
+ * Run git and return trimmed stdout, or null if the command failed
+ */
+async function git(folder: string, args: string[]): Promise<string | null> {
+	const result = await executeGenericCommand({
+		command: "git",
+		args,
+		workingDir: folder,
+		forceColor: false,
+	});
+	return result.code === 0 ? result.stdout.trim() : null;
+}
+
+/**
+ * Resolve a path inside the git directory, which also works for worktrees
+ */
+async function gitPath(folder: string, name: string): Promise<string | null> {
+	const resolved = await git(folder, ["rev-parse", "--git-path", name]);
+	return resolved ? path.resolve(folder, resolved) : null;
+}
+
+async function exists(filePath: string): Promise<boolean> {
+	try {
+		await fs.access(filePath);
+		return true;
+	} catch {
+		return false;
+	}
+}
+
+/**
+ * Detect which operation, if any, is stopped waiting on conflicts
+ */
+export async function getConflictOperation(
+	folder: string,
+): Promise<GitConflictOperation | null> {
+	for (const [operation, marker] of OPERATION_MARKERS) {
+		const markerPath = await gitPath(folder, marker);
+		if (markerPath && (await exists(markerPath))) {
+			return operation;
+		}
+	}
+	return null;
+}
+
+/**
+ * Detect a squash merge, as done by "container-use apply"
+ *
+ * It leaves no MERGE_HEAD, only the prepared commit message in SQUASH_MSG
+ * until the result is committed or the merge is reset.
+ */
+async function isSquashMerge(folder: string): Promise<boolean> {
+	const squashMessage = await gitPath(folder, "SQUASH_MSG");
+	return !!squashMessage && (await exists(squashMessage));
+}
+
+/**
+ * Get the short name of the commit being merged, picked or rebased onto HEAD
+ */
+async function getIncoming(
+	folder: string,
+	operation: GitConflictOperation,
+): Promise<string | undefined> {
+	if (operation === "rebase") {
+		// The commit being replayed is stopped at REBASE_HEAD
+		const rebaseHead = await git(folder, [
+			"rev-parse",
+			"--short",
+			"REBASE_HEAD",
+		]);
+		return rebaseHead ?? undefined;
+	}
+
+	const head = operation === "merge" ? "MERGE_HEAD" : "CHERRY_PICK_HEAD";
+	const name = await git(folder, ["name-rev", "--name-only", head]);
+	if (name && name !== "undefined") {
+		return name;
+	}
+	return (await git(folder, ["rev-parse", "--short", head])) ?? undefined;
+}
+
+/**
+ * List unmerged files with the kind of conflict each one has
+ */
+export async function getConflictedFiles(
+	folder: string,
+): Promise<GitConflictFile[]> {
+	const output = await git(folder, ["ls-files", "--unmerged", "-z"]);
+	if (!output) {
+		return [];
+	}
+
+	// Entries look like "<mode> <object> <stage>\t<path>"
+	const stages = new Map<string, Set<string>>();
+	for (const entry of output.split("\0")) {
+		const match = entry.match(/^\d+ [0-9a-f]+ ([123])\t(.+)$/);
+		if (!match) continue;
+
+		const [, stage, file] = match;
+		const fileStages = stages.get(file) ?? new Set<string>();
+		fileStages.add(stage);
+		stages.set(file, fileStages);
+	}
+
+	return [...stages.entries()].map(([file, fileStages]) => ({
+		path: file,
+		conflictType: CONFLICT_TYPES[[...fileStages].sort().join("")],
+	}));
+}
+
+/**
+ * Get the in-progress operation and its conflicted files
+ *
+ * A squash merge (as done by "container-use apply") leaves no operation
+ * marker, so it is in progress while its message is pending or files are
+ * conflicted.
+ */
+export async function getConflictStatus(
+	folder: string,
+): Promise<GitConflictStatus> {
+	const operation = await getConflictOperation(folder);
+	const root = (await git(folder, ["rev-parse", "--show-toplevel"])) ?? folder;
+	const files = await getConflictedFiles(folder);
+
+	if (!operation && files.length === 0 && !(await isSquashMerge(folder))) {
+		return { inProgress: false, root, files };
+	}
+
+	return {
+		inProgress: true,
+		...(operation && { operation }),
+		root,
+		head: (await git(folder, ["rev-parse", "--abbrev-ref", "HEAD"])) ?? "",
+		...(operation && { incoming: await getIncoming(folder, operation) }),
+		files,
+	};
+}
+
+/**
+ * Read a file from an index stage, if the stage exists
+ */
+async function readStage(
+	folder: string,
+	file: string,
+	stage: 1 | 2 | 3,
+): Promise<string | undefined> {
+	const result = await executeGenericCommand({
+		command: "git",
+		args: ["show", `:${stage}:${file}`],
+		workingDir: folder,
+		forceColor: false,
+	});
+	return result.code === 0 ? result.stdout : undefined;
+}
+
+/**
+ * Ensure a repository-relative path is a currently conflicted file
+ */
+async function assertConflicted(folder: string, file: string): Promise<void> {
+	const files = await getConflictedFiles(folder);
+	if (!files.some((conflict) => conflict.path === file)) {
+		throw new GitConflictError(`File is not conflicted: ${file}`);
+	}
+}
+
+/**
+ * Get the base, ours and theirs versions of a conflicted file
+ *
+ * The merged version is the working tree file including conflict markers.
+ */
+export async function getConflictVersions(
+	folder: string,
+	file: string,
+): Promise<GitConflictVersions> {
+	const root = (await git(folder, ["rev-parse", "--show-toplevel"])) ?? folder;
+	await assertConflicted(root, file);
+
+	let merged: string | undefined;
+	try {
+		merged = await fs.readFile(path.join(root, file), "utf-8");
+	} catch {
+		// Deleted on one side, so there is nothing in the working tree
+	}
+
+	return {
+		path: file,
+		base: await readStage(root, file, 1),
+		ours: await readStage(root, file, 2),
+		theirs: await readStage(root, file, 3),
+		merged,
+	};
+}
+
+/**
+ * Resolve a conflicted file with new content or one side, then stage it
+ */
+export async function resolveConflict(
+	folder: string,
+	file: string,
+	resolution: "ours" | "theirs" | "content",
+	content?: string,
+): Promise<void> {
+	const root = (await git(folder, ["rev-parse", "--show-toplevel"])) ?? folder;
+	await assertConflicted(root, file);
+
+	if (resolution === "content") {
+		if (content === undefined) {
+			throw new GitConflictError("Content is required for this resolution");
+		}
+		await fs.writeFile(path.join(root, file), content, "utf-8");
+	} else {
+		const side = await readStage(root, file, resolution === "ours" ? 2 : 3);
+		// The picked side deleted the file, so the resolution is a removal
+		if (side === undefined) {
+			if ((await git(root, ["rm", "--quiet", "--", file])) === null) {
+				throw new Error(`Failed to remove ${file}`);
+			}
+			return;
+		}
+		if (
+			(await git(root, ["checkout", `--${resolution}`, "--", file])) === null
+		) {
+			throw new Error(`Failed to checkout ${resolution} version of ${file}`);
+		}
+	}
+
+	if ((await git(root, ["add", "--", file])) === null) {
+		throw new Error(`Failed to stage ${file}`);
+	}
+}
+
+/**
+ * Continue or abort the in-progress merge, cherry-pick or rebase
+ */
+export async function finishConflictOperation(
+	folder: string,
+	action: "continue" | "abort",
+) {
+	const operation = await getConflictOperation(folder);
+	const squash = !operation && (await isSquashMerge(folder));
+	const remaining = await getConflictedFiles(folder);
+
+	if (!operation && !squash && remaining.length === 0) {
+		throw new GitConflictError("No merge, cherry-pick or rebase in progress");
+	}
+	if (action === "continue" && remaining.length > 0) {
+		throw new GitConflictError(
+			`Cannot continue: ${remaining.length} file(s) still have conflicts`,
+		);
+	}
+
+	// A squash merge has no --continue, its staged result is committed with
+	// the prepared message. Aborting resets it like a regular merge, which
+	// also drops the message.
+	const args = operation
+		? [operation, `--${action}`]
+		: action === "continue"
+			? ["commit", "--no-edit"]
+			: ["reset", "--merge"];
+
+	const result = await executeGenericCommand({
+		command: "git",
+		args,
+		workingDir: folder,
+		environment: NON_INTERACTIVE_ENV,
+		forceColor: false,
+	});
+
+	return { operation: operation ?? "squash merge", result };
+}
@@ -0,0 +1,313 @@
+import assert from "node:assert/strict";
+import { execFileSync } from "node:child_process";
+import { mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import * as path from "node:path";
+import { after, before, describe, it } from "node:test";
+import {
+	GitRemoteError,
+	isSafeRefName,
+	parseFetchRefUpdates,
+	parsePushRefUpdates,
+	runGitRemoteOperation,
+} from "./git-remote.js";
+
+describe("parseFetchRefUpdates", () => {
+	it("parses the ref lines of fetch and pull", () => {
+		const output = [
+			"POST git-upload-pack (186 bytes)",
+			"From /tmp/remote",
+			" * [new branch]      feature    -> origin/feature",
+			"   1a2b3c4..5d6e7f8  main       -> origin/main",
+			" + 9a8b7c6...1f2e3d4 rebased    -> origin/rebased  (forced update)",
+			" ! [rejected]        stale      -> origin/stale  (non-fast-forward)",
+			" = [up to date]      docs       -> origin/docs",
+			" * branch            main       -> FETCH_HEAD",
+		].join("\n");
+
+		assert.deepEqual(parseFetchRefUpdates(output), [
+			{
+				status: "new",
+				from: "feature",
+				to: "origin/feature",
+				summary: "[new branch]",
+			},
+			{
+				status: "fast-forward",
+				from: "main",
+				to: "origin/main",
+				summary: "1a2b3c4..5d6e7f8",
+			},
+			{
+				status: "forced",
+				from: "rebased",
+				to: "origin/rebased",
+				summary: "9a8b7c6...1f2e3d4",
+				reason: "forced update",
+			},
+			{
+				status: "rejected",
+				from: "stale",
+				to: "origin/stale",
+				summary: "[rejected]",
+				reason: "non-fast-forward",
+			},
+			{
+				status: "up-to-date",
+				from: "docs",
+				to: "origin/docs",
+				summary: "[up to date]",
+			},
+		]);
+	});
+});
+
+describe("parsePushRefUpdates", () => {
+	it("parses the porcelain output of push", () => {
+		const output = [
+			"To /tmp/remote.git",
+			"*\trefs/heads/feature:refs/heads/feature\t[new branch]",
+			" \trefs/heads/main:refs/heads/main\t1a2b3c4..5d6e7f8",
+			"!\trefs/heads/stale:refs/heads/stale\t[rejected] (fetch first)",
+			"-\t:refs/heads/old\t[deleted]",
+			"Done",
+		].join("\n");
+
+		assert.deepEqual(parsePushRefUpdates(output), [
+			{
+				status: "new",
+				from: "refs/heads/feature",
+				to: "refs/heads/feature",
+				summary: "[new branch]",
+			},
+			{
+				status: "fast-forward",
+				from: "refs/heads/main",
+				to: "refs/heads/main",
+				summary: "1a2b3c4..5d6e7f8",
+			},
+			{
+				status: "rejected",
+				from: "refs/heads/stale",
+				to: "refs/heads/stale",
+				summary: "[rejected]",
+				reason: "fetch first",
+			},
+			{
+				status: "deleted",
+				from: "",
+				to: "refs/heads/old",
+				summary: "[deleted]",
+			},
+		]);
+	});
+});
+
+describe("isSafeRefName", () => {
+	it("accepts branch names and full refs", () => {
+		for (const name of [
+			"main",
+			"feature/login-v2",
+			"cu-sharing-loon",
+			"refs/remotes/container-use/sharing-loon",
+		]) {
+			assert.equal(isSafeRefName(name), true, name);
+		}
+	});
+
+	it("rejects names that change the refspec or that git refuses", () => {
+		for (const name of [
+			"+main",
+			"main:refs/heads/other",
+			":refs/heads/main",
+			"main^",
+			"main~1",
+			"ma*in",
+			"ma?in",
+			"ma[in",
+			"ma\\in",
+			"main..other",
+			"main@{1}",
+			"-main",
+			"ma in",
+			".hidden",
+			"main.lock",
+			"main/",
+		]) {
+			assert.equal(isSafeRefName(name), false, name);
+		}
+	});
+});
+
+describe("runGitRemoteOperation with a local bare repository", () => {
+	let root: string;
+	let work: string;
+	let other: string;
+
+	const git = (cwd: string, ...args: string[]) =>
+		execFileSync("git", args, { cwd, encoding: "utf-8", stdio: "pipe" });
+
+	const commit = (cwd: string, file: string, content: string) => {
+		writeFileSync(path.join(cwd, file), content);
+		git(cwd, "add", file);
+		git(cwd, "commit", "-q", "-m", `Update ${file}`);
+	};
+
+	const clone = (name: string): string => {
+		const folder = path.join(root, name);
+		git(root, "clone", "-q", "remote.git", name);
+		git(folder, "config", "user.name", "Test");
+		git(folder, "config", "user.email", "test@example.com");
+		git(folder, "checkout", "-q", "-B", "main");
+		return folder;
+	};
+
+	before(() => {
+		root = mkdtempSync(path.join(tmpdir(), "cuweb-git-remote-"));
+		git(root, "init", "-q", "--bare", "remote.git");
+		git(
+			path.join(root, "remote.git"),
+			"symbolic-ref",
+			"HEAD",
+			"refs/heads/main",
+		);
+		work = clone("work");
+		other = clone("other");
+	});
+
+	after(() => {
+		rmSync(root, { recursive: true, force: true });
+	});
+
+	it("pushes a new branch", async () => {
+		commit(work, "README.md", "hello\n");
+		const progress: string[] = [];
+		const result = await runGitRemoteOperation(
+			{ operation: "push", folder: work, remote: "origin", branch: "main" },
+			(update) => progress.push(update.line),
+		);
+
+		assert.equal(result.success, true);
+		assert.deepEqual(
+			result.updatedRefs.map((ref) => [ref.status, ref.to]),
+			[["new", "refs/heads/main"]],
+		);
+		assert.deepEqual(result.rejectedRefs, []);
+		assert.ok(progress.length > 0);
+	});
+
+	it("fetches and fast-forwards with pull", async () => {
+		git(other, "pull", "-q", "origin", "main");
+		commit(other, "CHANGES.md", "one\n");
+		git(other, "push", "-q", "origin", "main");
+
+		const fetched = await runGitRemoteOperation({
+			operation: "fetch",
+			folder: work,
+			remote: "origin",
+		});
+		assert.equal(fetched.success, true);
+		assert.deepEqual(
+			fetched.updatedRefs.map((ref) => [ref.status, ref.to]),
+			[["fast-forward", "origin/main"]],
+		);
+
+		const pulled = await runGitRemoteOperation({
+			operation: "pull",
+			folder: work,
+			remote: "origin",
+			branch: "main",
+			ffOnly: true,
+		});
+		assert.equal(pulled.success, true);
+		assert.equal(
+			git(work, "rev-parse", "HEAD"),
+			git(other, "rev-parse", "HEAD"),
+		);
+	});
+
+	it("reports a push of diverged history as rejected", async () => {
+		commit(other, "CHANGES.md", "two\n");
+		git(other, "push", "-q", "origin", "main");
+		commit(work, "NOTES.md", "local\n");
+
+		const result = await runGitRemoteOperation({
+			operation: "push",
+			folder: work,
+			remote: "origin",
+			branch: "main",
+		});
+		assert.equal(result.success, false);
+		assert.deepEqual(result.updatedRefs, []);
+		assert.deepEqual(
+			result.rejectedRefs.map((ref) => [ref.to, ref.reason]),
+			[["refs/heads/main", "fetch first"]],
+		);
+	});
+
+	it("refuses to pull a diverged branch with ffOnly", async () => {
+		const result = await runGitRemoteOperation({
+			operation: "pull",
+			folder: work,
+			remote: "origin",
+			branch: "main",
+			ffOnly: true,
+		});
+		assert.equal(result.success, false);
+		assert.notEqual(result.exitCode, 0);
+	});
+
+	it("merges a diverged branch when pulling without ffOnly", async () => {
+		const result = await runGitRemoteOperation({
+			operation: "pull",
+			folder: work,
+			remote: "origin",
+			branch: "main",
+		});
+		assert.equal(result.success, true, result.output);
+		assert.equal(
+			git(work, "rev-list", "--count", "--merges", "HEAD").trim(),
+			"1",
+		);
+	});
+
+	it("rejects unknown remotes and unsafe arguments", async () => {
+		await assert.rejects(
+			runGitRemoteOperation({
+				operation: "fetch",
+				folder: work,
+				remote: "upstream",
+			}),
+			GitRemoteError,
+		);
+		await assert.rejects(
+			runGitRemoteOperation({
+				operation: "push",
+				folder: work,
+				remote: "origin",
+				branch: "--mirror",
+			}),
+			GitRemoteError,
+		);
+		// "+" would force the push without the force option
+		await assert.rejects(
+			runGitRemoteOperation({
+				operation: "push",
+				folder: work,
+				remote: "origin",
+				branch: "+main",
+			}),
+			GitRemoteError,
+		);
+		await assert.rejects(
+			runGitRemoteOperation({
+				operation: "push",
+				folder: work,
+				remote: "origin",
+				branch: "main",
+				remoteBranch: "main:refs/heads/other",
+			}),
+			GitRemoteError,
+		);
+	});
+});
@@ -0,0 +1,395 @@
+import type {
+	GitRefUpdate,
+	GitRemote,
+	GitRemoteResult,
+} from "../models/git.js";
+import { executeGenericCommand } from "./cli-executor.js";
+
+export type GitRemoteOperation = "fetch" | "pull" | "push";
+
+export interface GitRemoteOptions {
+	operation: GitRemoteOperation;
+	folder: string;
+	remote: string;
+	branch?: string; // pull: remote branch, push: local branch or ref
+	remoteBranch?: string; // push: destination branch on the remote
+	ffOnly?: boolean;
+	prune?: boolean;
+	setUpstream?: boolean;
+	force?: boolean;
+}
+
+export interface GitProgress {
+	phase: string;
+	percent?: number;
+	current?: number;
+	total?: number;
+	line: string;
+}
+
+/**
+ * Error raised when a remote operation is rejected before git is run
+ */
+export class GitRemoteError extends Error {}
+
+// Never let git block waiting for credentials on a terminal we don't have
+const NON_INTERACTIVE_ENV = {
+	GIT_TERMINAL_PROMPT: "0",
+	GCM_INTERACTIVE: "never",
+};
+
+// Progress lines look like "Receiving objects:  45% (9/20)" or "remote: Counting objects: 100% (3/3), done."
+const PROGRESS_PATTERN =
+	/^(?:remote: )?([A-Za-z][A-Za-z ]*):\s+(\d+)% \((\d+)\/(\d+)\)/;
+
+// Ref lines from fetch/pull, e.g. " * [new branch]      feature    -> origin/feature"
+const FETCH_REF_PATTERN =
+	/^ ([ +\-t*!=]) (\[[^\]]+\]|\S+)\s+(\S+)\s+-> (\S+)(?:\s+\((.+)\))?$/;
+
+// Ref lines from "git push --porcelain", e.g. "*\trefs/heads/main:refs/heads/main\t[new branch]"
+const PUSH_REF_PATTERN = /^([ +\-*!=])\t([^:]*):([^\t]+)\t(.*)$/;
+
+// What git check-ref-format refuses in ref names, e.g. "~", "^", ":" and
+// "..", which a push refspec would otherwise read as syntax
+const INVALID_REF_PATTERN =
+	/[\s\x00-\x1f\x7f~^:?*[\\]|\.\.|@\{|\/\/|\/\.|^\.|^@$|\.lock$|\/$|\.$/;
+
+const FLAG_STATUS: Record<string, GitRefUpdate["status"]> = {
+	" ": "fast-forward",
+	"+": "forced",
+	"-": "deleted",
+	"*": "new",
+	"!": "rejected",
+	"=": "up-to-date",
+	t: "tag-update",
+};
+
+/**
+ * Rejects values that git could interpret as an option or that contain whitespace
+ */
+export function isSafeGitArgument(value: string): boolean {
+	return (
+		value.length > 0 && !value.startsWith("-") && !/[\s\x00-\x1f]/.test(value)
+	);
+}
+
+/**
+ * Check a branch or ref name before it is put into a refspec
+ *
+ * Besides the names git refuses, a leading "+" is rejected as it would force
+ * the push without the force option.
+ */
+export function isSafeRefName(value: string): boolean {
+	return (
+		isSafeGitArgument(value) &&
+		!value.startsWith("+") &&
+		!INVALID_REF_PATTERN.test(value)
+	);
+}
+
+/**
+ * Get the names of the configured remotes
+ */
+export async function getRemoteNames(folder: string): Promise<string[]> {
+	const result = await executeGenericCommand({
+		command: "git",
+		args: ["remote"],
+		workingDir: folder,
+		forceColor: false,
+	});
+	if (result.code !== 0) {
+		return [];
+	}
+	return result.stdout
+		.split("\n")
+		.map((line) => line.trim())
+		.filter(Boolean);
+}
+
+/**
+ * Get the configured remotes with their fetch and push URLs
+ */
+export async function getRemotes(folder: string): Promise<GitRemote[]> {
+	const result = await executeGenericCommand({
+		command: "git",
+		args: ["remote", "-v"],
+		workingDir: folder,
+		forceColor: false,
+	});
+
+	const remotes = new Map<string, GitRemote>();
+	if (result.code !== 0) {
+		return [];
+	}
+
+	for (const line of result.stdout.split("\n")) {
+		// Format: "origin\tgit@github.com:user/repo.git (fetch)"
+		const match = line.match(/^(\S+)\t(.+) \((fetch|push)\)$/);
+		if (!match) continue;
+
+		const [, name, url, kind] = match;
+		const remote = remotes.get(name) ?? { name, fetchUrl: "", pushUrl: "" };
+		if (kind === "fetch") {
+			remote.fetchUrl = url;
+		} else {
+			remote.pushUrl = url;
+		}
+		remotes.set(name, remote);
+	}
+
+	return [...remotes.values()];
+}
+
+/**
+ * Parse a single git progress line, returning null for anything else
+ */
+export function parseProgressLine(line: string): GitProgress | null {
+	const match = line.match(PROGRESS_PATTERN);
+	if (!match) {
+		return null;
+	}
+	const [, phase, percent, current, total] = match;
+	return {
+		phase: phase.trim(),
+		percent: parseInt(percent, 10),
+		current: parseInt(current, 10),
+		total: parseInt(total, 10),
+		line,
+	};
+}
+
+/**
+ * Parse ref update lines printed by fetch and pull (with --verbose)
+ */
+export function parseFetchRefUpdates(output: string): GitRefUpdate[] {
+	const refs: GitRefUpdate[] = [];
+	for (const line of output.split(/\r?\n/)) {
+		const match = line.match(FETCH_REF_PATTERN);
+		if (!match) continue;
+
+		const [, flag, summary, from, to, reason] = match;
+		// "git pull" also reports the branch it merges, which is not a ref update
+		if (to === "FETCH_HEAD") continue;
+
+		refs.push({
+			status: FLAG_STATUS[flag],
+			from,
+			to,
+			summary,
+			...(reason && { reason }),
+		});
+	}
+	return refs;
+}
+
+/**
+ * Parse ref update lines printed by "git push --porcelain"
+ */
+export function parsePushRefUpdates(output: string): GitRefUpdate[] {
+	const refs: GitRefUpdate[] = [];
+	for (const line of output.split(/\r?\n/)) {
+		const match = line.match(PUSH_REF_PATTERN);
+		if (!match) continue;
+
+		const [, flag, from, to, rest] = match;
+		// Summary is e.g. "[rejected] (fetch first)" or "1a2b3c4..5d6e7f8"
+		const reasonMatch = rest.match(/^(.*?)\s*\((.+)\)$/);
+		refs.push({
+			status: FLAG_STATUS[flag],
+			from,
+			to,
+			summary: reasonMatch ? reasonMatch[1] : rest,
+			...(reasonMatch && { reason: reasonMatch[2] }),
+		});
+	}
+	return refs;
+}
+
+/**
+ * Build the git arguments for a remote operation
+ */
+async function buildArgs(options: GitRemoteOptions): Promise<string[]> {
+	const { operation, folder, remote, branch, remoteBranch } = options;
+
+	if (!isSafeGitArgument(remote)) {
+		throw new GitRemoteError(`Invalid argument: ${remote}`);
+	}
+	for (const value of [branch, remoteBranch]) {
+		if (value !== undefined && !isSafeRefName(value)) {
+			throw new GitRemoteError(`Invalid branch name: ${value}`);
+		}
+	}
+
+	const remotes = await getRemoteNames(folder);
+	if (!remotes.includes(remote)) {
+		throw new GitRemoteError(`Remote not configured: ${remote}`);
+	}
+
+	switch (operation) {
+		case "fetch":
+			return [
+				"fetch",
+				"--progress",
+				"--verbose",
+				...(options.prune ? ["--prune"] : []),
+				remote,
+			];
+		case "pull":
+			return [
+				"pull",
+				"--progress",
+				"--verbose",
+				// Since git 2.33 a diverged pull fails without a strategy unless
+				// pull.rebase is configured, so merge as git used to
+				...(options.ffOnly ? ["--ff-only"] : ["--no-rebase"]),
+				remote,
+				...(branch ? [branch] : []),
+			];
+		case "push": {
+			const source = branch || "HEAD";
+			const destination = remoteBranch
+				? `refs/heads/${remoteBranch}`
+				: branch && !branch.startsWith("refs/")
+					? `refs/heads/${branch}`
+					: undefined;
+			return [
+				"push",
+				"--porcelain",
+				"--progress",
+				...(options.setUpstream ? ["--set-upstream"] : []),
+				...(options.force ? ["--force-with-lease"] : []),
+				remote,
+				destination ? `${source}:${destination}` : source,
+			];
+		}
+	}
+}
+
+/**
+ * Run fetch, pull or push against a remote, reporting git's progress as it is produced
+ *
+ * Progress is written by git to stderr using carriage returns to redraw the
+ * same line; each redraw is reported separately through onProgress.
+ */
+export async function runGitRemoteOperation(
+	options: GitRemoteOptions,
+	onProgress?: (progress: GitProgress) => void,
+): Promise<GitRemoteResult> {
+	const args = await buildArgs(options);
+
+	let pending = "";
+	const outputLines: string[] = [];
+
+	const result = await executeGenericCommand({
+		command: "git",
+		args,
+		workingDir: options.folder,
+		environment: NON_INTERACTIVE_ENV,
+		forceColor: false,
+		onData: (chunk, stream) => {
+			if (stream !== "stderr") return;
+
+			pending += chunk;
+			const lines = pending.split(/\r\n|\r|\n/);
+			pending = lines.pop() ?? "";
+			for (const rawLine of lines) {
+				// Remote progress lines are padded with spaces to overwrite the previous redraw
+				const line = rawLine.trimEnd();
+				const progress = parseProgressLine(line);
+				if (progress) {
+					onProgress?.(progress);
+				} else if (line.trim()) {
+					onProgress?.({ phase: "output", line });
+				}
+			}
+		},
+	});
+
+	// Keep the final state of each redrawn progress line out of the textual output
+	for (const line of `${result.stderr}\n${result.stdout}`.split(/\r\n|\r|\n/)) {
+		if (line.trim() && !PROGRESS_PATTERN.test(line)) {
+			outputLines.push(line.trimEnd());
+		}
+	}
+
+	const refs =
+		options.operation === "push"
+			? parsePushRefUpdates(result.stdout)
+			: parseFetchRefUpdates(result.stderr);
+
+	return {
+		success: result.code === 0,
+		operation: options.operation,
+		remote: options.remote,
+		updatedRefs: refs.filter(
+			(ref) => ref.status !== "rejected" && ref.status !== "up-to-date",
+		),
+		rejectedRefs: refs.filter((ref) => ref.status === "rejected"),
+		output: outputLines.join("\n"),
+		exitCode: result.code,
+	};
+}
+
+/**
+ * Options for pushing a container-use environment branch to a remote for review
+ *
+ * Environment branches live under the "container-use" remote; they are pushed
+ * to the target remote as "cu-<id>" unless another name is given.
+ */
+export function getEnvironmentPushOptions(
+	folder: string,
+	environmentId: string,
+	remote: string,
+	remoteBranch?: string,
+): GitRemoteOptions {
+	return {
+		operation: "push",
+		folder,
+		remote,
+		branch: `refs/remotes/container-use/${environmentId}`,
+		remoteBranch: remoteBranch || `cu-${environmentId}`,
+	};
+}
+
+/**
+ * WebSocket handler that runs a remote operation and streams its progress
+ *
+ * Messages sent to the client:
+ * - { type: "progress", phase, percent?, current?, total?, line }
+ * - { type: "result", result }
+ * - { type: "error", error }
+ * The socket is closed once the operation finishes.
+ */
+export const handleGitRemoteStream = (
+	ws: WebSocket,
+	options: GitRemoteOptions,
+): void => {
+	let closed = false;
+	ws.addEventListener("close", () => {
+		closed = true;
+	});
+
+	const send = (message: object) => {
+		if (!closed) {
+			ws.send(JSON.stringify(message));
+		}
+	};
+
+	runGitRemoteOperation(options, (progress) => {
+		send({ type: "progress", ...progress });
+	})
+		.then((result) => {
+			send({ type: "result", result });
+		})
+		.catch((error) => {
+			send({
+				type: "error",
+				error: error instanceof Error ? error.message : "Unknown error",
+			});
+		})
+		.finally(() => {
+			if (!closed) {
+				ws.close(1000, "Operation finished");
+			}
+		});
+};
@@ -0,0 +1,167 @@
+import assert from "node:assert/strict";
+import { execFileSync } from "node:child_process";
+import { mkdirSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import * as path from "node:path";
+import { after, before, describe, it } from "node:test";
+import {
+	getWorkingTreeStatus,
+	isGitRepository,
+	parseNumstat,
+	parsePorcelainV2,
+} from "./git-status.js";
+
+const HASH = "0123456789abcdef0123456789abcdef01234567";
+
+describe("parsePorcelainV2", () => {
+	it("reads the branch headers", () => {
+		const { branch } = parsePorcelainV2(
+			[
+				`# branch.oid ${HASH}`,
+				"# branch.head main",
+				"# branch.upstream origin/main",
+				"# branch.ab +2 -1",
+				"",
+			].join("\0"),
+		);
+		assert.deepEqual(branch, {
+			oid: "0123456",
+			head: "main",
+			upstream: "origin/main",
+			ahead: 2,
+			behind: 1,
+		});
+	});
+
+	it("pairs a renamed entry with its original path", () => {
+		const { files } = parsePorcelainV2(
+			[
+				`2 R. N... 100644 100644 100644 ${HASH} ${HASH} R87 src/new name.ts`,
+				"src/old name.ts",
+				`1 .M N... 100644 100644 100644 ${HASH} ${HASH} README.md`,
+				"",
+			].join("\0"),
+		);
+		assert.equal(files.length, 2);
+		assert.equal(files[0].kind, "renamed");
+		assert.equal(files[0].path, "src/new name.ts");
+		assert.equal(files[0].originalPath, "src/old name.ts");
+		assert.equal(files[0].similarity, 87);
+		assert.equal(files[0].status, "R");
+		assert.equal(files[0].staged, true);
+		assert.equal(files[0].unstaged, false);
+		assert.equal(files[0].description, "staged renamed");
+		// The original path is not read as an entry of its own
+		assert.equal(files[1].path, "README.md");
+		assert.equal(files[1].description, "modified");
+	});
+
+	it("reads unmerged entries with their conflict type", () => {
+		const { files } = parsePorcelainV2(
+			[
+				`u UU N... 100644 100644 100644 100644 ${HASH} ${HASH} ${HASH} a.txt`,
+				`u DU N... 100644 000000 100644 100644 ${HASH} ${HASH} ${HASH} b.txt`,
+				"",
+			].join("\0"),
+		);
+		assert.deepEqual(
+			files.map((entry) => [
+				entry.path,
+				entry.kind,
+				entry.conflictType,
+				entry.description,
+			]),
+			[
+				["a.txt", "unmerged", "both-modified", "unmerged (both modified)"],
+				["b.txt", "unmerged", "deleted-by-us", "unmerged (deleted by us)"],
+			],
+		);
+	});
+
+	it("reads untracked entries with spaces in their path", () => {
+		const { files } = parsePorcelainV2("? notes/to do.md\0");
+		assert.equal(files.length, 1);
+		assert.equal(files[0].path, "notes/to do.md");
+		assert.equal(files[0].kind, "untracked");
+		assert.equal(files[0].status, "??");
+		assert.equal(files[0].staged, false);
+		assert.equal(files[0].unstaged, true);
+	});
+});
+
+describe("parseNumstat", () => {
+	it("reads text, binary and renamed files", () => {
+		const counts = parseNumstat(
+			[
+				"3\t1\tsrc/index.ts",
+				"-\t-\timage.png",
+				"5\t0\t",
+				"src/old.ts",
+				"src/new.ts",
+				"",
+			].join("\0"),
+		);
+		assert.deepEqual(
+			[...counts],
+			[
+				["src/index.ts", { insertions: 3, deletions: 1, binary: false }],
+				["image.png", { binary: true }],
+				// Renames are keyed by the new path
+				["src/new.ts", { insertions: 5, deletions: 0, binary: false }],
+			],
+		);
+	});
+});
+
+describe("git status of a linked worktree", () => {
+	let root: string;
+	let repo: string;
+	let worktree: string;
+
+	const git = (cwd: string, ...args: string[]) =>
+		execFileSync("git", args, { cwd, encoding: "utf-8", stdio: "pipe" });
+
+	before(() => {
+		root = mkdtempSync(path.join(tmpdir(), "cuweb-git-status-"));
+		repo = path.join(root, "repo");
+		mkdirSync(repo);
+		git(repo, "init", "-q", "-b", "main");
+		git(repo, "config", "user.name", "Test");
+		git(repo, "config", "user.email", "test@example.com");
+		writeFileSync(path.join(repo, "README.md"), "hello\n");
+		git(repo, "add", "README.md");
+		git(repo, "commit", "-q", "-m", "Initial commit");
+
+		// Its .git is a file pointing back to the main repository
+		worktree = path.join(root, "worktree");
+		git(repo, "worktree", "add", "-q", "-b", "feature", worktree);
+	});
+
+	after(() => {
+		rmSync(root, { recursive: true, force: true });
+	});
+
+	it("recognizes worktrees, subfolders and non-repositories", async () => {
+		mkdirSync(path.join(worktree, "src"));
+		assert.equal(await isGitRepository(repo), true);
+		assert.equal(await isGitRepository(worktree), true);
+		assert.equal(await isGitRepository(path.join(worktree, "src")), true);
+		assert.equal(await isGitRepository(root), false);
+		assert.equal(await isGitRepository(path.join(root, "missing")), false);
+	});
+
+	it("gets the branch and changes of the worktree", async () => {
+		writeFileSync(path.join(worktree, "README.md"), "hello\nworld\n");
+		writeFileSync(path.join(worktree, "NOTES.md"), "new\n");
+
+		const status = await getWorkingTreeStatus(worktree);
+		assert.equal(status.branch.head, "feature");
+		assert.deepEqual(
+			status.files.map((entry) => [entry.path, entry.kind, entry.insertions]),
+			[
+				["README.md", "changed", 1],
+				["NOTES.md", "untracked", 1],
+			],
+		);
+	});
+});
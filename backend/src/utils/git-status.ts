@@ -0,0 +1,461 @@
+import * as fs from "node:fs/promises";
+import * as path from "node:path";
+import type {
+	GitBranchStatus,
+	GitConflictType,
+	GitFileDiff,
+	GitFileState,
+	GitStatusFileEntry,
+} from "../models/git.js";
+import { executeGenericCommand } from "./cli-executor.js";
+
+/**
+ * Error raised when a status or diff request is rejected before git is run
+ */
+export class GitStatusError extends Error {}
+
+// Untracked files are not known to "git diff", so their lines are counted
+// directly; larger files are left without counts
+const MAX_UNTRACKED_COUNT_SIZE = 1024 * 1024;
+
+// Unmerged XY codes from porcelain v2
+const CONFLICT_TYPES: Record<string, GitConflictType> = {
+	UU: "both-modified",
+	AA: "both-added",
+	DU: "deleted-by-us",
+	UD: "deleted-by-them",
+	AU: "added-by-us",
+	UA: "added-by-them",
+	DD: "both-deleted",
+};
+
+const STATE_DESCRIPTIONS: Record<string, string> = {
+	M: "modified",
+	T: "type changed",
+	A: "added",
+	D: "deleted",
+	R: "renamed",
+	C: "copied",
+};
+
+interface LineCount {
+	insertions?: number;
+	deletions?: number;
+	binary: boolean;
+}
+
+async function git(folder: string, args: string[]) {
+	return executeGenericCommand({
+		command: "git",
+		args,
+		workingDir: folder,
+		forceColor: false,
+	});
+}
+
+/**
+ * Resolve the repository root, which porcelain paths are relative to
+ */
+export async function getRepositoryRoot(folder: string): Promise<string> {
+	const result = await git(folder, ["rev-parse", "--show-toplevel"]);
+	return result.code === 0 ? result.stdout.trim() : folder;
+}
+
+/**
+ * Check whether a folder is inside a git working tree
+ *
+ * Linked worktrees have a .git file instead of a folder, so git is asked
+ * rather than looking for .git.
+ */
+export async function isGitRepository(folder: string): Promise<boolean> {
+	try {
+		const result = await git(folder, ["rev-parse", "--is-inside-work-tree"]);
+		return result.code === 0 && result.stdout.trim() === "true";
+	} catch {
+		// The folder doesn't exist
+		return false;
+	}
+}
+
+/**
+ * Check whether a fully qualified ref (e.g. refs/heads/main) exists
+ */
+export async function refExists(folder: string, ref: string): Promise<boolean> {
+	const result = await git(folder, ["show-ref", "--verify", "--quiet", ref]);
+	return result.code === 0;
+}
+
+/**
+ * Parse a porcelain v2 submodule field, e.g. "N..." or "SC.U"
+ */
+function parseSubmodule(field: string): GitStatusFileEntry["submodule"] {
+	if (!field.startsWith("S")) {
+		return undefined;
+	}
+	return {
+		commitChanged: field[1] === "C",
+		trackedChanges: field[2] === "M",
+		untrackedChanges: field[3] === "U",
+	};
+}
+
+/**
+ * Describe an entry the way the short status letters read
+ */
+function describe(entry: GitStatusFileEntry): string {
+	if (entry.kind === "untracked" || entry.kind === "ignored") {
+		return entry.kind;
+	}
+	if (entry.kind === "unmerged") {
+		return `unmerged (${entry.conflictType?.replaceAll("-", " ")})`;
+	}
+
+	const staged = STATE_DESCRIPTIONS[entry.indexStatus];
+	const unstaged = STATE_DESCRIPTIONS[entry.worktreeStatus];
+	if (staged && unstaged) {
+		return `staged ${staged}, ${unstaged}`;
+	}
+	return staged ? `staged ${staged}` : (unstaged ?? "unknown change");
+}
+
+function createEntry(
+	kind: GitStatusFileEntry["kind"],
+	xy: string,
+	filePath: string,
+	extra: Partial<GitStatusFileEntry> = {},
+): GitStatusFileEntry {
+	const indexStatus = xy[0] as GitFileState;
+	const worktreeStatus = xy[1] as GitFileState;
+	const entry: GitStatusFileEntry = {
+		// Same short code as "git status --short", e.g. "M", "MM" or "??"
+		status: xy.replaceAll(".", " ").trim(),
+		path: filePath,
+		description: "",
+		kind,
+		indexStatus,
+		worktreeStatus,
+		staged: kind !== "untracked" && kind !== "ignored" && indexStatus !== ".",
+		unstaged:
+			kind === "untracked" || (kind !== "ignored" && worktreeStatus !== "."),
+		binary: false,
+		...extra,
+	};
+	entry.description = describe(entry);
+	return entry;
+}
+
+/**
+ * Parse "git status --porcelain=v2 --branch -z" output
+ *
+ * Fields are space separated and entries NUL separated; renamed and copied
+ * entries are followed by an extra NUL separated original path.
+ */
+export function parsePorcelainV2(output: string): {
+	branch: GitBranchStatus;
+	files: GitStatusFileEntry[];
+} {
+	const branch: GitBranchStatus = { head: "", oid: "" };
+	const files: GitStatusFileEntry[] = [];
+	const records = output.split("\0");
+
+	for (let i = 0; i < records.length; i++) {
+		const record = records[i];
+		if (!record) continue;
+
+		if (record.startsWith("# ")) {
+			const [key, ...values] = record.slice(2).split(" ");
+			if (key === "branch.oid") {
+				branch.oid =
+					values[0] === "(initial)" ? values[0] : values[0].slice(0, 7);
+			} else if (key === "branch.head") {
+				branch.head = values[0];
+			} else if (key === "branch.upstream") {
+				branch.upstream = values[0];
+			} else if (key === "branch.ab") {
+				branch.ahead = Number(values[0]);
+				branch.behind = Math.abs(Number(values[1]));
+			}
+			continue;
+		}
+
+		const type = record[0];
+		if (type === "1") {
+			// 1 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <path>
+			const fields = record.split(" ");
+			files.push(
+				createEntry("changed", fields[1], fields.slice(8).join(" "), {
+					submodule: parseSubmodule(fields[2]),
+				}),
+			);
+		} else if (type === "2") {
+			// 2 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <X><score> <path>\0<origPath>
+			const fields = record.split(" ");
+			const score = fields[8];
+			files.push(
+				createEntry(
+					score.startsWith("C") ? "copied" : "renamed",
+					fields[1],
+					fields.slice(9).join(" "),
+					{
+						originalPath: records[++i],
+						similarity: Number(score.slice(1)),
+						submodule: parseSubmodule(fields[2]),
+					},
+				),
+			);
+		} else if (type === "u") {
+			// u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>
+			const fields = record.split(" ");
+			files.push(
+				createEntry("unmerged", fields[1], fields.slice(10).join(" "), {
+					conflictType: CONFLICT_TYPES[fields[1]],
+					submodule: parseSubmodule(fields[2]),
+				}),
+			);
+		} else if (type === "?") {
+			files.push(createEntry("untracked", "??", record.slice(2)));
+		} else if (type === "!") {
+			files.push(createEntry("ignored", "!!", record.slice(2)));
+		}
+	}
+
+	return { branch, files };
+}
+
+/**
+ * Parse "git diff --numstat -z" output into per-path line counts
+ *
+ * Renames are reported as "<added>\t<deleted>\t\0<from>\0<to>", keyed here by
+ * the new path. Binary files show "-" for both counts.
+ */
+export function parseNumstat(output: string): Map<string, LineCount> {
+	const counts = new Map<string, LineCount>();
+	const records = output.split("\0");
+
+	for (let i = 0; i < records.length; i++) {
+		const match = records[i].match(/^(\d+|-)\t(\d+|-)\t(.*)$/s);
+		if (!match) continue;
+
+		const [, added, deleted, inlinePath] = match;
+		let filePath = inlinePath;
+		if (!filePath) {
+			i += 2;
+			filePath = records[i];
+		}
+
+		counts.set(
+			filePath,
+			added === "-"
+				? { binary: true }
+				: {
+						insertions: Number(added),
+						deletions: Number(deleted),
+						binary: false,
+					},
+		);
+	}
+
+	return counts;
+}
+
+/**
+ * Count the lines of a new file, treating files with NUL bytes as binary
+ */
+async function countUntrackedLines(
+	filePath: string,
+): Promise<LineCount | undefined> {
+	try {
+		const stat = await fs.stat(filePath);
+		if (!stat.isFile() || stat.size > MAX_UNTRACKED_COUNT_SIZE) {
+			return undefined;
+		}
+
+		const content = await fs.readFile(filePath);
+		if (content.includes(0)) {
+			return { binary: true };
+		}
+
+		const text = content.toString("utf-8");
+		const newlines = text.split("\n").length - 1;
+		return {
+			insertions: text.endsWith("\n") || !text ? newlines : newlines + 1,
+			deletions: 0,
+			binary: false,
+		};
+	} catch {
+		return undefined;
+	}
+}
+
+function addCounts(entry: GitStatusFileEntry, count?: LineCount) {
+	if (!count) return;
+	if (count.binary) {
+		entry.binary = true;
+		return;
+	}
+	entry.insertions = (entry.insertions ?? 0) + (count.insertions ?? 0);
+	entry.deletions = (entry.deletions ?? 0) + (count.deletions ?? 0);
+}
+
+/**
+ * Get the branch and file status of a repository from porcelain v2
+ *
+ * Line counts cover both staged and unstaged changes of each file.
+ */
+export async function getWorkingTreeStatus(
+	folder: string,
+	options: { ignored?: boolean } = {},
+): Promise<{
+	root: string;
+	branch: GitBranchStatus;
+	files: GitStatusFileEntry[];
+}> {
+	const root = await getRepositoryRoot(folder);
+	const result = await git(root, [
+		"status",
+		"--porcelain=v2",
+		"--branch",
+		"-z",
+		"--untracked-files=all",
+		...(options.ignored ? ["--ignored=matching"] : []),
+	]);
+	if (result.code !== 0) {
+		throw new Error(result.stderr.trim() || "Failed to get git status");
+	}
+
+	const { branch, files } = parsePorcelainV2(result.stdout);
+	if (files.length === 0) {
+		return { root, branch, files };
+	}
+
+	// "git diff --cached" also works on an unborn branch
+	const [staged, unstaged] = await Promise.all([
+		git(root, ["diff", "--cached", "--numstat", "-z", "-M"]),
+		git(root, ["diff", "--numstat", "-z"]),
+	]);
+	const stagedCounts = parseNumstat(staged.code === 0 ? staged.stdout : "");
+	const unstagedCounts = parseNumstat(
+		unstaged.code === 0 ? unstaged.stdout : "",
+	);
+
+	for (const entry of files) {
+		if (entry.kind === "ignored") continue;
+		if (entry.kind === "untracked") {
+			addCounts(entry, await countUntrackedLines(path.join(root, entry.path)));
+			continue;
+		}
+		// Unmerged files are only reported by the worktree diff
+		if (entry.staged && entry.kind !== "unmerged") {
+			addCounts(entry, stagedCounts.get(entry.path));
+		}
+		if (entry.unstaged) {
+			addCounts(entry, unstagedCounts.get(entry.path));
+		}
+	}
+
+	return { root, branch, files };
+}
+
+/**
+ * Get the status of the files under a folder, without line counts
+ *
+ * Returns null if the folder is not inside a git repository.
+ */
+export async function getFolderStatus(
+	folder: string,
+): Promise<{ root: string; files: GitStatusFileEntry[] } | null> {
+	const result = await git(folder, [
+		"status",
+		"--porcelain=v2",
+		"-z",
+		"--untracked-files=all",
+		"--",
+		".",
+	]);
+	if (result.code !== 0) {
+		return null;
+	}
+	return {
+		root: await getRepositoryRoot(folder),
+		files: parsePorcelainV2(result.stdout).files,
+	};
+}
+
+/**
+ * Find which of the given paths, relative to folder, are ignored by git
+ *
+ * Tracked files are never reported as ignored.
+ */
+export async function getIgnoredPaths(
+	folder: string,
+	paths: string[],
+): Promise<Set<string>> {
+	if (paths.length === 0) {
+		return new Set();
+	}
+	const result = await executeGenericCommand({
+		command: "git",
+		args: ["check-ignore", "-z", "--stdin"],
+		workingDir: folder,
+		forceColor: false,
+		input: paths.join("\0"),
+	});
+	// Exits with 1 when none of the paths are ignored
+	return new Set(
+		result.code === 0 ? result.stdout.split("\0").filter(Boolean) : [],
+	);
+}
+
+/**
+ * Get the staged or working-tree diff of a single file
+ *
+ * Renames are diffed against their original path, and untracked files are
+ * diffed as new files.
+ */
+export async function getFileDiff(
+	folder: string,
+	file: string,
+	staged = false,
+): Promise<GitFileDiff> {
+	const root = await getRepositoryRoot(folder);
+	const relative = path.relative(root, path.resolve(root, file));
+	if (!relative || relative.startsWith("..") || path.isAbsolute(relative)) {
+		throw new GitStatusError(`Path is outside the repository: ${file}`);
+	}
+
+	const { files } = await getWorkingTreeStatus(root);
+	const entry = files.find((candidate) => candidate.path === relative);
+	if (!entry) {
+		throw new GitStatusError(`File has no changes: ${relative}`);
+	}
+
+	const untracked = entry.kind === "untracked";
+	let args: string[];
+	if (untracked) {
+		if (staged) {
+			throw new GitStatusError(`File is untracked: ${relative}`);
+		}
+		args = ["diff", "--no-index", "--", "/dev/null", relative];
+	} else {
+		const paths =
+			staged && entry.originalPath
+				? [entry.originalPath, relative]
+				: [relative];
+		args = ["diff", ...(staged ? ["--cached", "-M"] : []), "--", ...paths];
+	}
+
+	const result = await git(root, args);
+	// "--no-index" exits with 1 when the files differ
+	if (result.code !== 0 && !(untracked && result.code === 1)) {
+		throw new Error(result.stderr.trim() || `Failed to diff ${relative}`);
+	}
+
+	return {
+		path: relative,
+		...(staged && entry.originalPath && { originalPath: entry.originalPath }),
+		staged,
+		untracked,
+		binary: /^Binary files .* differ$/m.test(result.stdout),
+		diff: result.stdout,
+	};
+}
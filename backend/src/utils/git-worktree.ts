@@ -0,0 +1,227 @@
+import { createHash } from "node:crypto";
+import * as fs from "node:fs/promises";
+import * as path from "node:path";
+import type { GitWorktree } from "../models/git.js";
+import { executeGenericCommand } from "./cli-executor.js";
+import { ENVIRONMENT_ID_PATTERN, getWorktreesDir } from "./constants.js";
+import { refExists } from "./git-status.js";
+import { realpathOfNearest } from "./path-access.js";
+
+/**
+ * Error raised when a worktree request is rejected before git is run
+ */
+export class GitWorktreeError extends Error {}
+
+/**
+ * Folder cuweb manages worktrees in, with its symlinks resolved like the
+ * worktree paths git reports, e.g. when the home directory is a symlink
+ */
+const getRealWorktreesDir = (): Promise<string> =>
+	realpathOfNearest(getWorktreesDir());
+
+/**
+ * Check whether a path is inside the folder cuweb manages worktrees in
+ *
+ * Both sides are compared as real paths.
+ */
+async function isManagedPath(worktreePath: string): Promise<boolean> {
+	const [worktreesDir, realPath] = await Promise.all([
+		getRealWorktreesDir(),
+		realpathOfNearest(worktreePath).catch(() => worktreePath),
+	]);
+	const relative = path.relative(worktreesDir, realPath);
+	return (
+		relative !== "" && !relative.startsWith("..") && !path.isAbsolute(relative)
+	);
+}
+
+/**
+ * Parse "git worktree list --porcelain" output
+ */
+export async function parseWorktreeList(
+	output: string,
+): Promise<GitWorktree[]> {
+	const worktrees: GitWorktree[] = [];
+
+	// Each worktree is a block of "key value" lines separated by a blank line
+	for (const block of output.split(/\n\n+/)) {
+		const lines = block.split("\n").filter(Boolean);
+		const worktreeLine = lines.find((line) => line.startsWith("worktree "));
+		if (!worktreeLine) continue;
+
+		const worktreePath = worktreeLine.slice("worktree ".length);
+		const head = lines.find((line) => line.startsWith("HEAD "));
+		const branch = lines.find((line) => line.startsWith("branch "));
+		const managed = await isManagedPath(worktreePath);
+
+		worktrees.push({
+			path: worktreePath,
+			head: head ? head.slice("HEAD ".length, "HEAD ".length + 7) : "",
+			...(branch && {
+				branch: branch.slice("branch ".length).replace(/^refs\/heads\//, ""),
+			}),
+			main: worktrees.length === 0,
+			managed,
+			...(managed && { environmentId: path.basename(worktreePath) }),
+			locked: lines.some((line) => line.split(" ")[0] === "locked"),
+			prunable: lines.some((line) => line.split(" ")[0] === "prunable"),
+		});
+	}
+
+	return worktrees;
+}
+
+/**
+ * List the worktrees of the repository containing folder
+ */
+export async function listWorktrees(folder: string): Promise<GitWorktree[]> {
+	const result = await executeGenericCommand({
+		command: "git",
+		args: ["worktree", "list", "--porcelain"],
+		workingDir: folder,
+		forceColor: false,
+	});
+	if (result.code !== 0) {
+		throw new Error(result.stderr.trim() || "Failed to list worktrees");
+	}
+	return parseWorktreeList(result.stdout);
+}
+
+/**
+ * Folder for an environment's worktree
+ *
+ * Worktrees are grouped per repository; the hash keeps repositories that
+ * share a folder name apart.
+ */
+async function getEnvironmentWorktreePath(
+	repositoryRoot: string,
+	environmentId: string,
+): Promise<string> {
+	const hash = createHash("sha1")
+		.update(repositoryRoot)
+		.digest("hex")
+		.slice(0, 8);
+	return path.join(
+		await getRealWorktreesDir(),
+		`${path.basename(repositoryRoot)}-${hash}`,
+		environmentId,
+	);
+}
+
+/**
+ * Open an environment's branch in a separate worktree managed by cuweb
+ *
+ * The worktree checks out a local "cu-<id>" branch tracking the
+ * container-use branch. An existing worktree for the environment is reused.
+ */
+export async function createEnvironmentWorktree(
+	folder: string,
+	environmentId: string,
+): Promise<{ created: boolean; worktree: GitWorktree }> {
+	if (!ENVIRONMENT_ID_PATTERN.test(environmentId)) {
+		throw new GitWorktreeError(`Invalid environment ID: ${environmentId}`);
+	}
+
+	const worktrees = await listWorktrees(folder);
+	const repositoryRoot = worktrees[0]?.path ?? folder;
+	const worktreePath = await getEnvironmentWorktreePath(
+		repositoryRoot,
+		environmentId,
+	);
+
+	const existing = worktrees.find((worktree) => worktree.path === worktreePath);
+	if (existing) {
+		return { created: false, worktree: existing };
+	}
+
+	// Update the environment branch, which may not exist locally yet
+	await executeGenericCommand({
+		command: "git",
+		args: ["fetch", "container-use", environmentId],
+		workingDir: repositoryRoot,
+		environment: { GIT_TERMINAL_PROMPT: "0" },
+		forceColor: false,
+	});
+
+	const remoteRef = `refs/remotes/container-use/${environmentId}`;
+	if (!(await refExists(repositoryRoot, remoteRef))) {
+		throw new GitWorktreeError(
+			`Environment branch not found: container-use/${environmentId}`,
+		);
+	}
+
+	const branch = `cu-${environmentId}`;
+	const args = (await refExists(repositoryRoot, `refs/heads/${branch}`))
+		? ["worktree", "add", worktreePath, branch]
+		: [
+				"worktree",
+				"add",
+				"--track",
+				"-b",
+				branch,
+				worktreePath,
+				`container-use/${environmentId}`,
+			];
+
+	await fs.mkdir(path.dirname(worktreePath), { recursive: true });
+	const result = await executeGenericCommand({
+		command: "git",
+		args,
+		workingDir: repositoryRoot,
+		forceColor: false,
+	});
+	if (result.code !== 0) {
+		// e.g. the branch is already checked out in another worktree
+		throw new GitWorktreeError(
+			result.stderr.trim() || "Failed to create worktree",
+		);
+	}
+
+	const worktree = (await listWorktrees(repositoryRoot)).find(
+		(entry) => entry.path === worktreePath,
+	);
+	if (!worktree) {
+		throw new Error(`Worktree was not created at ${worktreePath}`);
+	}
+	return { created: true, worktree };
+}
+
+/**
+ * Remove a worktree created by cuweb, keeping its branch
+ */
+export async function removeWorktree(
+	folder: string,
+	worktreePath: string,
+	force = false,
+): Promise<void> {
+	// git lists worktrees by their real path
+	const realPath = await realpathOfNearest(path.resolve(worktreePath)).catch(
+		() => path.resolve(worktreePath),
+	);
+	const worktree = (await listWorktrees(folder)).find(
+		(entry) => entry.path === realPath,
+	);
+	if (!worktree) {
+		throw new GitWorktreeError(
+			`Not a worktree of this repository: ${worktreePath}`,
+		);
+	}
+	if (!worktree.managed) {
+		throw new GitWorktreeError(
+			`Only worktrees created by cuweb can be removed: ${worktreePath}`,
+		);
+	}
+
+	const result = await executeGenericCommand({
+		command: "git",
+		args: ["worktree", "remove", ...(force ? ["--force"] : []), worktree.path],
+		workingDir: folder,
+		forceColor: false,
+	});
+	if (result.code !== 0) {
+		// e.g. the worktree has uncommitted changes and force was not set
+		throw new GitWorktreeError(
+			result.stderr.trim() || "Failed to remove worktree",
+		);
+	}
+}
@@ -0,0 +1,106 @@
+import assert from "node:assert/strict";
+import {
+	mkdirSync,
+	mkdtempSync,
+	realpathSync,
+	rmSync,
+	symlinkSync,
+	writeFileSync,
+} from "node:fs";
+import { tmpdir } from "node:os";
+import * as path from "node:path";
+import { after, before, describe, it } from "node:test";
+import { PathAccessError, resolveAllowedPath } from "./path-access.js";
+
+describe("resolveAllowedPath", () => {
+	const ENV_KEYS = [
+		"CUWEB_WORKING_DIR",
+		"CUWEB_WORKTREES_DIR",
+		"CUWEB_ALLOWED_ROOTS",
+		"CUWEB_CONFIG",
+	];
+	const savedEnv: Record<string, string | undefined> = {};
+	let root: string;
+	let work: string;
+	let outside: string;
+	let registered: string;
+
+	const rejects = (requested: string) =>
+		assert.rejects(resolveAllowedPath(requested), PathAccessError);
+
+	before(() => {
+		for (const key of ENV_KEYS) {
+			savedEnv[key] = process.env[key];
+		}
+		root = realpathSync(mkdtempSync(path.join(tmpdir(), "cuweb-access-")));
+		work = path.join(root, "work");
+		outside = path.join(root, "outside");
+		registered = path.join(root, "registered");
+		for (const folder of [work, outside, registered]) {
+			mkdirSync(folder);
+		}
+		writeFileSync(path.join(outside, "secret.txt"), "secret\n");
+		writeFileSync(path.join(work, "README.md"), "hello\n");
+
+		process.env.CUWEB_WORKING_DIR = work;
+		process.env.CUWEB_WORKTREES_DIR = path.join(root, "worktrees");
+		process.env.CUWEB_ALLOWED_ROOTS = "";
+		process.env.CUWEB_CONFIG = path.join(root, "config.json");
+		writeFileSync(
+			process.env.CUWEB_CONFIG,
+			JSON.stringify({ repositories: [registered] }),
+		);
+	});
+
+	after(() => {
+		for (const key of ENV_KEYS) {
+			if (savedEnv[key] === undefined) {
+				delete process.env[key];
+			} else {
+				process.env[key] = savedEnv[key];
+			}
+		}
+		rmSync(root, { recursive: true, force: true });
+	});
+
+	it("allows paths inside the working directory", async () => {
+		const readme = path.join(work, "README.md");
+		assert.equal(await resolveAllowedPath(readme), readme);
+		assert.equal(await resolveAllowedPath(work), work);
+	});
+
+	it("rejects paths outside the allowed roots", async () => {
+		await rejects(path.join(outside, "secret.txt"));
+		await rejects(root);
+	});
+
+	it("rejects .. traversal out of a root", async () => {
+		await rejects(path.join(work, "..", "outside", "secret.txt"));
+		// Staying inside the root after .. is fine
+		assert.equal(
+			await resolveAllowedPath(path.join(work, "sub", "..", "README.md")),
+			path.join(work, "README.md"),
+		);
+	});
+
+	it("rejects a symlink pointing out of a root", async () => {
+		symlinkSync(outside, path.join(work, "escape"));
+		await rejects(path.join(work, "escape", "secret.txt"));
+		// Also for new files created through the link
+		await rejects(path.join(work, "escape", "new", "file.txt"));
+	});
+
+	it("allows a path that doesn't exist yet under a root", async () => {
+		const newFile = path.join(work, "new", "folder", "file.txt");
+		assert.equal(await resolveAllowedPath(newFile), newFile);
+	});
+
+	it("allows the repositories registered in the config file", async () => {
+		const file = path.join(registered, "src", "index.ts");
+		assert.equal(await resolveAllowedPath(file), file);
+
+		// The config is read again on every call
+		writeFileSync(process.env.CUWEB_CONFIG as string, "{}");
+		await rejects(file);
+	});
+});
@@ -0,0 +1,144 @@
+import { promises as fs } from "node:fs";
+import * as os from "node:os";
+import * as path from "node:path";
+import { ConfigError, readConfig } from "./config.js";
+import { getAllowedRoots } from "./constants.js";
+
+/**
+ * Error raised for a path outside the folders the file system API may access
+ */
+export class PathAccessError extends Error {
+	path: string;
+	allowedRoots: string[];
+
+	constructor(requestedPath: string, allowedRoots: string[]) {
+		super(`Path is outside the allowed folders: ${requestedPath}`);
+		this.path = requestedPath;
+		this.allowedRoots = allowedRoots;
+	}
+}
+
+/**
+ * Error response for a path outside the allowed roots
+ */
+export function pathForbiddenResponse(err: PathAccessError) {
+	return {
+		error: err.message,
+		path: err.path,
+		allowedRoots: err.allowedRoots,
+	};
+}
+
+/**
+ * Resolves a directory path, handling special cases like '.' and '~'
+ */
+export function resolveDirectory(dir: string): string {
+	if (dir === ".") {
+		return process.cwd();
+	}
+	if (dir.startsWith("~/")) {
+		return path.join(os.homedir(), dir.slice(2));
+	}
+	if (dir === "~") {
+		return os.homedir();
+	}
+	return path.resolve(dir);
+}
+
+function isInside(parent: string, child: string): boolean {
+	const relative = path.relative(parent, child);
+	return (
+		relative === "" ||
+		(!relative.startsWith("..") && !path.isAbsolute(relative))
+	);
+}
+
+/**
+ * Resolve symlinks in a path that may not exist yet
+ *
+ * The nearest existing ancestor is resolved and the missing part appended,
+ * so new files can't be created through a symlink pointing elsewhere.
+ */
+export async function realpathOfNearest(entryPath: string): Promise<string> {
+	const missing: string[] = [];
+	let current = entryPath;
+	while (true) {
+		try {
+			return path.join(await fs.realpath(current), ...missing.reverse());
+		} catch (error) {
+			const code = (error as NodeJS.ErrnoException).code;
+			const parent = path.dirname(current);
+			if ((code !== "ENOENT" && code !== "ENOTDIR") || parent === current) {
+				throw error;
+			}
+			missing.push(path.basename(current));
+			current = parent;
+		}
+	}
+}
+
+/**
+ * Get the repositories registered in the "repositories" list of the config
+ * file
+ *
+ * The config file is read on every call, so newly registered repositories
+ * can be opened without restarting the server.
+ */
+export async function getRegisteredRepositories(): Promise<string[]> {
+	const { repositories } = await readConfig();
+	if (repositories === undefined) {
+		return [];
+	}
+	if (
+		!Array.isArray(repositories) ||
+		!repositories.every((repository) => typeof repository === "string")
+	) {
+		throw new ConfigError("repositories must be an array of folders");
+	}
+	return repositories.map(resolveDirectory);
+}
+
+/**
+ * Get the allowed roots and the registered repositories with their symlinks
+ * resolved
+ *
+ * Roots that don't exist are left out, as nothing can be inside them yet.
+ */
+export async function getAllowedRealRoots(): Promise<string[]> {
+	const allowedRoots = [
+		...getAllowedRoots(),
+		...(await getRegisteredRepositories()),
+	];
+	const roots = await Promise.all(
+		allowedRoots.map((root) => fs.realpath(root).catch(() => null)),
+	);
+	return [...new Set(roots.filter((root): root is string => root !== null))];
+}
+
+/**
+ * Resolve a requested path and check that it is inside an allowed root
+ *
+ * The check uses the real path, so symlinks and ".." can't escape the roots.
+ * The returned path is the normalized path as requested.
+ */
+export async function resolveAllowedPath(requested: string): Promise<string> {
+	const resolvedPath = path.resolve(requested);
+	const realPath = await realpathOfNearest(resolvedPath);
+	const roots = await getAllowedRealRoots();
+	if (!roots.some((root) => isInside(root, realPath))) {
+		throw new PathAccessError(realPath, roots);
+	}
+	return resolvedPath;
+}
+
+/**
+ * Check whether a path is inside an allowed root, without throwing
+ */
+export async function isPathAllowed(requested: string): Promise<boolean> {
+	try {
+		await resolveAllowedPath(requested);
+		return true;
+	} catch {
+		return false;
+	}
+}
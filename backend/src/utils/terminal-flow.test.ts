@@ -0,0 +1,117 @@
+import assert from "node:assert/strict";
+import { afterEach, beforeEach, describe, it, mock } from "node:test";
+import {
+	createOutputFlow,
+	type OutputFlow,
+	queueOutput,
+	stopOutputFlow,
+} from "./terminal-flow.js";
+
+describe("terminal output flow", () => {
+	let sent: string[];
+	let paused: boolean;
+	let pauseCalls: number;
+	let bufferedAmount: number;
+	let flow: OutputFlow;
+
+	const createFlow = () =>
+		createOutputFlow({
+			ptyShell: {
+				pause: () => {
+					paused = true;
+					pauseCalls++;
+				},
+				resume: () => {
+					paused = false;
+				},
+			},
+			send: (data) => sent.push(data),
+			getBufferedAmount: () => bufferedAmount,
+		});
+
+	beforeEach(() => {
+		mock.timers.enable({ apis: ["setTimeout", "setInterval", "Date"] });
+		// No rate limit unless a test sets one
+		process.env.CUWEB_TERMINAL_OUTPUT_RATE = "0";
+		sent = [];
+		paused = false;
+		pauseCalls = 0;
+		bufferedAmount = 0;
+		flow = createFlow();
+	});
+
+	afterEach(() => {
+		stopOutputFlow(flow);
+		mock.timers.reset();
+		delete process.env.CUWEB_TERMINAL_OUTPUT_RATE;
+	});
+
+	it("sends small writes as one batch", () => {
+		queueOutput(flow, "a");
+		queueOutput(flow, "b");
+		assert.deepEqual(sent, []);
+		mock.timers.tick(10);
+		assert.deepEqual(sent, ["ab"]);
+	});
+
+	it("sends a full batch right away", () => {
+		queueOutput(flow, "x".repeat(64 * 1024));
+		assert.equal(sent.length, 1);
+	});
+
+	it("pauses above the high water mark until below the low one", () => {
+		bufferedAmount = 2 * 1024 * 1024;
+		queueOutput(flow, "output");
+		mock.timers.tick(10);
+		assert.equal(paused, true);
+
+		// Between the water marks the terminal stays paused
+		bufferedAmount = 512 * 1024;
+		mock.timers.tick(50);
+		assert.equal(paused, true);
+
+		bufferedAmount = 100 * 1024;
+		mock.timers.tick(50);
+		assert.equal(paused, false);
+	});
+
+	it("pauses once the output rate is used up, until it is earned back", () => {
+		process.env.CUWEB_TERMINAL_OUTPUT_RATE = "1000";
+		flow = createFlow();
+		queueOutput(flow, "x".repeat(1500));
+		mock.timers.tick(10);
+		assert.equal(paused, true);
+
+		// 500 characters over the rate take half a second to earn back
+		mock.timers.tick(499);
+		assert.equal(paused, true);
+		mock.timers.tick(1);
+		assert.equal(paused, false);
+	});
+
+	it("resumes only once every reason to pause is gone", () => {
+		process.env.CUWEB_TERMINAL_OUTPUT_RATE = "1000";
+		flow = createFlow();
+		bufferedAmount = 2 * 1024 * 1024;
+		queueOutput(flow, "x".repeat(1100));
+		mock.timers.tick(10);
+		assert.equal(paused, true);
+		assert.equal(pauseCalls, 1);
+
+		// The rate is earned back while the client still lags behind
+		mock.timers.tick(100);
+		assert.equal(paused, true);
+
+		bufferedAmount = 0;
+		mock.timers.tick(50);
+		assert.equal(paused, false);
+	});
+
+	it("sends what is left when stopped", () => {
+		queueOutput(flow, "last words");
+		stopOutputFlow(flow);
+		assert.deepEqual(sent, ["last words"]);
+		mock.timers.tick(10);
+		assert.deepEqual(sent, ["last words"]);
+	});
+});
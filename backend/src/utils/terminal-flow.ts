@@ -0,0 +1,222 @@
+import type * as pty from "node-pty";
+import {
+	getTerminalOutputRate,
+	getTerminalPingInterval,
+} from "./constants.js";
+
+// Output is sent in batches, collected for at most this many milliseconds
+const OUTPUT_BATCH_INTERVAL = 10;
+
+// Batches are sent right away once they reach this many characters
+const MAX_OUTPUT_BATCH = 64 * 1024;
+
+// The terminal is paused while a client's socket buffers more than the high
+// water mark, and resumed once every client is below the low one (bytes)
+const HIGH_WATER_MARK = 1024 * 1024;
+const LOW_WATER_MARK = 256 * 1024;
+
+// Milliseconds between checks of the buffers of a paused terminal
+const DRAIN_CHECK_INTERVAL = 50;
+
+type PauseReason = "backpressure" | "rate";
+
+/**
+ * Where a session's output goes and how to slow its terminal down
+ */
+export interface OutputTarget {
+	ptyShell: Pick<pty.IPty, "pause" | "resume">;
+	// Deliver a batch of output to the session's clients
+	send: (data: string) => void;
+	// Largest amount of data buffered by one of the clients' sockets
+	getBufferedAmount: () => number;
+}
+
+/**
+ * Output of a session on its way to the clients
+ *
+ * Output is coalesced into batches. The terminal stops being read, so the
+ * program writing to it blocks, while a client can't keep up or the
+ * session sent more than its output rate allows.
+ */
+export interface OutputFlow {
+	target: OutputTarget;
+	pending: string[];
+	pendingLength: number;
+	flushTimer: NodeJS.Timeout | null;
+	// Characters the session may still send right away, refilled over time
+	// at the output rate
+	tokens: number;
+	refilledAt: number;
+	rateTimer: NodeJS.Timeout | null;
+	drainTimer: NodeJS.Timeout | null;
+	pauses: Set<PauseReason>;
+}
+
+export const createOutputFlow = (target: OutputTarget): OutputFlow => ({
+	target,
+	pending: [],
+	pendingLength: 0,
+	flushTimer: null,
+	tokens: getTerminalOutputRate(),
+	refilledAt: Date.now(),
+	rateTimer: null,
+	drainTimer: null,
+	pauses: new Set(),
+});
+
+const pause = (flow: OutputFlow, reason: PauseReason): void => {
+	if (flow.pauses.size === 0) {
+		flow.target.ptyShell.pause();
+	}
+	flow.pauses.add(reason);
+};
+
+const resume = (flow: OutputFlow, reason: PauseReason): void => {
+	if (!flow.pauses.delete(reason)) {
+		return;
+	}
+	if (flow.pauses.size === 0) {
+		flow.target.ptyShell.resume();
+	}
+};
+
+/**
+ * Pause the terminal once the session has used up its output rate, until
+ * it has been earned back
+ */
+const limitRate = (flow: OutputFlow, length: number): void => {
+	const rate = getTerminalOutputRate();
+	if (rate === 0) {
+		return;
+	}
+	const now = Date.now();
+	flow.tokens =
+		Math.min(rate, flow.tokens + ((now - flow.refilledAt) / 1000) * rate) -
+		length;
+	flow.refilledAt = now;
+	if (flow.tokens >= 0 || flow.rateTimer) {
+		return;
+	}
+
+	pause(flow, "rate");
+	flow.rateTimer = setTimeout(
+		() => {
+			flow.rateTimer = null;
+			resume(flow, "rate");
+		},
+		(-flow.tokens / rate) * 1000,
+	);
+};
+
+/**
+ * Pause the terminal while a client's socket buffers too much, until every
+ * client has caught up
+ */
+const checkBackpressure = (flow: OutputFlow): void => {
+	if (flow.drainTimer || flow.target.getBufferedAmount() < HIGH_WATER_MARK) {
+		return;
+	}
+
+	pause(flow, "backpressure");
+	flow.drainTimer = setInterval(() => {
+		if (flow.target.getBufferedAmount() > LOW_WATER_MARK) {
+			return;
+		}
+		if (flow.drainTimer) {
+			clearInterval(flow.drainTimer);
+			flow.drainTimer = null;
+		}
+		resume(flow, "backpressure");
+	}, DRAIN_CHECK_INTERVAL);
+};
+
+/**
+ * Send the pending output as one batch
+ */
+export const flushOutput = (flow: OutputFlow): void => {
+	if (flow.flushTimer) {
+		clearTimeout(flow.flushTimer);
+		flow.flushTimer = null;
+	}
+	if (flow.pendingLength === 0) {
+		return;
+	}
+
+	const data = flow.pending.join("");
+	flow.pending = [];
+	flow.pendingLength = 0;
+	flow.target.send(data);
+	limitRate(flow, data.length);
+	checkBackpressure(flow);
+};
+
+/**
+ * Add output of the terminal to the next batch
+ */
+export const queueOutput = (flow: OutputFlow, data: string): void => {
+	flow.pending.push(data);
+	flow.pendingLength += data.length;
+	if (flow.pendingLength >= MAX_OUTPUT_BATCH) {
+		flushOutput(flow);
+	} else if (!flow.flushTimer) {
+		flow.flushTimer = setTimeout(() => {
+			flow.flushTimer = null;
+			flushOutput(flow);
+		}, OUTPUT_BATCH_INTERVAL);
+	}
+};
+
+/**
+ * Send what is left and stop the timers, e.g. once the terminal exited
+ */
+export const stopOutputFlow = (flow: OutputFlow): void => {
+	flushOutput(flow);
+	if (flow.rateTimer) {
+		clearTimeout(flow.rateTimer);
+		flow.rateTimer = null;
+	}
+	if (flow.drainTimer) {
+		clearInterval(flow.drainTimer);
+		flow.drainTimer = null;
+	}
+	flow.pauses.clear();
+};
+
+/**
+ * Parts of the ws library's WebSocket missing from the DOM interface
+ */
+interface PingableWebSocket {
+	ping: () => void;
+	terminate: () => void;
+	on: (event: "pong", listener: () => void) => void;
+}
+
+/**
+ * Ping a client regularly and drop it if a ping goes unanswered
+ *
+ * Browsers answer pings on their own, so a missing pong means the client
+ * is gone or stuck, e.g. can't keep up with the output. Dropping it frees
+ * the terminal it holds paused. Returns a function stopping the pings.
+ */
+export const watchConnection = (
+	ws: WebSocket,
+	onDead: () => void,
+): (() => void) => {
+	const socket = ws as unknown as PingableWebSocket;
+	let isAlive = true;
+	socket.on("pong", () => {
+		isAlive = true;
+	});
+
+	const timer = setInterval(() => {
+		if (!isAlive) {
+			clearInterval(timer);
+			onDead();
+			socket.terminate();
+			return;
+		}
+		isAlive = false;
+		socket.ping();
+	}, getTerminalPingInterval());
+	return () => clearInterval(timer);
+};
@@ -0,0 +1,110 @@
+import assert from "node:assert/strict";
+import { mkdirSync, mkdtempSync, rmSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import * as path from "node:path";
+import { after, before, describe, it } from "node:test";
+import {
+	resolveShellOptions,
+	TerminalOptionsError,
+} from "./terminal-options.js";
+
+describe("resolveShellOptions", () => {
+	const ENV_KEYS = [
+		"CUWEB_CONFIG",
+		"CUWEB_WORKING_DIR",
+		"CUWEB_WORKTREES_DIR",
+		"CUWEB_ALLOWED_ROOTS",
+		"CUWEB_TERMINAL_SHELL",
+		"CUWEB_TERMINAL_SHELL_ARGS",
+		"CUWEB_TERMINAL_ENV",
+		"CUWEB_TERMINAL_SIZE",
+	];
+	const savedEnv: Record<string, string | undefined> = {};
+	let root: string;
+	let work: string;
+
+	before(() => {
+		for (const key of ENV_KEYS) {
+			savedEnv[key] = process.env[key];
+			delete process.env[key];
+		}
+		root = mkdtempSync(path.join(tmpdir(), "cuweb-terminal-options-"));
+		work = path.join(root, "work");
+		mkdirSync(work);
+
+		process.env.CUWEB_WORKING_DIR = work;
+		process.env.CUWEB_WORKTREES_DIR = path.join(root, "worktrees");
+		process.env.CUWEB_CONFIG = path.join(root, "config.json");
+		writeFileSync(
+			process.env.CUWEB_CONFIG,
+			JSON.stringify({
+				terminal: {
+					shell: "/bin/sh",
+					args: ["-i"],
+					env: { GREETING: "hello" },
+					allowedShells: ["/usr/local/bin/fish"],
+					allowedEnv: ["LANG"],
+				},
+			}),
+		);
+	});
+
+	after(() => {
+		for (const key of ENV_KEYS) {
+			if (savedEnv[key] === undefined) {
+				delete process.env[key];
+			} else {
+				process.env[key] = savedEnv[key];
+			}
+		}
+		rmSync(root, { recursive: true, force: true });
+	});
+
+	it("starts with the defaults from the config file", async () => {
+		const options = await resolveShellOptions();
+		assert.equal(options.shell, "/bin/sh");
+		assert.deepEqual(options.args, ["-i"]);
+		assert.equal(options.cwd, work);
+		assert.deepEqual(options.env, { GREETING: "hello" });
+	});
+
+	it("applies the shell and variables a session may ask for", async () => {
+		const options = await resolveShellOptions({
+			shell: "/usr/local/bin/fish",
+			env: ["LANG=C.UTF-8"],
+		});
+		assert.equal(options.shell, "/usr/local/bin/fish");
+		assert.deepEqual(options.env, { GREETING: "hello", LANG: "C.UTF-8" });
+	});
+
+	it("rejects a shell that isn't allowed", async () => {
+		await assert.rejects(
+			resolveShellOptions({ shell: "/tmp/evil" }),
+			(error: unknown) =>
+				error instanceof TerminalOptionsError &&
+				error.message === "Shell not allowed: /tmp/evil",
+		);
+	});
+
+	it("rejects environment variables that aren't allowed", async () => {
+		for (const entry of ["LD_PRELOAD=/tmp/evil.so", "PATH=/tmp"]) {
+			await assert.rejects(
+				resolveShellOptions({ env: [entry] }),
+				(error: unknown) =>
+					error instanceof TerminalOptionsError &&
+					error.message.startsWith("Environment variable not allowed"),
+			);
+		}
+		await assert.rejects(
+			resolveShellOptions({ env: ["LANG"] }),
+			TerminalOptionsError,
+		);
+	});
+
+	it("rejects a folder outside the allowed roots", async () => {
+		await assert.rejects(
+			resolveShellOptions({ folder: root }),
+			/outside the allowed folders/,
+		);
+	});
+});
@@ -0,0 +1,246 @@
+import { promises as fs } from "node:fs";
+import process from "node:process";
+import { ConfigError, readConfig } from "./config.js";
+import {
+	getDefaultWorkingDir,
+	getTerminalEnv,
+	getTerminalShell,
+	getTerminalShellArgs,
+	getTerminalSize,
+} from "./constants.js";
+import { resolveAllowedPath } from "./path-access.js";
+import { isTerminalSize, MAX_TERMINAL_SIZE } from "./terminal-protocol.js";
+
+/**
+ * How a plain terminal starts
+ *
+ * Without a size the terminal starts at the default one, clients resize it
+ * once attached.
+ */
+export interface ShellOptions {
+	shell: string;
+	args: string[];
+	cwd: string;
+	env: Record<string, string>;
+	cols?: number;
+	rows?: number;
+}
+
+/**
+ * Options a session asks for in its query string, all optional
+ */
+export interface ShellRequest {
+	shell?: string;
+	args?: string[];
+	folder?: string;
+	env?: string[]; // "NAME=value" entries
+	cols?: string;
+	rows?: string;
+}
+
+/**
+ * The "terminal" section of the config file
+ *
+ * The allowed* lists are what sessions may ask for besides the defaults.
+ */
+interface TerminalConfig {
+	shell?: string;
+	args?: string[];
+	env?: Record<string, string>;
+	cols?: number;
+	rows?: number;
+	allowedShells?: string[];
+	allowedArgs?: string[];
+	allowedEnv?: string[];
+}
+
+/**
+ * Error raised for options a session isn't allowed to ask for
+ */
+export class TerminalOptionsError extends Error {}
+
+// Shell arguments and variables sessions may set unless the config file
+// lists others
+const DEFAULT_ALLOWED_ARGS = ["-l", "--login", "-i"];
+const DEFAULT_ALLOWED_ENV = [
+	"LANG",
+	"LC_ALL",
+	"TZ",
+	"EDITOR",
+	"VISUAL",
+	"PAGER",
+];
+
+const ENV_NAME_PATTERN = /^[A-Za-z_][A-Za-z0-9_]*$/;
+
+// Login shells installed on the system, always allowed
+const SHELLS_FILE = "/etc/shells";
+
+/**
+ * Get the shell used when neither the user nor the config picks one
+ */
+export const getOSShell = (): string => {
+	return process.platform === "win32" ? "powershell.exe" : "bash";
+};
+
+/**
+ * Get the arguments of the default shell
+ */
+export const getDefaultShellArgs = (): string[] => {
+	// Use login shell to load user's profile (.bash_profile, .bashrc, etc.)
+	return process.platform === "win32" ? [] : ["-l"];
+};
+
+const isStringArray = (value: unknown): value is string[] =>
+	Array.isArray(value) && value.every((item) => typeof item === "string");
+
+const isEnv = (value: unknown): value is Record<string, string> =>
+	typeof value === "object" &&
+	value !== null &&
+	!Array.isArray(value) &&
+	Object.entries(value).every(
+		([name, item]) => ENV_NAME_PATTERN.test(name) && typeof item === "string",
+	);
+
+/**
+ * Check the "terminal" section of the config file
+ */
+const parseTerminalConfig = (value: unknown): TerminalConfig => {
+	if (value === undefined) {
+		return {};
+	}
+	if (typeof value !== "object" || value === null || Array.isArray(value)) {
+		throw new ConfigError("terminal must be an object");
+	}
+
+	const config = value as Record<string, unknown>;
+	if (config.shell !== undefined && typeof config.shell !== "string") {
+		throw new ConfigError("terminal.shell must be a string");
+	}
+	for (const key of ["args", "allowedShells", "allowedArgs", "allowedEnv"]) {
+		if (config[key] !== undefined && !isStringArray(config[key])) {
+			throw new ConfigError(`terminal.${key} must be an array of strings`);
+		}
+	}
+	if (config.env !== undefined && !isEnv(config.env)) {
+		throw new ConfigError("terminal.env must map variable names to strings");
+	}
+	for (const key of ["cols", "rows"]) {
+		if (config[key] !== undefined && !isTerminalSize(config[key])) {
+			throw new ConfigError(
+				`terminal.${key} must be a whole number from 1 to ${MAX_TERMINAL_SIZE}`,
+			);
+		}
+	}
+	return config as TerminalConfig;
+};
+
+const readSystemShells = async (): Promise<string[]> => {
+	try {
+		const content = await fs.readFile(SHELLS_FILE, "utf-8");
+		return content
+			.split("\n")
+			.map((line) => line.trim())
+			.filter((line) => line.startsWith("/"));
+	} catch {
+		return [];
+	}
+};
+
+const parseSize = (
+	value: string | undefined,
+	name: string,
+): number | undefined => {
+	if (value === undefined) {
+		return undefined;
+	}
+	const size = Number(value);
+	if (!isTerminalSize(size)) {
+		throw new TerminalOptionsError(
+			`${name} must be a whole number from 1 to ${MAX_TERMINAL_SIZE}`,
+		);
+	}
+	return size;
+};
+
+/**
+ * Work out how a plain terminal starts
+ *
+ * The defaults come from the config file, overridden by the CLI flags: the
+ * shell falls back to $SHELL and the folder to the working directory. What
+ * a session asks for replaces them if it's allowed: shells listed in the
+ * config or /etc/shells, arguments and variables from the config's lists,
+ * and folders inside the allowed roots.
+ */
+export const resolveShellOptions = async (
+	request: ShellRequest = {},
+): Promise<ShellOptions> => {
+	const config = parseTerminalConfig((await readConfig()).terminal);
+
+	const defaultShell =
+		getTerminalShell() || config.shell || process.env.SHELL || getOSShell();
+	let shell = defaultShell;
+	if (request.shell && request.shell !== defaultShell) {
+		const allowedShells = [
+			...(config.allowedShells || []),
+			...(await readSystemShells()),
+		];
+		if (!allowedShells.includes(request.shell)) {
+			throw new TerminalOptionsError(`Shell not allowed: ${request.shell}`);
+		}
+		shell = request.shell;
+	}
+
+	let args = getTerminalShellArgs() ?? config.args ?? getDefaultShellArgs();
+	if (request.args) {
+		const allowedArgs = config.allowedArgs || DEFAULT_ALLOWED_ARGS;
+		const denied = request.args.find((arg) => !allowedArgs.includes(arg));
+		if (denied !== undefined) {
+			throw new TerminalOptionsError(`Shell argument not allowed: ${denied}`);
+		}
+		args = request.args;
+	}
+
+	const env = { ...config.env, ...getTerminalEnv() };
+	const allowedEnv = config.allowedEnv || DEFAULT_ALLOWED_ENV;
+	for (const entry of request.env || []) {
+		const separator = entry.indexOf("=");
+		if (separator === -1) {
+			throw new TerminalOptionsError(
+				`Environment variables must be NAME=value: ${entry}`,
+			);
+		}
+		const name = entry.slice(0, separator);
+		if (!ENV_NAME_PATTERN.test(name) || !allowedEnv.includes(name)) {
+			throw new TerminalOptionsError(
+				`Environment variable not allowed: ${name}`,
+			);
+		}
+		env[name] = entry.slice(separator + 1);
+	}
+
+	// Only start in folders the file browser may access
+	const cwd = request.folder
+		? await resolveAllowedPath(request.folder)
+		: getDefaultWorkingDir();
+	const stats = await fs.stat(cwd).catch(() => null);
+	if (!stats?.isDirectory()) {
+		throw new TerminalOptionsError(`Not a folder: ${cwd}`);
+	}
+
+	const size = getTerminalSize();
+	const isSizeValid =
+		!!size && isTerminalSize(size.cols) && isTerminalSize(size.rows);
+	return {
+		shell,
+		args,
+		cwd,
+		env,
+		cols:
+			parseSize(request.cols, "cols") ??
+			(isSizeValid ? size.cols : config.cols),
+		rows:
+			parseSize(request.rows, "rows") ??
+			(isSizeValid ? size.rows : config.rows),
+	};
+};
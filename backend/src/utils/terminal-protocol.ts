@@ -0,0 +1,174 @@
+import { constants } from "node:os";
+import {
+	type TerminalParticipant,
+	type TerminalSignalRequest,
+	TerminalSignalRequestSchema,
+} from "../models/terminal.js";
+
+/**
+ * WebSocket subprotocol of the terminals, the version changes with any
+ * incompatible change to the messages below
+ *
+ * Terminal data travels in binary frames: input from the client and output
+ * to it, as UTF-8. Text frames carry the JSON messages below.
+ */
+export const TERMINAL_PROTOCOL = "cuweb.terminal.v1";
+
+// Largest terminal size a client may ask for, in columns and rows
+export const MAX_TERMINAL_SIZE = 1000;
+
+export type TerminalControlAction = "request" | "grant" | "deny" | "take";
+
+const CONTROL_ACTIONS: TerminalControlAction[] = [
+	"request",
+	"grant",
+	"deny",
+	"take",
+];
+
+export type TerminalClientMessage =
+	| { type: "resize"; cols: number; rows: number }
+	| { type: "signal"; signal: TerminalSignalRequest["signal"] }
+	| { type: "ping"; id?: string | number }
+	| { type: "control"; action: TerminalControlAction; clientId?: string }
+	| { type: "rename"; name: string };
+
+export type TerminalServerMessage =
+	| {
+			type: "presence";
+			you: string;
+			token: string;
+			participants: TerminalParticipant[];
+			requests: string[];
+	  }
+	| { type: "exit"; code: number; signal: string | null }
+	| { type: "pong"; id?: string | number }
+	| { type: "error"; error: string };
+
+export type TerminalExitMessage = Extract<
+	TerminalServerMessage,
+	{ type: "exit" }
+>;
+
+/**
+ * Error raised for a message that doesn't follow the protocol
+ */
+export class TerminalProtocolError extends Error {}
+
+export const isTerminalSize = (value: unknown): value is number =>
+	Number.isInteger(value) &&
+	(value as number) >= 1 &&
+	(value as number) <= MAX_TERMINAL_SIZE;
+
+/**
+ * Parse and check a JSON message from a client
+ */
+export const parseClientMessage = (data: string): TerminalClientMessage => {
+	let message: Record<string, unknown>;
+	try {
+		message = JSON.parse(data);
+	} catch {
+		throw new TerminalProtocolError("Messages must be JSON");
+	}
+	if (typeof message !== "object" || message === null) {
+		throw new TerminalProtocolError("Messages must be JSON objects");
+	}
+
+	switch (message.type) {
+		case "resize":
+			if (!isTerminalSize(message.cols) || !isTerminalSize(message.rows)) {
+				throw new TerminalProtocolError(
+					`cols and rows must be whole numbers from 1 to ${MAX_TERMINAL_SIZE}`,
+				);
+			}
+			return { type: "resize", cols: message.cols, rows: message.rows };
+		case "signal": {
+			const signal = TerminalSignalRequestSchema.shape.signal.safeParse(
+				message.signal,
+			);
+			if (!signal.success) {
+				throw new TerminalProtocolError(
+					`Unsupported signal: ${String(message.signal)}`,
+				);
+			}
+			return { type: "signal", signal: signal.data };
+		}
+		case "ping":
+			if (
+				message.id !== undefined &&
+				typeof message.id !== "string" &&
+				typeof message.id !== "number"
+			) {
+				throw new TerminalProtocolError("id must be a string or a number");
+			}
+			return { type: "ping", id: message.id };
+		case "control":
+			if (
+				!CONTROL_ACTIONS.includes(message.action as TerminalControlAction)
+			) {
+				throw new TerminalProtocolError(
+					`Unknown control action: ${String(message.action)}`,
+				);
+			}
+			return {
+				type: "control",
+				action: message.action as TerminalControlAction,
+				clientId:
+					typeof message.clientId === "string" ? message.clientId : undefined,
+			};
+		case "rename":
+			if (typeof message.name !== "string") {
+				throw new TerminalProtocolError("name must be a string");
+			}
+			return { type: "rename", name: message.name };
+		default:
+			throw new TerminalProtocolError(
+				`Unknown message type: ${String(message.type)}`,
+			);
+	}
+};
+
+/**
+ * Decode terminal input from a binary frame
+ */
+export const decodeInput = (data: unknown): string => {
+	if (Buffer.isBuffer(data)) {
+		return data.toString("utf-8");
+	}
+	if (Array.isArray(data)) {
+		// Fragmented message
+		return Buffer.concat(data).toString("utf-8");
+	}
+	return Buffer.from(data as ArrayBuffer).toString("utf-8");
+};
+
+export const sendMessage = (
+	ws: WebSocket,
+	message: TerminalServerMessage,
+): void => {
+	if (ws.readyState === ws.OPEN) {
+		ws.send(JSON.stringify(message));
+	}
+};
+
+/**
+ * Send terminal output to a client, in a binary frame
+ */
+export const sendOutput = (ws: WebSocket, data: string | Buffer): void => {
+	if (ws.readyState === ws.OPEN) {
+		ws.send(typeof data === "string" ? Buffer.from(data, "utf-8") : data);
+	}
+};
+
+/**
+ * Get the name of a signal number, e.g. "SIGTERM" for 15
+ */
+export const getSignalName = (signal: number | undefined): string | null => {
+	if (!signal) {
+		return null;
+	}
+	const entry = Object.entries(constants.signals).find(
+		([, number]) => number === signal,
+	);
+	return entry ? entry[0] : `SIG${signal}`;
+};
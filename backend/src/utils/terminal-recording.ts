@@ -0,0 +1,245 @@
+import { createWriteStream, promises as fs, type WriteStream } from "node:fs";
+import * as path from "node:path";
+import type { TerminalRecording } from "../models/terminal.js";
+import { getRecordingsDir } from "./constants.js";
+
+/**
+ * Error raised for recordings that can't be found, read or deleted
+ */
+export class TerminalRecordingError extends Error {}
+
+// Recording IDs are file names without the extension, so they can't
+// point outside the recordings folder
+const RECORDING_ID_PATTERN = /^[\w-]{1,128}$/;
+const CAST_EXTENSION = ".cast";
+
+// Bytes read from the end of a recording to find its last event
+const TAIL_SIZE = 64 * 1024;
+
+/**
+ * Header of an asciicast v2 file, its first line
+ *
+ * See https://docs.asciinema.org/manual/asciicast/v2/
+ */
+interface CastHeader {
+	version: 2;
+	width: number;
+	height: number;
+	timestamp: number;
+	title?: string;
+	env?: Record<string, string>;
+}
+
+/**
+ * Recording being written for a terminal session
+ */
+export interface TerminalRecorder {
+	id: string;
+	stream: WriteStream;
+	// Event times are seconds since this time
+	startedAt: number;
+}
+
+const activeRecorders = new Map<string, TerminalRecorder>();
+
+/**
+ * Get the file of a recording from its ID
+ */
+export function getRecordingPath(id: string): string {
+	if (!RECORDING_ID_PATTERN.test(id)) {
+		throw new TerminalRecordingError(`Invalid recording ID: ${id}`);
+	}
+	return path.join(getRecordingsDir(), `${id}${CAST_EXTENSION}`);
+}
+
+/**
+ * Start recording to a new asciicast file
+ *
+ * The label ends up in the recording ID, e.g. the environment ID.
+ */
+export async function startRecorder(
+	label: string,
+	header: Omit<CastHeader, "version" | "timestamp">,
+): Promise<TerminalRecorder> {
+	const startedAt = Date.now();
+	const stamp = new Date(startedAt)
+		.toISOString()
+		.replace(/[-:]/g, "")
+		.replace("T", "-")
+		.slice(0, 15);
+	const safeLabel = label.replace(/[^\w-]/g, "_").slice(0, 64);
+	const suffix = Math.random().toString(36).slice(2, 8);
+	const id = `${stamp}-${safeLabel}-${suffix}`;
+
+	await fs.mkdir(getRecordingsDir(), { recursive: true });
+	const stream = createWriteStream(getRecordingPath(id), { flags: "wx" });
+	await new Promise<void>((resolve, reject) => {
+		stream.once("open", () => resolve());
+		stream.once("error", reject);
+	});
+	const castHeader: CastHeader = {
+		version: 2,
+		timestamp: Math.floor(startedAt / 1000),
+		...header,
+	};
+	stream.write(`${JSON.stringify(castHeader)}\n`);
+
+	const recorder = { id, stream, startedAt };
+	activeRecorders.set(id, recorder);
+	return recorder;
+}
+
+function writeEvent(
+	recorder: TerminalRecorder,
+	code: "o" | "r",
+	data: string,
+): void {
+	const elapsed = (Date.now() - recorder.startedAt) / 1000;
+	recorder.stream.write(
+		`${JSON.stringify([Number(elapsed.toFixed(6)), code, data])}\n`,
+	);
+}
+
+/**
+ * Record output of the terminal
+ */
+export function recordOutput(recorder: TerminalRecorder, data: string): void {
+	writeEvent(recorder, "o", data);
+}
+
+/**
+ * Record a change of the terminal size
+ */
+export function recordResize(
+	recorder: TerminalRecorder,
+	cols: number,
+	rows: number,
+): void {
+	writeEvent(recorder, "r", `${cols}x${rows}`);
+}
+
+/**
+ * Finish a recording, resolving once it is written to disk
+ */
+export function stopRecorder(recorder: TerminalRecorder): Promise<void> {
+	activeRecorders.delete(recorder.id);
+	return new Promise((resolve, reject) => {
+		recorder.stream.end((error?: Error | null) =>
+			error ? reject(error) : resolve(),
+		);
+	});
+}
+
+/**
+ * Read the first line of a recording
+ */
+async function readHeader(handle: fs.FileHandle): Promise<CastHeader> {
+	const buffer = Buffer.alloc(4096);
+	const { bytesRead } = await handle.read(buffer, 0, buffer.length, 0);
+	const firstLine = buffer.subarray(0, bytesRead).toString("utf-8");
+	const header = JSON.parse(firstLine.split("\n")[0]);
+	if (header.version !== 2) {
+		throw new TerminalRecordingError("Not an asciicast v2 recording");
+	}
+	return header;
+}
+
+/**
+ * Get the time of the last event, which is the length of the recording
+ */
+async function readDuration(
+	handle: fs.FileHandle,
+	size: number,
+): Promise<number> {
+	const length = Math.min(size, TAIL_SIZE);
+	const buffer = Buffer.alloc(length);
+	await handle.read(buffer, 0, length, size - length);
+	const lines = buffer.toString("utf-8").trimEnd().split("\n");
+
+	// The last line may be cut off while the recording is being written
+	for (let i = lines.length - 1; i >= 0; i--) {
+		try {
+			const event = JSON.parse(lines[i]);
+			if (Array.isArray(event) && typeof event[0] === "number") {
+				return event[0];
+			}
+		} catch {
+			// Partial line, try the one before
+		}
+	}
+	return 0;
+}
+
+/**
+ * Describe a recording from its file
+ */
+export async function getRecordingInfo(
+	id: string,
+): Promise<TerminalRecording> {
+	const filePath = getRecordingPath(id);
+	const handle = await fs.open(filePath, "r");
+	try {
+		const { size } = await handle.stat();
+		const header = await readHeader(handle);
+		const active = activeRecorders.get(id);
+		return {
+			id,
+			title: header.title,
+			width: header.width,
+			height: header.height,
+			startedAt: new Date(header.timestamp * 1000).toISOString(),
+			duration: active
+				? (Date.now() - active.startedAt) / 1000
+				: await readDuration(handle, size),
+			size,
+			active: !!active,
+		};
+	} finally {
+		await handle.close();
+	}
+}
+
+/**
+ * List the recordings, newest first
+ */
+export async function listRecordings(): Promise<TerminalRecording[]> {
+	let fileNames: string[];
+	try {
+		fileNames = await fs.readdir(getRecordingsDir());
+	} catch (error) {
+		if ((error as NodeJS.ErrnoException).code === "ENOENT") {
+			return [];
+		}
+		throw error;
+	}
+
+	const recordings: TerminalRecording[] = [];
+	for (const fileName of fileNames) {
+		const id = fileName.slice(0, -CAST_EXTENSION.length);
+		if (
+			!fileName.endsWith(CAST_EXTENSION) ||
+			!RECORDING_ID_PATTERN.test(id)
+		) {
+			continue;
+		}
+		try {
+			recordings.push(await getRecordingInfo(id));
+		} catch (error) {
+			// Skip files that aren't readable recordings
+			console.warn(`Skipping recording ${fileName}:`, error);
+		}
+	}
+	return recordings.sort((a, b) => b.startedAt.localeCompare(a.startedAt));
+}
+
+/**
+ * Delete a recording that is no longer being written
+ */
+export async function deleteRecording(id: string): Promise<void> {
+	if (activeRecorders.has(id)) {
+		throw new TerminalRecordingError(
+			"The recording is still running, stop it before deleting it",
+		);
+	}
+	await fs.unlink(getRecordingPath(id));
+}
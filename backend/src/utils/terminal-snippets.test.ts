@@ -0,0 +1,67 @@
+import assert from "node:assert/strict";
+import { execFileSync } from "node:child_process";
+import { describe, it } from "node:test";
+import type { TerminalSnippet } from "../models/terminal.js";
+import { renderSnippet, TerminalSnippetError } from "./terminal-snippets.js";
+
+const snippet = (command: string): TerminalSnippet => ({
+	name: "test",
+	command,
+	source: "global",
+	variables: [],
+});
+
+// Arguments a POSIX shell splits a command line into
+const shellArguments = (commandLine: string): string[] =>
+	execFileSync("sh", ["-c", `printf '%s\\0' ${commandLine}`], {
+		encoding: "utf-8",
+	})
+		.split("\0")
+		.slice(0, -1);
+
+describe("renderSnippet", () => {
+	it("leaves plain values unquoted", () => {
+		assert.equal(
+			renderSnippet(snippet("container-use log {{environmentId}}"), {
+				environmentId: "sharing-loon",
+			}),
+			"container-use log sharing-loon",
+		);
+	});
+
+	it("keeps values with shell syntax as one literal argument", () => {
+		const values = [
+			"it's",
+			"$(touch /tmp/cuweb-pwned)",
+			"`id`",
+			"a; rm -rf ~",
+			"first line\nsecond line",
+			"'\n'$HOME'",
+			"",
+		];
+		for (const value of values) {
+			const rendered = renderSnippet(snippet("{{value}} {{value}}"), {
+				value,
+			});
+			assert.deepEqual(shellArguments(rendered), [value, value]);
+		}
+	});
+
+	it("fills in every occurrence, with spaces inside the braces", () => {
+		assert.equal(
+			renderSnippet(snippet("cd {{ folder }} && ls {{folder}}"), {
+				folder: "/home/me/my project",
+			}),
+			"cd '/home/me/my project' && ls '/home/me/my project'",
+		);
+	});
+
+	it("rejects missing values", () => {
+		assert.throws(
+			() => renderSnippet(snippet("echo {{a}} {{b}}"), { a: "1" }),
+			(error: unknown) =>
+				error instanceof TerminalSnippetError &&
+				error.message === "Missing values for b in snippet test",
+		);
+	});
+});
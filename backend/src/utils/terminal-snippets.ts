@@ -0,0 +1,127 @@
+import * as path from "node:path";
+import type { TerminalSnippet } from "../models/terminal.js";
+import { ConfigError, readConfig } from "./config.js";
+import { getConfigFile } from "./constants.js";
+import { getRepositoryRoot } from "./git-status.js";
+
+// Config file in the root of a repository, with the repository's snippets
+export const REPOSITORY_CONFIG_FILE = ".cuweb.json";
+
+// Variables filled in from the terminal, the others are asked for
+export const BUILTIN_VARIABLES = ["environmentId", "folder"];
+
+// {{name}} placeholders of the command templates
+const VARIABLE_PATTERN = /\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}/g;
+
+// Values made only of these characters are inserted without quotes
+const SAFE_VALUE_PATTERN = /^[\w./:=@%+,-]+$/;
+
+/**
+ * Error raised when a snippet can't be run, e.g. a variable has no value
+ */
+export class TerminalSnippetError extends Error {}
+
+const getVariableNames = (command: string): string[] => [
+	...new Set(
+		Array.from(command.matchAll(VARIABLE_PATTERN), (match) => match[1]),
+	),
+];
+
+/**
+ * Check the "snippets" section of a config file
+ *
+ * Snippets map a name to a command template, or to an object with the
+ * command and a description.
+ */
+const parseSnippets = (
+	value: unknown,
+	source: TerminalSnippet["source"],
+	file: string,
+): TerminalSnippet[] => {
+	if (value === undefined) {
+		return [];
+	}
+	if (typeof value !== "object" || value === null || Array.isArray(value)) {
+		throw new ConfigError(`${file}: snippets must be an object`);
+	}
+
+	return Object.entries(value).map(([name, snippet]) => {
+		const entry = (
+			typeof snippet === "string" ? { command: snippet } : snippet
+		) as { command?: unknown; description?: unknown } | null;
+		const command = entry?.command;
+		const description = entry?.description;
+		if (typeof command !== "string" || !command.trim()) {
+			throw new ConfigError(`${file}: snippets.${name} needs a command`);
+		}
+		if (description !== undefined && typeof description !== "string") {
+			throw new ConfigError(
+				`${file}: snippets.${name}.description must be a string`,
+			);
+		}
+		return {
+			name,
+			command,
+			description,
+			source,
+			variables: getVariableNames(command).filter(
+				(variable) => !BUILTIN_VARIABLES.includes(variable),
+			),
+		};
+	});
+};
+
+/**
+ * List the snippets of a folder's repository and the global ones, by name
+ *
+ * A repository snippet replaces a global one with the same name.
+ */
+export const listSnippets = async (
+	folder: string,
+): Promise<TerminalSnippet[]> => {
+	// Outside a repository, or in a missing folder, the folder is the root
+	const root = await getRepositoryRoot(folder).catch(() => folder);
+	const repositoryFile = path.join(root, REPOSITORY_CONFIG_FILE);
+	const [globalConfig, repositoryConfig] = await Promise.all([
+		readConfig(),
+		readConfig(repositoryFile),
+	]);
+
+	const snippets = new Map<string, TerminalSnippet>();
+	for (const snippet of [
+		...parseSnippets(globalConfig.snippets, "global", getConfigFile()),
+		...parseSnippets(repositoryConfig.snippets, "repository", repositoryFile),
+	]) {
+		snippets.set(snippet.name, snippet);
+	}
+	return [...snippets.values()].sort((a, b) => a.name.localeCompare(b.name));
+};
+
+/**
+ * Quote a value for a POSIX shell, unless it doesn't need it
+ */
+const quoteShellValue = (value: string): string =>
+	SAFE_VALUE_PATTERN.test(value) ? value : `'${value.replace(/'/g, "'\\''")}'`;
+
+/**
+ * Fill in the variables of a snippet's command
+ *
+ * Values are quoted for the shell, so a folder with spaces stays one
+ * argument.
+ */
+export const renderSnippet = (
+	snippet: TerminalSnippet,
+	values: Record<string, string | undefined>,
+): string => {
+	const missing = getVariableNames(snippet.command).filter(
+		(name) => values[name] === undefined,
+	);
+	if (missing.length > 0) {
+		throw new TerminalSnippetError(
+			`Missing values for ${missing.join(", ")} in snippet ${snippet.name}`,
+		);
+	}
+	return snippet.command.replace(VARIABLE_PATTERN, (_match, name: string) =>
+		quoteShellValue(values[name] as string),
+	);
+};
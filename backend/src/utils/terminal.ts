@@ -1,26 +1,161 @@
-import { readFile } from "node:fs/promises";
+import { randomUUID } from "node:crypto";
 import { homedir } from "node:os";
 import process from "node:process";
-import chokidar, { type FSWatcher } from "chokidar";
 import * as pty from "node-pty";
-import { CLI_COMMANDS, type CLICommand } from "./constants.js";
+import type {
+	TerminalParticipant,
+	TerminalSession as TerminalSessionInfo,
+	TerminalRecording,
+	TerminalSignalRequest,
+} from "../models/terminal.js";
+import { executeGenericCommand } from "./cli-executor.js";
+import {
+	CLI_COMMANDS,
+	type CLICommand,
+	ENVIRONMENT_ID_PATTERN,
+	getDefaultCLIPath,
+	getTerminalIdleTimeout,
+	getTerminalLoginShell,
+	getTerminalScrollback,
+} from "./constants.js";
+import {
+	createOutputFlow,
+	type OutputFlow,
+	queueOutput,
+	stopOutputFlow,
+	watchConnection,
+} from "./terminal-flow.js";
+import {
+	getDefaultShellArgs,
+	getOSShell,
+	type ShellOptions,
+} from "./terminal-options.js";
+import {
+	decodeInput,
+	getSignalName,
+	parseClientMessage,
+	sendMessage,
+	sendOutput,
+	TERMINAL_PROTOCOL,
+	type TerminalClientMessage,
+	type TerminalExitMessage,
+} from "./terminal-protocol.js";
+import {
+	getRecordingInfo,
+	recordOutput,
+	recordResize,
+	startRecorder,
+	stopRecorder,
+	type TerminalRecorder,
+} from "./terminal-recording.js";
 
-interface TerminalOptions {
+export interface TerminalOptions {
 	command?: CLICommand;
 	environmentId?: string;
 	workingDir?: string;
-	cliPath?: string;
 	filePath?: string; // For file watching
+	shell?: ShellOptions; // Shell, environment and size of plain terminals
+	sessionId?: string; // Session to reattach to, or the ID of a new one
+	clientName?: string; // Shown to the other clients of a shared session
 }
 
-const getOSShell = (): string => {
-	return process.platform === "win32" ? "powershell.exe" : "bash";
-};
+export type TerminalSessionKind = TerminalSessionInfo["kind"];
 
-const getShellArgs = (): string[] => {
-	// Use login shell to load user's profile (.bash_profile, .bashrc, etc.)
-	return process.platform === "win32" ? [] : ["-l"];
-};
+export type TerminalSignal = TerminalSignalRequest["signal"];
+
+/**
+ * Error raised when a session can't be managed, e.g. a signal can't be sent
+ */
+export class TerminalSessionError extends Error {}
+
+/**
+ * Error raised when a client that isn't the driver tries to type into,
+ * signal or kill a session
+ */
+export class TerminalDriverError extends Error {}
+
+/**
+ * Latest output of a session, replayed to clients that reconnect
+ *
+ * Output is dropped from the front once the limit is reached, like a ring
+ * buffer.
+ */
+interface Scrollback {
+	chunks: string[];
+	length: number;
+}
+
+/**
+ * Browser connection attached to a session
+ */
+interface TerminalClient {
+	id: string;
+	// Only sent to the client itself, it proves who is calling the REST API,
+	// e.g. to run a snippet, while the ID is shown to everyone
+	token: string;
+	name: string;
+	joinedAt: Date;
+	// Size of the client's terminal, applied when it takes control
+	cols?: number;
+	rows?: number;
+}
+
+/**
+ * Pseudo-terminal that outlives the WebSockets attached to it
+ *
+ * Any number of clients can attach. One of them, the driver, types into
+ * the terminal and sets its size, the others only see the output.
+ */
+interface TerminalSession {
+	id: string;
+	kind: TerminalSessionKind;
+	environmentId?: string;
+	workingDir: string;
+	// Describes the session in recordings
+	title: string;
+	ptyShell: pty.IPty;
+	cols: number;
+	rows: number;
+	scrollback: Scrollback;
+	// Output on its way to the clients, see OutputFlow
+	output: OutputFlow;
+	clients: Map<WebSocket, TerminalClient>;
+	driver: WebSocket | null;
+	// Viewers waiting for the driver to hand over control
+	controlRequests: Set<WebSocket>;
+	startedAt: Date;
+	lastActivity: Date;
+	// Ends the session once no client has been attached for a while
+	idleTimer: NodeJS.Timeout | null;
+	recorder: TerminalRecorder | null;
+	// How the terminal ended, kept until a client has been told
+	exit: TerminalExitMessage | null;
+}
+
+/**
+ * Program and arguments run in a session's pseudo-terminal
+ */
+interface SpawnCommand {
+	file: string;
+	args: string[];
+}
+
+const sessions = new Map<string, TerminalSession>();
+
+// Clients pick the ID, e.g. a UUID kept for the browser tab, so they can
+// reattach after a reload without a handshake
+const SESSION_ID_PATTERN = /^[\w-]{8,64}$/;
+
+// Size of new terminals until the client sends its own, unless the shell
+// options set one
+const DEFAULT_COLS = 120;
+const DEFAULT_ROWS = 30;
+
+const MAX_CLIENT_NAME_LENGTH = 40;
+
+// Quiet time after the first output before a new session counts as ready
+// for input, container-use terminal prints while it starts the container
+const INPUT_READY_DELAY_MS = 500;
 
 const getEnhancedEnv = () => {
 	const enhancedEnv = { ...process.env };
@@ -45,214 +180,863 @@ const getEnhancedEnv = () => {
 };
 
 /**
- * Unified terminal handler that supports different CLI commands
+ * Get what a session runs: the CLI command, or a plain shell without one
  *
- * Examples:
- * - Plain terminal: handleTerminal(ws)
- * - Environment terminal: handleTerminal(ws, { command: CLI_COMMANDS.TERMINAL, environmentId: "my-env", workingDir: "/path", cliPath: "/usr/bin/container-use" })
- * - Watch terminal: handleTerminal(ws, { command: CLI_COMMANDS.WATCH, workingDir: "/path", cliPath: "/usr/bin/container-use" })
- * - Any CLI command: handleTerminal(ws, { command: CLI_COMMANDS.LIST, workingDir: "/path", cliPath: "/usr/bin/container-use" })
+ * The CLI is spawned with an argv array, nothing is typed into a shell. In
+ * login shell mode a shell loads the user's profile, e.g. a PATH set there,
+ * then replaces itself with the command. Its arguments are passed as
+ * positional parameters, so the shell doesn't parse them.
  */
-export const handleTerminal = (
-	ws: WebSocket,
-	options: TerminalOptions = {},
-): void => {
-	const { command, environmentId, workingDir, cliPath } = options;
-
-	// Create a pseudo-terminal shell
-	const shell = getOSShell();
-	const args = getShellArgs();
-	const env = getEnhancedEnv();
-
-	const ptyShell = pty.spawn(shell, args, {
-		name: "xterm-256color",
-		cwd: workingDir || process.cwd(),
-		env,
-		encoding: "utf-8",
-		cols: 120,
-		rows: 30,
-		handleFlowControl: false, // Disable flow control to prevent blocking
-		useConpty: false, // Use legacy mode for better compatibility
-	});
+const getSpawnCommand = (options: TerminalOptions): SpawnCommand => {
+	const { command, environmentId } = options;
+	if (!command) {
+		return options.shell
+			? { file: options.shell.shell, args: options.shell.args }
+			: { file: getOSShell(), args: getDefaultShellArgs() };
+	}
+
+	const cliArgs =
+		environmentId && command !== CLI_COMMANDS.WATCH
+			? [command, environmentId]
+			: [command];
+	// Always the configured CLI, clients can't choose what runs on the host
+	const cliPath = getDefaultCLIPath();
+	if (getTerminalLoginShell() && process.platform !== "win32") {
+		return {
+			file: getOSShell(),
+			args: ["-l", "-c", 'exec "$0" "$@"', cliPath, ...cliArgs],
+		};
+	}
+	return { file: cliPath, args: cliArgs };
+};
+
+const getSessionKind = (options: TerminalOptions): TerminalSessionKind => {
+	if (options.command === CLI_COMMANDS.WATCH) {
+		return "watch";
+	}
+	return options.environmentId ? "environment" : "shell";
+};
+
+const getSessionTitle = (options: TerminalOptions, cwd: string): string => {
+	switch (getSessionKind(options)) {
+		case "environment":
+			return `container-use terminal ${options.environmentId}`;
+		case "watch":
+			return "container-use watch";
+		default:
+			return `Shell in ${cwd}`;
+	}
+};
+
+/**
+ * Add output to the scrollback, dropping the oldest output over the limit
+ */
+const appendScrollback = (scrollback: Scrollback, data: string): void => {
+	const limit = getTerminalScrollback();
+	scrollback.chunks.push(data);
+	scrollback.length += data.length;
+
+	while (scrollback.length > limit) {
+		const first = scrollback.chunks[0];
+		const excess = scrollback.length - limit;
+		// Cut at a line break so the replay doesn't start mid escape sequence
+		const newline = first.indexOf("\n", excess);
+		if (first.length <= excess || newline === -1) {
+			scrollback.chunks.shift();
+			scrollback.length -= first.length;
+		} else {
+			scrollback.chunks[0] = first.slice(newline + 1);
+			scrollback.length -= newline + 1;
+		}
+	}
+};
+
+const broadcast = (session: TerminalSession, data: string): void => {
+	const output = Buffer.from(data, "utf-8");
+	for (const client of session.clients.keys()) {
+		sendOutput(client, output);
+	}
+};
+
+/**
+ * Clean up a name sent by a client, falling back to a generic one
+ */
+const sanitizeClientName = (name: string | undefined): string => {
+	const cleaned = (name || "")
+		.replace(/\p{Cc}/gu, "")
+		.trim()
+		.slice(0, MAX_CLIENT_NAME_LENGTH);
+	return cleaned || "Anonymous";
+};
+
+const describeParticipants = (
+	session: TerminalSession,
+): TerminalParticipant[] => {
+	return [...session.clients].map(([ws, client]) => ({
+		id: client.id,
+		name: client.name,
+		role: ws === session.driver ? "driver" : "viewer",
+		joinedAt: client.joinedAt.toISOString(),
+	}));
+};
+
+/**
+ * Tell every client who is attached, who drives and who asked to
+ */
+const broadcastPresence = (session: TerminalSession): void => {
+	const participants = describeParticipants(session);
+	const requests = [...session.controlRequests].flatMap(
+		(ws) => session.clients.get(ws)?.id ?? [],
+	);
+	for (const [ws, client] of session.clients) {
+		sendMessage(ws, {
+			type: "presence",
+			you: client.id,
+			token: client.token,
+			participants,
+			requests,
+		});
+	}
+};
+
+/**
+ * Start a session's pseudo-terminal running its command
+ *
+ * The session ends when the command exits.
+ */
+const createSession = (
+	id: string,
+	options: TerminalOptions,
+): TerminalSession => {
+	const { environmentId, workingDir, shell } = options;
+	const { file, args } = getSpawnCommand(options);
+	const env = { ...getEnhancedEnv(), ...shell?.env };
+	const cols = shell?.cols ?? DEFAULT_COLS;
+	const rows = shell?.rows ?? DEFAULT_ROWS;
+
+	const cwd = workingDir || shell?.cwd || process.cwd();
+	let ptyShell: pty.IPty;
+	try {
+		ptyShell = pty.spawn(file, args, {
+			name: "xterm-256color",
+			cwd,
+			env,
+			encoding: "utf-8",
+			cols,
+			rows,
+			handleFlowControl: false, // Output is paused by the session's OutputFlow
+			useConpty: false, // Use legacy mode for better compatibility
+		});
+	} catch (error) {
+		throw new TerminalSessionError(
+			`Failed to start ${file}: ${error instanceof Error ? error.message : "Unknown error"}`,
+		);
+	}
+
+	const session: TerminalSession = {
+		id,
+		kind: getSessionKind(options),
+		environmentId,
+		workingDir: cwd,
+		title: getSessionTitle(options, cwd),
+		ptyShell,
+		cols,
+		rows,
+		scrollback: { chunks: [], length: 0 },
+		output: createOutputFlow({
+			ptyShell,
+			// Output joins the scrollback once sent, so clients attaching in
+			// between don't get it twice
+			send: (data) => {
+				appendScrollback(session.scrollback, data);
+				broadcast(session, data);
+			},
+			getBufferedAmount: () =>
+				Math.max(
+					0,
+					...[...session.clients.keys()].map((ws) => ws.bufferedAmount),
+				),
+		}),
+		clients: new Map(),
+		driver: null,
+		controlRequests: new Set(),
+		startedAt: new Date(),
+		lastActivity: new Date(),
+		idleTimer: null,
+		recorder: null,
+		exit: null,
+	};
+	sessions.set(id, session);
 
 	// Set up event listeners for the pseudo-terminal
-	// Data flow: shell+pty -> WebSocket -> client
+	// Data flow: shell+pty -> batches -> scrollback and every attached
+	// WebSocket -> clients
 	ptyShell.onData((data: string) => {
-		ws.send(data);
+		session.lastActivity = new Date();
+		if (session.recorder) {
+			recordOutput(session.recorder, data);
+		}
+		queueOutput(session.output, data);
 	});
 
-	// Handle terminal exit based on command type
-	ptyShell.onExit((exitCode) => {
-		if (!command) {
-			// Plain terminal - just send exit code
-			ws.send(exitCode.toString());
+	// Tell the clients how the terminal ended, then disconnect them
+	ptyShell.onExit(({ exitCode, signal }) => {
+		// The last output goes out before the exit
+		stopOutputFlow(session.output);
+		const exit: TerminalExitMessage = {
+			type: "exit",
+			code: exitCode,
+			signal: getSignalName(signal),
+		};
+		session.exit = exit;
+		for (const client of session.clients.keys()) {
+			sendMessage(client, exit);
+			client.close(1000, "Terminal session ended");
+		}
+
+		if (session.idleTimer) {
+			clearTimeout(session.idleTimer);
+			session.idleTimer = null;
+		}
+		if (session.recorder) {
+			stopRecorder(session.recorder).catch((error) => {
+				console.error(`Failed to save recording of session ${id}:`, error);
+			});
+			session.recorder = null;
+		}
+		if (sessions.get(id) !== session) {
+			return;
+		}
+		if (session.clients.size > 0) {
+			// Reconnecting with this ID starts a new session
+			sessions.delete(id);
 		} else {
-			// Command-based terminal - send formatted exit message
-			const commandName =
-				command === CLI_COMMANDS.TERMINAL
-					? "Terminal"
-					: command.charAt(0).toUpperCase() + command.slice(1);
-			ws.send(
-				`\r\n\x1b[31m${commandName} session ended with exit code: ${exitCode}\x1b[0m\r\n`,
-			);
+			// Nobody was attached, keep the output and exit for the next client
+			scheduleIdleCleanup(session);
 		}
 	});
 
-	// Set up event listener for WebSocket messages
-	// Data flow: client -> WebSocket -> pty+shell
-	ws.addEventListener("message", (event: MessageEvent) => {
-		// Check if this is a resize message (JSON format from frontend)
-		if (typeof event.data === "string" && event.data.startsWith("{")) {
-			try {
-				const message = JSON.parse(event.data);
-				if (message.type === "resize" && message.cols && message.rows) {
-					ptyShell.resize(message.cols, message.rows);
-					return;
-				}
-			} catch {
-				// Not a valid JSON message, treat as terminal input
-			}
+	return session;
+};
+
+/**
+ * End a session once it has had no client for the idle timeout
+ */
+const scheduleIdleCleanup = (session: TerminalSession): void => {
+	session.idleTimer = setTimeout(() => {
+		session.idleTimer = null;
+		if (sessions.get(session.id) !== session) {
+			return;
 		}
-		ptyShell.write(event.data);
-	});
+		if (session.exit) {
+			sessions.delete(session.id);
+		} else {
+			console.log(`Closing idle terminal session ${session.id}`);
+			session.ptyShell.kill();
+		}
+	}, getTerminalIdleTimeout());
+};
 
-	// Set up WebSocket close event handler
-	ws.addEventListener("close", () => {
-		ptyShell.kill();
-	});
+/**
+ * Get a session whose terminal is still running
+ */
+const getRunningSession = (id: string): TerminalSession | undefined => {
+	const session = sessions.get(id);
+	return session && !session.exit ? session : undefined;
+};
 
-	// Bootstrap based on command type
-	if (command && cliPath) {
-		// Give the shell a moment to initialize properly
-		setTimeout(() => {
-			switch (command) {
-				case CLI_COMMANDS.TERMINAL:
-					if (environmentId) {
-						// Clear any existing prompt and execute the command
-						ptyShell.write("\u0003"); // Send Ctrl+C to clear any running command
-						setTimeout(() => {
-							ptyShell.write(`clear\r`); // Clear the screen with just CR
-							setTimeout(() => {
-								ptyShell.write(`${cliPath} terminal ${environmentId}\r`);
-							}, 50);
-						}, 50);
-					}
-					break;
-				case CLI_COMMANDS.WATCH:
-					// Clear any existing prompt and execute the command
-					ptyShell.write("\u0003"); // Send Ctrl+C to clear any running command
-					setTimeout(() => {
-						ptyShell.write(`clear\r`); // Clear the screen with just CR
-						setTimeout(() => {
-							ptyShell.write(`${cliPath} watch\r`);
-						}, 50);
-					}, 50);
-					break;
-				default:
-					// For other commands (LIST, LOG, DIFF), just run the command
-					ptyShell.write("\u0003"); // Send Ctrl+C to clear any running command
-					setTimeout(() => {
-						ptyShell.write(`clear\r`); // Clear the screen with just CR
-						setTimeout(() => {
-							if (environmentId) {
-								ptyShell.write(`${cliPath} ${command} ${environmentId}\r`);
-							} else {
-								ptyShell.write(`${cliPath} ${command}\r`);
-							}
-						}, 50);
-					}, 50);
-					break;
-			}
-		}, 200); // Increased delay to let shell initialize
+const resizeSession = (
+	session: TerminalSession,
+	cols: number,
+	rows: number,
+): void => {
+	if (cols === session.cols && rows === session.rows) {
+		return;
+	}
+	session.ptyShell.resize(cols, rows);
+	session.cols = cols;
+	session.rows = rows;
+	if (session.recorder) {
+		recordResize(session.recorder, cols, rows);
 	}
 };
 
 /**
- * File watching handler that streams file content changes in real-time
+ * Hand control of a session to a client, or to nobody
+ */
+const setDriver = (session: TerminalSession, ws: WebSocket | null): void => {
+	session.driver = ws;
+	if (!ws) {
+		return;
+	}
+	session.controlRequests.delete(ws);
+	// The terminal follows the size of whoever drives it
+	const client = session.clients.get(ws);
+	if (client?.cols && client.rows) {
+		resizeSession(session, client.cols, client.rows);
+	}
+};
+
+const findClient = (
+	session: TerminalSession,
+	clientId: unknown,
+): WebSocket | undefined => {
+	for (const [ws, client] of session.clients) {
+		if (client.id === clientId) {
+			return ws;
+		}
+	}
+	return undefined;
+};
+
+/**
+ * Handle a control handoff message from a client
  *
- * @param ws WebSocket connection from @hono/node-ws
- * @param filePath Absolute path to the file to watch
+ * - request: a viewer asks the driver for control
+ * - grant: the driver hands control to a client
+ * - deny: the driver turns down a client's request
+ * - take: a client takes control without asking, only while nobody drives
+ *   or the driver's connection dropped, otherwise it has to request control
  */
-export const handleFileWatch = (ws: WebSocket, filePath: string): void => {
-	let watcher: FSWatcher | null = null;
-
-	const sendFileContent = async () => {
-		try {
-			const content = await readFile(filePath, "utf-8");
-			ws.send(
-				JSON.stringify({
-					type: "content",
-					filePath,
-					content,
-					timestamp: new Date().toISOString(),
-				}),
-			);
-		} catch (error) {
-			ws.send(
-				JSON.stringify({
+const handleControlMessage = (
+	session: TerminalSession,
+	ws: WebSocket,
+	message: Extract<TerminalClientMessage, { type: "control" }>,
+): void => {
+	const isDriver = ws === session.driver;
+	switch (message.action) {
+		case "request":
+			if (!isDriver) {
+				session.controlRequests.add(ws);
+			}
+			break;
+		case "grant":
+		case "deny": {
+			if (!isDriver) {
+				sendMessage(ws, {
 					type: "error",
-					filePath,
-					error: error instanceof Error ? error.message : "Unknown error",
-					timestamp: new Date().toISOString(),
-				}),
-			);
+					error: "Only the driver can hand over control",
+				});
+				return;
+			}
+			const target = findClient(session, message.clientId);
+			if (!target) {
+				sendMessage(ws, {
+					type: "error",
+					error: `No client ${message.clientId} in this session`,
+				});
+				return;
+			}
+			if (message.action === "grant") {
+				setDriver(session, target);
+			} else {
+				session.controlRequests.delete(target);
+			}
+			break;
 		}
-	};
+		case "take": {
+			const driver = session.driver;
+			if (!isDriver && driver && driver.readyState === driver.OPEN) {
+				sendMessage(ws, {
+					type: "error",
+					error: "Someone else is driving, request control instead",
+				});
+				return;
+			}
+			setDriver(session, ws);
+			break;
+		}
+	}
+	broadcastPresence(session);
+};
+
+/**
+ * Handle a JSON message from a client
+ */
+const handleClientMessage = (
+	session: TerminalSession,
+	ws: WebSocket,
+	client: TerminalClient,
+	message: TerminalClientMessage,
+): void => {
+	switch (message.type) {
+		case "resize":
+			client.cols = message.cols;
+			client.rows = message.rows;
+			if (ws === session.driver) {
+				resizeSession(session, message.cols, message.rows);
+			}
+			break;
+		case "signal":
+			if (ws !== session.driver) {
+				sendMessage(ws, {
+					type: "error",
+					error: "Only the driver can send signals",
+				});
+				return;
+			}
+			sendSignal(session, message.signal).catch((error) => {
+				sendMessage(ws, {
+					type: "error",
+					error: error instanceof Error ? error.message : "Unknown error",
+				});
+			});
+			break;
+		case "ping":
+			sendMessage(ws, { type: "pong", id: message.id });
+			break;
+		case "control":
+			handleControlMessage(session, ws, message);
+			break;
+		case "rename":
+			client.name = sanitizeClientName(message.name);
+			broadcastPresence(session);
+			break;
+	}
+};
 
-	// Send initial content
-	sendFileContent();
+/**
+ * Connect a WebSocket to a session, replaying the output it missed
+ *
+ * The first client becomes the driver, later ones join as viewers.
+ */
+const attachClient = (
+	session: TerminalSession,
+	ws: WebSocket,
+	clientName?: string,
+): void => {
+	if (session.idleTimer) {
+		clearTimeout(session.idleTimer);
+		session.idleTimer = null;
+	}
 
-	// Set up file watcher
-	watcher = chokidar.watch(filePath, {
-		persistent: true,
-		ignoreInitial: true, // Don't emit events for initial scan
-		awaitWriteFinish: {
-			stabilityThreshold: 100,
-			pollInterval: 50,
-		},
-	});
+	if (session.scrollback.length > 0) {
+		sendOutput(ws, session.scrollback.chunks.join(""));
+	}
+	// The terminal ended while detached, this client learns how and the ID
+	// is free for a new session
+	if (session.exit) {
+		sessions.delete(session.id);
+		sendMessage(ws, session.exit);
+		ws.close(1000, "Terminal session ended");
+		return;
+	}
 
-	// Watch for file changes
-	watcher.on("change", () => {
-		sendFileContent();
-	});
+	const client: TerminalClient = {
+		id: randomUUID(),
+		token: randomUUID(),
+		name: sanitizeClientName(clientName),
+		joinedAt: new Date(),
+	};
+	session.clients.set(ws, client);
+	if (!session.driver) {
+		setDriver(session, ws);
+	}
+	broadcastPresence(session);
 
-	// Handle file deletion
-	watcher.on("unlink", () => {
-		ws.send(
-			JSON.stringify({
-				type: "deleted",
-				filePath,
-				timestamp: new Date().toISOString(),
-			}),
+	// A client that stops answering pings is dropped, like a closed one
+	const stopWatching = watchConnection(ws, () => {
+		console.log(
+			`Dropping unresponsive client ${client.name} of session ${session.id}`,
 		);
 	});
 
-	// Handle errors
-	watcher.on("error", (error: unknown) => {
-		ws.send(
-			JSON.stringify({
+	// Set up event listener for WebSocket messages
+	// Data flow: driver -> WebSocket -> pty+shell
+	ws.addEventListener("message", (event: MessageEvent) => {
+		if (typeof event.data === "string") {
+			let message: TerminalClientMessage;
+			try {
+				message = parseClientMessage(event.data);
+			} catch (error) {
+				sendMessage(ws, {
+					type: "error",
+					error: error instanceof Error ? error.message : "Invalid message",
+				});
+				return;
+			}
+			handleClientMessage(session, ws, client, message);
+			return;
+		}
+
+		// Binary frames are input, viewers are read-only
+		if (ws !== session.driver) {
+			sendMessage(ws, {
 				type: "error",
-				filePath,
-				error: error instanceof Error ? error.message : "Unknown error",
-				timestamp: new Date().toISOString(),
-			}),
-		);
+				error: "The terminal is read-only, request control to type",
+			});
+			return;
+		}
+		session.lastActivity = new Date();
+		session.ptyShell.write(decodeInput(event.data));
 	});
 
-	// Clean up when WebSocket closes
+	// Closing the WebSocket only detaches, the session keeps running so the
+	// client can reattach after a reload or a network drop
 	ws.addEventListener("close", () => {
-		if (watcher) {
-			watcher.close();
-			watcher = null;
+		stopWatching();
+		session.clients.delete(ws);
+		session.controlRequests.delete(ws);
+		if (session.driver === ws) {
+			// Control passes to the client that has been attached longest
+			const [next] = session.clients.keys();
+			setDriver(session, next ?? null);
+		}
+		broadcastPresence(session);
+		if (session.clients.size === 0 && sessions.get(session.id) === session) {
+			scheduleIdleCleanup(session);
 		}
 	});
+};
+
+/**
+ * Unified terminal handler that supports different CLI commands
+ *
+ * A terminal runs in a session that survives its WebSocket. Passing the
+ * sessionId of a running session reattaches to it and replays its
+ * scrollback, otherwise a new session is started under that ID. Several
+ * clients can share a session, see attachClient.
+ *
+ * CLI commands run directly in the terminal, see getSpawnCommand, and the
+ * session ends when they exit. An exit while no client is attached is
+ * reported to the next client that reattaches.
+ *
+ * Output is sent in batches, and the terminal is paused while a client
+ * can't keep up or the session exceeds its output rate, see OutputFlow.
+ * Clients that stop answering WebSocket pings are dropped.
+ *
+ * The WebSocket must use the TERMINAL_PROTOCOL subprotocol. Binary frames
+ * carry the terminal input and output as UTF-8, text frames JSON messages.
+ * Messages from the client:
+ * - { type: "resize", cols, rows } sets the size of the client's terminal
+ * - { type: "signal", signal } signals the foreground process, e.g. SIGINT
+ * - { type: "ping", id? } is answered with a pong with the same id
+ * - { type: "control", action, clientId? } hands over control, see
+ *   handleControlMessage
+ * - { type: "rename", name } changes the name shown to the other clients
+ * Messages sent to the client:
+ * - { type: "presence", you, token, participants, requests } when clients
+ *   attach, leave or hand over control, the token is only sent to "you"
+ * - { type: "exit", code, signal } when the terminal ends, the WebSocket is
+ *   closed right after
+ * - { type: "pong", id? }
+ * - { type: "error", error } for messages that can't be handled
+ *
+ * Examples:
+ * - Plain terminal: handleTerminal(ws)
+ * - Configured shell: handleTerminal(ws, { shell: await resolveShellOptions(request) })
+ * - Environment terminal: handleTerminal(ws, { command: CLI_COMMANDS.TERMINAL, environmentId: "my-env", workingDir: "/path" })
+ * - Watch terminal: handleTerminal(ws, { command: CLI_COMMANDS.WATCH, workingDir: "/path" })
+ * - Any CLI command: handleTerminal(ws, { command: CLI_COMMANDS.LIST, workingDir: "/path" })
+ * - Reattach: handleTerminal(ws, { sessionId: "2f1c...", ...sameOptions })
+ * - Join as someone: handleTerminal(ws, { clientName: "Ada", ...options })
+ */
+export const handleTerminal = (
+	ws: WebSocket,
+	options: TerminalOptions = {},
+): void => {
+	const { sessionId, clientName, command, environmentId } = options;
+	if (ws.protocol !== TERMINAL_PROTOCOL) {
+		ws.close(1002, `Unsupported protocol, expected ${TERMINAL_PROTOCOL}`);
+		return;
+	}
+	if (sessionId !== undefined && !SESSION_ID_PATTERN.test(sessionId)) {
+		ws.close(1008, "Invalid terminal session ID");
+		return;
+	}
+	// The ID is passed to the CLI as an argument
+	if (
+		environmentId !== undefined &&
+		!ENVIRONMENT_ID_PATTERN.test(environmentId)
+	) {
+		ws.close(1008, "Invalid environment ID");
+		return;
+	}
+	if (command === CLI_COMMANDS.TERMINAL && !environmentId) {
+		ws.close(1008, "An environment ID is required");
+		return;
+	}
+
+	const existing = sessionId ? sessions.get(sessionId) : undefined;
+	if (existing) {
+		// Don't hand a session to a different kind of terminal
+		if (
+			existing.kind !== getSessionKind(options) ||
+			existing.environmentId !== options.environmentId
+		) {
+			ws.close(1008, "Terminal session belongs to another terminal");
+			return;
+		}
+		attachClient(existing, ws, clientName);
+		return;
+	}
+
+	let session: TerminalSession;
+	try {
+		session = createSession(sessionId || randomUUID(), options);
+	} catch (error) {
+		console.error("Failed to start terminal session:", error);
+		ws.close(1011, "Failed to start the terminal");
+		return;
+	}
+	attachClient(session, ws, clientName);
+};
 
-	// Handle WebSocket errors
-	ws.addEventListener("error", () => {
-		if (watcher) {
-			watcher.close();
-			watcher = null;
+const describeSession = (session: TerminalSession): TerminalSessionInfo => ({
+	id: session.id,
+	kind: session.kind,
+	environmentId: session.environmentId,
+	workingDir: session.workingDir,
+	pid: session.ptyShell.pid,
+	process: session.ptyShell.process,
+	startedAt: session.startedAt.toISOString(),
+	clients: session.clients.size,
+	participants: describeParticipants(session),
+	lastActivity: session.lastActivity.toISOString(),
+	recordingId: session.recorder?.id,
+});
+
+/**
+ * List the running terminal sessions, oldest first
+ */
+export const listTerminalSessions = (): TerminalSessionInfo[] => {
+	return [...sessions.values()]
+		.filter((session) => !session.exit)
+		.map(describeSession);
+};
+
+/**
+ * Type into a new session once its shell is ready
+ *
+ * The shell is ready once it has printed, e.g. its prompt, and then stayed
+ * quiet for a moment. Input typed earlier could be swallowed while the
+ * shell or the container starts.
+ */
+const writeWhenReady = (session: TerminalSession, data: string): void => {
+	let timer: NodeJS.Timeout | null = null;
+	const listener = session.ptyShell.onData(() => {
+		if (timer) {
+			clearTimeout(timer);
 		}
+		timer = setTimeout(() => {
+			listener.dispose();
+			if (!session.exit) {
+				session.ptyShell.write(data);
+				session.lastActivity = new Date();
+			}
+		}, INPUT_READY_DELAY_MS);
+	});
+};
+
+/**
+ * Start a session without a client, e.g. to run a snippet in it
+ *
+ * The input, if any, is typed once the shell is ready. The session is
+ * closed after the idle timeout unless a client attaches to it.
+ */
+export const startTerminalSession = (
+	options: TerminalOptions,
+	input?: string,
+): TerminalSessionInfo => {
+	const { command, environmentId } = options;
+	// The ID is passed to the CLI as an argument
+	if (
+		environmentId !== undefined &&
+		!ENVIRONMENT_ID_PATTERN.test(environmentId)
+	) {
+		throw new TerminalSessionError("Invalid environment ID");
+	}
+	if (command === CLI_COMMANDS.TERMINAL && !environmentId) {
+		throw new TerminalSessionError("An environment ID is required");
+	}
+
+	const session = createSession(randomUUID(), options);
+	scheduleIdleCleanup(session);
+	if (input) {
+		writeWhenReady(session, input);
+	}
+	return describeSession(session);
+};
+
+/**
+ * Get a running session
+ *
+ * Returns null if no session has this ID.
+ */
+export const getTerminalSession = (id: string): TerminalSessionInfo | null => {
+	const session = getRunningSession(id);
+	return session ? describeSession(session) : null;
+};
+
+/**
+ * Check that a REST call comes from the session's driver
+ *
+ * The token is the one the driver got in its presence messages.
+ */
+const checkDriverToken = (
+	session: TerminalSession,
+	token: string | undefined,
+	action: string,
+): void => {
+	const driver = session.driver && session.clients.get(session.driver);
+	if (!token || driver?.token !== token) {
+		throw new TerminalDriverError(`Only the driver can ${action} the session`);
+	}
+};
+
+/**
+ * Type into a session's terminal for its driver
+ *
+ * Other clients can't type. Returns false if no session has this ID.
+ */
+export const writeTerminalSession = (
+	id: string,
+	data: string,
+	token: string | undefined,
+): boolean => {
+	const session = getRunningSession(id);
+	if (!session) {
+		return false;
+	}
+	checkDriverToken(session, token, "type into");
+	session.ptyShell.write(data);
+	session.lastActivity = new Date();
+	return true;
+};
+
+/**
+ * Kill a session and the processes running in it for its driver
+ *
+ * Returns false if no session has this ID.
+ */
+export const killTerminalSession = (
+	id: string,
+	token: string | undefined,
+): boolean => {
+	const session = getRunningSession(id);
+	if (!session) {
+		return false;
+	}
+	checkDriverToken(session, token, "kill");
+
+	// Forget the session right away, the exit event follows the kill
+	sessions.delete(id);
+	if (session.idleTimer) {
+		clearTimeout(session.idleTimer);
+		session.idleTimer = null;
+	}
+	session.ptyShell.kill();
+	return true;
+};
+
+/**
+ * Get the process group in the foreground of a session's terminal
+ *
+ * That is the command running in the shell, or the shell itself when it is
+ * waiting at the prompt.
+ */
+const getForegroundProcessGroup = async (pid: number): Promise<number> => {
+	const result = await executeGenericCommand({
+		command: "ps",
+		args: ["-o", "tpgid=", "-p", String(pid)],
+		forceColor: false,
 	});
+	const processGroup = Number.parseInt(result.stdout.trim(), 10);
+	return Number.isInteger(processGroup) && processGroup > 0
+		? processGroup
+		: pid;
+};
+
+const sendSignal = async (
+	session: TerminalSession,
+	signal: TerminalSignal,
+): Promise<void> => {
+	if (process.platform === "win32") {
+		throw new TerminalSessionError("Signals are not supported on Windows");
+	}
+
+	const processGroup = await getForegroundProcessGroup(session.ptyShell.pid);
+	try {
+		process.kill(-processGroup, signal);
+	} catch (error) {
+		throw new TerminalSessionError(
+			`Failed to send ${signal}: ${error instanceof Error ? error.message : "Unknown error"}`,
+		);
+	}
+	session.lastActivity = new Date();
+};
+
+/**
+ * Send a signal to the foreground process of a session for its driver
+ *
+ * Returns false if no session has this ID.
+ */
+export const signalTerminalSession = async (
+	id: string,
+	signal: TerminalSignal,
+	token: string | undefined,
+): Promise<boolean> => {
+	const session = getRunningSession(id);
+	if (!session) {
+		return false;
+	}
+	checkDriverToken(session, token, "signal");
+	await sendSignal(session, signal);
+	return true;
+};
+
+/**
+ * Start recording the output of a session in asciicast format
+ *
+ * Returns null if no session has this ID.
+ */
+export const startTerminalRecording = async (
+	id: string,
+): Promise<TerminalRecording | null> => {
+	const session = getRunningSession(id);
+	if (!session) {
+		return null;
+	}
+	if (session.recorder) {
+		throw new TerminalSessionError("The session is already being recorded");
+	}
+
+	const recorder = await startRecorder(
+		session.environmentId || session.kind,
+		{
+			width: session.cols,
+			height: session.rows,
+			title: session.title,
+			env: { TERM: "xterm-256color", SHELL: getOSShell() },
+		},
+	);
+	// The session may have exited while the file was created
+	if (sessions.get(id) !== session || session.recorder) {
+		await stopRecorder(recorder);
+		throw new TerminalSessionError("The session ended or is being recorded");
+	}
+	session.recorder = recorder;
+	return getRecordingInfo(recorder.id);
+};
+
+/**
+ * Stop recording a session
+ *
+ * Returns null if no session has this ID.
+ */
+export const stopTerminalRecording = async (
+	id: string,
+): Promise<TerminalRecording | null> => {
+	const session = getRunningSession(id);
+	if (!session) {
+		return null;
+	}
+	const { recorder } = session;
+	if (!recorder) {
+		throw new TerminalSessionError("The session is not being recorded");
+	}
+
+	session.recorder = null;
+	await stopRecorder(recorder);
+	return getRecordingInfo(recorder.id);
 };
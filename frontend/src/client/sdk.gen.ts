@@ -3,7 +3,7 @@
 import type { CancelablePromise } from './core/CancelablePromise';
 import { OpenAPI } from './core/OpenAPI';
 import { request as __request } from './core/request';
-import type { GetApiV1EnvironmentsData, GetApiV1EnvironmentsResponse, GetApiV1EnvironmentsByIdLogsData, GetApiV1EnvironmentsByIdLogsResponse, GetApiV1EnvironmentsByIdDiffData, GetApiV1EnvironmentsByIdDiffResponse, PostApiV1EnvironmentsByIdApplyData, PostApiV1EnvironmentsByIdApplyResponse, PostApiV1EnvironmentsByIdMergeData, PostApiV1EnvironmentsByIdMergeResponse, PostApiV1EnvironmentsByIdCheckoutData, PostApiV1EnvironmentsByIdCheckoutResponse, GetApiV1FilesData, GetApiV1FilesResponse, GetApiV1GitData, GetApiV1GitResponse, PostApiV1GitCheckoutData, PostApiV1GitCheckoutResponse, GetApiV1GitLogData, GetApiV1GitLogResponse, GetApiV1GitStatusData, GetApiV1GitStatusResponse } from './types.gen';
+import type { GetApiV1EnvironmentsData, GetApiV1EnvironmentsResponse, GetApiV1EnvironmentsByIdLogsData, GetApiV1EnvironmentsByIdLogsResponse, GetApiV1EnvironmentsByIdDiffData, GetApiV1EnvironmentsByIdDiffResponse, PostApiV1EnvironmentsByIdApplyData, PostApiV1EnvironmentsByIdApplyResponse, PostApiV1EnvironmentsByIdMergeData, PostApiV1EnvironmentsByIdMergeResponse, PostApiV1EnvironmentsByIdCheckoutData, PostApiV1EnvironmentsByIdCheckoutResponse, PostApiV1EnvironmentsByIdPushData, PostApiV1EnvironmentsByIdPushResponse, PostApiV1EnvironmentsByIdWorktreeData, PostApiV1EnvironmentsByIdWorktreeResponse, GetApiV1FilesData, GetApiV1FilesResponse, GetApiV1FilesTreeData, GetApiV1FilesTreeResponse, GetApiV1FilesContentData, GetApiV1FilesContentResponse, PutApiV1FilesContentData, PutApiV1FilesContentResponse, GetApiV1FilesRawData, GetApiV1FilesRawResponse, PostApiV1FilesCreateData, PostApiV1FilesCreateResponse, PostApiV1FilesMoveData, PostApiV1FilesMoveResponse, PostApiV1FilesCopyData, PostApiV1FilesCopyResponse, PostApiV1FilesTrashData, PostApiV1FilesTrashResponse, GetApiV1FilesTrashResponse, PostApiV1FilesTrashRestoreData, PostApiV1FilesTrashRestoreResponse, GetApiV1FilesDownloadData, GetApiV1FilesDownloadResponse, PostApiV1FilesUploadData, PostApiV1FilesUploadResponse, GetApiV1GitData, GetApiV1GitResponse, PostApiV1GitCheckoutData, PostApiV1GitCheckoutResponse, GetApiV1GitLogData, GetApiV1GitLogResponse, GetApiV1GitStatusData, GetApiV1GitStatusResponse, GetApiV1GitStatusDiffData, GetApiV1GitStatusDiffResponse, GetApiV1GitRemotesData, GetApiV1GitRemotesResponse, PostApiV1GitFetchData, PostApiV1GitFetchResponse, PostApiV1GitPullData, PostApiV1GitPullResponse, PostApiV1GitPushData, PostApiV1GitPushResponse, GetApiV1GitConflictsData, GetApiV1GitConflictsResponse, GetApiV1GitConflictsFileData, GetApiV1GitConflictsFileResponse, PostApiV1GitConflictsResolveData, PostApiV1GitConflictsResolveResponse, PostApiV1GitConflictsContinueData, PostApiV1GitConflictsContinueResponse, PostApiV1GitConflictsAbortData, PostApiV1GitConflictsAbortResponse, GetApiV1GitWorktreesData, GetApiV1GitWorktreesResponse, DeleteApiV1GitWorktreesData, DeleteApiV1GitWorktreesResponse, GetApiV1TerminalSessionsResponse, DeleteApiV1TerminalSessionsByIdData, DeleteApiV1TerminalSessionsByIdResponse, PostApiV1TerminalSessionsByIdSignalData, PostApiV1TerminalSessionsByIdSignalResponse, PostApiV1TerminalSessionsByIdRecordingData, PostApiV1TerminalSessionsByIdRecordingResponse, DeleteApiV1TerminalSessionsByIdRecordingData, DeleteApiV1TerminalSessionsByIdRecordingResponse, GetApiV1TerminalRecordingsResponse, GetApiV1TerminalRecordingsByIdData, GetApiV1TerminalRecordingsByIdResponse, DeleteApiV1TerminalRecordingsByIdData, DeleteApiV1TerminalRecordingsByIdResponse, GetApiV1TerminalSnippetsData, GetApiV1TerminalSnippetsResponse, PostApiV1TerminalSnippetsRunData, PostApiV1TerminalSnippetsRunResponse } from './types.gen';
 
 export class DefaultService {
     /**
@@ -22,6 +22,7 @@ export class DefaultService {
                 cli: data.cli
             },
             errors: {
+                403: 'Folder is outside the allowed folders',
                 500: 'Internal server error'
             }
         });
@@ -47,6 +48,7 @@ export class DefaultService {
                 cli: data.cli
             },
             errors: {
+                403: 'Folder is outside the allowed folders',
                 500: 'Internal server error'
             }
         });
@@ -72,6 +74,7 @@ export class DefaultService {
                 cli: data.cli
             },
             errors: {
+                403: 'Folder is outside the allowed folders',
                 500: 'Internal server error'
             }
         });
@@ -97,6 +100,8 @@ export class DefaultService {
                 cli: data.cli
             },
             errors: {
+                403: 'Folder is outside the allowed folders',
+                409: 'Stopped on conflicts that must be resolved or aborted',
                 500: 'Internal server error'
             }
         });
@@ -122,6 +127,8 @@ export class DefaultService {
                 cli: data.cli
             },
             errors: {
+                403: 'Folder is outside the allowed folders',
+                409: 'Stopped on conflicts that must be resolved or aborted',
                 500: 'Internal server error'
             }
         });
@@ -147,6 +154,7 @@ export class DefaultService {
                 cli: data.cli
             },
             errors: {
+                403: 'Folder is outside the allowed folders',
                 500: 'Internal server error'
             }
         });
@@ -154,7 +162,60 @@ export class DefaultService {
     
     /**
      * @param data The data for the request.
-     * @param data.path Folder path to list. Defaults to home folder if not provided
+     * @param data.id Environment ID
+     * @param data.folder Working folder for the CLI command
+     * @param data.requestBody
+     * @returns GitRemoteResult Push result, including updated and rejected refs
+     * @throws ApiError
+     */
+    public static postApiV1EnvironmentsByIdPush(data: PostApiV1EnvironmentsByIdPushData): CancelablePromise<PostApiV1EnvironmentsByIdPushResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/environments/{id}/push',
+            path: {
+                id: data.id
+            },
+            query: {
+                folder: data.folder
+            },
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: 'Bad request (unknown remote or invalid branch name)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.id Environment ID
+     * @param data.folder Working folder for the CLI command
+     * @returns unknown Worktree for the environment, created if needed
+     * @throws ApiError
+     */
+    public static postApiV1EnvironmentsByIdWorktree(data: PostApiV1EnvironmentsByIdWorktreeData): CancelablePromise<PostApiV1EnvironmentsByIdWorktreeResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/environments/{id}/worktree',
+            path: {
+                id: data.id
+            },
+            query: {
+                folder: data.folder
+            },
+            errors: {
+                400: 'Bad request (invalid environment or branch checked out elsewhere)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.path Folder path to list. Defaults to the working directory if not provided
      * @returns unknown Folder listing
      * @throws ApiError
      */
@@ -166,6 +227,274 @@ export class DefaultService {
                 path: data.path
             },
             errors: {
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.path Folder to list. Defaults to the working directory if not provided
+     * @param data.depth Number of levels to list
+     * @param data.showIgnored Also list files and folders ignored by git
+     * @returns FileTree Folder tree
+     * @throws ApiError
+     */
+    public static getApiV1FilesTree(data: GetApiV1FilesTreeData = {}): CancelablePromise<GetApiV1FilesTreeResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/files/tree',
+            query: {
+                path: data.path,
+                depth: data.depth,
+                showIgnored: data.showIgnored
+            },
+            errors: {
+                400: 'Path is not a folder',
+                403: 'Folder is outside the allowed folders',
+                404: 'Folder not found',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.path File path to read
+     * @param data.offset Byte offset to start reading at
+     * @param data.length Number of bytes to read. Defaults to and is capped at the maximum file size
+     * @returns FileContent File content
+     * @throws ApiError
+     */
+    public static getApiV1FilesContent(data: GetApiV1FilesContentData): CancelablePromise<GetApiV1FilesContentResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/files/content',
+            query: {
+                path: data.path,
+                offset: data.offset,
+                length: data.length
+            },
+            errors: {
+                304: 'File has not changed since the given ETag or date',
+                400: 'Bad request (path is a folder or offset is out of range)',
+                403: 'File is outside the allowed folders',
+                404: 'File not found',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.requestBody
+     * @returns unknown File saved
+     * @throws ApiError
+     */
+    public static putApiV1FilesContent(data: PutApiV1FilesContentData = {}): CancelablePromise<PutApiV1FilesContentResponse> {
+        return __request(OpenAPI, {
+            method: 'PUT',
+            url: '/api/v1/files/content',
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: 'Bad request (path is a folder)',
+                403: 'File is outside the allowed folders',
+                404: 'Parent folder not found',
+                409: 'File changed on disk since the expected version',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.path File path to read
+     * @returns unknown File content, with the Content-Type of the file
+     * @throws ApiError
+     */
+    public static getApiV1FilesRaw(data: GetApiV1FilesRawData): CancelablePromise<GetApiV1FilesRawResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/files/raw',
+            query: {
+                path: data.path
+            },
+            errors: {
+                304: 'File has not changed since the given ETag or date',
+                400: 'Bad request (path is a folder)',
+                403: 'File is outside the allowed folders',
+                404: 'File not found',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.requestBody
+     * @returns unknown File or folder created
+     * @throws ApiError
+     */
+    public static postApiV1FilesCreate(data: PostApiV1FilesCreateData = {}): CancelablePromise<PostApiV1FilesCreateResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/files/create',
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: 'Bad request (invalid path)',
+                403: 'Path is outside the allowed folders',
+                404: 'Parent path not found',
+                409: 'Path already exists',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.requestBody
+     * @returns unknown File or folder moved
+     * @throws ApiError
+     */
+    public static postApiV1FilesMove(data: PostApiV1FilesMoveData = {}): CancelablePromise<PostApiV1FilesMoveResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/files/move',
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: 'Bad request (e.g. moving a folder into itself)',
+                403: 'Source or destination is outside the allowed folders',
+                404: 'Source not found',
+                409: 'Destination already exists',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.requestBody
+     * @returns unknown File or folder copied
+     * @throws ApiError
+     */
+    public static postApiV1FilesCopy(data: PostApiV1FilesCopyData = {}): CancelablePromise<PostApiV1FilesCopyResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/files/copy',
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: 'Bad request (e.g. copying a folder into itself)',
+                403: 'Source or destination is outside the allowed folders',
+                404: 'Source not found',
+                409: 'Destination already exists',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.requestBody
+     * @returns unknown File or folder moved to the trash
+     * @throws ApiError
+     */
+    public static postApiV1FilesTrash(data: PostApiV1FilesTrashData = {}): CancelablePromise<PostApiV1FilesTrashResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/files/trash',
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: 'Bad request (path cannot be deleted)',
+                403: 'Path is outside the allowed folders',
+                404: 'Path not found',
+                409: 'Conflict with an existing path',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @returns unknown Trashed files and folders
+     * @throws ApiError
+     */
+    public static getApiV1FilesTrash(): CancelablePromise<GetApiV1FilesTrashResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/files/trash',
+            errors: {
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.requestBody
+     * @returns unknown File or folder restored
+     * @throws ApiError
+     */
+    public static postApiV1FilesTrashRestore(data: PostApiV1FilesTrashRestoreData = {}): CancelablePromise<PostApiV1FilesTrashRestoreResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/files/trash/restore',
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: 'Bad request (invalid trash ID)',
+                403: 'Original path is outside the allowed folders',
+                404: 'Trash item not found',
+                409: 'Original path is taken',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.path File or folder to download
+     * @param data.format Archive format for folders. Defaults to zip
+     * @returns unknown File content, or the folder as a zip or tar.gz archive
+     * @throws ApiError
+     */
+    public static getApiV1FilesDownload(data: GetApiV1FilesDownloadData): CancelablePromise<GetApiV1FilesDownloadResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/files/download',
+            query: {
+                path: data.path,
+                format: data.format
+            },
+            errors: {
+                403: 'Path is outside the allowed folders',
+                404: 'File or folder not found',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.requestBody
+     * @returns unknown Files uploaded
+     * @throws ApiError
+     */
+    public static postApiV1FilesUpload(data: PostApiV1FilesUploadData = {}): CancelablePromise<PostApiV1FilesUploadResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/files/upload',
+            formData: data.requestBody,
+            mediaType: 'multipart/form-data',
+            errors: {
+                400: 'Bad request (no files, invalid file name or not a folder)',
+                403: 'Folder or a file in it is outside the allowed folders',
+                404: 'Folder not found',
+                409: 'A file already exists',
                 500: 'Internal server error'
             }
         });
@@ -185,6 +514,7 @@ export class DefaultService {
                 folder: data.folder
             },
             errors: {
+                403: 'Folder is outside the allowed folders',
                 404: 'Folder not found',
                 500: 'Internal server error'
             }
@@ -209,6 +539,7 @@ export class DefaultService {
             mediaType: 'application/json',
             errors: {
                 400: 'Bad request (not a git repository or uncommitted changes)',
+                403: 'Folder is outside the allowed folders',
                 500: 'Internal server error'
             }
         });
@@ -233,6 +564,7 @@ export class DefaultService {
             },
             errors: {
                 400: 'Bad request (not a git repository or branch not found)',
+                403: 'Folder is outside the allowed folders',
                 500: 'Internal server error'
             }
         });
@@ -241,6 +573,7 @@ export class DefaultService {
     /**
      * @param data The data for the request.
      * @param data.folder Folder path for git operations
+     * @param data.ignored Also list ignored files
      * @returns unknown Detailed git status result
      * @throws ApiError
      */
@@ -248,14 +581,493 @@ export class DefaultService {
         return __request(OpenAPI, {
             method: 'GET',
             url: '/api/v1/git/status',
+            query: {
+                folder: data.folder,
+                ignored: data.ignored
+            },
+            errors: {
+                400: 'Bad request (not a git repository)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @param data.path File path relative to the repository root
+     * @param data.staged Diff the index against HEAD instead of the worktree
+     * @returns unknown Diff of the file
+     * @throws ApiError
+     */
+    public static getApiV1GitStatusDiff(data: GetApiV1GitStatusDiffData): CancelablePromise<GetApiV1GitStatusDiffResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/git/status/diff',
+            query: {
+                folder: data.folder,
+                path: data.path,
+                staged: data.staged
+            },
+            errors: {
+                400: 'Bad request (not a git repository or file has no changes)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @returns unknown Configured remotes
+     * @throws ApiError
+     */
+    public static getApiV1GitRemotes(data: GetApiV1GitRemotesData): CancelablePromise<GetApiV1GitRemotesResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/git/remotes',
             query: {
                 folder: data.folder
             },
             errors: {
                 400: 'Bad request (not a git repository)',
+                403: 'Folder is outside the allowed folders',
                 500: 'Internal server error'
             }
         });
     }
     
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @param data.requestBody
+     * @returns GitRemoteResult Fetch result, including updated and rejected refs
+     * @throws ApiError
+     */
+    public static postApiV1GitFetch(data: PostApiV1GitFetchData): CancelablePromise<PostApiV1GitFetchResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/git/fetch',
+            query: {
+                folder: data.folder
+            },
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: 'Bad request (not a git repository or unknown remote)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @param data.requestBody
+     * @returns GitRemoteResult Pull result, including updated and rejected refs
+     * @throws ApiError
+     */
+    public static postApiV1GitPull(data: PostApiV1GitPullData): CancelablePromise<PostApiV1GitPullResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/git/pull',
+            query: {
+                folder: data.folder
+            },
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: 'Bad request (not a git repository or unknown remote)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @param data.requestBody
+     * @returns GitRemoteResult Push result, including updated and rejected refs
+     * @throws ApiError
+     */
+    public static postApiV1GitPush(data: PostApiV1GitPushData): CancelablePromise<PostApiV1GitPushResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/git/push',
+            query: {
+                folder: data.folder
+            },
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: 'Bad request (not a git repository or unknown remote)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @returns unknown Conflict state of the repository
+     * @throws ApiError
+     */
+    public static getApiV1GitConflicts(data: GetApiV1GitConflictsData): CancelablePromise<GetApiV1GitConflictsResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/git/conflicts',
+            query: {
+                folder: data.folder
+            },
+            errors: {
+                400: 'Bad request (not a git repository)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @param data.path Conflicted file path relative to the repository root
+     * @returns unknown Versions of the conflicted file
+     * @throws ApiError
+     */
+    public static getApiV1GitConflictsFile(data: GetApiV1GitConflictsFileData): CancelablePromise<GetApiV1GitConflictsFileResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/git/conflicts/file',
+            query: {
+                folder: data.folder,
+                path: data.path
+            },
+            errors: {
+                400: 'Bad request (not a git repository or file is not conflicted)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @param data.requestBody
+     * @returns unknown Conflict resolved and staged
+     * @throws ApiError
+     */
+    public static postApiV1GitConflictsResolve(data: PostApiV1GitConflictsResolveData): CancelablePromise<PostApiV1GitConflictsResolveResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/git/conflicts/resolve',
+            query: {
+                folder: data.folder
+            },
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: 'Bad request (not a git repository or file is not conflicted)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @returns unknown Result of continuing the operation
+     * @throws ApiError
+     */
+    public static postApiV1GitConflictsContinue(data: PostApiV1GitConflictsContinueData): CancelablePromise<PostApiV1GitConflictsContinueResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/git/conflicts/continue',
+            query: {
+                folder: data.folder
+            },
+            errors: {
+                400: 'Bad request (nothing in progress or unresolved conflicts)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @returns unknown Result of aborting the operation
+     * @throws ApiError
+     */
+    public static postApiV1GitConflictsAbort(data: PostApiV1GitConflictsAbortData): CancelablePromise<PostApiV1GitConflictsAbortResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/git/conflicts/abort',
+            query: {
+                folder: data.folder
+            },
+            errors: {
+                400: 'Bad request (nothing in progress)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @returns unknown Worktrees of the repository
+     * @throws ApiError
+     */
+    public static getApiV1GitWorktrees(data: GetApiV1GitWorktreesData): CancelablePromise<GetApiV1GitWorktreesResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/git/worktrees',
+            query: {
+                folder: data.folder
+            },
+            errors: {
+                400: 'Bad request (not a git repository)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder path for git operations
+     * @param data.path Absolute path of the worktree to remove
+     * @param data.force Remove even if the worktree has uncommitted changes
+     * @returns unknown Worktree removed
+     * @throws ApiError
+     */
+    public static deleteApiV1GitWorktrees(data: DeleteApiV1GitWorktreesData): CancelablePromise<DeleteApiV1GitWorktreesResponse> {
+        return __request(OpenAPI, {
+            method: 'DELETE',
+            url: '/api/v1/git/worktrees',
+            query: {
+                folder: data.folder,
+                path: data.path,
+                force: data.force
+            },
+            errors: {
+                400: 'Bad request (not a worktree created by cuweb, or it has changes)',
+                403: 'Folder is outside the allowed folders',
+                500: 'Internal server error'
+            }
+        });
+    }
+    
+    /**
+     * @returns TerminalSessionList Running terminal sessions
+     * @throws ApiError
+     */
+    public static getApiV1TerminalSessions(): CancelablePromise<GetApiV1TerminalSessionsResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/terminal/sessions'
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.id Terminal session ID
+     * @param data.token Token from the caller's presence messages, only the session's driver can kill it
+     * @returns TerminalSessionAction Session killed
+     * @throws ApiError
+     */
+    public static deleteApiV1TerminalSessionsById(data: DeleteApiV1TerminalSessionsByIdData): CancelablePromise<DeleteApiV1TerminalSessionsByIdResponse> {
+        return __request(OpenAPI, {
+            method: 'DELETE',
+            url: '/api/v1/terminal/sessions/{id}',
+            path: {
+                id: data.id
+            },
+            query: {
+                token: data.token
+            },
+            errors: {
+                404: 'Session not found',
+                409: "The caller is not the session's driver"
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.id Terminal session ID
+     * @param data.requestBody
+     * @returns TerminalSessionAction Signal sent
+     * @throws ApiError
+     */
+    public static postApiV1TerminalSessionsByIdSignal(data: PostApiV1TerminalSessionsByIdSignalData): CancelablePromise<PostApiV1TerminalSessionsByIdSignalResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/terminal/sessions/{id}/signal',
+            path: {
+                id: data.id
+            },
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                404: 'Session not found',
+                409: "The caller is not the session's driver",
+                500: 'The signal could not be sent'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.id Terminal session ID
+     * @returns TerminalRecording Recording started
+     * @throws ApiError
+     */
+    public static postApiV1TerminalSessionsByIdRecording(data: PostApiV1TerminalSessionsByIdRecordingData): CancelablePromise<PostApiV1TerminalSessionsByIdRecordingResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/terminal/sessions/{id}/recording',
+            path: {
+                id: data.id
+            },
+            errors: {
+                404: 'Session not found',
+                409: 'The session is already being recorded',
+                500: 'The recording could not be created'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.id Terminal session ID
+     * @returns TerminalRecording Recording stopped
+     * @throws ApiError
+     */
+    public static deleteApiV1TerminalSessionsByIdRecording(data: DeleteApiV1TerminalSessionsByIdRecordingData): CancelablePromise<DeleteApiV1TerminalSessionsByIdRecordingResponse> {
+        return __request(OpenAPI, {
+            method: 'DELETE',
+            url: '/api/v1/terminal/sessions/{id}/recording',
+            path: {
+                id: data.id
+            },
+            errors: {
+                404: 'Session not found',
+                409: 'The session is not being recorded',
+                500: 'The recording could not be saved'
+            }
+        });
+    }
+    
+    /**
+     * @returns TerminalRecordingList Saved recordings
+     * @throws ApiError
+     */
+    public static getApiV1TerminalRecordings(): CancelablePromise<GetApiV1TerminalRecordingsResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/terminal/recordings',
+            errors: {
+                500: 'The recordings folder could not be read'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.id Recording ID
+     * @returns unknown Recording in asciicast v2 format
+     * @throws ApiError
+     */
+    public static getApiV1TerminalRecordingsById(data: GetApiV1TerminalRecordingsByIdData): CancelablePromise<GetApiV1TerminalRecordingsByIdResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/terminal/recordings/{id}',
+            path: {
+                id: data.id
+            },
+            errors: {
+                400: 'Invalid recording ID',
+                404: 'Recording not found'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.id Recording ID
+     * @returns TerminalSessionAction Recording deleted
+     * @throws ApiError
+     */
+    public static deleteApiV1TerminalRecordingsById(data: DeleteApiV1TerminalRecordingsByIdData): CancelablePromise<DeleteApiV1TerminalRecordingsByIdResponse> {
+        return __request(OpenAPI, {
+            method: 'DELETE',
+            url: '/api/v1/terminal/recordings/{id}',
+            path: {
+                id: data.id
+            },
+            errors: {
+                400: 'Invalid recording ID',
+                404: 'Recording not found',
+                409: 'The recording is still running',
+                500: 'The recording could not be deleted'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder whose repository's snippets are used, and new sessions start in
+     * @returns TerminalSnippetList Available snippets
+     * @throws ApiError
+     */
+    public static getApiV1TerminalSnippets(data: GetApiV1TerminalSnippetsData = {}): CancelablePromise<GetApiV1TerminalSnippetsResponse> {
+        return __request(OpenAPI, {
+            method: 'GET',
+            url: '/api/v1/terminal/snippets',
+            query: {
+                folder: data.folder
+            },
+            errors: {
+                403: 'Folder is outside the allowed folders',
+                500: 'A config file could not be read'
+            }
+        });
+    }
+    
+    /**
+     * @param data The data for the request.
+     * @param data.folder Folder whose repository's snippets are used, and new sessions start in
+     * @param data.requestBody
+     * @returns TerminalSnippetRun Command typed into the session, a new one once it is ready
+     * @throws ApiError
+     */
+    public static postApiV1TerminalSnippetsRun(data: PostApiV1TerminalSnippetsRunData = {}): CancelablePromise<PostApiV1TerminalSnippetsRunResponse> {
+        return __request(OpenAPI, {
+            method: 'POST',
+            url: '/api/v1/terminal/snippets/run',
+            query: {
+                folder: data.folder
+            },
+            body: data.requestBody,
+            mediaType: 'application/json',
+            errors: {
+                400: "A variable is missing or the session can't be started",
+                403: 'Folder is outside the allowed folders',
+                404: 'Snippet or session not found',
+                409: "The caller isn't the driver of the session",
+                500: 'A config file could not be read'
+            }
+        });
+    }
+    
 }
\ No newline at end of file
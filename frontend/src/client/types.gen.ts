@@ -67,120 +67,105 @@ export type Error = {
     };
 };
 
-export type GetApiV1EnvironmentsData = {
-    /**
-     * Path to the container-use CLI
-     */
-    cli?: string;
+export type FileContent = {
     /**
-     * Working folder for the CLI command
+     * Full path to the file
      */
-    folder?: string;
-};
-
-export type GetApiV1EnvironmentsResponse = (EnvironmentListResponse);
-
-export type GetApiV1EnvironmentsByIdLogsData = {
+    path: string;
     /**
-     * Path to the container-use CLI
+     * Total size of the file in bytes
      */
-    cli?: string;
+    size: number;
     /**
-     * Working folder for the CLI command
+     * MIME type guessed from the file extension
      */
-    folder?: string;
+    mimeType: string;
     /**
-     * Environment ID
+     * Whether the file content looks binary
      */
-    id: string;
-};
-
-export type GetApiV1EnvironmentsByIdLogsResponse = (EnvironmentLogs);
-
-export type GetApiV1EnvironmentsByIdDiffData = {
+    binary: boolean;
     /**
-     * Path to the container-use CLI
+     * Encoding of content, base64 for binary files
      */
-    cli?: string;
+    encoding: 'utf-8' | 'base64';
     /**
-     * Working folder for the CLI command
+     * Content of the requested byte range
      */
-    folder?: string;
+    content: string;
     /**
-     * Environment ID
+     * Byte offset the content starts at
      */
-    id: string;
-};
-
-export type GetApiV1EnvironmentsByIdDiffResponse = (EnvironmentDiff);
-
-export type PostApiV1EnvironmentsByIdApplyData = {
+    offset: number;
     /**
-     * Path to the container-use CLI
+     * Number of bytes returned
      */
-    cli?: string;
+    length: number;
     /**
-     * Working folder for the CLI command
+     * Whether the file continues past the returned range
      */
-    folder?: string;
+    truncated: boolean;
     /**
-     * Environment ID
+     * Maximum number of bytes returned per request
      */
-    id: string;
-};
-
-export type PostApiV1EnvironmentsByIdApplyResponse = (EnvironmentApply);
-
-export type PostApiV1EnvironmentsByIdMergeData = {
+    maxSize: number;
     /**
-     * Path to the container-use CLI
+     * Last modified timestamp
      */
-    cli?: string;
+    modified: string;
     /**
-     * Working folder for the CLI command
+     * Entity tag of this version of the file
      */
-    folder?: string;
+    etag: string;
     /**
-     * Environment ID
+     * SHA-256 of the content, only set when the whole file is read
      */
-    id: string;
+    hash?: string;
 };
 
-export type PostApiV1EnvironmentsByIdMergeResponse = (EnvironmentMerge);
-
-export type PostApiV1EnvironmentsByIdCheckoutData = {
+export type FileOperation = {
     /**
-     * Path to the container-use CLI
+     * Operation that was performed
      */
-    cli?: string;
+    operation: 'create' | 'mkdir' | 'move' | 'copy' | 'trash' | 'restore';
     /**
-     * Working folder for the CLI command
+     * Type of the entry
      */
-    folder?: string;
+    type: 'file' | 'folder';
     /**
-     * Environment ID
+     * Original path for move and copy
      */
-    id: string;
-};
-
-export type PostApiV1EnvironmentsByIdCheckoutResponse = (EnvironmentCheckout);
-
-export type GetApiV1FilesData = {
+    source?: string;
     /**
-     * Folder path to list. Defaults to home folder if not provided
+     * Path of the resulting file or folder
      */
-    path?: string;
+    path: string;
+    /**
+     * ID of the item in the trash, for trash and restore
+     */
+    trashId?: string;
 };
 
-export type GetApiV1FilesResponse = ({
+export type FileTree = {
     /**
-     * Current folder path
+     * Full path of the requested folder
      */
     path: string;
     /**
-     * List of files and directories in the current path
+     * Parent folder path, null if at root or outside the roots
      */
-    items: Array<{
+    parent: (string) | null;
+    /**
+     * Number of levels included
+     */
+    depth: number;
+    /**
+     * Root of the git repository, null outside of one
+     */
+    repositoryRoot: (string) | null;
+    /**
+     * Entries of all included levels, folders first and by name within each folder
+     */
+    entries: Array<{
         /**
          * Name of the file or folder
          */
@@ -201,259 +186,1656 @@ export type GetApiV1FilesResponse = ({
          * Last modified timestamp
          */
         modified?: string;
+        /**
+         * Full path of the folder containing the entry
+         */
+        parent: string;
+        /**
+         * Level below the requested folder, 1 for its direct entries
+         */
+        depth: number;
+        /**
+         * Whether the entry is a symbolic link
+         */
+        symlink: boolean;
+        /**
+         * Whether git ignores the entry
+         */
+        ignored: boolean;
+        /**
+         * Git status of the file, or of the changes inside a folder
+         */
+        gitStatus?: 'modified' | 'added' | 'deleted' | 'renamed' | 'untracked' | 'conflicted' | 'ignored';
+        /**
+         * For folders, whether their entries are part of this response. Request the folder itself to load them
+         */
+        childrenLoaded?: boolean;
     }>;
     /**
-     * Parent folder path, null if at root
-     */
-    parent: (string) | null;
-});
-
-export type GetApiV1GitData = {
-    /**
-     * Folder path to get git information for
+     * Whether the entry limit was reached, leaving some folders unloaded
      */
-    folder: string;
+    truncated: boolean;
 };
 
-export type GetApiV1GitResponse = ({
+export type FileUpload = {
     /**
-     * Whether the operation was successful
+     * Folder the files were uploaded into
      */
-    success: boolean;
+    path: string;
     /**
-     * Git repository information
+     * Uploaded files, in the order they were sent
      */
-    data: {
-        /**
-         * Current branch name
-         */
-        currentBranch: string;
+    files: Array<{
         /**
-         * Whether the folder is a git repository
-         */
-        isRepository: boolean;
-        /**
-         * Whether there are uncommitted changes
+         * Full path of the written file
          */
-        hasUncommittedChanges: boolean;
+        path: string;
         /**
-         * List of all branches
+         * Size of the file in bytes
          */
-        branches: Array<{
-            /**
-             * Branch name
-             */
-            name: string;
-            /**
-             * Whether this is the current branch
-             */
-            current: boolean;
-            /**
-             * Whether this is a remote branch
-             */
-            remote: boolean;
-            /**
-             * Upstream branch name
-             */
-            upstream?: string;
-            /**
-             * Number of commits ahead of upstream
-             */
-            ahead?: number;
-            /**
-             * Number of commits behind upstream
-             */
-            behind?: number;
-            /**
-             * Short commit hash
-             */
-            commitHash?: string;
-            /**
-             * Commit message (truncated)
-             */
-            commitMessage?: string;
-            /**
-             * Tracking status (gone, up to date, etc.)
-             */
-            trackingStatus?: string;
-        }>;
-    };
-});
+        size: number;
+    }>;
+};
 
-export type PostApiV1GitCheckoutData = {
+export type FileVersion = {
     /**
-     * Folder path for git operations
+     * Size of the file in bytes
      */
-    folder: string;
-    requestBody?: {
-        /**
-         * Branch name to checkout
-         */
-        branch: string;
-    };
+    size: number;
+    /**
+     * Last modified timestamp
+     */
+    modified: string;
+    /**
+     * Entity tag of this version of the file
+     */
+    etag: string;
+    /**
+     * SHA-256 of the content
+     */
+    hash: string;
 };
 
-export type PostApiV1GitCheckoutResponse = ({
+export type GitConflictStatus = {
     /**
-     * Whether the checkout was successful
+     * Whether a merge, cherry-pick or rebase is in progress
      */
-    success: boolean;
+    inProgress: boolean;
     /**
-     * Success or error message
+     * Operation that stopped on conflicts
      */
-    message: string;
+    operation?: 'merge' | 'cherry-pick' | 'rebase';
     /**
-     * Updated git repository information
+     * Absolute path of the repository root
      */
-    data?: {
-        /**
-         * Current branch name
-         */
-        currentBranch: string;
-        /**
-         * Whether the folder is a git repository
-         */
-        isRepository: boolean;
+    root: string;
+    /**
+     * Branch (or HEAD) the changes are applied to
+     */
+    head?: string;
+    /**
+     * Commit or branch being merged, picked or replayed
+     */
+    incoming?: string;
+    /**
+     * Files that still have unresolved conflicts
+     */
+    files: Array<{
         /**
-         * Whether there are uncommitted changes
+         * File path relative to the repository root
          */
-        hasUncommittedChanges: boolean;
+        path: string;
         /**
-         * List of all branches
+         * How the two sides conflict
          */
-        branches: Array<{
-            /**
-             * Branch name
-             */
-            name: string;
-            /**
-             * Whether this is the current branch
-             */
-            current: boolean;
-            /**
-             * Whether this is a remote branch
-             */
-            remote: boolean;
-            /**
-             * Upstream branch name
-             */
-            upstream?: string;
-            /**
-             * Number of commits ahead of upstream
-             */
-            ahead?: number;
-            /**
-             * Number of commits behind upstream
-             */
-            behind?: number;
-            /**
-             * Short commit hash
-             */
-            commitHash?: string;
-            /**
-             * Commit message (truncated)
-             */
-            commitMessage?: string;
-            /**
-             * Tracking status (gone, up to date, etc.)
-             */
-            trackingStatus?: string;
-        }>;
-    };
-});
+        conflictType: 'both-modified' | 'both-added' | 'deleted-by-us' | 'deleted-by-them' | 'added-by-us' | 'added-by-them' | 'both-deleted';
+    }>;
+};
 
-export type GetApiV1GitLogData = {
-    /**
-     * Branch name to get log for
-     */
-    branch: string;
+export type GitFetchRequest = {
     /**
-     * Folder path for git operations
+     * Remote to fetch from
      */
-    folder: string;
+    remote: string;
     /**
-     * Number of commits to retrieve (default: 10)
+     * Remove remote-tracking refs that no longer exist on the remote
      */
-    limit?: string;
+    prune?: boolean;
 };
 
-export type GetApiV1GitLogResponse = ({
+export type GitPullRequest = {
     /**
-     * Whether the operation was successful
+     * Remote to pull from
      */
-    success: boolean;
+    remote: string;
     /**
-     * Git log information for a branch
+     * Remote branch to pull. Defaults to the configured upstream
      */
+    branch?: string;
+    /**
+     * Refuse to merge and only update if a fast-forward is possible
+     */
+    ffOnly?: boolean;
+};
+
+export type GitPushRequest = {
+    /**
+     * Remote to push to
+     */
+    remote: string;
+    /**
+     * Local branch or ref to push. Defaults to the current branch
+     */
+    branch?: string;
+    /**
+     * Branch name on the remote. Defaults to the local branch name
+     */
+    remoteBranch?: string;
+    /**
+     * Set the pushed branch as upstream of the local branch
+     */
+    setUpstream?: boolean;
+    /**
+     * Force push using --force-with-lease
+     */
+    force?: boolean;
+};
+
+export type GitRefUpdate = {
+    /**
+     * How the ref was updated
+     */
+    status: 'fast-forward' | 'forced' | 'new' | 'deleted' | 'rejected' | 'up-to-date' | 'tag-update';
+    /**
+     * Source ref
+     */
+    from: string;
+    /**
+     * Destination ref
+     */
+    to: string;
+    /**
+     * Summary of the update as reported by git
+     */
+    summary: string;
+    /**
+     * Reason reported by git, e.g. for rejected refs
+     */
+    reason?: string;
+};
+
+export type GitRemoteResult = {
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Remote operation that was run
+     */
+    operation: 'fetch' | 'pull' | 'push';
+    /**
+     * Remote the operation ran against
+     */
+    remote: string;
+    /**
+     * Refs that were created, updated or deleted
+     */
+    updatedRefs: Array<GitRefUpdate>;
+    /**
+     * Refs that git refused to update
+     */
+    rejectedRefs: Array<GitRefUpdate>;
+    /**
+     * Combined git output without progress lines
+     */
+    output: string;
+    /**
+     * Git exit code
+     */
+    exitCode: number;
+};
+
+export type GitStatusFileEntry = {
+    /**
+     * Git status code (M=modified, A=added, D=deleted, R=renamed, etc.)
+     */
+    status: string;
+    /**
+     * File path relative to repository root
+     */
+    path: string;
+    /**
+     * Human-readable description of the change
+     */
+    description: string;
+    /**
+     * Kind of porcelain v2 entry
+     */
+    kind: 'changed' | 'renamed' | 'copied' | 'unmerged' | 'untracked' | 'ignored';
+    /**
+     * Porcelain v2 state letter (.=unmodified, T=type changed, U=unmerged, ?=untracked, !=ignored)
+     */
+    indexStatus: '.' | 'M' | 'T' | 'A' | 'D' | 'R' | 'C' | 'U' | '?' | '!';
+    /**
+     * Porcelain v2 state letter (.=unmodified, T=type changed, U=unmerged, ?=untracked, !=ignored)
+     */
+    worktreeStatus: '.' | 'M' | 'T' | 'A' | 'D' | 'R' | 'C' | 'U' | '?' | '!';
+    /**
+     * Whether the file has changes in the index
+     */
+    staged: boolean;
+    /**
+     * Whether the file has changes in the working tree
+     */
+    unstaged: boolean;
+    /**
+     * Path the file was renamed or copied from
+     */
+    originalPath?: string;
+    /**
+     * Rename or copy similarity score in percent
+     */
+    similarity?: number;
+    /**
+     * How the two sides conflict
+     */
+    conflictType?: 'both-modified' | 'both-added' | 'deleted-by-us' | 'deleted-by-them' | 'added-by-us' | 'added-by-them' | 'both-deleted';
+    submodule?: {
+        /**
+         * Whether the submodule points at a different commit
+         */
+        commitChanged: boolean;
+        /**
+         * Whether the submodule has modified tracked files
+         */
+        trackedChanges: boolean;
+        /**
+         * Whether the submodule has untracked files
+         */
+        untrackedChanges: boolean;
+    };
+    /**
+     * Lines added across staged and unstaged changes
+     */
+    insertions?: number;
+    /**
+     * Lines deleted across staged and unstaged changes
+     */
+    deletions?: number;
+    /**
+     * Whether git treats the file as binary
+     */
+    binary: boolean;
+};
+
+export type GitWorktree = {
+    /**
+     * Absolute path of the worktree
+     */
+    path: string;
+    /**
+     * Short hash of the checked out commit
+     */
+    head: string;
+    /**
+     * Checked out branch, absent when HEAD is detached
+     */
+    branch?: string;
+    /**
+     * Whether this is the main worktree of the repository
+     */
+    main: boolean;
+    /**
+     * Whether the worktree was created by cuweb
+     */
+    managed: boolean;
+    /**
+     * Environment the worktree was created for
+     */
+    environmentId?: string;
+    /**
+     * Whether the worktree is locked against removal
+     */
+    locked: boolean;
+    /**
+     * Whether the worktree folder is missing and can be pruned
+     */
+    prunable: boolean;
+};
+
+export type PathForbidden = {
+    /**
+     * Error message
+     */
+    error: string;
+    /**
+     * Requested path, with symlinks resolved
+     */
+    path: string;
+    /**
+     * Folders the file system API may access
+     */
+    allowedRoots: Array<string>;
+};
+
+export type TerminalParticipant = {
+    /**
+     * ID of the connection, new for every reconnect
+     */
+    id: string;
+    /**
+     * Name the client joined with
+     */
+    name: string;
+    /**
+     * The driver types into the terminal, viewers only see its output
+     */
+    role: 'driver' | 'viewer';
+    /**
+     * When the client attached
+     */
+    joinedAt: string;
+};
+
+export type TerminalRecording = {
+    /**
+     * Recording ID, the name of its .cast file
+     */
+    id: string;
+    /**
+     * What was recorded
+     */
+    title?: string;
+    /**
+     * Terminal columns when the recording started
+     */
+    width: number;
+    /**
+     * Terminal rows when the recording started
+     */
+    height: number;
+    /**
+     * When the recording started
+     */
+    startedAt: string;
+    /**
+     * Length of the recording in seconds
+     */
+    duration: number;
+    /**
+     * Size of the .cast file in bytes
+     */
+    size: number;
+    /**
+     * Whether the session is still being recorded
+     */
+    active: boolean;
+};
+
+export type TerminalRecordingList = {
+    /**
+     * Recordings, newest first
+     */
+    recordings: Array<TerminalRecording>;
+};
+
+export type TerminalSession = {
+    /**
+     * Session ID, used to reattach to the session
+     */
+    id: string;
+    /**
+     * Plain shell, container-use terminal of an environment, or container-use watch
+     */
+    kind: 'shell' | 'environment' | 'watch';
+    /**
+     * Environment the terminal is opened in
+     */
+    environmentId?: string;
+    /**
+     * Folder the session was started in
+     */
+    workingDir: string;
+    /**
+     * Process ID of the session's shell
+     */
+    pid: number;
+    /**
+     * Name of the process in the foreground of the terminal
+     */
+    process: string;
+    /**
+     * When the session was started
+     */
+    startedAt: string;
+    /**
+     * Number of attached browser connections, sessions without any are closed after the idle timeout
+     */
+    clients: number;
+    /**
+     * Attached clients, in the order they joined
+     */
+    participants: Array<TerminalParticipant>;
+    /**
+     * Last input or output of the terminal
+     */
+    lastActivity: string;
+    /**
+     * Recording the session output is written to, if any
+     */
+    recordingId?: string;
+};
+
+export type TerminalSessionAction = {
+    /**
+     * Whether the action succeeded
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+};
+
+export type TerminalSessionList = {
+    /**
+     * Running sessions, oldest first
+     */
+    sessions: Array<TerminalSession>;
+};
+
+export type TerminalSignalRequest = {
+    /**
+     * Signal to send to the foreground process
+     */
+    signal: 'SIGINT' | 'SIGTERM' | 'SIGKILL' | 'SIGHUP' | 'SIGQUIT' | 'SIGTSTP' | 'SIGCONT' | 'SIGUSR1' | 'SIGUSR2';
+    /**
+     * Token from the caller's presence messages, only the session's driver can signal it
+     */
+    token?: string;
+};
+
+export type TerminalSnippet = {
+    /**
+     * Name of the snippet
+     */
+    name: string;
+    /**
+     * Command template, {{name}} placeholders are variables
+     */
+    command: string;
+    /**
+     * What the snippet does
+     */
+    description?: string;
+    /**
+     * Whether the snippet comes from the repository's .cuweb.json or the global config
+     */
+    source: 'repository' | 'global';
+    /**
+     * Variables to ask for, environmentId and folder are filled in from the terminal
+     */
+    variables: Array<string>;
+};
+
+export type TerminalSnippetList = {
+    /**
+     * Snippets of the repository and global ones, by name
+     */
+    snippets: Array<TerminalSnippet>;
+};
+
+export type TerminalSnippetRun = {
+    /**
+     * Session the command was typed into
+     */
+    sessionId: string;
+    /**
+     * Command with the variables filled in
+     */
+    command: string;
+    /**
+     * Whether the session was started for the snippet
+     */
+    created: boolean;
+};
+
+export type TerminalSnippetRunRequest = {
+    /**
+     * Name of the snippet to run
+     */
+    name: string;
+    /**
+     * Session to type the command into, a new session is started without it
+     */
+    sessionId?: string;
+    /**
+     * Token from the caller's presence messages, required with a session as only its driver can type into it
+     */
+    token?: string;
+    /**
+     * Environment to open the new session in, a plain shell without it
+     */
+    environmentId?: string;
+    /**
+     * Values of the snippet's variables
+     */
+    variables?: {
+        [key: string]: string;
+    };
+};
+
+export type GetApiV1EnvironmentsData = {
+    /**
+     * Path to the container-use CLI
+     */
+    cli?: string;
+    /**
+     * Working folder for the CLI command
+     */
+    folder?: string;
+};
+
+export type GetApiV1EnvironmentsResponse = (EnvironmentListResponse);
+
+export type GetApiV1EnvironmentsByIdLogsData = {
+    /**
+     * Path to the container-use CLI
+     */
+    cli?: string;
+    /**
+     * Working folder for the CLI command
+     */
+    folder?: string;
+    /**
+     * Environment ID
+     */
+    id: string;
+};
+
+export type GetApiV1EnvironmentsByIdLogsResponse = (EnvironmentLogs);
+
+export type GetApiV1EnvironmentsByIdDiffData = {
+    /**
+     * Path to the container-use CLI
+     */
+    cli?: string;
+    /**
+     * Working folder for the CLI command
+     */
+    folder?: string;
+    /**
+     * Environment ID
+     */
+    id: string;
+};
+
+export type GetApiV1EnvironmentsByIdDiffResponse = (EnvironmentDiff);
+
+export type PostApiV1EnvironmentsByIdApplyData = {
+    /**
+     * Path to the container-use CLI
+     */
+    cli?: string;
+    /**
+     * Working folder for the CLI command
+     */
+    folder?: string;
+    /**
+     * Environment ID
+     */
+    id: string;
+};
+
+export type PostApiV1EnvironmentsByIdApplyResponse = (EnvironmentApply);
+
+export type PostApiV1EnvironmentsByIdMergeData = {
+    /**
+     * Path to the container-use CLI
+     */
+    cli?: string;
+    /**
+     * Working folder for the CLI command
+     */
+    folder?: string;
+    /**
+     * Environment ID
+     */
+    id: string;
+};
+
+export type PostApiV1EnvironmentsByIdMergeResponse = (EnvironmentMerge);
+
+export type PostApiV1EnvironmentsByIdCheckoutData = {
+    /**
+     * Path to the container-use CLI
+     */
+    cli?: string;
+    /**
+     * Working folder for the CLI command
+     */
+    folder?: string;
+    /**
+     * Environment ID
+     */
+    id: string;
+};
+
+export type PostApiV1EnvironmentsByIdCheckoutResponse = (EnvironmentCheckout);
+
+export type PostApiV1EnvironmentsByIdPushData = {
+    /**
+     * Working folder for the CLI command
+     */
+    folder?: string;
+    /**
+     * Environment ID
+     */
+    id: string;
+    requestBody?: {
+        /**
+         * Remote to push the environment branch to
+         */
+        remote: string;
+        /**
+         * Branch name on the remote. Defaults to cu-<environment id>
+         */
+        remoteBranch?: string;
+    };
+};
+
+export type PostApiV1EnvironmentsByIdPushResponse = (GitRemoteResult);
+
+export type PostApiV1EnvironmentsByIdWorktreeData = {
+    /**
+     * Working folder for the CLI command
+     */
+    folder?: string;
+    /**
+     * Environment ID
+     */
+    id: string;
+};
+
+export type PostApiV1EnvironmentsByIdWorktreeResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    data?: GitWorktree;
+});
+
+export type GetApiV1FilesData = {
+    /**
+     * Folder path to list. Defaults to the working directory if not provided
+     */
+    path?: string;
+};
+
+export type GetApiV1FilesResponse = ({
+    /**
+     * Current folder path
+     */
+    path: string;
+    /**
+     * List of files and directories in the current path
+     */
+    items: Array<{
+        /**
+         * Name of the file or folder
+         */
+        name: string;
+        /**
+         * Full path to the file or folder
+         */
+        path: string;
+        /**
+         * Type of the entry
+         */
+        type: 'file' | 'folder';
+        /**
+         * Size of the file in bytes (only for files)
+         */
+        size?: number;
+        /**
+         * Last modified timestamp
+         */
+        modified?: string;
+    }>;
+    /**
+     * Parent folder path, null if at root
+     */
+    parent: (string) | null;
+});
+
+export type GetApiV1FilesTreeData = {
+    /**
+     * Number of levels to list
+     */
+    depth?: number;
+    /**
+     * Folder to list. Defaults to the working directory if not provided
+     */
+    path?: string;
+    /**
+     * Also list files and folders ignored by git
+     */
+    showIgnored?: 'true' | 'false';
+};
+
+export type GetApiV1FilesTreeResponse = (FileTree);
+
+export type GetApiV1FilesContentData = {
+    /**
+     * Number of bytes to read. Defaults to and is capped at the maximum file size
+     */
+    length?: number;
+    /**
+     * Byte offset to start reading at
+     */
+    offset?: number;
+    /**
+     * File path to read
+     */
+    path: string;
+};
+
+export type GetApiV1FilesContentResponse = (FileContent);
+
+export type PutApiV1FilesContentData = {
+    requestBody?: {
+        /**
+         * File path to write
+         */
+        path: string;
+        /**
+         * New content of the file as UTF-8 text
+         */
+        content: string;
+        /**
+         * Last modified timestamp the client loaded. The save is rejected if the file changed
+         */
+        expectedModified?: string;
+        /**
+         * SHA-256 of the content the client loaded. The save is rejected if the file changed
+         */
+        expectedHash?: string;
+    };
+};
+
+export type PutApiV1FilesContentResponse = ({
+    /**
+     * Whether the file was saved
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    data: FileVersion;
+});
+
+export type GetApiV1FilesRawData = {
+    /**
+     * File path to read
+     */
+    path: string;
+};
+
+export type GetApiV1FilesRawResponse = ((Blob | File));
+
+export type PostApiV1FilesCreateData = {
+    requestBody?: {
+        /**
+         * Path of the new file or folder
+         */
+        path: string;
+        /**
+         * Whether to create a file or a folder
+         */
+        type: 'file' | 'folder';
+        /**
+         * Initial content of a new file
+         */
+        content?: string;
+    };
+};
+
+export type PostApiV1FilesCreateResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    data: FileOperation;
+});
+
+export type PostApiV1FilesMoveData = {
+    requestBody?: {
+        /**
+         * Path of the file or folder to move or copy
+         */
+        source: string;
+        /**
+         * New path, including the file or folder name
+         */
+        destination: string;
+        /**
+         * Move an existing destination to the trash instead of failing
+         */
+        overwrite?: boolean;
+    };
+};
+
+export type PostApiV1FilesMoveResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    data: FileOperation;
+});
+
+export type PostApiV1FilesCopyData = {
+    requestBody?: {
+        /**
+         * Path of the file or folder to move or copy
+         */
+        source: string;
+        /**
+         * New path, including the file or folder name
+         */
+        destination: string;
+        /**
+         * Move an existing destination to the trash instead of failing
+         */
+        overwrite?: boolean;
+    };
+};
+
+export type PostApiV1FilesCopyResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    data: FileOperation;
+});
+
+export type PostApiV1FilesTrashData = {
+    requestBody?: {
+        /**
+         * Path of the file or folder to delete
+         */
+        path: string;
+    };
+};
+
+export type PostApiV1FilesTrashResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    data: FileOperation;
+});
+
+export type GetApiV1FilesTrashResponse = ({
+    /**
+     * Trashed items, most recently deleted first
+     */
+    items: Array<{
+        /**
+         * ID of the trashed item
+         */
+        id: string;
+        /**
+         * Path the item was deleted from
+         */
+        originalPath: string;
+        /**
+         * Type of the entry
+         */
+        type: 'file' | 'folder';
+        /**
+         * When the item was moved to the trash
+         */
+        deletedAt: string;
+    }>;
+});
+
+export type PostApiV1FilesTrashRestoreData = {
+    requestBody?: {
+        /**
+         * ID of the trashed item
+         */
+        id: string;
+        /**
+         * Move a file now at the original path to the trash instead of failing
+         */
+        overwrite?: boolean;
+    };
+};
+
+export type PostApiV1FilesTrashRestoreResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    data: FileOperation;
+});
+
+export type GetApiV1FilesDownloadData = {
+    /**
+     * Archive format for folders. Defaults to zip
+     */
+    format?: 'zip' | 'tar.gz';
+    /**
+     * File or folder to download
+     */
+    path: string;
+};
+
+export type GetApiV1FilesDownloadResponse = ((Blob | File));
+
+export type PostApiV1FilesUploadData = {
+    requestBody?: {
+        /**
+         * Folder to upload into
+         */
+        path: string;
+        /**
+         * Move existing files to the trash instead of failing
+         */
+        overwrite?: 'true' | 'false';
+        /**
+         * Files to upload. File names may include folders, e.g. data/users.json
+         */
+        files?: Array<(Blob | File)>;
+    };
+};
+
+export type PostApiV1FilesUploadResponse = ({
+    /**
+     * Whether all files were uploaded
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    data: FileUpload;
+});
+
+export type GetApiV1GitData = {
+    /**
+     * Folder path to get git information for
+     */
+    folder: string;
+};
+
+export type GetApiV1GitResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Git repository information
+     */
+    data: {
+        /**
+         * Current branch name
+         */
+        currentBranch: string;
+        /**
+         * Whether the folder is a git repository
+         */
+        isRepository: boolean;
+        /**
+         * Whether there are uncommitted changes
+         */
+        hasUncommittedChanges: boolean;
+        /**
+         * List of all branches
+         */
+        branches: Array<{
+            /**
+             * Branch name
+             */
+            name: string;
+            /**
+             * Whether this is the current branch
+             */
+            current: boolean;
+            /**
+             * Whether this is a remote branch
+             */
+            remote: boolean;
+            /**
+             * Upstream branch name
+             */
+            upstream?: string;
+            /**
+             * Number of commits ahead of upstream
+             */
+            ahead?: number;
+            /**
+             * Number of commits behind upstream
+             */
+            behind?: number;
+            /**
+             * Short commit hash
+             */
+            commitHash?: string;
+            /**
+             * Commit message (truncated)
+             */
+            commitMessage?: string;
+            /**
+             * Tracking status (gone, up to date, etc.)
+             */
+            trackingStatus?: string;
+        }>;
+    };
+});
+
+export type PostApiV1GitCheckoutData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+    requestBody?: {
+        /**
+         * Local branch, remote branch, tag or commit to checkout
+         */
+        branch: string;
+        /**
+         * Checkout in detached HEAD state. Tags and commits are always detached
+         */
+        detach?: boolean;
+    };
+};
+
+export type PostApiV1GitCheckoutResponse = ({
+    /**
+     * Whether the checkout was successful
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    /**
+     * Kind of ref that was checked out, as resolved by git
+     */
+    refType?: 'local' | 'remote' | 'tag' | 'commit';
+    /**
+     * Local branch now checked out, absent when HEAD is detached
+     */
+    branch?: string;
+    /**
+     * Whether HEAD is now detached
+     */
+    detached?: boolean;
+    /**
+     * Updated git repository information
+     */
+    data?: {
+        /**
+         * Current branch name
+         */
+        currentBranch: string;
+        /**
+         * Whether the folder is a git repository
+         */
+        isRepository: boolean;
+        /**
+         * Whether there are uncommitted changes
+         */
+        hasUncommittedChanges: boolean;
+        /**
+         * List of all branches
+         */
+        branches: Array<{
+            /**
+             * Branch name
+             */
+            name: string;
+            /**
+             * Whether this is the current branch
+             */
+            current: boolean;
+            /**
+             * Whether this is a remote branch
+             */
+            remote: boolean;
+            /**
+             * Upstream branch name
+             */
+            upstream?: string;
+            /**
+             * Number of commits ahead of upstream
+             */
+            ahead?: number;
+            /**
+             * Number of commits behind upstream
+             */
+            behind?: number;
+            /**
+             * Short commit hash
+             */
+            commitHash?: string;
+            /**
+             * Commit message (truncated)
+             */
+            commitMessage?: string;
+            /**
+             * Tracking status (gone, up to date, etc.)
+             */
+            trackingStatus?: string;
+        }>;
+    };
+});
+
+export type GetApiV1GitLogData = {
+    /**
+     * Branch name to get log for
+     */
+    branch: string;
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+    /**
+     * Number of commits to retrieve (default: 10)
+     */
+    limit?: string;
+};
+
+export type GetApiV1GitLogResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Git log information for a branch
+     */
+    data: {
+        /**
+         * Branch name
+         */
+        branch: string;
+        /**
+         * List of recent commits
+         */
+        commits: Array<{
+            /**
+             * Commit hash (short)
+             */
+            hash: string;
+            /**
+             * Commit message
+             */
+            message: string;
+            /**
+             * Commit author
+             */
+            author: string;
+            /**
+             * Commit date in ISO format
+             */
+            date: string;
+            /**
+             * Relative time from now
+             */
+            relative: string;
+        }>;
+    };
+});
+
+export type GetApiV1GitStatusData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+    /**
+     * Also list ignored files
+     */
+    ignored?: 'true' | 'false';
+};
+
+export type GetApiV1GitStatusResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Detailed git status information
+     */
+    data: {
+        /**
+         * Whether there are any uncommitted changes
+         */
+        hasChanges: boolean;
+        branch: {
+            /**
+             * Current branch, or (detached)
+             */
+            head: string;
+            /**
+             * Short commit hash of HEAD, or (initial)
+             */
+            oid: string;
+            /**
+             * Upstream branch
+             */
+            upstream?: string;
+            /**
+             * Commits ahead of upstream
+             */
+            ahead?: number;
+            /**
+             * Commits behind upstream
+             */
+            behind?: number;
+        };
+        /**
+         * List of files with changes
+         */
+        files: Array<GitStatusFileEntry>;
+    };
+});
+
+export type GetApiV1GitStatusDiffData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+    /**
+     * File path relative to the repository root
+     */
+    path: string;
+    /**
+     * Diff the index against HEAD instead of the worktree
+     */
+    staged?: 'true' | 'false';
+};
+
+export type GetApiV1GitStatusDiffResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
     data: {
         /**
-         * Branch name
+         * File path relative to repository root
+         */
+        path: string;
+        /**
+         * Path the file was renamed or copied from
+         */
+        originalPath?: string;
+        /**
+         * Whether the diff is of the index instead of the worktree
+         */
+        staged: boolean;
+        /**
+         * Whether the file is untracked and diffed as new
+         */
+        untracked: boolean;
+        /**
+         * Whether git treats the file as binary
          */
-        branch: string;
+        binary: boolean;
         /**
-         * List of recent commits
+         * Unified diff of the file
          */
-        commits: Array<{
-            /**
-             * Commit hash (short)
-             */
-            hash: string;
-            /**
-             * Commit message
-             */
-            message: string;
-            /**
-             * Commit author
-             */
-            author: string;
-            /**
-             * Commit date in ISO format
-             */
-            date: string;
-            /**
-             * Relative time from now
-             */
-            relative: string;
-        }>;
+        diff: string;
     };
 });
 
-export type GetApiV1GitStatusData = {
+export type GetApiV1GitRemotesData = {
     /**
      * Folder path for git operations
      */
     folder: string;
 };
 
-export type GetApiV1GitStatusResponse = ({
+export type GetApiV1GitRemotesResponse = ({
     /**
      * Whether the operation was successful
      */
     success: boolean;
     /**
-     * Detailed git status information
+     * Configured remotes
+     */
+    data: Array<{
+        /**
+         * Remote name
+         */
+        name: string;
+        /**
+         * URL used for fetching
+         */
+        fetchUrl: string;
+        /**
+         * URL used for pushing
+         */
+        pushUrl: string;
+    }>;
+});
+
+export type PostApiV1GitFetchData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+    requestBody?: GitFetchRequest;
+};
+
+export type PostApiV1GitFetchResponse = (GitRemoteResult);
+
+export type PostApiV1GitPullData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+    requestBody?: GitPullRequest;
+};
+
+export type PostApiV1GitPullResponse = (GitRemoteResult);
+
+export type PostApiV1GitPushData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+    requestBody?: GitPushRequest;
+};
+
+export type PostApiV1GitPushResponse = (GitRemoteResult);
+
+export type GetApiV1GitConflictsData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+};
+
+export type GetApiV1GitConflictsResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    data: GitConflictStatus;
+});
+
+export type GetApiV1GitConflictsFileData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+    /**
+     * Conflicted file path relative to the repository root
+     */
+    path: string;
+};
+
+export type GetApiV1GitConflictsFileResponse = ({
+    /**
+     * Whether the operation was successful
      */
+    success: boolean;
     data: {
         /**
-         * Whether there are any uncommitted changes
+         * File path relative to the repository root
          */
-        hasChanges: boolean;
+        path: string;
         /**
-         * List of files with changes
+         * Common ancestor version, absent if the file was added
          */
-        files: Array<{
-            /**
-             * Git status code (M=modified, A=added, D=deleted, R=renamed, etc.)
-             */
-            status: string;
-            /**
-             * File path relative to repository root
-             */
-            path: string;
-            /**
-             * Human-readable description of the change
-             */
-            description: string;
-        }>;
+        base?: string;
+        /**
+         * Version on the current branch, absent if deleted there
+         */
+        ours?: string;
+        /**
+         * Incoming version, absent if deleted there
+         */
+        theirs?: string;
+        /**
+         * Working tree version including conflict markers
+         */
+        merged?: string;
+    };
+});
+
+export type PostApiV1GitConflictsResolveData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+    requestBody?: {
+        /**
+         * Conflicted file path relative to the repository root
+         */
+        path: string;
+        /**
+         * Pick one side, or use the provided content
+         */
+        resolution: 'ours' | 'theirs' | 'content';
+        /**
+         * Resolved file content, required for content resolutions
+         */
+        content?: string;
     };
-});
\ No newline at end of file
+};
+
+export type PostApiV1GitConflictsResolveResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    /**
+     * Git output
+     */
+    output: string;
+    data: GitConflictStatus;
+});
+
+export type PostApiV1GitConflictsContinueData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+};
+
+export type PostApiV1GitConflictsContinueResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    /**
+     * Git output
+     */
+    output: string;
+    data: GitConflictStatus;
+});
+
+export type PostApiV1GitConflictsAbortData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+};
+
+export type PostApiV1GitConflictsAbortResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    /**
+     * Git output
+     */
+    output: string;
+    data: GitConflictStatus;
+});
+
+export type GetApiV1GitWorktreesData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+};
+
+export type GetApiV1GitWorktreesResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Worktrees of the repository, main worktree first
+     */
+    data: Array<GitWorktree>;
+});
+
+export type DeleteApiV1GitWorktreesData = {
+    /**
+     * Folder path for git operations
+     */
+    folder: string;
+    /**
+     * Remove even if the worktree has uncommitted changes
+     */
+    force?: 'true' | 'false';
+    /**
+     * Absolute path of the worktree to remove
+     */
+    path: string;
+};
+
+export type DeleteApiV1GitWorktreesResponse = ({
+    /**
+     * Whether the operation was successful
+     */
+    success: boolean;
+    /**
+     * Success or error message
+     */
+    message: string;
+    data?: GitWorktree;
+});
+
+export type GetApiV1TerminalSessionsResponse = (TerminalSessionList);
+
+export type DeleteApiV1TerminalSessionsByIdData = {
+    /**
+     * Terminal session ID
+     */
+    id: string;
+    /**
+     * Token from the caller's presence messages, only the session's driver can kill it
+     */
+    token?: string;
+};
+
+export type DeleteApiV1TerminalSessionsByIdResponse = (TerminalSessionAction);
+
+export type PostApiV1TerminalSessionsByIdSignalData = {
+    /**
+     * Terminal session ID
+     */
+    id: string;
+    requestBody?: TerminalSignalRequest;
+};
+
+export type PostApiV1TerminalSessionsByIdSignalResponse = (TerminalSessionAction);
+
+export type PostApiV1TerminalSessionsByIdRecordingData = {
+    /**
+     * Terminal session ID
+     */
+    id: string;
+};
+
+export type PostApiV1TerminalSessionsByIdRecordingResponse = (TerminalRecording);
+
+export type DeleteApiV1TerminalSessionsByIdRecordingData = {
+    /**
+     * Terminal session ID
+     */
+    id: string;
+};
+
+export type DeleteApiV1TerminalSessionsByIdRecordingResponse = (TerminalRecording);
+
+export type GetApiV1TerminalRecordingsResponse = (TerminalRecordingList);
+
+export type GetApiV1TerminalRecordingsByIdData = {
+    /**
+     * Recording ID
+     */
+    id: string;
+};
+
+export type GetApiV1TerminalRecordingsByIdResponse = ((Blob | File));
+
+export type DeleteApiV1TerminalRecordingsByIdData = {
+    /**
+     * Recording ID
+     */
+    id: string;
+};
+
+export type DeleteApiV1TerminalRecordingsByIdResponse = (TerminalSessionAction);
+
+export type GetApiV1TerminalSnippetsData = {
+    /**
+     * Folder whose repository's snippets are used, and new sessions start in
+     */
+    folder?: string;
+};
+
+export type GetApiV1TerminalSnippetsResponse = (TerminalSnippetList);
+
+export type PostApiV1TerminalSnippetsRunData = {
+    /**
+     * Folder whose repository's snippets are used, and new sessions start in
+     */
+    folder?: string;
+    requestBody?: TerminalSnippetRunRequest;
+};
+
+export type PostApiV1TerminalSnippetsRunResponse = (TerminalSnippetRun);
\ No newline at end of file
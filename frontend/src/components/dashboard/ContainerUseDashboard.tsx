@@ -1,3 +1,4 @@
+import { useQueryClient } from "@tanstack/react-query"
 import { useNavigate } from "@tanstack/react-router"
 import {
     Eye,
@@ -8,6 +9,7 @@ import {
     Plug,
     RefreshCw,
     Server,
+    SquareTerminal,
     Terminal,
 } from "lucide-react"
 import { lazy, Suspense, useCallback, useState } from "react"
@@ -43,6 +45,11 @@ const LogViewer = lazy(() =>
         default: module.LogViewer,
     })),
 )
+const SessionsViewer = lazy(() =>
+    import("./sections/SessionsViewer").then((module) => ({
+        default: module.SessionsViewer,
+    })),
+)
 const TerminalViewer = lazy(() =>
     import("./sections/TerminalViewer").then((module) => ({
         default: module.TerminalViewer,
@@ -87,6 +94,7 @@ export function ContainerUseDashboard({
     cli,
 }: ContainerUseDashboardProps) {
     const navigate = useNavigate()
+    const queryClient = useQueryClient()
 
     const [activeViews, setActiveViews] = useState<ActiveViews>({
         terminal: null,
@@ -96,6 +104,9 @@ export function ContainerUseDashboard({
 
     const [watchConnected, setWatchConnected] = useState<boolean>(false)
 
+    // Folder of the plain shell shown when no environment terminal is open
+    const [terminalFolder, setTerminalFolder] = useState<string | null>(null)
+
     const [environmentStatus, setEnvironmentStatus] = useState<{
         hasEnvironments: boolean
         isLoading: boolean
@@ -108,6 +119,9 @@ export function ContainerUseDashboard({
 
     const handleViewAction = useCallback(
         (environmentId: string, viewType: ViewType) => {
+            if (viewType === "terminal") {
+                setTerminalFolder(null)
+            }
             setActiveViews((prev) => ({
                 ...prev,
                 [viewType]:
@@ -129,7 +143,8 @@ export function ContainerUseDashboard({
     }, [])
 
     const handleTerminalReload = useCallback((environmentId: string) => {
-        // Force reload the terminal by clearing and setting the active view
+        // Remount the terminal, it reattaches to its running session and
+        // replays the output
         setActiveViews((prev) => ({
             ...prev,
             terminal: null,
@@ -178,15 +193,30 @@ export function ContainerUseDashboard({
                         })
                     console.log("Checkout result:", result)
                     // You could add a toast notification here for success
+                } else if (actionType === "worktree") {
+                    const result =
+                        await DefaultService.postApiV1EnvironmentsByIdWorktree({
+                            id: environmentId,
+                            ...(folder && { folder }),
+                        })
+                    // Show the new worktree in the workspace
+                    queryClient.invalidateQueries({
+                        queryKey: ["git-worktrees"],
+                    })
                 }
             } catch (error) {
                 console.error(`Failed to ${actionType} environment:`, error)
                 // You could add a toast notification here for error
             }
         },
-        [folder, cli],
+        [folder, cli, queryClient],
     )
 
+    const handleOpenTerminal = useCallback((terminalPath: string) => {
+        setActiveViews((prev) => ({ ...prev, terminal: null }))
+        setTerminalFolder(terminalPath)
+    }, [])
+
     const handleWorkspaceFolderChange = useCallback(
         (newFolder: string) => {
             // If no environments are currently loaded (empty or error state),
@@ -272,6 +302,9 @@ export function ContainerUseDashboard({
                                                 onShowEnvironments={
                                                     handleShowEnvironments
                                                 }
+                                                onOpenTerminal={
+                                                    handleOpenTerminal
+                                                }
                                             />
                                         </Suspense>
                                     </CardContent>
@@ -373,7 +406,6 @@ export function ContainerUseDashboard({
                                             >
                                                 <WatchViewer
                                                     folder={folder}
-                                                    cli={cli}
                                                     connected={watchConnected}
                                                 />
                                             </Suspense>
@@ -432,6 +464,20 @@ export function ContainerUseDashboard({
                                                         </Button>
                                                     </>
                                                 )}
+                                                {!activeViews.terminal &&
+                                                    terminalFolder && (
+                                                        <Badge
+                                                            variant="outline"
+                                                            className="text-xs font-mono px-2 py-0.5 max-w-64 truncate"
+                                                            title={
+                                                                terminalFolder
+                                                            }
+                                                        >
+                                                            {terminalFolder
+                                                                .split("/")
+                                                                .pop()}
+                                                        </Badge>
+                                                    )}
                                             </div>
                                         </CardTitle>
                                     </CardHeader>
@@ -461,7 +507,9 @@ export function ContainerUseDashboard({
                                                         activeViews.terminal
                                                     }
                                                     folder={folder}
-                                                    cli={cli}
+                                                    shellFolder={
+                                                        terminalFolder ?? folder
+                                                    }
                                                 />
                                             </Suspense>
                                         )}
@@ -473,14 +521,14 @@ export function ContainerUseDashboard({
 
                     <ResizableHandle />
 
-                    {/* Right Section (1/4) - Environment + Git + Log + Diff */}
+                    {/* Right Section (1/4) - Environment + Git + Log + Diff + Sessions */}
                     <ResizablePanel defaultSize={25} minSize={25} maxSize={40}>
                         <ResizablePanelGroup
                             direction="vertical"
                             className="h-full"
                         >
                             {/* Environment Section */}
-                            <ResizablePanel defaultSize={25} minSize={15}>
+                            <ResizablePanel defaultSize={20} minSize={10}>
                                 <Card className="h-full rounded-none border-l border-r-0 border-t-0 border-b-0">
                                     <CardHeader>
                                         <CardTitle className="text-lg flex items-center gap-2">
@@ -511,7 +559,7 @@ export function ContainerUseDashboard({
                             <ResizableHandle />
 
                             {/* Git Section */}
-                            <ResizablePanel defaultSize={25} minSize={15}>
+                            <ResizablePanel defaultSize={20} minSize={10}>
                                 <Card className="h-full rounded-none border-l border-t border-r-0 border-b-0">
                                     <CardHeader>
                                         <CardTitle className="text-lg flex items-center gap-2">
@@ -551,7 +599,7 @@ export function ContainerUseDashboard({
                             <ResizableHandle />
 
                             {/* Log Section */}
-                            <ResizablePanel defaultSize={25} minSize={15}>
+                            <ResizablePanel defaultSize={20} minSize={10}>
                                 <Card
                                     className={`h-full rounded-none border-l border-t border-r-0 border-b-0 ${shouldDisableViews ? "opacity-50" : ""}`}
                                 >
@@ -618,7 +666,7 @@ export function ContainerUseDashboard({
                             <ResizableHandle />
 
                             {/* Diff Section */}
-                            <ResizablePanel defaultSize={25} minSize={15}>
+                            <ResizablePanel defaultSize={20} minSize={10}>
                                 <Card
                                     className={`h-full rounded-none border-l border-t border-r-0 border-b-0 ${shouldDisableViews ? "opacity-50" : ""}`}
                                 >
@@ -681,6 +729,26 @@ export function ContainerUseDashboard({
                                     </CardContent>
                                 </Card>
                             </ResizablePanel>
+
+                            <ResizableHandle />
+
+                            {/* Sessions Section */}
+                            <ResizablePanel defaultSize={20} minSize={10}>
+                                <Card className="h-full rounded-none border-l border-t border-r-0 border-b-0">
+                                    <CardHeader>
+                                        <CardTitle className="text-lg flex items-center gap-2">
+                                            <SquareTerminal className="h-5 w-5" />
+                                            Sessions
+                                        </CardTitle>
+                                    </CardHeader>
+                                    <Separator />
+                                    <CardContent className="p-0 h-[calc(100%-4rem)] overflow-hidden">
+                                        <Suspense fallback={<SectionLoader />}>
+                                            <SessionsViewer />
+                                        </Suspense>
+                                    </CardContent>
+                                </Card>
+                            </ResizablePanel>
                         </ResizablePanelGroup>
                     </ResizablePanel>
                 </ResizablePanelGroup>
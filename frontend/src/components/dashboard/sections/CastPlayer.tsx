@@ -0,0 +1,311 @@
+import { Terminal } from "@xterm/xterm"
+import { ArrowLeft, Pause, Play, RefreshCw, RotateCcw } from "lucide-react"
+import {
+    type ChangeEvent,
+    useCallback,
+    useEffect,
+    useRef,
+    useState,
+} from "react"
+import "@xterm/xterm/css/xterm.css"
+import { Button } from "@/components/ui/button"
+import { type Cast, parseCast, recordingUrl } from "@/lib/terminal-recording"
+import { TERMINAL_OPTIONS } from "@/lib/xterm"
+
+const SPEEDS = [0.5, 1, 2, 4, 8]
+
+// Longest pause kept when skipping pauses, in seconds
+const SKIPPED_PAUSE = 1
+
+// Longest wait between updates of the progress bar, in milliseconds
+const PROGRESS_INTERVAL = 100
+
+function formatTime(seconds: number): string {
+    const whole = Math.floor(seconds)
+    return `${Math.floor(whole / 60)}:${String(whole % 60).padStart(2, "0")}`
+}
+
+// Apply a "COLSxROWS" resize event
+function resizeTerminal(terminal: Terminal, size: string) {
+    const match = /^(\d+)x(\d+)$/.exec(size)
+    if (match) {
+        terminal.resize(Number(match[1]), Number(match[2]))
+    }
+}
+
+interface CastPlayerProps {
+    recordingId: string
+    title?: string
+    onClose: () => void
+}
+
+export function CastPlayer({ recordingId, title, onClose }: CastPlayerProps) {
+    const containerRef = useRef<HTMLDivElement>(null)
+    const terminalRef = useRef<Terminal | null>(null)
+    // Next event to play and the time played so far
+    const indexRef = useRef(0)
+    const positionRef = useRef(0)
+
+    const [text, setText] = useState<string | null>(null)
+    const [cast, setCast] = useState<Cast | null>(null)
+    const [error, setError] = useState<string | null>(null)
+    const [isPlaying, setIsPlaying] = useState(false)
+    const [speed, setSpeed] = useState(1)
+    const [skipPauses, setSkipPauses] = useState(true)
+    const [position, setPosition] = useState(0)
+
+    // Download the recording
+    useEffect(() => {
+        let isCancelled = false
+        setText(null)
+        setError(null)
+        fetch(recordingUrl(recordingId))
+            .then((response) => {
+                if (!response.ok) {
+                    throw new Error(
+                        `Failed to load recording: ${response.status}`,
+                    )
+                }
+                return response.text()
+            })
+            .then((content) => {
+                if (!isCancelled) setText(content)
+            })
+            .catch((err) => {
+                if (!isCancelled) {
+                    setError(
+                        err instanceof Error ? err.message : "Unknown error",
+                    )
+                }
+            })
+        return () => {
+            isCancelled = true
+        }
+    }, [recordingId])
+
+    useEffect(() => {
+        if (text === null) return
+        try {
+            setCast(parseCast(text, skipPauses ? SKIPPED_PAUSE : Infinity))
+        } catch (err) {
+            setError(err instanceof Error ? err.message : "Invalid recording")
+        }
+    }, [text, skipPauses])
+
+    // Apply the events up to a time at once, e.g. to seek
+    const seek = useCallback(
+        (time: number) => {
+            const terminal = terminalRef.current
+            if (!terminal || !cast) return
+
+            terminal.reset()
+            terminal.resize(cast.width, cast.height)
+            let output = ""
+            let index = 0
+            for (; index < cast.events.length; index++) {
+                const event = cast.events[index]
+                if (event.time > time) break
+                if (event.code === "o") {
+                    output += event.data
+                } else if (event.code === "r") {
+                    terminal.write(output)
+                    output = ""
+                    resizeTerminal(terminal, event.data)
+                }
+            }
+            terminal.write(output)
+            indexRef.current = index
+            positionRef.current = time
+            setPosition(time)
+        },
+        [cast],
+    )
+
+    // The terminal is sized like the recording, larger ones scroll
+    useEffect(() => {
+        if (!containerRef.current || !cast) return
+
+        const terminal = new Terminal({
+            ...TERMINAL_OPTIONS,
+            cursorBlink: false,
+            disableStdin: true,
+            cols: cast.width,
+            rows: cast.height,
+        })
+        terminal.open(containerRef.current)
+        terminalRef.current = terminal
+        indexRef.current = 0
+        positionRef.current = 0
+        setPosition(0)
+
+        return () => {
+            terminal.dispose()
+            terminalRef.current = null
+        }
+    }, [cast])
+
+    // Play events in real time, scaled by the speed
+    useEffect(() => {
+        const terminal = terminalRef.current
+        if (!isPlaying || !terminal || !cast) return
+
+        let timeout: ReturnType<typeof setTimeout>
+        let last = performance.now()
+        const tick = () => {
+            const now = performance.now()
+            positionRef.current += ((now - last) / 1000) * speed
+            last = now
+
+            const { events } = cast
+            while (
+                indexRef.current < events.length &&
+                events[indexRef.current].time <= positionRef.current
+            ) {
+                const event = events[indexRef.current]
+                if (event.code === "o") {
+                    terminal.write(event.data)
+                } else if (event.code === "r") {
+                    resizeTerminal(terminal, event.data)
+                }
+                indexRef.current++
+            }
+
+            if (indexRef.current >= events.length) {
+                positionRef.current = cast.duration
+                setPosition(cast.duration)
+                setIsPlaying(false)
+                return
+            }
+            setPosition(positionRef.current)
+            const wait =
+                ((events[indexRef.current].time - positionRef.current) * 1000) /
+                speed
+            timeout = setTimeout(tick, Math.min(wait, PROGRESS_INTERVAL))
+        }
+        timeout = setTimeout(tick, 0)
+
+        return () => clearTimeout(timeout)
+    }, [isPlaying, speed, cast])
+
+    const handlePlayPause = () => {
+        if (!cast) return
+        if (!isPlaying && positionRef.current >= cast.duration) {
+            seek(0)
+        }
+        setIsPlaying((playing) => !playing)
+    }
+
+    const handleRestart = () => {
+        seek(0)
+        setIsPlaying(true)
+    }
+
+    const handleSeek = (event: ChangeEvent<HTMLInputElement>) => {
+        seek(Number(event.target.value))
+    }
+
+    const handleSkipPauses = () => {
+        // Times change with the pauses, so start over
+        setIsPlaying(false)
+        setSkipPauses((value) => !value)
+    }
+
+    return (
+        <div className="h-full flex flex-col">
+            <div className="px-3 py-2 border-b bg-muted/30 space-y-2">
+                <div className="flex items-center gap-2">
+                    <Button
+                        variant="ghost"
+                        size="sm"
+                        className="h-6 w-6 p-0"
+                        onClick={onClose}
+                        title="Back to recordings"
+                    >
+                        <ArrowLeft className="h-3 w-3" />
+                    </Button>
+                    <span
+                        className="text-xs font-medium truncate"
+                        title={recordingId}
+                    >
+                        {title || recordingId}
+                    </span>
+                </div>
+                <div className="flex flex-wrap items-center gap-1">
+                    <Button
+                        variant="outline"
+                        size="sm"
+                        className="h-6 w-6 p-0"
+                        onClick={handlePlayPause}
+                        disabled={!cast}
+                        title={isPlaying ? "Pause" : "Play"}
+                    >
+                        {isPlaying ? (
+                            <Pause className="h-3 w-3" />
+                        ) : (
+                            <Play className="h-3 w-3" />
+                        )}
+                    </Button>
+                    <Button
+                        variant="ghost"
+                        size="sm"
+                        className="h-6 w-6 p-0"
+                        onClick={handleRestart}
+                        disabled={!cast}
+                        title="Play from the start"
+                    >
+                        <RotateCcw className="h-3 w-3" />
+                    </Button>
+                    {SPEEDS.map((value) => (
+                        <Button
+                            key={value}
+                            variant={speed === value ? "secondary" : "ghost"}
+                            size="sm"
+                            onClick={() => setSpeed(value)}
+                            className="h-6 px-2 text-xs"
+                        >
+                            {value}x
+                        </Button>
+                    ))}
+                    <Button
+                        variant={skipPauses ? "secondary" : "ghost"}
+                        size="sm"
+                        onClick={handleSkipPauses}
+                        className="h-6 px-2 text-xs"
+                        title={`Shorten pauses to ${SKIPPED_PAUSE}s`}
+                    >
+                        Skip pauses
+                    </Button>
+                </div>
+                <div className="flex items-center gap-2 text-xs text-muted-foreground font-mono">
+                    <span>{formatTime(position)}</span>
+                    <input
+                        type="range"
+                        min={0}
+                        max={cast?.duration || 0}
+                        step={0.1}
+                        value={position}
+                        onChange={handleSeek}
+                        disabled={!cast}
+                        className="flex-1"
+                        aria-label="Position"
+                    />
+                    <span>{formatTime(cast?.duration || 0)}</span>
+                </div>
+            </div>
+            <div className="flex-1 overflow-auto bg-black">
+                {error ? (
+                    <div className="m-3 text-sm text-red-600 p-3 bg-red-50 border border-red-200 rounded">
+                        <strong>Error:</strong> {error}
+                    </div>
+                ) : !cast ? (
+                    <div className="text-sm text-muted-foreground flex items-center justify-center h-20">
+                        <RefreshCw className="animate-spin h-4 w-4 mr-2" />
+                        Loading recording...
+                    </div>
+                ) : (
+                    <div ref={containerRef} className="inline-block" />
+                )}
+            </div>
+        </div>
+    )
+}
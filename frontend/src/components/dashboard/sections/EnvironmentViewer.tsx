@@ -2,6 +2,7 @@ import { useQuery } from "@tanstack/react-query"
 import {
     Container,
     FileText,
+    FolderGit2,
     GitBranch,
     GitCompare,
     GitMerge,
@@ -26,7 +27,7 @@ import {
 } from "@/components/ui/tooltip"
 
 type ViewType = "terminal" | "logs" | "diff"
-export type ActionType = "apply" | "merge" | "checkout"
+export type ActionType = "apply" | "merge" | "checkout" | "worktree"
 
 interface ActiveViews {
     terminal: string | null
@@ -505,6 +506,54 @@ export function EnvironmentViewer({
                                                     </TooltipContent>
                                                 </Tooltip>
                                             </TooltipProvider>
+
+                                            <TooltipProvider>
+                                                <Tooltip>
+                                                    <TooltipTrigger asChild>
+                                                        <Button
+                                                            variant="ghost"
+                                                            size="sm"
+                                                            className="h-6 w-6 p-0 rounded hover:bg-amber-100 hover:text-amber-700 transition-all relative"
+                                                            disabled={
+                                                                actionInProgress[
+                                                                    env.id || ""
+                                                                ] === "worktree"
+                                                            }
+                                                            onClick={(e) => {
+                                                                e.stopPropagation()
+                                                                env.id &&
+                                                                    handleEnvironmentAction(
+                                                                        env.id,
+                                                                        "worktree",
+                                                                    )
+                                                            }}
+                                                        >
+                                                            {actionInProgress[
+                                                                env.id || ""
+                                                            ] === "worktree" ? (
+                                                                <Loader2 className="h-3 w-3 animate-spin" />
+                                                            ) : (
+                                                                <FolderGit2 className="h-3 w-3" />
+                                                            )}
+                                                            {actionInProgress[
+                                                                env.id || ""
+                                                            ] ===
+                                                                "worktree" && (
+                                                                <div className="absolute inset-0 bg-amber-500/20 rounded animate-pulse" />
+                                                            )}
+                                                        </Button>
+                                                    </TooltipTrigger>
+                                                    <TooltipContent>
+                                                        <p>
+                                                            {actionInProgress[
+                                                                env.id || ""
+                                                            ] === "worktree"
+                                                                ? "Opening Worktree..."
+                                                                : "Open in Worktree"}
+                                                        </p>
+                                                    </TooltipContent>
+                                                </Tooltip>
+                                            </TooltipProvider>
                                         </div>
 
                                         {/* Monitoring Actions Group (Right) */}
@@ -2,7 +2,10 @@ import { useQuery } from "@tanstack/react-query"
 import {
     AlertTriangle,
     ArrowDown,
+    ArrowDownToLine,
     ArrowUp,
+    ArrowUpFromLine,
+    CloudDownload,
     FileEdit,
     GitBranch,
     Globe,
@@ -17,7 +20,9 @@ import {
     DefaultService,
     type GetApiV1GitLogResponse,
     type GetApiV1GitResponse,
+    type GetApiV1GitStatusDiffResponse,
     type GetApiV1GitStatusResponse,
+    type GitRemoteResult,
 } from "@/client"
 import { Badge } from "@/components/ui/badge"
 import { Button } from "@/components/ui/button"
@@ -30,6 +35,21 @@ import {
 
 type GitBranchType = GetApiV1GitResponse["data"]["branches"][number]
 
+type GitRemoteOperation = "fetch" | "pull" | "push"
+
+interface GitFileDiffState {
+    key: string
+    diff?: GetApiV1GitStatusDiffResponse["data"]
+    error?: string
+}
+
+interface GitRemoteMessage {
+    type: "progress" | "result" | "error"
+    line?: string
+    result?: GitRemoteResult
+    error?: string
+}
+
 interface GitViewerProps {
     folder?: string
 }
@@ -48,6 +68,14 @@ export function GitViewer({ folder }: GitViewerProps) {
     >(null)
     const [loadingStatus, setLoadingStatus] = useState(false)
     const [showStatusTooltip, setShowStatusTooltip] = useState(false)
+    const [fileDiff, setFileDiff] = useState<GitFileDiffState | null>(null)
+    const [remoteOperation, setRemoteOperation] =
+        useState<GitRemoteOperation | null>(null)
+    const [remoteProgress, setRemoteProgress] = useState<string | null>(null)
+    const [remoteResult, setRemoteResult] = useState<GitRemoteResult | null>(
+        null,
+    )
+    const [remoteError, setRemoteError] = useState<string | null>(null)
 
     const {
         data: gitResponse,
@@ -66,7 +94,23 @@ export function GitViewer({ folder }: GitViewerProps) {
         enabled: !!folder, // Only run query if folder is provided
     })
 
+    const { data: remotesResponse } = useQuery({
+        queryKey: ["git-remotes", folder],
+        queryFn: () =>
+            DefaultService.getApiV1GitRemotes({
+                folder: folder || "",
+            }),
+        retry: false,
+        refetchOnWindowFocus: false,
+        enabled: !!folder,
+    })
+
     const gitStatus = gitResponse?.success ? gitResponse.data : null
+    const remotes = remotesResponse?.success ? remotesResponse.data : []
+    // Prefer "origin" when the repository has several remotes
+    const defaultRemote =
+        remotes.find((remote) => remote.name === "origin")?.name ??
+        remotes[0]?.name
     const branches = gitStatus?.branches || []
 
     // Sort branches: current first, then local branches alphabetically, then remote branches
@@ -175,6 +219,7 @@ export function GitViewer({ folder }: GitViewerProps) {
 
             if (response.success) {
                 setGitStatusData(response.data)
+                setFileDiff(null)
                 setShowStatusTooltip(true)
             }
         } catch (err) {
@@ -184,6 +229,93 @@ export function GitViewer({ folder }: GitViewerProps) {
         }
     }, [folder, loadingStatus, gitStatusData, showStatusTooltip])
 
+    // Toggle the staged or unstaged diff of a single file in the status list
+    const handleShowFileDiff = useCallback(
+        async (filePath: string, staged: boolean) => {
+            if (!folder) return
+
+            const key = `${filePath}:${staged ? "staged" : "unstaged"}`
+            if (fileDiff?.key === key) {
+                setFileDiff(null)
+                return
+            }
+
+            setFileDiff({ key })
+            try {
+                const response = await DefaultService.getApiV1GitStatusDiff({
+                    folder,
+                    path: filePath,
+                    staged: staged ? "true" : "false",
+                })
+                setFileDiff({ key, diff: response.data })
+            } catch (err) {
+                console.error("Failed to get file diff:", err)
+                setFileDiff({ key, error: "Failed to load diff" })
+            }
+        },
+        [folder, fileDiff],
+    )
+
+    // Run fetch/pull/push over a WebSocket so git's progress can be shown live
+    const handleRemoteOperation = useCallback(
+        (operation: GitRemoteOperation) => {
+            if (!folder || !defaultRemote || remoteOperation) return
+
+            setRemoteOperation(operation)
+            setRemoteProgress(null)
+            setRemoteResult(null)
+            setRemoteError(null)
+
+            const params = new URLSearchParams({
+                folder,
+                operation,
+                remote: defaultRemote,
+            })
+            if (operation === "pull") {
+                // Never create merge commits from the dashboard
+                params.set("ffOnly", "true")
+            }
+
+            const ws = new WebSocket(
+                `ws://localhost:8000/api/v1/git/remote?${params.toString()}`,
+            )
+
+            ws.onmessage = (event) => {
+                try {
+                    const message: GitRemoteMessage = JSON.parse(event.data)
+
+                    switch (message.type) {
+                        case "progress":
+                            setRemoteProgress(message.line || null)
+                            break
+                        case "result":
+                            setRemoteResult(message.result || null)
+                            break
+                        case "error":
+                            setRemoteError(message.error || "Unknown error")
+                            break
+                    }
+                } catch (err) {
+                    console.error("Failed to parse git remote message:", err)
+                }
+            }
+
+            ws.onclose = (event) => {
+                if (event.code !== 1000) {
+                    setRemoteError(event.reason || `git ${operation} failed`)
+                }
+                setRemoteOperation(null)
+                setRemoteProgress(null)
+                refetch()
+            }
+
+            ws.onerror = (err) => {
+                console.error("Git remote WebSocket error:", err)
+            }
+        },
+        [folder, defaultRemote, remoteOperation, refetch],
+    )
+
     // Update last updated timestamp when git data changes
     useEffect(() => {
         if (gitResponse && !isLoading) {
@@ -307,6 +439,45 @@ export function GitViewer({ folder }: GitViewerProps) {
                                 </div>
                             </TooltipContent>
                         </Tooltip>
+                        <Button
+                            onClick={() => handleRemoteOperation("fetch")}
+                            size="sm"
+                            variant="outline"
+                            disabled={!defaultRemote || !!remoteOperation}
+                            className="h-7 px-2"
+                            title={`Fetch from ${defaultRemote ?? ""}`}
+                        >
+                            <CloudDownload
+                                className={`h-3 w-3 mr-1 ${remoteOperation === "fetch" ? "animate-pulse" : ""}`}
+                            />
+                            Fetch
+                        </Button>
+                        <Button
+                            onClick={() => handleRemoteOperation("pull")}
+                            size="sm"
+                            variant="outline"
+                            disabled={!defaultRemote || !!remoteOperation}
+                            className="h-7 px-2"
+                            title={`Pull (fast-forward only) from ${defaultRemote ?? ""}`}
+                        >
+                            <ArrowDownToLine
+                                className={`h-3 w-3 mr-1 ${remoteOperation === "pull" ? "animate-pulse" : ""}`}
+                            />
+                            Pull
+                        </Button>
+                        <Button
+                            onClick={() => handleRemoteOperation("push")}
+                            size="sm"
+                            variant="outline"
+                            disabled={!defaultRemote || !!remoteOperation}
+                            className="h-7 px-2"
+                            title={`Push to ${defaultRemote ?? ""}`}
+                        >
+                            <ArrowUpFromLine
+                                className={`h-3 w-3 mr-1 ${remoteOperation === "push" ? "animate-pulse" : ""}`}
+                            />
+                            Push
+                        </Button>
                         <Button
                             onClick={toggleAutoRefresh}
                             size="sm"
@@ -595,13 +766,136 @@ export function GitViewer({ folder }: GitViewerProps) {
                                                                                                     file.path
                                                                                                 }
                                                                                             </div>
-                                                                                            <div className="text-xs text-muted-foreground mt-1 capitalize">
-                                                                                                {
-                                                                                                    file.description
-                                                                                                }
+                                                                                            {file.originalPath && (
+                                                                                                <div className="text-xs text-muted-foreground break-all">
+                                                                                                    from{" "}
+                                                                                                    {
+                                                                                                        file.originalPath
+                                                                                                    }
+                                                                                                </div>
+                                                                                            )}
+                                                                                            <div className="text-xs text-muted-foreground mt-1 flex items-center gap-2 flex-wrap">
+                                                                                                <span className="capitalize">
+                                                                                                    {
+                                                                                                        file.description
+                                                                                                    }
+                                                                                                </span>
+                                                                                                {file.binary ? (
+                                                                                                    <span>
+                                                                                                        binary
+                                                                                                    </span>
+                                                                                                ) : (
+                                                                                                    file.insertions !==
+                                                                                                        undefined && (
+                                                                                                        <span className="font-mono">
+                                                                                                            <span className="text-green-600">
+                                                                                                                +
+                                                                                                                {
+                                                                                                                    file.insertions
+                                                                                                                }
+                                                                                                            </span>{" "}
+                                                                                                            <span className="text-red-600">
+                                                                                                                −
+                                                                                                                {
+                                                                                                                    file.deletions
+                                                                                                                }
+                                                                                                            </span>
+                                                                                                        </span>
+                                                                                                    )
+                                                                                                )}
+                                                                                                {file.staged &&
+                                                                                                    file.kind !==
+                                                                                                        "unmerged" && (
+                                                                                                        <button
+                                                                                                            type="button"
+                                                                                                            className="underline hover:text-foreground"
+                                                                                                            onClick={() =>
+                                                                                                                handleShowFileDiff(
+                                                                                                                    file.path,
+                                                                                                                    true,
+                                                                                                                )
+                                                                                                            }
+                                                                                                        >
+                                                                                                            Staged
+                                                                                                        </button>
+                                                                                                    )}
+                                                                                                {file.unstaged && (
+                                                                                                    <button
+                                                                                                        type="button"
+                                                                                                        className="underline hover:text-foreground"
+                                                                                                        onClick={() =>
+                                                                                                            handleShowFileDiff(
+                                                                                                                file.path,
+                                                                                                                false,
+                                                                                                            )
+                                                                                                        }
+                                                                                                    >
+                                                                                                        {file.kind ===
+                                                                                                        "untracked"
+                                                                                                            ? "Diff"
+                                                                                                            : "Unstaged"}
+                                                                                                    </button>
+                                                                                                )}
                                                                                             </div>
                                                                                         </div>
                                                                                     </div>
+                                                                                    {fileDiff?.key.startsWith(
+                                                                                        `${file.path}:`,
+                                                                                    ) && (
+                                                                                        <div className="max-h-48 overflow-auto border rounded bg-white text-[11px] font-mono whitespace-pre">
+                                                                                            {fileDiff.error ? (
+                                                                                                <div className="p-2 text-red-600">
+                                                                                                    {
+                                                                                                        fileDiff.error
+                                                                                                    }
+                                                                                                </div>
+                                                                                            ) : !fileDiff.diff ? (
+                                                                                                <div className="p-2 text-muted-foreground">
+                                                                                                    Loading
+                                                                                                    diff...
+                                                                                                </div>
+                                                                                            ) : (
+                                                                                                fileDiff.diff.diff
+                                                                                                    .split(
+                                                                                                        "\n",
+                                                                                                    )
+                                                                                                    .map(
+                                                                                                        (
+                                                                                                            line,
+                                                                                                            lineIndex,
+                                                                                                        ) => (
+                                                                                                            <div
+                                                                                                                key={`${lineIndex}-${line}`}
+                                                                                                                className={`px-1 ${
+                                                                                                                    line.startsWith(
+                                                                                                                        "+",
+                                                                                                                    ) &&
+                                                                                                                    !line.startsWith(
+                                                                                                                        "+++",
+                                                                                                                    )
+                                                                                                                        ? "text-green-700 bg-green-50"
+                                                                                                                        : line.startsWith(
+                                                                                                                                "-",
+                                                                                                                            ) &&
+                                                                                                                            !line.startsWith(
+                                                                                                                                "---",
+                                                                                                                            )
+                                                                                                                          ? "text-red-700 bg-red-50"
+                                                                                                                          : line.startsWith(
+                                                                                                                                  "@@",
+                                                                                                                              )
+                                                                                                                            ? "text-blue-700"
+                                                                                                                            : "text-gray-700"
+                                                                                                                }`}
+                                                                                                            >
+                                                                                                                {line ||
+                                                                                                                    " "}
+                                                                                                            </div>
+                                                                                                        ),
+                                                                                                    )
+                                                                                            )}
+                                                                                        </div>
+                                                                                    )}
                                                                                 </div>
                                                                             </div>
                                                                         )
@@ -619,6 +913,40 @@ export function GitViewer({ folder }: GitViewerProps) {
                         )}
                     </div>
                 )}
+                {(remoteOperation || remoteResult || remoteError) && (
+                    <div className="text-xs mt-1 font-mono truncate">
+                        {remoteOperation ? (
+                            <span className="text-muted-foreground">
+                                git {remoteOperation}: {remoteProgress || "…"}
+                            </span>
+                        ) : remoteError ? (
+                            <span className="text-red-600">{remoteError}</span>
+                        ) : remoteResult ? (
+                            <span
+                                className={
+                                    remoteResult.success &&
+                                    !remoteResult.rejectedRefs.length
+                                        ? "text-green-700"
+                                        : "text-red-600"
+                                }
+                                title={remoteResult.output}
+                            >
+                                git {remoteResult.operation}{" "}
+                                {remoteResult.remote}:{" "}
+                                {remoteResult.updatedRefs.length} updated
+                                {remoteResult.rejectedRefs.length > 0 &&
+                                    `, ${remoteResult.rejectedRefs.length} rejected (${remoteResult.rejectedRefs
+                                        .map(
+                                            (ref) =>
+                                                `${ref.from}: ${ref.reason || ref.summary}`,
+                                        )
+                                        .join(", ")})`}
+                                {!remoteResult.success &&
+                                    ` (exit code ${remoteResult.exitCode})`}
+                            </span>
+                        ) : null}
+                    </div>
+                )}
             </div>
 
             {/* Branch List Content */}
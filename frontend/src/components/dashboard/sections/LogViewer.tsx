@@ -1,8 +1,11 @@
-import { useQuery } from "@tanstack/react-query"
-import { RefreshCw, RotateCcw } from "lucide-react"
+import { Radio, RefreshCw } from "lucide-react"
 import { useEffect, useRef, useState } from "react"
-import { DefaultService } from "@/client"
 import { Button } from "@/components/ui/button"
+import {
+    applyCLIStreamMessage,
+    type CLIStreamMessage,
+    cliStreamUrl,
+} from "@/lib/cli-stream"
 
 interface LogViewerProps {
     environmentId: string | null
@@ -11,41 +14,71 @@ interface LogViewerProps {
 }
 
 export function LogViewer({ environmentId, folder, cli }: LogViewerProps) {
-    const [autoRefresh, setAutoRefresh] = useState(false)
+    // Keep the logs open and add new entries as the agent commits
+    const [follow, setFollow] = useState(true)
+    // Bumped to load the logs again
+    const [reloads, setReloads] = useState(0)
+    const [logs, setLogs] = useState<string | null>(null)
+    const [updatedAt, setUpdatedAt] = useState<string | null>(null)
+    const [error, setError] = useState<string | null>(null)
+    const [isLoading, setIsLoading] = useState(false)
     const containerRef = useRef<HTMLDivElement>(null)
 
-    const {
-        data: logData,
-        isLoading,
-        error,
-        refetch,
-    } = useQuery({
-        queryKey: ["environmentLogs", environmentId, folder, cli],
-        queryFn: () => {
-            if (!environmentId) throw new Error("Environment ID is required")
-            return DefaultService.getApiV1EnvironmentsByIdLogs({
-                id: environmentId,
-                ...(folder && { folder }),
-                ...(cli && { cli }),
-            })
-        },
-        enabled: !!environmentId,
-        refetchInterval: autoRefresh ? 30000 : false, // Refresh every 30 seconds when auto-refresh is enabled
-        retry: false,
-        refetchOnWindowFocus: false,
-    })
+    // biome-ignore lint/correctness/useExhaustiveDependencies: reloads reconnects on purpose
+    useEffect(() => {
+        if (!environmentId) return
+
+        let isDisposed = false
+        let output = ""
+        setLogs(null)
+        setError(null)
+        setIsLoading(true)
+
+        const websocket = new WebSocket(
+            cliStreamUrl("logs", environmentId, { folder, cli, follow }),
+        )
+        websocket.onmessage = (event) => {
+            const message = JSON.parse(event.data) as CLIStreamMessage
+            switch (message.type) {
+                case "error":
+                    setError(message.error)
+                    setIsLoading(false)
+                    break
+                case "end":
+                    setUpdatedAt(message.timestamp)
+                    setIsLoading(false)
+                    break
+                default:
+                    output = applyCLIStreamMessage(output, message)
+                    setLogs(output)
+                    setError(null)
+            }
+        }
+        websocket.onclose = (event) => {
+            if (isDisposed) return
+            setIsLoading(false)
+            if (event.code !== 1000) {
+                setError(event.reason || "Connection to the server lost")
+            }
+        }
+
+        return () => {
+            isDisposed = true
+            websocket.close(1000, "Logs closed")
+        }
+    }, [environmentId, folder, cli, follow, reloads])
 
     const handleManualRefresh = () => {
-        refetch()
+        setReloads((count) => count + 1)
     }
 
-    const toggleAutoRefresh = () => {
-        setAutoRefresh((prev) => !prev)
+    const toggleFollow = () => {
+        setFollow((prev) => !prev)
     }
 
     // Auto-scroll to bottom when new data arrives
     useEffect(() => {
-        if (logData && containerRef.current) {
+        if (logs && containerRef.current) {
             setTimeout(() => {
                 if (containerRef.current) {
                     containerRef.current.scrollTop =
@@ -53,7 +86,7 @@ export function LogViewer({ environmentId, folder, cli }: LogViewerProps) {
                 }
             }, 0)
         }
-    }, [logData])
+    }, [logs])
 
     if (!environmentId) {
         return (
@@ -76,24 +109,25 @@ export function LogViewer({ environmentId, folder, cli }: LogViewerProps) {
             {/* Controls Header */}
             <div className="px-3 py-2 border-b bg-muted/30">
                 <div className="flex items-center justify-between">
-                    {logData && (
+                    {updatedAt && (
                         <div className="text-xs text-muted-foreground">
                             Last updated:{" "}
-                            {new Date(logData.timestamp).toLocaleTimeString()}
-                            {autoRefresh && " (auto-refresh enabled)"}
+                            {new Date(updatedAt).toLocaleTimeString()}
+                            {follow && " (following new entries)"}
                         </div>
                     )}
                     <div className="flex items-center gap-2">
                         <Button
-                            onClick={toggleAutoRefresh}
+                            onClick={toggleFollow}
                             size="sm"
-                            variant={autoRefresh ? "default" : "outline"}
+                            variant={follow ? "default" : "outline"}
                             className="h-7 px-2"
+                            title="Add new log entries as the agent commits"
                         >
-                            <RotateCcw
-                                className={`h-3 w-3 mr-1 ${autoRefresh ? "animate-spin" : ""}`}
+                            <Radio
+                                className={`h-3 w-3 mr-1 ${follow ? "animate-pulse" : ""}`}
                             />
-                            Auto
+                            Follow
                         </Button>
                         <Button
                             onClick={handleManualRefresh}
@@ -115,22 +149,22 @@ export function LogViewer({ environmentId, folder, cli }: LogViewerProps) {
                 <div className="p-4">
                     {error ? (
                         <div className="text-sm text-red-600 p-3 bg-red-50 border border-red-200 rounded">
-                            <strong>Error:</strong> {error.message}
+                            <strong>Error:</strong> {error}
                         </div>
-                    ) : isLoading && !logData ? (
+                    ) : isLoading && !logs ? (
                         <div className="text-sm text-muted-foreground flex items-center justify-center h-20">
                             <RefreshCw className="animate-spin h-4 w-4 mr-2" />
                             Loading logs...
                         </div>
-                    ) : logData ? (
+                    ) : logs !== null ? (
                         <div className="border rounded-md bg-white overflow-hidden">
-                            {logData.logs.trim() === "" ? (
+                            {logs.trim() === "" ? (
                                 <div className="p-4 text-sm text-muted-foreground text-center bg-gray-50">
                                     No logs available
                                 </div>
                             ) : (
                                 <div className="space-y-1 p-3">
-                                    {logData.logs
+                                    {logs
                                         .split("\n")
                                         .map((line: string, index: number) => (
                                             <div
@@ -0,0 +1,183 @@
+import { useQuery } from "@tanstack/react-query"
+import { Download, Play, RefreshCw, Trash2 } from "lucide-react"
+import { useState } from "react"
+import { DefaultService, type TerminalRecording } from "@/client"
+import { Badge } from "@/components/ui/badge"
+import { Button } from "@/components/ui/button"
+import { apiErrorMessage } from "@/lib/api-error"
+import { recordingUrl } from "@/lib/terminal-recording"
+import { CastPlayer } from "./CastPlayer"
+
+function formatDuration(seconds: number): string {
+    const whole = Math.round(seconds)
+    if (whole < 60) return `${whole}s`
+    return `${Math.floor(whole / 60)}m ${whole % 60}s`
+}
+
+function formatSize(bytes: number): string {
+    if (bytes < 1024) return `${bytes} B`
+    if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`
+    return `${(bytes / (1024 * 1024)).toFixed(1)} MB`
+}
+
+// Start a browser download of a recording's .cast file
+function downloadRecording(id: string) {
+    const link = document.createElement("a")
+    link.href = recordingUrl(id)
+    link.download = `${id}.cast`
+    document.body.appendChild(link)
+    link.click()
+    link.remove()
+}
+
+export function RecordingsViewer() {
+    const [playing, setPlaying] = useState<TerminalRecording | null>(null)
+    const [error, setError] = useState<string | null>(null)
+
+    const { data, isLoading, error: listError, refetch } = useQuery({
+        queryKey: ["terminal-recordings"],
+        queryFn: () => DefaultService.getApiV1TerminalRecordings(),
+        refetchInterval: 5000,
+        retry: false,
+        refetchOnWindowFocus: false,
+        enabled: !playing,
+    })
+    const recordings = data?.recordings || []
+
+    const handleDelete = async (recording: TerminalRecording) => {
+        if (
+            !window.confirm(
+                `Delete the recording "${recording.title || recording.id}"?`,
+            )
+        ) {
+            return
+        }
+        try {
+            await DefaultService.deleteApiV1TerminalRecordingsById({
+                id: recording.id,
+            })
+            setError(null)
+        } catch (err) {
+            setError(apiErrorMessage(err))
+        } finally {
+            refetch()
+        }
+    }
+
+    if (playing) {
+        return (
+            <CastPlayer
+                recordingId={playing.id}
+                title={playing.title}
+                onClose={() => setPlaying(null)}
+            />
+        )
+    }
+
+    return (
+        <div className="h-full flex flex-col">
+            {error && (
+                <div className="px-3 py-2 border-b text-xs text-red-600 break-all">
+                    {error}
+                </div>
+            )}
+            <div className="flex-1 overflow-auto">
+                {listError ? (
+                    <div className="m-3 text-sm text-red-600 p-3 bg-red-50 border border-red-200 rounded">
+                        <strong>Error:</strong> {listError.message}
+                    </div>
+                ) : isLoading && !data ? (
+                    <div className="text-sm text-muted-foreground flex items-center justify-center h-20">
+                        <RefreshCw className="animate-spin h-4 w-4 mr-2" />
+                        Loading recordings...
+                    </div>
+                ) : recordings.length === 0 ? (
+                    <div className="p-4 text-sm text-muted-foreground text-center">
+                        No recordings yet, record a session from the
+                        sessions list
+                    </div>
+                ) : (
+                    <div className="divide-y">
+                        {recordings.map((recording) => (
+                            <div
+                                key={recording.id}
+                                className="px-3 py-2 hover:bg-muted/30"
+                            >
+                                <div className="flex items-center gap-2">
+                                    <span
+                                        className="text-sm font-medium truncate"
+                                        title={recording.id}
+                                    >
+                                        {recording.title || recording.id}
+                                    </span>
+                                    {recording.active && (
+                                        <Badge
+                                            variant="outline"
+                                            className="text-[10px] px-1.5 py-0 border-red-300 text-red-700"
+                                        >
+                                            Recording
+                                        </Badge>
+                                    )}
+                                    <div className="ml-auto flex items-center gap-1">
+                                        <Button
+                                            variant="ghost"
+                                            size="sm"
+                                            className="h-6 w-6 p-0"
+                                            onClick={() =>
+                                                setPlaying(recording)
+                                            }
+                                            title="Replay"
+                                        >
+                                            <Play className="h-3 w-3" />
+                                        </Button>
+                                        <Button
+                                            variant="ghost"
+                                            size="sm"
+                                            className="h-6 w-6 p-0"
+                                            onClick={() =>
+                                                downloadRecording(recording.id)
+                                            }
+                                            title="Download .cast file"
+                                        >
+                                            <Download className="h-3 w-3" />
+                                        </Button>
+                                        <Button
+                                            variant="ghost"
+                                            size="sm"
+                                            className="h-6 w-6 p-0 text-muted-foreground hover:text-red-600 hover:bg-red-50"
+                                            onClick={() =>
+                                                handleDelete(recording)
+                                            }
+                                            disabled={recording.active}
+                                            title={
+                                                recording.active
+                                                    ? "Stop the recording to delete it"
+                                                    : "Delete recording"
+                                            }
+                                        >
+                                            <Trash2 className="h-3 w-3" />
+                                        </Button>
+                                    </div>
+                                </div>
+                                <div className="mt-1 flex flex-wrap items-center gap-x-3 gap-y-0.5 text-xs text-muted-foreground">
+                                    <span>
+                                        {new Date(
+                                            recording.startedAt,
+                                        ).toLocaleString()}
+                                    </span>
+                                    <span>
+                                        {formatDuration(recording.duration)}
+                                    </span>
+                                    <span>{formatSize(recording.size)}</span>
+                                    <span>
+                                        {recording.width}x{recording.height}
+                                    </span>
+                                </div>
+                            </div>
+                        ))}
+                    </div>
+                )}
+            </div>
+        </div>
+    )
+}
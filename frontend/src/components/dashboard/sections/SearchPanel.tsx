@@ -0,0 +1,342 @@
+import { CaseSensitive, FileIcon, Loader2, Regex, X } from "lucide-react"
+import { useEffect, useRef, useState } from "react"
+import { Button } from "@/components/ui/button"
+
+type SearchMode = "name" | "content"
+
+interface ContentMatch {
+    line: number
+    column: number
+    length: number
+    text: string
+    textOffset: number
+    before: string[]
+    after: string[]
+}
+
+type SearchMessage =
+    | { type: "name"; path: string; score: number; indices: number[] }
+    | { type: "content"; path: string; matches: ContentMatch[] }
+    | {
+          type: "done"
+          filesSearched: number
+          results: number
+          truncated: boolean
+          durationMs: number
+      }
+    | { type: "error"; error: string }
+
+type SearchResult = Extract<SearchMessage, { type: "name" | "content" }>
+type SearchSummary = Extract<SearchMessage, { type: "done" }>
+
+export interface SearchHitPosition {
+    line: number
+    column: number
+    length: number
+}
+
+interface SearchPanelProps {
+    // Folder to search, results are relative to it
+    root: string
+    onOpen: (filePath: string, position?: SearchHitPosition) => void
+    onClose?: () => void
+}
+
+// Lines of context shown around content matches
+const CONTEXT_LINES = 2
+
+function joinPath(folder: string, relativePath: string): string {
+    return folder === "/" ? `/${relativePath}` : `${folder}/${relativePath}`
+}
+
+// Bold the characters a fuzzy file name match picked
+function HighlightedPath({
+    path,
+    indices,
+}: {
+    path: string
+    indices: number[]
+}) {
+    const picked = new Set(indices)
+    // Runs of picked or unpicked characters, keyed by where they start
+    const runs: Array<{ start: number; text: string; picked: boolean }> = []
+    for (let index = 0; index < path.length; index++) {
+        const last = runs[runs.length - 1]
+        if (last && last.picked === picked.has(index)) {
+            last.text += path[index]
+        } else {
+            runs.push({
+                start: index,
+                text: path[index],
+                picked: picked.has(index),
+            })
+        }
+    }
+    return (
+        <span className="truncate">
+            {runs.map((run) => (
+                <span
+                    key={run.start}
+                    className={run.picked ? "font-semibold text-primary" : ""}
+                >
+                    {run.text}
+                </span>
+            ))}
+        </span>
+    )
+}
+
+function MatchLine({ match }: { match: ContentMatch }) {
+    const start = match.column - 1 - match.textOffset
+    return (
+        <span className="whitespace-pre">
+            {match.text.slice(0, start)}
+            <mark className="bg-yellow-200 rounded-sm">
+                {match.text.slice(start, start + match.length)}
+            </mark>
+            {match.text.slice(start + match.length)}
+        </span>
+    )
+}
+
+export function SearchPanel({ root, onOpen, onClose }: SearchPanelProps) {
+    const [query, setQuery] = useState("")
+    const [mode, setMode] = useState<SearchMode>("name")
+    const [regex, setRegex] = useState(false)
+    const [caseSensitive, setCaseSensitive] = useState(false)
+    const [results, setResults] = useState<SearchResult[]>([])
+    const [summary, setSummary] = useState<SearchSummary | null>(null)
+    const [error, setError] = useState<string | null>(null)
+    const [isSearching, setIsSearching] = useState(false)
+    const inputRef = useRef<HTMLInputElement>(null)
+
+    useEffect(() => {
+        inputRef.current?.focus()
+    }, [])
+
+    // Search as the query changes, replacing any search still running
+    useEffect(() => {
+        setResults([])
+        setSummary(null)
+        setError(null)
+        if (!query.trim() || !root) {
+            setIsSearching(false)
+            return
+        }
+
+        let websocket: WebSocket | null = null
+        // Messages of a replaced search may still arrive while it closes
+        let isReplaced = false
+        const timeout = setTimeout(() => {
+            const params = new URLSearchParams({
+                path: root,
+                query,
+                mode,
+                regex: String(regex),
+                caseSensitive: String(caseSensitive),
+                context: String(CONTEXT_LINES),
+            })
+            websocket = new WebSocket(
+                `ws://localhost:8000/api/v1/files/search?${params.toString()}`,
+            )
+            setIsSearching(true)
+
+            websocket.onmessage = (event) => {
+                if (isReplaced) return
+                const message = JSON.parse(event.data) as SearchMessage
+                if (message.type === "done") {
+                    setSummary(message)
+                } else if (message.type === "error") {
+                    setError(message.error)
+                } else {
+                    setResults((prev) => [...prev, message])
+                }
+            }
+            websocket.onclose = () => {
+                if (!isReplaced) setIsSearching(false)
+            }
+            websocket.onerror = () => {
+                if (!isReplaced) setError("Failed to connect to search")
+            }
+        }, 300)
+
+        return () => {
+            isReplaced = true
+            clearTimeout(timeout)
+            websocket?.close(1000, "Search replaced")
+        }
+    }, [root, query, mode, regex, caseSensitive])
+
+    return (
+        <div className="flex-1 flex flex-col min-h-0">
+            <div className="px-3 py-2 border-b space-y-2">
+                <div className="flex items-center gap-1">
+                    <input
+                        ref={inputRef}
+                        value={query}
+                        onChange={(event) => setQuery(event.target.value)}
+                        onKeyDown={(event) => {
+                            if (event.key === "Escape") onClose?.()
+                        }}
+                        placeholder={
+                            mode === "name"
+                                ? "Search file names"
+                                : "Search file contents"
+                        }
+                        className="h-7 flex-1 min-w-0 rounded-md border bg-transparent px-2 text-sm outline-none focus-visible:ring-1 focus-visible:ring-ring"
+                    />
+                    {mode === "content" && (
+                        <>
+                            <Button
+                                variant={caseSensitive ? "secondary" : "ghost"}
+                                size="sm"
+                                onClick={() => setCaseSensitive((on) => !on)}
+                                title="Match case"
+                                className="h-7 w-7 p-0"
+                            >
+                                <CaseSensitive className="w-4 h-4" />
+                            </Button>
+                            <Button
+                                variant={regex ? "secondary" : "ghost"}
+                                size="sm"
+                                onClick={() => setRegex((on) => !on)}
+                                title="Use regular expression"
+                                className="h-7 w-7 p-0"
+                            >
+                                <Regex className="w-4 h-4" />
+                            </Button>
+                        </>
+                    )}
+                    {onClose && (
+                        <Button
+                            variant="ghost"
+                            size="sm"
+                            onClick={onClose}
+                            title="Close search"
+                            className="h-7 w-7 p-0"
+                        >
+                            <X className="w-3 h-3" />
+                        </Button>
+                    )}
+                </div>
+                <div className="flex items-center gap-1">
+                    {(["name", "content"] as const).map((option) => (
+                        <Button
+                            key={option}
+                            variant={mode === option ? "secondary" : "ghost"}
+                            size="sm"
+                            onClick={() => setMode(option)}
+                            className="h-6 px-2 text-xs"
+                        >
+                            {option === "name" ? "Files" : "Contents"}
+                        </Button>
+                    ))}
+                    <span className="ml-auto text-xs text-muted-foreground truncate">
+                        {isSearching && (
+                            <Loader2 className="w-3 h-3 animate-spin inline-block mr-1" />
+                        )}
+                        {summary &&
+                            `${summary.results} results in ${summary.filesSearched} files${
+                                summary.truncated ? " (limit reached)" : ""
+                            }`}
+                    </span>
+                </div>
+            </div>
+
+            <div className="flex-1 overflow-auto">
+                {error && (
+                    <div className="p-3 text-xs text-destructive">{error}</div>
+                )}
+
+                {results.map((result) =>
+                    result.type === "name" ? (
+                        <button
+                            key={result.path}
+                            type="button"
+                            className="flex items-center gap-2 w-full px-3 py-1 text-left text-sm hover:bg-muted/50"
+                            onClick={() => onOpen(joinPath(root, result.path))}
+                            title={result.path}
+                        >
+                            <FileIcon className="w-4 h-4 flex-shrink-0 text-muted-foreground" />
+                            <HighlightedPath
+                                path={result.path}
+                                indices={result.indices}
+                            />
+                        </button>
+                    ) : (
+                        <div key={result.path} className="py-1">
+                            <div
+                                className="flex items-center gap-2 px-3 py-1 text-xs font-medium"
+                                title={result.path}
+                            >
+                                <FileIcon className="w-3 h-3 flex-shrink-0 text-muted-foreground" />
+                                <span className="truncate">{result.path}</span>
+                                <span className="ml-auto text-muted-foreground">
+                                    {result.matches.length}
+                                </span>
+                            </div>
+                            {result.matches.map((match) => (
+                                <button
+                                    key={match.line}
+                                    type="button"
+                                    className="block w-full px-3 py-0.5 text-left font-mono text-xs overflow-hidden hover:bg-muted/50"
+                                    onClick={() =>
+                                        onOpen(joinPath(root, result.path), {
+                                            line: match.line,
+                                            column: match.column,
+                                            length: match.length,
+                                        })
+                                    }
+                                >
+                                    {match.before.map((line, index) => {
+                                        const lineNumber =
+                                            match.line -
+                                            match.before.length +
+                                            index
+                                        return (
+                                            <div
+                                                key={lineNumber}
+                                                className="text-muted-foreground/70 whitespace-pre truncate"
+                                            >
+                                                <span className="inline-block w-10 text-right mr-2">
+                                                    {lineNumber}
+                                                </span>
+                                                {line}
+                                            </div>
+                                        )
+                                    })}
+                                    <div className="truncate">
+                                        <span className="inline-block w-10 text-right mr-2 text-muted-foreground">
+                                            {match.line}
+                                        </span>
+                                        <MatchLine match={match} />
+                                    </div>
+                                    {match.after.map((line, index) => {
+                                        const lineNumber = match.line + 1 + index
+                                        return (
+                                            <div
+                                                key={lineNumber}
+                                                className="text-muted-foreground/70 whitespace-pre truncate"
+                                            >
+                                                <span className="inline-block w-10 text-right mr-2">
+                                                    {lineNumber}
+                                                </span>
+                                                {line}
+                                            </div>
+                                        )
+                                    })}
+                                </button>
+                            ))}
+                        </div>
+                    ),
+                )}
+
+                {summary && results.length === 0 && !error && (
+                    <div className="p-4 text-center text-sm text-muted-foreground">
+                        No results
+                    </div>
+                )}
+            </div>
+        </div>
+    )
+}
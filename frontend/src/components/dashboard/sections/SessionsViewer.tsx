@@ -0,0 +1,390 @@
+import { useQuery } from "@tanstack/react-query"
+import {
+    Circle,
+    Eye,
+    MoreVertical,
+    OctagonX,
+    RefreshCw,
+    Server,
+    Terminal,
+    X,
+} from "lucide-react"
+import { useState } from "react"
+import {
+    DefaultService,
+    type TerminalSession,
+    type TerminalSignalRequest,
+} from "@/client"
+import { Badge } from "@/components/ui/badge"
+import { Button } from "@/components/ui/button"
+import {
+    DropdownMenu,
+    DropdownMenuContent,
+    DropdownMenuItem,
+    DropdownMenuTrigger,
+} from "@/components/ui/dropdown-menu"
+import { apiErrorMessage } from "@/lib/api-error"
+import { getTerminalDriverToken } from "@/lib/terminal-session"
+import { RecordingsViewer } from "./RecordingsViewer"
+
+type TerminalSignal = TerminalSignalRequest["signal"]
+
+// Signals offered besides the interrupt button, with what they usually do
+const SIGNALS: { signal: TerminalSignal; label: string }[] = [
+    { signal: "SIGTERM", label: "Terminate" },
+    { signal: "SIGKILL", label: "Kill" },
+    { signal: "SIGHUP", label: "Hang up" },
+    { signal: "SIGQUIT", label: "Quit" },
+    { signal: "SIGTSTP", label: "Suspend" },
+    { signal: "SIGCONT", label: "Continue" },
+    { signal: "SIGUSR1", label: "User signal 1" },
+    { signal: "SIGUSR2", label: "User signal 2" },
+]
+
+const KIND_ICONS = {
+    shell: Terminal,
+    environment: Server,
+    watch: Eye,
+}
+
+// Short "5s ago" style time since a date
+function formatAge(date: string, now: number): string {
+    const seconds = Math.max(
+        0,
+        Math.round((now - new Date(date).getTime()) / 1000),
+    )
+    if (seconds < 60) return `${seconds}s ago`
+    if (seconds < 3600) return `${Math.floor(seconds / 60)}m ago`
+    if (seconds < 86400) return `${Math.floor(seconds / 3600)}h ago`
+    return `${Math.floor(seconds / 86400)}d ago`
+}
+
+function sessionLabel(session: TerminalSession): string {
+    if (session.kind === "watch") return "Watch"
+    if (session.environmentId) return session.environmentId
+    return session.workingDir.split("/").pop() || session.workingDir
+}
+
+interface SessionRowProps {
+    session: TerminalSession
+    // Time the list was fetched, ages are relative to it
+    now: number
+    isPending: boolean
+    onSignal: (signal: TerminalSignal) => void
+    onToggleRecording: () => void
+    onKill: () => void
+}
+
+function SessionRow({
+    session,
+    now,
+    isPending,
+    onSignal,
+    onToggleRecording,
+    onKill,
+}: SessionRowProps) {
+    const Icon = KIND_ICONS[session.kind]
+
+    return (
+        <div className="px-3 py-2 hover:bg-muted/30">
+            <div className="flex items-center gap-2">
+                <Icon className="h-3.5 w-3.5 flex-shrink-0 text-muted-foreground" />
+                <span
+                    className="text-sm font-medium truncate"
+                    title={session.workingDir}
+                >
+                    {sessionLabel(session)}
+                </span>
+                <Badge
+                    variant="outline"
+                    className="text-[10px] px-1.5 py-0 font-mono"
+                >
+                    {session.process}
+                </Badge>
+                <div className="ml-auto flex items-center gap-1">
+                    <Button
+                        variant="ghost"
+                        size="sm"
+                        className="h-6 w-6 p-0"
+                        disabled={isPending}
+                        onClick={onToggleRecording}
+                        title={
+                            session.recordingId
+                                ? "Stop recording"
+                                : "Record session"
+                        }
+                    >
+                        <Circle
+                            className={`h-3 w-3 ${
+                                session.recordingId
+                                    ? "fill-red-600 text-red-600 animate-pulse"
+                                    : "text-muted-foreground"
+                            }`}
+                        />
+                    </Button>
+                    <Button
+                        variant="ghost"
+                        size="sm"
+                        className="h-6 px-2 text-xs"
+                        disabled={isPending}
+                        onClick={() => onSignal("SIGINT")}
+                        title="Send SIGINT (Ctrl+C) to the foreground process"
+                    >
+                        Interrupt
+                    </Button>
+                    <DropdownMenu>
+                        <DropdownMenuTrigger asChild>
+                            <Button
+                                variant="ghost"
+                                size="sm"
+                                className="h-6 w-6 p-0"
+                                disabled={isPending}
+                                title="Send a signal"
+                            >
+                                <MoreVertical className="h-3 w-3" />
+                            </Button>
+                        </DropdownMenuTrigger>
+                        <DropdownMenuContent align="end">
+                            {SIGNALS.map(({ signal, label }) => (
+                                <DropdownMenuItem
+                                    key={signal}
+                                    onClick={() => onSignal(signal)}
+                                    className="cursor-pointer"
+                                >
+                                    {label}
+                                    <span className="ml-auto pl-4 font-mono text-xs text-muted-foreground">
+                                        {signal}
+                                    </span>
+                                </DropdownMenuItem>
+                            ))}
+                        </DropdownMenuContent>
+                    </DropdownMenu>
+                    <Button
+                        variant="ghost"
+                        size="sm"
+                        className="h-6 w-6 p-0 text-muted-foreground hover:text-red-600 hover:bg-red-50"
+                        disabled={isPending}
+                        onClick={onKill}
+                        title="Kill session"
+                    >
+                        <OctagonX className="h-3 w-3" />
+                    </Button>
+                </div>
+            </div>
+            <div className="mt-1 flex flex-wrap items-center gap-x-3 gap-y-0.5 text-xs text-muted-foreground">
+                <span>PID {session.pid}</span>
+                <span title={new Date(session.startedAt).toLocaleString()}>
+                    Started {formatAge(session.startedAt, now)}
+                </span>
+                <span title={new Date(session.lastActivity).toLocaleString()}>
+                    Active {formatAge(session.lastActivity, now)}
+                </span>
+                {session.clients > 0 ? (
+                    <span
+                        className="text-green-700"
+                        title={session.participants
+                            .map((p) => `${p.name} (${p.role})`)
+                            .join(", ")}
+                    >
+                        {session.clients}{" "}
+                        {session.clients === 1 ? "client" : "clients"}
+                    </span>
+                ) : (
+                    <span className="text-amber-700">Detached</span>
+                )}
+            </div>
+        </div>
+    )
+}
+
+export function SessionsViewer() {
+    const [view, setView] = useState<"sessions" | "recordings">("sessions")
+    const [pendingId, setPendingId] = useState<string | null>(null)
+    const [status, setStatus] = useState<{
+        message: string
+        isError: boolean
+    } | null>(null)
+
+    const { data, isLoading, error, refetch, dataUpdatedAt } = useQuery({
+        queryKey: ["terminal-sessions"],
+        queryFn: () => DefaultService.getApiV1TerminalSessions(),
+        refetchInterval: 5000,
+        retry: false,
+        refetchOnWindowFocus: false,
+        enabled: view === "sessions",
+    })
+    const sessions = data?.sessions || []
+
+    const runAction = async (
+        session: TerminalSession,
+        action: () => Promise<string>,
+    ) => {
+        setPendingId(session.id)
+        try {
+            setStatus({ message: await action(), isError: false })
+        } catch (err) {
+            setStatus({ message: apiErrorMessage(err), isError: true })
+        } finally {
+            setPendingId(null)
+            refetch()
+        }
+    }
+
+    const handleSignal = (session: TerminalSession, signal: TerminalSignal) =>
+        runAction(session, async () => {
+            const result =
+                await DefaultService.postApiV1TerminalSessionsByIdSignal({
+                    id: session.id,
+                    // Only the session's driver can signal it
+                    requestBody: {
+                        signal,
+                        token: getTerminalDriverToken(session.id),
+                    },
+                })
+            return result.message
+        })
+
+    const handleToggleRecording = (session: TerminalSession) =>
+        runAction(session, async () => {
+            if (session.recordingId) {
+                const recording =
+                    await DefaultService.deleteApiV1TerminalSessionsByIdRecording(
+                        { id: session.id },
+                    )
+                return `Saved recording ${recording.id}`
+            }
+            const recording =
+                await DefaultService.postApiV1TerminalSessionsByIdRecording({
+                    id: session.id,
+                })
+            return `Recording to ${recording.id}`
+        })
+
+    const handleKill = (session: TerminalSession) => {
+        if (
+            !window.confirm(
+                `Kill the ${session.kind} session "${sessionLabel(session)}"? Everything running in it will be stopped.`,
+            )
+        ) {
+            return
+        }
+        runAction(session, async () => {
+            const result = await DefaultService.deleteApiV1TerminalSessionsById(
+                { id: session.id, token: getTerminalDriverToken(session.id) },
+            )
+            return result.message
+        })
+    }
+
+    return (
+        <div className="h-full flex flex-col">
+            {/* Controls Header */}
+            <div className="px-3 py-2 border-b bg-muted/30">
+                <div className="flex items-center justify-between gap-2">
+                    <div className="flex items-center gap-1">
+                        <Button
+                            variant={
+                                view === "sessions" ? "secondary" : "ghost"
+                            }
+                            size="sm"
+                            onClick={() => setView("sessions")}
+                            className="h-6 px-2 text-xs"
+                        >
+                            Sessions
+                        </Button>
+                        <Button
+                            variant={
+                                view === "recordings" ? "secondary" : "ghost"
+                            }
+                            size="sm"
+                            onClick={() => setView("recordings")}
+                            className="h-6 px-2 text-xs"
+                        >
+                            Recordings
+                        </Button>
+                    </div>
+                    {view === "sessions" && (
+                        <div className="flex items-center gap-2">
+                            <div className="text-xs text-muted-foreground">
+                                {sessions.length}{" "}
+                                {sessions.length === 1 ? "session" : "sessions"}
+                                {dataUpdatedAt > 0 &&
+                                    `, updated ${new Date(dataUpdatedAt).toLocaleTimeString()}`}
+                            </div>
+                            <Button
+                                onClick={() => refetch()}
+                                size="sm"
+                                variant="outline"
+                                disabled={isLoading}
+                                className="h-7 px-2"
+                                title="Refresh sessions"
+                            >
+                                <RefreshCw
+                                    className={`h-3 w-3 ${isLoading ? "animate-spin" : ""}`}
+                                />
+                            </Button>
+                        </div>
+                    )}
+                </div>
+                {status && (
+                    <div
+                        className={`mt-2 flex items-start gap-2 text-xs ${
+                            status.isError
+                                ? "text-red-600"
+                                : "text-muted-foreground"
+                        }`}
+                    >
+                        <span className="flex-1 break-all">
+                            {status.message}
+                        </span>
+                        <button
+                            type="button"
+                            onClick={() => setStatus(null)}
+                            title="Dismiss"
+                        >
+                            <X className="h-3 w-3" />
+                        </button>
+                    </div>
+                )}
+            </div>
+
+            {/* Session List */}
+            <div className="flex-1 overflow-auto">
+                {view === "recordings" ? (
+                    <RecordingsViewer />
+                ) : error ? (
+                    <div className="m-3 text-sm text-red-600 p-3 bg-red-50 border border-red-200 rounded">
+                        <strong>Error:</strong> {error.message}
+                    </div>
+                ) : isLoading && !data ? (
+                    <div className="text-sm text-muted-foreground flex items-center justify-center h-20">
+                        <RefreshCw className="animate-spin h-4 w-4 mr-2" />
+                        Loading sessions...
+                    </div>
+                ) : sessions.length === 0 ? (
+                    <div className="p-4 text-sm text-muted-foreground text-center">
+                        No terminal sessions running
+                    </div>
+                ) : (
+                    <div className="divide-y">
+                        {sessions.map((session) => (
+                            <SessionRow
+                                key={session.id}
+                                session={session}
+                                now={dataUpdatedAt}
+                                isPending={pendingId === session.id}
+                                onSignal={(signal) =>
+                                    handleSignal(session, signal)
+                                }
+                                onToggleRecording={() =>
+                                    handleToggleRecording(session)
+                                }
+                                onKill={() => handleKill(session)}
+                            />
+                        ))}
+                    </div>
+                )}
+            </div>
+        </div>
+    )
+}
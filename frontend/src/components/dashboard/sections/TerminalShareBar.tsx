@@ -0,0 +1,261 @@
+import { useQuery } from "@tanstack/react-query"
+import { Check, Eye, Keyboard, Plus, Users, X } from "lucide-react"
+import { type ReactNode, useState } from "react"
+import {
+    DefaultService,
+    type TerminalParticipant,
+    type TerminalSession,
+} from "@/client"
+import { Badge } from "@/components/ui/badge"
+import { Button } from "@/components/ui/button"
+import {
+    DropdownMenu,
+    DropdownMenuContent,
+    DropdownMenuItem,
+    DropdownMenuLabel,
+    DropdownMenuSeparator,
+    DropdownMenuTrigger,
+} from "@/components/ui/dropdown-menu"
+import type {
+    TerminalControlAction,
+    TerminalPresence,
+} from "@/lib/terminal-protocol"
+
+function initials(name: string): string {
+    const words = name.split(/\s+/).filter(Boolean)
+    return words
+        .slice(0, 2)
+        .map((word) => word[0].toUpperCase())
+        .join("")
+}
+
+function participantTitle(
+    participant: TerminalParticipant,
+    isMe: boolean,
+): string {
+    const role = participant.role === "driver" ? "driving" : "viewing"
+    return `${participant.name}${isMe ? " (you)" : ""}, ${role}`
+}
+
+interface TerminalShareBarProps {
+    presence: TerminalPresence | null
+    // Sessions offered in the join menu, besides the current one
+    isJoinable: (session: TerminalSession) => boolean
+    onControl: (action: TerminalControlAction, clientId?: string) => void
+    onRename: (name: string) => void
+    onJoin: (sessionId: string) => void
+    onNewSession: () => void
+    // Shown before the share menu, e.g. the snippet picker
+    actions?: ReactNode
+}
+
+/**
+ * Who is attached to a shared terminal, with the control handoff and a menu
+ * to join another session of the same terminal
+ */
+export function TerminalShareBar({
+    presence,
+    isJoinable,
+    onControl,
+    onRename,
+    onJoin,
+    onNewSession,
+    actions,
+}: TerminalShareBarProps) {
+    const [isMenuOpen, setIsMenuOpen] = useState(false)
+
+    const { data, isLoading } = useQuery({
+        queryKey: ["terminal-sessions"],
+        queryFn: () => DefaultService.getApiV1TerminalSessions(),
+        enabled: isMenuOpen,
+        retry: false,
+        refetchOnWindowFocus: false,
+    })
+    const joinable = (data?.sessions || []).filter(isJoinable)
+
+    const participants = presence?.participants || []
+    const me = participants.find((p) => p.id === presence?.you)
+    const driver = participants.find((p) => p.role === "driver")
+    const isDriver = me?.role === "driver"
+    const hasRequested = !!me && !!presence?.requests.includes(me.id)
+    const requesters = isDriver
+        ? participants.filter((p) => presence?.requests.includes(p.id))
+        : []
+
+    const handleRename = () => {
+        const name = window.prompt("Name shown to others", me?.name)
+        if (name?.trim()) {
+            onRename(name.trim())
+        }
+    }
+
+    return (
+        <div className="px-2 py-1 border-b bg-muted/30 flex flex-wrap items-center gap-2 text-xs">
+            <div className="flex items-center -space-x-1">
+                {participants.map((participant) => (
+                    <span
+                        key={participant.id}
+                        className={`h-5 w-5 rounded-full border bg-background flex items-center justify-center text-[9px] font-medium ${
+                            participant.role === "driver"
+                                ? "border-green-600 text-green-700"
+                                : "text-muted-foreground"
+                        }`}
+                        title={participantTitle(
+                            participant,
+                            participant.id === me?.id,
+                        )}
+                    >
+                        {initials(participant.name)}
+                    </span>
+                ))}
+            </div>
+            {me &&
+                (isDriver ? (
+                    <Badge
+                        variant="outline"
+                        className="text-[10px] px-1.5 py-0 border-green-300 text-green-700"
+                    >
+                        <Keyboard className="h-3 w-3 mr-1" />
+                        Driving
+                    </Badge>
+                ) : (
+                    <Badge
+                        variant="outline"
+                        className="text-[10px] px-1.5 py-0"
+                        title={
+                            driver
+                                ? `${driver.name} is typing, your terminal is read-only`
+                                : "Your terminal is read-only"
+                        }
+                    >
+                        <Eye className="h-3 w-3 mr-1" />
+                        Viewing
+                    </Badge>
+                ))}
+            {me && !isDriver && (
+                <>
+                    <Button
+                        variant="ghost"
+                        size="sm"
+                        className="h-6 px-2 text-xs"
+                        disabled={hasRequested}
+                        onClick={() => onControl("request")}
+                        title={
+                            driver
+                                ? `Ask ${driver.name} to hand over control`
+                                : undefined
+                        }
+                    >
+                        {hasRequested ? "Control requested" : "Request control"}
+                    </Button>
+                    {/* Only a session nobody drives can be taken over */}
+                    {!driver && (
+                        <Button
+                            variant="ghost"
+                            size="sm"
+                            className="h-6 px-2 text-xs"
+                            onClick={() => onControl("take")}
+                            title="Take control without waiting"
+                        >
+                            Take control
+                        </Button>
+                    )}
+                </>
+            )}
+            {requesters.map((requester) => (
+                <span
+                    key={requester.id}
+                    className="flex items-center gap-1 text-amber-700"
+                >
+                    {requester.name} asks for control
+                    <Button
+                        variant="ghost"
+                        size="sm"
+                        className="h-5 w-5 p-0"
+                        onClick={() => onControl("grant", requester.id)}
+                        title="Hand over control"
+                    >
+                        <Check className="h-3 w-3" />
+                    </Button>
+                    <Button
+                        variant="ghost"
+                        size="sm"
+                        className="h-5 w-5 p-0"
+                        onClick={() => onControl("deny", requester.id)}
+                        title="Keep control"
+                    >
+                        <X className="h-3 w-3" />
+                    </Button>
+                </span>
+            ))}
+            <div className="ml-auto flex items-center gap-1">
+                {actions}
+                <DropdownMenu onOpenChange={setIsMenuOpen}>
+                    <DropdownMenuTrigger asChild>
+                        <Button
+                            variant="ghost"
+                            size="sm"
+                            className="h-6 px-2 text-xs"
+                            title="Share or join a terminal session"
+                        >
+                            <Users className="h-3 w-3 mr-1" />
+                            {participants.length}
+                        </Button>
+                    </DropdownMenuTrigger>
+                    <DropdownMenuContent align="end" className="w-64">
+                        <DropdownMenuLabel className="text-xs">
+                            Join a running session
+                        </DropdownMenuLabel>
+                        {isLoading ? (
+                            <DropdownMenuItem disabled className="text-xs">
+                                Loading sessions...
+                            </DropdownMenuItem>
+                        ) : joinable.length === 0 ? (
+                            <DropdownMenuItem disabled className="text-xs">
+                                No other sessions of this terminal
+                            </DropdownMenuItem>
+                        ) : (
+                            joinable.map((session) => (
+                                <DropdownMenuItem
+                                    key={session.id}
+                                    onClick={() => onJoin(session.id)}
+                                    className="cursor-pointer text-xs"
+                                >
+                                    <span className="truncate">
+                                        {session.participants.length > 0
+                                            ? session.participants
+                                                  .map((p) => p.name)
+                                                  .join(", ")
+                                            : "Detached"}
+                                    </span>
+                                    <span className="ml-auto pl-2 font-mono text-muted-foreground">
+                                        {session.process}
+                                    </span>
+                                </DropdownMenuItem>
+                            ))
+                        )}
+                        <DropdownMenuSeparator />
+                        <DropdownMenuItem
+                            onClick={onNewSession}
+                            className="cursor-pointer text-xs"
+                        >
+                            <Plus className="h-3 w-3 mr-2" />
+                            New session
+                        </DropdownMenuItem>
+                        <DropdownMenuItem
+                            onClick={handleRename}
+                            className="cursor-pointer text-xs"
+                        >
+                            Change your name
+                            {me && (
+                                <span className="ml-auto pl-2 text-muted-foreground truncate">
+                                    {me.name}
+                                </span>
+                            )}
+                        </DropdownMenuItem>
+                    </DropdownMenuContent>
+                </DropdownMenu>
+            </div>
+        </div>
+    )
+}
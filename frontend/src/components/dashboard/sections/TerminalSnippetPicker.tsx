@@ -0,0 +1,178 @@
+import { useQuery } from "@tanstack/react-query"
+import { Zap } from "lucide-react"
+import { useState } from "react"
+import { DefaultService, type TerminalSnippet } from "@/client"
+import { Button } from "@/components/ui/button"
+import {
+    DropdownMenu,
+    DropdownMenuCheckboxItem,
+    DropdownMenuContent,
+    DropdownMenuItem,
+    DropdownMenuLabel,
+    DropdownMenuSeparator,
+    DropdownMenuTrigger,
+} from "@/components/ui/dropdown-menu"
+import { apiErrorMessage } from "@/lib/api-error"
+
+interface TerminalSnippetPickerProps {
+    environmentId: string | null
+    // Folder whose repository's snippets are offered
+    folder?: string
+    sessionId: string
+    // Proves this client drives the current session
+    token?: string
+    // Whether this client may type into the current session
+    canType: boolean
+    onSessionStarted: (sessionId: string) => void
+}
+
+/**
+ * Ask for the values of a snippet's variables, null if one was cancelled
+ */
+function promptVariables(
+    snippet: TerminalSnippet,
+): Record<string, string> | null {
+    const variables: Record<string, string> = {}
+    for (const name of snippet.variables) {
+        const value = window.prompt(`${snippet.name}: value of ${name}`)
+        if (value === null) {
+            return null
+        }
+        variables[name] = value
+    }
+    return variables
+}
+
+/**
+ * Menu of the repository's and global snippets, typed into the terminal or
+ * run in a new session
+ */
+export function TerminalSnippetPicker({
+    environmentId,
+    folder,
+    sessionId,
+    token,
+    canType,
+    onSessionStarted,
+}: TerminalSnippetPickerProps) {
+    const [isMenuOpen, setIsMenuOpen] = useState(false)
+    const [inNewSession, setInNewSession] = useState(false)
+    const [isRunning, setIsRunning] = useState(false)
+    const [error, setError] = useState<string | null>(null)
+
+    const { data, isLoading, error: listError } = useQuery({
+        queryKey: ["terminal-snippets", folder],
+        queryFn: () => DefaultService.getApiV1TerminalSnippets({ folder }),
+        enabled: isMenuOpen,
+        retry: false,
+        refetchOnWindowFocus: false,
+    })
+    const snippets = data?.snippets || []
+    // Viewers can't type into the shared session, only start their own
+    const useNewSession = inNewSession || !canType || !token
+
+    const handleRun = async (snippet: TerminalSnippet) => {
+        const variables = promptVariables(snippet)
+        if (!variables) return
+
+        setIsRunning(true)
+        setError(null)
+        try {
+            const result = await DefaultService.postApiV1TerminalSnippetsRun({
+                folder,
+                requestBody: {
+                    name: snippet.name,
+                    sessionId: useNewSession ? undefined : sessionId,
+                    token: useNewSession ? undefined : token,
+                    environmentId: environmentId ?? undefined,
+                    variables,
+                },
+            })
+            if (result.created) {
+                onSessionStarted(result.sessionId)
+            }
+        } catch (err) {
+            setError(apiErrorMessage(err))
+        } finally {
+            setIsRunning(false)
+        }
+    }
+
+    return (
+        <div className="flex items-center gap-1">
+            {error && (
+                <span className="text-red-600 truncate max-w-48" title={error}>
+                    {error}
+                </span>
+            )}
+            <DropdownMenu onOpenChange={setIsMenuOpen}>
+                <DropdownMenuTrigger asChild>
+                    <Button
+                        variant="ghost"
+                        size="sm"
+                        className="h-6 px-2 text-xs"
+                        disabled={isRunning}
+                        title="Run a snippet"
+                    >
+                        <Zap className="h-3 w-3 mr-1" />
+                        Snippets
+                    </Button>
+                </DropdownMenuTrigger>
+                <DropdownMenuContent align="end" className="w-72">
+                    <DropdownMenuLabel className="text-xs">
+                        Run a snippet
+                    </DropdownMenuLabel>
+                    {isLoading ? (
+                        <DropdownMenuItem disabled className="text-xs">
+                            Loading snippets...
+                        </DropdownMenuItem>
+                    ) : listError ? (
+                        <DropdownMenuItem
+                            disabled
+                            className="text-xs text-red-600"
+                        >
+                            {apiErrorMessage(listError)}
+                        </DropdownMenuItem>
+                    ) : snippets.length === 0 ? (
+                        <DropdownMenuItem disabled className="text-xs">
+                            No snippets in .cuweb.json or the global config
+                        </DropdownMenuItem>
+                    ) : (
+                        snippets.map((snippet) => (
+                            <DropdownMenuItem
+                                key={snippet.name}
+                                onClick={() => handleRun(snippet)}
+                                className="cursor-pointer text-xs flex-col items-start gap-0"
+                                title={snippet.command}
+                            >
+                                <span className="flex w-full items-center">
+                                    <span className="font-medium truncate">
+                                        {snippet.name}
+                                    </span>
+                                    {snippet.source === "global" && (
+                                        <span className="ml-auto pl-2 text-muted-foreground">
+                                            global
+                                        </span>
+                                    )}
+                                </span>
+                                <span className="w-full truncate text-muted-foreground">
+                                    {snippet.description || snippet.command}
+                                </span>
+                            </DropdownMenuItem>
+                        ))
+                    )}
+                    <DropdownMenuSeparator />
+                    <DropdownMenuCheckboxItem
+                        checked={useNewSession}
+                        disabled={!canType}
+                        onCheckedChange={setInNewSession}
+                        onSelect={(event) => event.preventDefault()}
+                        className="text-xs"
+                    >
+                        Run in a new session
+                    </DropdownMenuCheckboxItem>
+                </DropdownMenuContent>
+            </DropdownMenu>
+        </div>
+    )
+}
@@ -1,18 +1,43 @@
 import { FitAddon } from "@xterm/addon-fit"
 import { Terminal } from "@xterm/xterm"
-import { useCallback, useEffect, useRef } from "react"
+import { useCallback, useEffect, useRef, useState } from "react"
 import "@xterm/xterm/css/xterm.css"
+import type { TerminalSession } from "@/client"
+import {
+    formatExit,
+    openTerminalSocket,
+    parseServerMessage,
+    sendTerminalInput,
+    sendTerminalMessage,
+    startHeartbeat,
+    type TerminalControlAction,
+    type TerminalPresence,
+} from "@/lib/terminal-protocol"
+import {
+    getTerminalClientName,
+    getTerminalSessionId,
+    setTerminalClientName,
+    setTerminalDriverToken,
+    setTerminalSessionId,
+} from "@/lib/terminal-session"
+import { TERMINAL_OPTIONS } from "@/lib/xterm"
+import { TerminalShareBar } from "./TerminalShareBar"
+import { TerminalSnippetPicker } from "./TerminalSnippetPicker"
 
 interface TerminalViewerProps {
     environmentId: string | null
     folder?: string
-    cli?: string
+    // Open a plain shell in this folder when no environment is selected
+    shellFolder?: string | null
 }
 
+// Delay before reattaching to the session after the connection drops
+const RECONNECT_DELAY = 2000
+
 export function TerminalViewer({
     environmentId,
     folder,
-    cli,
+    shellFolder,
 }: TerminalViewerProps) {
     const terminalRef = useRef<HTMLDivElement>(null)
     const terminalInstanceRef = useRef<Terminal | null>(null)
@@ -20,6 +45,19 @@ export function TerminalViewer({
     const websocketRef = useRef<WebSocket | null>(null)
     const resizeObserverRef = useRef<ResizeObserver | null>(null)
     const resizeTimeoutRef = useRef<NodeJS.Timeout | null>(null)
+    const reconnectTimeoutRef = useRef<NodeJS.Timeout | null>(null)
+    const [presence, setPresence] = useState<TerminalPresence | null>(null)
+    // Bumped to reconnect after switching to another session
+    const [, setSessionSwitches] = useState(0)
+
+    // Same session for this terminal until the browser tab is closed, or
+    // until another one is joined
+    const sessionKey = environmentId
+        ? `environment:${folder ?? ""}:${environmentId}`
+        : shellFolder
+          ? `shell:${shellFolder}`
+          : null
+    const sessionId = sessionKey ? getTerminalSessionId(sessionKey) : null
 
     // Debounced resize handler to avoid excessive calls during dragging
     const handleResize = useCallback(() => {
@@ -34,25 +72,10 @@ export function TerminalViewer({
     }, [])
 
     useEffect(() => {
-        if (!terminalRef.current || !environmentId) return
+        if (!terminalRef.current || !sessionId) return
 
         // Create terminal instance
-        const terminal = new Terminal({
-            cursorBlink: true,
-            theme: {
-                background: "#000000",
-                foreground: "#ffffff",
-                cursor: "#ffffff",
-                cursorAccent: "#000000",
-                selectionBackground: "rgba(255, 255, 255, 0.3)",
-            },
-            fontSize: 12,
-            fontFamily:
-                '"Cascadia Code", "Fira Code", "JetBrains Mono", "SF Mono", Consolas, "Liberation Mono", Menlo, Monaco, monospace',
-            allowTransparency: true,
-            scrollback: 1000,
-            convertEol: true, // Convert \n to \r\n
-        })
+        const terminal = new Terminal(TERMINAL_OPTIONS)
 
         const fitAddon = new FitAddon()
         terminal.loadAddon(fitAddon)
@@ -64,61 +87,125 @@ export function TerminalViewer({
         terminalInstanceRef.current = terminal
         fitAddonRef.current = fitAddon
 
-        // Connect to environment-specific WebSocket
+        let isDisposed = false
+        // Set once the terminal ended, the session is gone then
+        let hasExited = false
+
+        // Connect to environment-specific WebSocket, or a plain shell
         const connectWebSocket = () => {
             // Build WebSocket URL with query parameters
-            const baseUrl = `ws://localhost:8000/api/v1/environments/${environmentId}/terminal`
+            const baseUrl = environmentId
+                ? `ws://localhost:8000/api/v1/environments/${environmentId}/terminal`
+                : "ws://localhost:8000/api/v1/terminal"
             const params = new URLSearchParams()
-            if (folder) params.append("folder", folder)
-            if (cli) params.append("cli", cli)
+            if (environmentId) {
+                if (folder) params.append("folder", folder)
+            } else {
+                if (shellFolder) params.append("folder", shellFolder)
+                // Start the shell at the size of the terminal on screen
+                params.append("cols", String(terminal.cols))
+                params.append("rows", String(terminal.rows))
+            }
+            params.append("session", sessionId)
+            params.append("name", getTerminalClientName())
             const wsUrl = params.toString()
                 ? `${baseUrl}?${params.toString()}`
                 : baseUrl
 
             try {
-                const websocket = new WebSocket(wsUrl)
+                const websocket = openTerminalSocket(wsUrl)
                 websocketRef.current = websocket
+                const heartbeat = startHeartbeat(websocket)
 
                 websocket.onopen = () => {
+                    // The server replays the session output on attach
+                    terminal.reset()
+
                     // Give the terminal a moment to initialize before sending resize
                     setTimeout(() => {
                         if (fitAddonRef.current) {
                             fitAddonRef.current.fit()
                             const dimensions =
                                 fitAddonRef.current.proposeDimensions()
-                            if (
-                                dimensions &&
-                                websocket.readyState === WebSocket.OPEN
-                            ) {
-                                websocket.send(
-                                    JSON.stringify({
-                                        type: "resize",
-                                        cols: dimensions.cols,
-                                        rows: dimensions.rows,
-                                    }),
-                                )
+                            if (dimensions) {
+                                sendTerminalMessage(websocket, {
+                                    type: "resize",
+                                    cols: dimensions.cols,
+                                    rows: dimensions.rows,
+                                })
                             }
                         }
                     }, 100)
                 }
 
                 websocket.onmessage = (event) => {
-                    // Write data directly to terminal
-                    terminal.write(event.data)
+                    // Binary frames are terminal output
+                    if (event.data instanceof ArrayBuffer) {
+                        terminal.write(new Uint8Array(event.data))
+                        return
+                    }
+
+                    const message = parseServerMessage(event.data)
+                    switch (message?.type) {
+                        case "presence": {
+                            // Only the driver can type, viewers are read-only
+                            const me = message.participants.find(
+                                (p) => p.id === message.you,
+                            )
+                            terminal.options.disableStdin =
+                                me?.role !== "driver"
+                            setTerminalDriverToken(sessionId, message.token)
+                            setPresence(message)
+                            break
+                        }
+                        case "exit":
+                            hasExited = true
+                            terminal.write(
+                                formatExit(
+                                    environmentId ? "Terminal" : "Shell",
+                                    message,
+                                ),
+                            )
+                            break
+                        case "pong":
+                            heartbeat.onPong()
+                            break
+                        case "error":
+                            console.warn("Terminal error:", message.error)
+                            break
+                    }
                 }
 
                 websocket.onclose = (event) => {
+                    heartbeat.stop()
                     websocketRef.current = null
+                    if (isDisposed) return
+                    setPresence(null)
 
-                    if (event.code !== 1000) {
+                    // Reloading the terminal starts a new session
+                    if (hasExited) {
+                        terminal.options.disableStdin = true
                         terminal.writeln(
-                            `\r\n\x1b[31mConnection closed: ${event.code} ${event.reason}\x1b[0m\r\n`,
+                            "\x1b[2mReload the terminal to start a new session\x1b[0m",
                         )
-                    } else {
+                        return
+                    }
+                    // The server refused or failed to start the session,
+                    // retrying won't help
+                    if ([1002, 1008, 1011].includes(event.code)) {
                         terminal.writeln(
-                            "\r\n\x1b[33mConnection closed normally\x1b[0m\r\n",
+                            `\r\n\x1b[31mConnection closed: ${event.reason}\x1b[0m\r\n`,
                         )
+                        return
                     }
+                    // The session keeps running, so reattach to it
+                    terminal.writeln(
+                        "\r\n\x1b[33mConnection lost, reconnecting...\x1b[0m",
+                    )
+                    reconnectTimeoutRef.current = setTimeout(
+                        connectWebSocket,
+                        RECONNECT_DELAY,
+                    )
                 }
 
                 websocket.onerror = () => {
@@ -136,12 +223,7 @@ export function TerminalViewer({
 
         // Handle terminal input
         terminal.onData((data) => {
-            if (
-                websocketRef.current &&
-                websocketRef.current.readyState === WebSocket.OPEN
-            ) {
-                websocketRef.current.send(data)
-            }
+            sendTerminalInput(websocketRef.current, data)
         })
 
         // Set up ResizeObserver to watch for container size changes
@@ -158,19 +240,12 @@ export function TerminalViewer({
 
         // Handle terminal resize events
         terminal.onResize(({ cols, rows }) => {
-            if (
-                websocketRef.current &&
-                websocketRef.current.readyState === WebSocket.OPEN
-            ) {
-                // Send resize message to backend
-                websocketRef.current.send(
-                    JSON.stringify({
-                        type: "resize",
-                        cols,
-                        rows,
-                    }),
-                )
-            }
+            // Send resize message to backend
+            sendTerminalMessage(websocketRef.current, {
+                type: "resize",
+                cols,
+                rows,
+            })
         })
 
         // Connect to WebSocket
@@ -188,7 +263,11 @@ export function TerminalViewer({
                 resizeObserverRef.current = null
             }
 
-            // Clean up WebSocket connection
+            // Clean up WebSocket connection, the session keeps running
+            isDisposed = true
+            if (reconnectTimeoutRef.current) {
+                clearTimeout(reconnectTimeoutRef.current)
+            }
             if (websocketRef.current) {
                 websocketRef.current.close(1000, "Component unmounting")
                 websocketRef.current = null
@@ -198,10 +277,44 @@ export function TerminalViewer({
             window.removeEventListener("resize", handleResize)
             terminalInstanceRef.current = null
             fitAddonRef.current = null
+            setPresence(null)
         }
-    }, [environmentId, folder, cli, handleResize])
+    }, [environmentId, folder, shellFolder, sessionId, handleResize])
+
+    const handleControl = (action: TerminalControlAction, clientId?: string) =>
+        sendTerminalMessage(websocketRef.current, {
+            type: "control",
+            action,
+            clientId,
+        })
+
+    const handleRename = (name: string) => {
+        setTerminalClientName(name)
+        sendTerminalMessage(websocketRef.current, { type: "rename", name })
+    }
 
-    if (!environmentId) {
+    const switchSession = (id: string) => {
+        if (!sessionKey) return
+        setTerminalSessionId(sessionKey, id)
+        setSessionSwitches((count) => count + 1)
+    }
+
+    // Other sessions of the same terminal, e.g. opened by someone else
+    const isJoinable = (session: TerminalSession) =>
+        session.id !== sessionId &&
+        (environmentId
+            ? session.kind === "environment" &&
+              session.environmentId === environmentId
+            : session.kind === "shell" && session.workingDir === shellFolder)
+
+    // Until the first presence update the client is alone, so it drives
+    const isDriver =
+        !presence ||
+        presence.participants.some(
+            (p) => p.id === presence.you && p.role === "driver",
+        )
+
+    if (!sessionId) {
         return (
             <div className="flex items-center justify-center h-full">
                 <div className="text-center space-y-2">
@@ -219,6 +332,26 @@ export function TerminalViewer({
 
     return (
         <div className="h-full flex flex-col">
+            <TerminalShareBar
+                presence={presence}
+                isJoinable={isJoinable}
+                onControl={handleControl}
+                onRename={handleRename}
+                onJoin={switchSession}
+                onNewSession={() => switchSession(crypto.randomUUID())}
+                actions={
+                    <TerminalSnippetPicker
+                        environmentId={environmentId}
+                        folder={
+                            environmentId ? folder : (shellFolder ?? undefined)
+                        }
+                        sessionId={sessionId}
+                        token={presence?.token}
+                        canType={isDriver}
+                        onSessionStarted={switchSession}
+                    />
+                }
+            />
             {/* Terminal Content */}
             <div className="flex-1 bg-black relative">
                 <div ref={terminalRef} className="h-full" />
@@ -2,24 +2,37 @@ import { FitAddon } from "@xterm/addon-fit"
 import { Terminal } from "@xterm/xterm"
 import { useCallback, useEffect, useRef } from "react"
 import "@xterm/xterm/css/xterm.css"
+import {
+    formatExit,
+    openTerminalSocket,
+    parseServerMessage,
+    sendTerminalInput,
+    sendTerminalMessage,
+    startHeartbeat,
+} from "@/lib/terminal-protocol"
+import {
+    getTerminalClientName,
+    getTerminalSessionId,
+    setTerminalDriverToken,
+} from "@/lib/terminal-session"
+import { TERMINAL_OPTIONS } from "@/lib/xterm"
 
 interface WatchViewerProps {
     folder?: string
-    cli?: string
     connected?: boolean
 }
 
-export function WatchViewer({
-    folder,
-    cli,
-    connected = false,
-}: WatchViewerProps) {
+// Delay before reattaching to the session after the connection drops
+const RECONNECT_DELAY = 2000
+
+export function WatchViewer({ folder, connected = false }: WatchViewerProps) {
     const terminalRef = useRef<HTMLDivElement>(null)
     const terminalInstanceRef = useRef<Terminal | null>(null)
     const fitAddonRef = useRef<FitAddon | null>(null)
     const websocketRef = useRef<WebSocket | null>(null)
     const resizeObserverRef = useRef<ResizeObserver | null>(null)
     const resizeTimeoutRef = useRef<NodeJS.Timeout | null>(null)
+    const reconnectTimeoutRef = useRef<NodeJS.Timeout | null>(null)
 
     // Debounced resize handler to avoid excessive calls during dragging
     const handleResize = useCallback(() => {
@@ -37,22 +50,7 @@ export function WatchViewer({
         if (!terminalRef.current || !connected) return
 
         // Create terminal instance
-        const terminal = new Terminal({
-            cursorBlink: true,
-            theme: {
-                background: "#000000",
-                foreground: "#ffffff",
-                cursor: "#ffffff",
-                cursorAccent: "#000000",
-                selectionBackground: "rgba(255, 255, 255, 0.3)",
-            },
-            fontSize: 12,
-            fontFamily:
-                '"Cascadia Code", "Fira Code", "JetBrains Mono", "SF Mono", Consolas, "Liberation Mono", Menlo, Monaco, monospace',
-            allowTransparency: true,
-            scrollback: 1000,
-            convertEol: true, // Convert \n to \r\n
-        })
+        const terminal = new Terminal(TERMINAL_OPTIONS)
 
         const fitAddon = new FitAddon()
         terminal.loadAddon(fitAddon)
@@ -64,61 +62,105 @@ export function WatchViewer({
         terminalInstanceRef.current = terminal
         fitAddonRef.current = fitAddon
 
+        // Same session for the watch until the browser tab is closed
+        const sessionId = getTerminalSessionId(`watch:${folder ?? ""}`)
+        let isDisposed = false
+        // Set once the watch ended, the session is gone then
+        let hasExited = false
+
         // Connect to environment-specific WebSocket
         const connectWebSocket = () => {
             // Build WebSocket URL with query parameters
             const baseUrl = `ws://localhost:8000/api/v1/environments/watch`
             const params = new URLSearchParams()
             if (folder) params.append("folder", folder)
-            if (cli) params.append("cli", cli)
+            params.append("session", sessionId)
+            params.append("name", getTerminalClientName())
             const wsUrl = params.toString()
                 ? `${baseUrl}?${params.toString()}`
                 : baseUrl
 
             try {
-                const websocket = new WebSocket(wsUrl)
+                const websocket = openTerminalSocket(wsUrl)
                 websocketRef.current = websocket
+                const heartbeat = startHeartbeat(websocket)
 
                 websocket.onopen = () => {
+                    // The server replays the session output on attach
+                    terminal.reset()
+
                     // Give the terminal a moment to initialize before sending resize
                     setTimeout(() => {
                         if (fitAddonRef.current) {
                             fitAddonRef.current.fit()
                             const dimensions =
                                 fitAddonRef.current.proposeDimensions()
-                            if (
-                                dimensions &&
-                                websocket.readyState === WebSocket.OPEN
-                            ) {
-                                websocket.send(
-                                    JSON.stringify({
-                                        type: "resize",
-                                        cols: dimensions.cols,
-                                        rows: dimensions.rows,
-                                    }),
-                                )
+                            if (dimensions) {
+                                sendTerminalMessage(websocket, {
+                                    type: "resize",
+                                    cols: dimensions.cols,
+                                    rows: dimensions.rows,
+                                })
                             }
                         }
                     }, 100)
                 }
 
                 websocket.onmessage = (event) => {
-                    // Write data directly to terminal
-                    terminal.write(event.data)
+                    // Binary frames are terminal output
+                    if (event.data instanceof ArrayBuffer) {
+                        terminal.write(new Uint8Array(event.data))
+                        return
+                    }
+
+                    // Presence of other tabs sharing the watch isn't shown
+                    const message = parseServerMessage(event.data)
+                    switch (message?.type) {
+                        case "presence":
+                            // Lets the sessions list stop the watch
+                            setTerminalDriverToken(sessionId, message.token)
+                            break
+                        case "exit":
+                            hasExited = true
+                            terminal.write(formatExit("Watch", message))
+                            break
+                        case "pong":
+                            heartbeat.onPong()
+                            break
+                        case "error":
+                            console.warn("Watch error:", message.error)
+                            break
+                    }
                 }
 
                 websocket.onclose = (event) => {
+                    heartbeat.stop()
                     websocketRef.current = null
+                    if (isDisposed) return
 
-                    if (event.code !== 1000) {
+                    // Reconnecting starts a new watch
+                    if (hasExited) {
                         terminal.writeln(
-                            `\r\n\x1b[31mConnection closed: ${event.code} ${event.reason}\x1b[0m\r\n`,
+                            "\x1b[2mReload the watch to start it again\x1b[0m",
                         )
-                    } else {
+                        return
+                    }
+                    // The server refused or failed to start the session,
+                    // retrying won't help
+                    if ([1002, 1008, 1011].includes(event.code)) {
                         terminal.writeln(
-                            "\r\n\x1b[33mConnection closed normally\x1b[0m\r\n",
+                            `\r\n\x1b[31mConnection closed: ${event.reason}\x1b[0m\r\n`,
                         )
+                        return
                     }
+                    // The session keeps running, so reattach to it
+                    terminal.writeln(
+                        "\r\n\x1b[33mConnection lost, reconnecting...\x1b[0m",
+                    )
+                    reconnectTimeoutRef.current = setTimeout(
+                        connectWebSocket,
+                        RECONNECT_DELAY,
+                    )
                 }
 
                 websocket.onerror = () => {
@@ -136,12 +178,7 @@ export function WatchViewer({
 
         // Handle terminal input
         terminal.onData((data) => {
-            if (
-                websocketRef.current &&
-                websocketRef.current.readyState === WebSocket.OPEN
-            ) {
-                websocketRef.current.send(data)
-            }
+            sendTerminalInput(websocketRef.current, data)
         })
 
         // Set up ResizeObserver to watch for container size changes
@@ -158,19 +195,12 @@ export function WatchViewer({
 
         // Handle terminal resize events
         terminal.onResize(({ cols, rows }) => {
-            if (
-                websocketRef.current &&
-                websocketRef.current.readyState === WebSocket.OPEN
-            ) {
-                // Send resize message to backend
-                websocketRef.current.send(
-                    JSON.stringify({
-                        type: "resize",
-                        cols,
-                        rows,
-                    }),
-                )
-            }
+            // Send resize message to backend
+            sendTerminalMessage(websocketRef.current, {
+                type: "resize",
+                cols,
+                rows,
+            })
         })
 
         // Connect to WebSocket
@@ -188,7 +218,11 @@ export function WatchViewer({
                 resizeObserverRef.current = null
             }
 
-            // Clean up WebSocket connection
+            // Clean up WebSocket connection, the session keeps running
+            isDisposed = true
+            if (reconnectTimeoutRef.current) {
+                clearTimeout(reconnectTimeoutRef.current)
+            }
             if (websocketRef.current) {
                 websocketRef.current.close(1000, "Component unmounting")
                 websocketRef.current = null
@@ -199,7 +233,7 @@ export function WatchViewer({
             terminalInstanceRef.current = null
             fitAddonRef.current = null
         }
-    }, [folder, cli, connected, handleResize])
+    }, [folder, connected, handleResize])
 
     return (
         <div className="h-full flex flex-col">
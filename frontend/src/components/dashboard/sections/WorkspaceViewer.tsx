@@ -1,17 +1,48 @@
+import { useQuery } from "@tanstack/react-query"
 import {
     ArrowUp,
     ChevronRight,
+    ClipboardCopy,
+    Copy,
+    Download,
     ExternalLink,
     FileIcon,
+    FilePlus,
+    FolderGit2,
     FolderIcon,
+    FolderInput,
+    FolderPlus,
+    GitMerge,
     Home,
     Loader2,
     MoreVertical,
+    Pencil,
     RefreshCcw,
+    Search,
     Server,
+    Terminal,
+    Trash2,
+    Upload,
 } from "lucide-react"
-import { useCallback, useEffect, useMemo, useState } from "react"
-import { DefaultService, type GetApiV1FilesResponse } from "@/client"
+import {
+    type ChangeEvent,
+    type DragEvent,
+    useCallback,
+    useEffect,
+    useMemo,
+    useRef,
+    useState,
+} from "react"
+import {
+    ApiError,
+    DefaultService,
+    type FileTree as FileTreeData,
+    OpenAPI,
+} from "@/client"
+import {
+    type SearchHitPosition,
+    SearchPanel,
+} from "@/components/dashboard/sections/SearchPanel"
 import { FileEditor } from "@/components/editor/FileEditor"
 import {
     Breadcrumb,
@@ -22,8 +53,16 @@ import {
     BreadcrumbSeparator,
 } from "@/components/ui/breadcrumb"
 import { Button } from "@/components/ui/button"
+import {
+    ContextMenu,
+    ContextMenuContent,
+    ContextMenuItem,
+    ContextMenuSeparator,
+    ContextMenuTrigger,
+} from "@/components/ui/context-menu"
 import {
     DropdownMenu,
+    DropdownMenuCheckboxItem,
     DropdownMenuContent,
     DropdownMenuItem,
     DropdownMenuTrigger,
@@ -42,25 +81,85 @@ interface FileEntry {
     modified?: string
 }
 
+type FileTreeEntry = FileTreeData["entries"][number]
+
+type FileGitStatus = NonNullable<FileTreeEntry["gitStatus"]>
+
+type FileAction =
+    | "rename"
+    | "move"
+    | "duplicate"
+    | "copyPath"
+    | "download"
+    | "downloadTarGz"
+    | "trash"
+
+// File to upload, with its path relative to the folder it is dropped on
+interface UploadFile {
+    file: File
+    path: string
+}
+
+// Change below the browsed folder, sent by the folder watch WebSocket
+interface FolderChange {
+    type: "add" | "change" | "unlink" | "addDir" | "unlinkDir"
+    path: string
+    size?: number
+    modified?: string
+    ignored: boolean
+}
+
+// Name color and letter for each git status, like IDE file decorations
+const GIT_STATUS_DECORATIONS: Record<
+    FileGitStatus,
+    { className: string; letter: string; label: string }
+> = {
+    modified: { className: "text-amber-600", letter: "M", label: "Modified" },
+    added: { className: "text-green-600", letter: "A", label: "Added" },
+    deleted: { className: "text-red-600", letter: "D", label: "Deleted" },
+    renamed: { className: "text-sky-600", letter: "R", label: "Renamed" },
+    untracked: { className: "text-green-600", letter: "U", label: "Untracked" },
+    conflicted: { className: "text-red-600", letter: "C", label: "Conflicted" },
+    ignored: {
+        className: "text-muted-foreground",
+        letter: "",
+        label: "Ignored",
+    },
+}
+
 interface FileTreeProps {
-    entry: FileEntry
+    entry: FileTreeEntry
     level: number
     onFolderClick: (path: string) => void
+    onFolderOpen?: (path: string) => void
     onFileClick?: (path: string) => void
+    onAction?: (action: FileAction, entry: FileEntry) => void
+    onDropFiles?: (folder: string, dataTransfer: DataTransfer) => void
     currentFolder?: string
     selectedFile?: string | null
+    isExpanded?: boolean
+    isLoading?: boolean
 }
 
 function FileTree({
     entry,
     level,
     onFolderClick,
+    onFolderOpen,
     onFileClick,
+    onAction,
+    onDropFiles,
     currentFolder,
     selectedFile,
+    isExpanded,
+    isLoading,
 }: FileTreeProps) {
+    const [isDropTarget, setIsDropTarget] = useState(false)
     const isSelected = currentFolder === entry.path
     const isFileSelected = entry.type === "file" && selectedFile === entry.path
+    const decoration = entry.gitStatus
+        ? GIT_STATUS_DECORATIONS[entry.gitStatus]
+        : undefined
 
     const handleClick = () => {
         if (entry.type === "folder") {
@@ -70,20 +169,62 @@ function FileTree({
         }
     }
 
-    return (
+    // Files dropped on a file go to the folder containing it
+    const dropFolder =
+        entry.type === "folder" ? entry.path : parentPath(entry.path)
+
+    const handleDragOver = (event: DragEvent) => {
+        if (!onDropFiles || !event.dataTransfer.types.includes("Files")) {
+            return
+        }
+        event.preventDefault()
+        event.stopPropagation()
+        setIsDropTarget(true)
+    }
+
+    const handleDrop = (event: DragEvent) => {
+        setIsDropTarget(false)
+        if (!onDropFiles || !event.dataTransfer.types.includes("Files")) {
+            return
+        }
+        event.preventDefault()
+        event.stopPropagation()
+        onDropFiles(dropFolder, event.dataTransfer)
+    }
+
+    const button = (
         <button
             type="button"
             className={`flex items-center py-2 px-2 cursor-pointer hover:bg-muted/50 w-full text-left transition-colors ${
                 isSelected || isFileSelected
                     ? "bg-primary/10 border-r-2 border-primary"
                     : ""
+            } ${entry.ignored ? "opacity-60" : ""} ${
+                isDropTarget ? "bg-primary/20" : ""
             }`}
             style={{ paddingLeft: `${level * 16 + 8}px` }}
             onClick={handleClick}
+            onDragOver={handleDragOver}
+            onDragLeave={() => setIsDropTarget(false)}
+            onDrop={handleDrop}
+            onDoubleClick={() =>
+                entry.type === "folder" && onFolderOpen?.(entry.path)
+            }
+            title={
+                decoration ? `${entry.path} (${decoration.label})` : entry.path
+            }
         >
             {entry.type === "folder" ? (
                 <>
-                    <ChevronRight className="w-3 h-3 mr-1 text-muted-foreground" />
+                    {isLoading ? (
+                        <Loader2 className="w-3 h-3 mr-1 text-muted-foreground animate-spin" />
+                    ) : (
+                        <ChevronRight
+                            className={`w-3 h-3 mr-1 text-muted-foreground transition-transform ${
+                                isExpanded ? "rotate-90" : ""
+                            }`}
+                        />
+                    )}
                     <FolderIcon className="w-4 h-4 mr-2 text-blue-500" />
                 </>
             ) : (
@@ -92,14 +233,234 @@ function FileTree({
                     <FileIcon className="w-4 h-4 mr-2 text-muted-foreground" />
                 </>
             )}
-            <span className="text-sm flex-1">{entry.name}</span>
+            <span
+                className={`text-sm flex-1 truncate ${decoration?.className ?? ""}`}
+            >
+                {entry.name}
+            </span>
             {entry.type === "file" && entry.size !== undefined && (
                 <span className="text-xs text-muted-foreground ml-2">
                     {formatFileSize(entry.size)}
                 </span>
             )}
+            {decoration?.letter && (
+                <span
+                    className={`text-xs font-semibold w-3 ml-2 text-center ${decoration.className}`}
+                >
+                    {/* Folders only hint at changes inside them */}
+                    {entry.type === "folder" ? "•" : decoration.letter}
+                </span>
+            )}
         </button>
     )
+
+    if (!onAction) {
+        return button
+    }
+
+    return (
+        <ContextMenu>
+            <ContextMenuTrigger asChild>{button}</ContextMenuTrigger>
+            <ContextMenuContent className="w-48">
+                <ContextMenuItem onSelect={() => onAction("rename", entry)}>
+                    <Pencil className="w-4 h-4 mr-2" />
+                    Rename
+                </ContextMenuItem>
+                <ContextMenuItem onSelect={() => onAction("move", entry)}>
+                    <FolderInput className="w-4 h-4 mr-2" />
+                    Move to...
+                </ContextMenuItem>
+                <ContextMenuItem onSelect={() => onAction("duplicate", entry)}>
+                    <Copy className="w-4 h-4 mr-2" />
+                    Duplicate
+                </ContextMenuItem>
+                <ContextMenuItem onSelect={() => onAction("copyPath", entry)}>
+                    <ClipboardCopy className="w-4 h-4 mr-2" />
+                    Copy path
+                </ContextMenuItem>
+                <ContextMenuItem onSelect={() => onAction("download", entry)}>
+                    <Download className="w-4 h-4 mr-2" />
+                    {entry.type === "folder" ? "Download as zip" : "Download"}
+                </ContextMenuItem>
+                {entry.type === "folder" && (
+                    <ContextMenuItem
+                        onSelect={() => onAction("downloadTarGz", entry)}
+                    >
+                        <Download className="w-4 h-4 mr-2" />
+                        Download as tar.gz
+                    </ContextMenuItem>
+                )}
+                <ContextMenuSeparator />
+                <ContextMenuItem
+                    variant="destructive"
+                    onSelect={() => onAction("trash", entry)}
+                >
+                    <Trash2 className="w-4 h-4 mr-2" />
+                    Move to trash
+                </ContextMenuItem>
+            </ContextMenuContent>
+        </ContextMenu>
+    )
+}
+
+function joinPath(folder: string, name: string): string {
+    return folder === "/" ? `/${name}` : `${folder}/${name}`
+}
+
+function parentPath(filePath: string): string {
+    return filePath.split("/").slice(0, -1).join("/") || "/"
+}
+
+// "notes.md" becomes "notes copy.md", folders just get the suffix
+function duplicateName(entry: FileEntry): string {
+    const dot = entry.name.lastIndexOf(".")
+    if (entry.type === "folder" || dot <= 0) {
+        return `${entry.name} copy`
+    }
+    return `${entry.name.slice(0, dot)} copy${entry.name.slice(dot)}`
+}
+
+// Prefer the backend's error message over the generic HTTP status text
+function operationErrorMessage(err: unknown): string {
+    if (err instanceof ApiError) {
+        const body = err.body as { error?: string } | undefined
+        return body?.error || err.message
+    }
+    return err instanceof Error ? err.message : "Unknown error"
+}
+
+// Start a browser download of a file, or of a folder as an archive
+function downloadPath(path: string, format: "zip" | "tar.gz" = "zip") {
+    const query = new URLSearchParams({ path, format })
+    const link = document.createElement("a")
+    link.href = `${OpenAPI.BASE}/api/v1/files/download?${query}`
+    link.download = ""
+    link.click()
+}
+
+// Read a dropped file, or every file below a dropped folder
+async function readDroppedEntry(entry: FileSystemEntry): Promise<UploadFile[]> {
+    // Full paths of dropped entries start at the drop, e.g. /fixtures/a.json
+    const path = entry.fullPath.replace(/^\//, "")
+    if (entry.isFile) {
+        const file = await new Promise<File>((resolve, reject) =>
+            (entry as FileSystemFileEntry).file(resolve, reject),
+        )
+        return [{ file, path }]
+    }
+
+    const reader = (entry as FileSystemDirectoryEntry).createReader()
+    const children: FileSystemEntry[] = []
+    // Each call returns the next batch, an empty one at the end
+    for (;;) {
+        const batch = await new Promise<FileSystemEntry[]>((resolve, reject) =>
+            reader.readEntries(resolve, reject),
+        )
+        if (batch.length === 0) break
+        children.push(...batch)
+    }
+    const files = await Promise.all(children.map(readDroppedEntry))
+    return files.flat()
+}
+
+// Files dropped from the desktop, including the contents of dropped folders
+async function getDroppedFiles(
+    dataTransfer: DataTransfer,
+): Promise<UploadFile[]> {
+    // Entries must be taken before the drop event returns
+    const entries = Array.from(dataTransfer.items)
+        .map((item) => item.webkitGetAsEntry())
+        .filter((entry): entry is FileSystemEntry => entry !== null)
+    if (entries.length === 0) {
+        return Array.from(dataTransfer.files).map((file) => ({
+            file,
+            path: file.name,
+        }))
+    }
+    const files = await Promise.all(entries.map(readDroppedEntry))
+    return files.flat()
+}
+
+// Group a tree response by folder, with an empty list for loaded folders
+function groupTreeEntries(tree: FileTreeData): Map<string, FileTreeEntry[]> {
+    const children = new Map<string, FileTreeEntry[]>([[tree.path, []]])
+    for (const entry of tree.entries) {
+        if (entry.type === "folder" && entry.childrenLoaded) {
+            children.set(entry.path, children.get(entry.path) ?? [])
+        }
+        const siblings = children.get(entry.parent) ?? []
+        siblings.push(entry)
+        children.set(entry.parent, siblings)
+    }
+    return children
+}
+
+function compareEntries(a: FileTreeEntry, b: FileTreeEntry): number {
+    if (a.type !== b.type) {
+        return a.type === "folder" ? -1 : 1
+    }
+    return a.name.localeCompare(b.name)
+}
+
+// Apply watched changes to the loaded folders, without reloading them
+function applyFolderChanges(
+    children: Map<string, FileTreeEntry[]>,
+    changes: FolderChange[],
+    showIgnored: boolean,
+): Map<string, FileTreeEntry[]> {
+    const next = new Map(children)
+    for (const change of changes) {
+        const parent = parentPath(change.path)
+        const siblings = next.get(parent)
+
+        if (change.type === "unlink" || change.type === "unlinkDir") {
+            if (siblings) {
+                next.set(
+                    parent,
+                    siblings.filter((entry) => entry.path !== change.path),
+                )
+            }
+            // Forget the contents of a removed folder
+            for (const folder of next.keys()) {
+                if (
+                    folder === change.path ||
+                    folder.startsWith(`${change.path}/`)
+                ) {
+                    next.delete(folder)
+                }
+            }
+            continue
+        }
+
+        // Entries of folders that aren't loaded show up once they are
+        if (!siblings || (change.ignored && !showIgnored)) continue
+
+        const existing = siblings.find((entry) => entry.path === change.path)
+        const entry: FileTreeEntry = {
+            name: change.path.split("/").pop() || change.path,
+            path: change.path,
+            type: change.type === "addDir" ? "folder" : "file",
+            parent,
+            depth: siblings[0]?.depth ?? 1,
+            symlink: false,
+            ignored: change.ignored,
+            gitStatus: change.ignored ? "ignored" : undefined,
+            ...existing,
+            size: change.size,
+            modified: change.modified,
+        }
+        if (entry.type === "folder" && !existing) {
+            entry.childrenLoaded = false
+        }
+        next.set(
+            parent,
+            [
+                ...siblings.filter((sibling) => sibling.path !== change.path),
+                entry,
+            ].sort(compareEntries),
+        )
+    }
+    return next
 }
 
 function formatFileSize(bytes: number): string {
@@ -114,19 +475,79 @@ export function WorkspaceViewer({
     initialFolder,
     onFolderChange,
     onShowEnvironments,
+    onOpenTerminal,
 }: {
     initialFolder?: string
     onFolderChange?: (folder: string) => void
     onShowEnvironments?: (folder: string) => void
+    onOpenTerminal?: (folder: string) => void
 }) {
     const [currentFolder, setCurrentFolder] = useState<string>(
         initialFolder || "",
     )
     const [currentFolderData, setCurrentFolderData] =
-        useState<GetApiV1FilesResponse | null>(null)
+        useState<FileTreeData | null>(null)
+    // Entries of each loaded folder, by folder path
+    const [childrenByFolder, setChildrenByFolder] = useState<
+        Map<string, FileTreeEntry[]>
+    >(new Map())
+    const [expandedFolders, setExpandedFolders] = useState<Set<string>>(
+        new Set(),
+    )
+    const [loadingFolders, setLoadingFolders] = useState<Set<string>>(
+        new Set(),
+    )
+    const [showIgnored, setShowIgnored] = useState(false)
+    // Read when loading, so toggling it doesn't reset the browsed folder
+    const showIgnoredRef = useRef(false)
     const [isLoading, setIsLoading] = useState(false) // Start with loading state
     const [error, setError] = useState<string | null>(null)
     const [selectedFile, setSelectedFile] = useState<string | null>(null)
+    const [isFinishingConflict, setIsFinishingConflict] = useState(false)
+    const [isSearchOpen, setIsSearchOpen] = useState(false)
+    // Where to scroll the editor to after opening a search hit
+    const [revealPosition, setRevealPosition] = useState<
+        SearchHitPosition | undefined
+    >(undefined)
+    // Result of the last create, move, copy or trash action
+    const [operationStatus, setOperationStatus] = useState<{
+        message: string
+        isError: boolean
+        trashId?: string
+    } | null>(null)
+    // Set while files are dragged over the explorer
+    const [isDraggingFiles, setIsDraggingFiles] = useState(false)
+    const uploadInputRef = useRef<HTMLInputElement>(null)
+
+    // Poll for a merge, cherry-pick or rebase stopped on conflicts
+    const { data: conflictResponse, refetch: refetchConflicts } = useQuery({
+        queryKey: ["git-conflicts", initialFolder],
+        queryFn: () =>
+            DefaultService.getApiV1GitConflicts({
+                folder: initialFolder || "",
+            }),
+        retry: false,
+        refetchInterval: 10000,
+        refetchOnWindowFocus: false,
+        enabled: !!initialFolder,
+    })
+    const conflictStatus = conflictResponse?.data.inProgress
+        ? conflictResponse.data
+        : null
+
+    // Environment worktrees are listed alongside the folder tree
+    const { data: worktreesResponse, refetch: refetchWorktrees } = useQuery({
+        queryKey: ["git-worktrees", initialFolder],
+        queryFn: () =>
+            DefaultService.getApiV1GitWorktrees({
+                folder: initialFolder || "",
+            }),
+        retry: false,
+        refetchOnWindowFocus: false,
+        enabled: !!initialFolder,
+    })
+    const worktrees =
+        worktreesResponse?.data.filter((worktree) => !worktree.main) || []
 
     const fetchFolderData = useCallback(
         async (folder?: string) => {
@@ -134,17 +555,30 @@ export function WorkspaceViewer({
             setError(null)
 
             try {
-                const response = await DefaultService.getApiV1Files({
+                const response = await DefaultService.getApiV1FilesTree({
                     path: folder,
+                    showIgnored: showIgnoredRef.current ? "true" : "false",
                 })
                 setCurrentFolderData(response)
+                setChildrenByFolder(groupTreeEntries(response))
+                // Keep the folders that are still shown expanded
+                setExpandedFolders(
+                    (expanded) =>
+                        new Set(
+                            [...expanded].filter((path) =>
+                                path.startsWith(`${response.path}/`),
+                            ),
+                        ),
+                )
                 setCurrentFolder(response.path)
                 // Notify parent component of folder change
                 onFolderChange?.(response.path)
             } catch (err) {
                 console.error("Failed to fetch folder:", err)
                 setError(
-                    "Failed to load folder. Please check the path and try again.",
+                    err instanceof ApiError && err.status === 403
+                        ? "This folder is outside the folders cuweb may access. Start cuweb with --allow to add it."
+                        : "Failed to load folder. Please check the path and try again.",
                 )
             } finally {
                 setIsLoading(false)
@@ -185,10 +619,158 @@ export function WorkspaceViewer({
         }
     }
 
+    const handleToggleFolder = useCallback((folder: string) => {
+        setExpandedFolders((expanded) => {
+            const next = new Set(expanded)
+            if (!next.delete(folder)) {
+                next.add(folder)
+            }
+            return next
+        })
+    }, [])
+
+    const handleToggleIgnored = (checked: boolean) => {
+        showIgnoredRef.current = checked
+        setShowIgnored(checked)
+        fetchFolderData(currentFolder || initialFolder)
+    }
+
+    // Load the entries of a folder deeper than the tree already has
+    const loadFolderChildren = useCallback(async (folder: string) => {
+        setLoadingFolders((loading) => new Set(loading).add(folder))
+        try {
+            const response = await DefaultService.getApiV1FilesTree({
+                path: folder,
+                showIgnored: showIgnoredRef.current ? "true" : "false",
+            })
+            setChildrenByFolder((children) => {
+                const next = new Map(children)
+                for (const [path, entries] of groupTreeEntries(response)) {
+                    next.set(path, entries)
+                }
+                return next
+            })
+        } catch (err) {
+            console.error("Failed to load folder:", err)
+            setOperationStatus({
+                message: operationErrorMessage(err),
+                isError: true,
+            })
+            setExpandedFolders((expanded) => {
+                const next = new Set(expanded)
+                next.delete(folder)
+                return next
+            })
+        } finally {
+            setLoadingFolders((loading) => {
+                const next = new Set(loading)
+                next.delete(folder)
+                return next
+            })
+        }
+    }, [])
+
+    // Expanded folders that aren't loaded yet are fetched on demand
+    useEffect(() => {
+        for (const folder of expandedFolders) {
+            if (!childrenByFolder.has(folder) && !loadingFolders.has(folder)) {
+                loadFolderChildren(folder)
+            }
+        }
+    }, [expandedFolders, childrenByFolder, loadingFolders, loadFolderChildren])
+
+    // Keep the tree up to date with changes made by agents or other tools
+    useEffect(() => {
+        if (!currentFolder) return
+
+        const params = new URLSearchParams({ path: currentFolder })
+        const websocket = new WebSocket(
+            `ws://localhost:8000/api/v1/files/watch/folder?${params.toString()}`,
+        )
+        websocket.onmessage = (event) => {
+            const message = JSON.parse(event.data)
+            if (message.type === "changes") {
+                setChildrenByFolder((children) =>
+                    applyFolderChanges(
+                        children,
+                        message.changes as FolderChange[],
+                        showIgnoredRef.current,
+                    ),
+                )
+            } else if (message.type === "error") {
+                console.error("Folder watch error:", message.error)
+            }
+        }
+        websocket.onerror = (error) => {
+            console.error("Folder watch WebSocket error:", error)
+        }
+
+        return () => {
+            websocket.close(1000, "Folder changed")
+        }
+    }, [currentFolder])
+
+    // Rows of the tree: entries of the browsed folder and expanded folders
+    const visibleEntries = useMemo(() => {
+        const rows: Array<{ entry: FileTreeEntry; level: number }> = []
+        const addFolder = (folder: string, level: number) => {
+            for (const entry of childrenByFolder.get(folder) ?? []) {
+                rows.push({ entry, level })
+                if (expandedFolders.has(entry.path)) {
+                    addFolder(entry.path, level + 1)
+                }
+            }
+        }
+        addFolder(currentFolder, 0)
+        return rows
+    }, [childrenByFolder, expandedFolders, currentFolder])
+
     const handleFileClick = useCallback((filePath: string) => {
         setSelectedFile(filePath)
+        setRevealPosition(undefined)
     }, [])
 
+    const handleOpenSearchHit = useCallback(
+        (filePath: string, position?: SearchHitPosition) => {
+            setSelectedFile(filePath)
+            // A new object, so the same hit can be revealed again
+            setRevealPosition(position && { ...position })
+        },
+        [],
+    )
+
+    const handleConflictResolved = useCallback(() => {
+        refetchConflicts()
+    }, [refetchConflicts])
+
+    const handleFinishConflict = useCallback(
+        async (action: "continue" | "abort") => {
+            if (!initialFolder) return
+
+            setIsFinishingConflict(true)
+            try {
+                const request = { folder: initialFolder }
+                const response =
+                    action === "continue"
+                        ? await DefaultService.postApiV1GitConflictsContinue(
+                              request,
+                          )
+                        : await DefaultService.postApiV1GitConflictsAbort(
+                              request,
+                          )
+                if (!response.success) {
+                    console.error(response.message, response.output)
+                }
+            } catch (err) {
+                console.error(`Failed to ${action} operation:`, err)
+            } finally {
+                setIsFinishingConflict(false)
+                refetchConflicts()
+            }
+        },
+        [initialFolder, refetchConflicts],
+    )
+
     const handleOpenInVSCode = useCallback((filePath: string) => {
         // Open file in VS Code using the vscode:// URL scheme
         const vscodeUrl = `vscode://file/${filePath}`
@@ -207,6 +789,272 @@ export function WorkspaceViewer({
         }
     }
 
+    const handleOpenTerminal = () => {
+        if (currentFolder && onOpenTerminal) {
+            onOpenTerminal(currentFolder)
+        }
+    }
+
+    const handleRemoveWorktree = useCallback(
+        async (worktreePath: string) => {
+            if (!initialFolder) return
+
+            try {
+                await DefaultService.deleteApiV1GitWorktrees({
+                    folder: initialFolder,
+                    path: worktreePath,
+                })
+                // Leave the removed worktree if it is being browsed
+                if (currentFolder.startsWith(worktreePath)) {
+                    fetchFolderData(initialFolder)
+                }
+            } catch (err) {
+                console.error("Failed to remove worktree:", err)
+            } finally {
+                refetchWorktrees()
+            }
+        },
+        [initialFolder, currentFolder, fetchFolderData, refetchWorktrees],
+    )
+
+    // Run a file operation, then reload the folder to show its result
+    const runFileOperation = useCallback(
+        async (
+            operation: () => Promise<{
+                message: string
+                data: { operation: string; path: string; trashId?: string }
+            }>,
+        ) => {
+            try {
+                const result = await operation()
+                setOperationStatus({
+                    message: result.message,
+                    isError: false,
+                    // Only a trash action can be undone from here
+                    trashId:
+                        result.data.operation === "trash"
+                            ? result.data.trashId
+                            : undefined,
+                })
+                return result.data
+            } catch (err) {
+                console.error("File operation failed:", err)
+                setOperationStatus({
+                    message: operationErrorMessage(err),
+                    isError: true,
+                })
+                return null
+            } finally {
+                fetchFolderData(currentFolder)
+            }
+        },
+        [currentFolder, fetchFolderData],
+    )
+
+    const handleCreate = useCallback(
+        async (type: "file" | "folder") => {
+            if (!currentFolder) return
+
+            const name = window.prompt(
+                type === "folder" ? "New folder name" : "New file name",
+            )
+            if (!name) return
+
+            const created = await runFileOperation(() =>
+                DefaultService.postApiV1FilesCreate({
+                    requestBody: {
+                        path: joinPath(currentFolder, name),
+                        type,
+                    },
+                }),
+            )
+            if (created && type === "file") {
+                setSelectedFile(created.path)
+            }
+        },
+        [currentFolder, runFileOperation],
+    )
+
+    const handleFileAction = useCallback(
+        async (action: FileAction, entry: FileEntry) => {
+            // Paths open in the editor follow the entry they belong to
+            const followMove = (from: string, to: string) => {
+                setSelectedFile((file) =>
+                    file && (file === from || file.startsWith(`${from}/`))
+                        ? to + file.slice(from.length)
+                        : file,
+                )
+            }
+
+            if (action === "copyPath") {
+                await navigator.clipboard.writeText(entry.path)
+                setOperationStatus({
+                    message: `Copied ${entry.path}`,
+                    isError: false,
+                })
+                return
+            }
+
+            if (action === "download" || action === "downloadTarGz") {
+                downloadPath(
+                    entry.path,
+                    action === "downloadTarGz" ? "tar.gz" : "zip",
+                )
+                return
+            }
+
+            if (action === "rename" || action === "move") {
+                const destination =
+                    action === "rename"
+                        ? window.prompt("Rename to", entry.name)
+                        : window.prompt("Move to", entry.path)
+                if (!destination) return
+
+                const target =
+                    action === "rename"
+                        ? joinPath(parentPath(entry.path), destination)
+                        : destination
+                if (target === entry.path) return
+
+                const moved = await runFileOperation(() =>
+                    DefaultService.postApiV1FilesMove({
+                        requestBody: {
+                            source: entry.path,
+                            destination: target,
+                        },
+                    }),
+                )
+                if (moved) {
+                    followMove(entry.path, moved.path)
+                }
+                return
+            }
+
+            if (action === "duplicate") {
+                await runFileOperation(() =>
+                    DefaultService.postApiV1FilesCopy({
+                        requestBody: {
+                            source: entry.path,
+                            destination: joinPath(
+                                parentPath(entry.path),
+                                duplicateName(entry),
+                            ),
+                        },
+                    }),
+                )
+                return
+            }
+
+            const message = `Move ${entry.name} to the trash? It can be restored afterwards.`
+            if (!window.confirm(message)) {
+                return
+            }
+            const trashed = await runFileOperation(() =>
+                DefaultService.postApiV1FilesTrash({
+                    requestBody: { path: entry.path },
+                }),
+            )
+            if (trashed) {
+                setSelectedFile((file) =>
+                    file &&
+                    (file === entry.path || file.startsWith(`${entry.path}/`))
+                        ? null
+                        : file,
+                )
+            }
+        },
+        [runFileOperation],
+    )
+
+    const handleRestore = useCallback(
+        async (trashId: string) => {
+            await runFileOperation(() =>
+                DefaultService.postApiV1FilesTrashRestore({
+                    requestBody: { id: trashId },
+                }),
+            )
+        },
+        [runFileOperation],
+    )
+
+    const handleUpload = useCallback(
+        async (folder: string, files: UploadFile[]) => {
+            if (files.length === 0) return
+
+            const upload = (overwrite: boolean) =>
+                DefaultService.postApiV1FilesUpload({
+                    requestBody: {
+                        path: folder,
+                        overwrite: overwrite ? "true" : "false",
+                        // The file name carries the path inside the folder
+                        files: files.map(
+                            ({ file, path }) =>
+                                new File([file], path, { type: file.type }),
+                        ),
+                    },
+                })
+
+            setOperationStatus({
+                message: `Uploading ${files.length} ${
+                    files.length === 1 ? "file" : "files"
+                }...`,
+                isError: false,
+            })
+            try {
+                let result: Awaited<ReturnType<typeof upload>>
+                try {
+                    result = await upload(false)
+                } catch (err) {
+                    // Nothing is written when a file already exists
+                    const message = `${operationErrorMessage(err)}. Move the existing files to the trash and upload anyway?`
+                    if (
+                        !(err instanceof ApiError && err.status === 409) ||
+                        !window.confirm(message)
+                    ) {
+                        throw err
+                    }
+                    result = await upload(true)
+                }
+                setOperationStatus({ message: result.message, isError: false })
+            } catch (err) {
+                console.error("Upload failed:", err)
+                setOperationStatus({
+                    message: operationErrorMessage(err),
+                    isError: true,
+                })
+            } finally {
+                fetchFolderData(currentFolder)
+            }
+        },
+        [currentFolder, fetchFolderData],
+    )
+
+    const handleDropFiles = useCallback(
+        async (folder: string, dataTransfer: DataTransfer) => {
+            setIsDraggingFiles(false)
+            try {
+                await handleUpload(folder, await getDroppedFiles(dataTransfer))
+            } catch (err) {
+                console.error("Failed to read dropped files:", err)
+                setOperationStatus({
+                    message: operationErrorMessage(err),
+                    isError: true,
+                })
+            }
+        },
+        [handleUpload],
+    )
+
+    const handleUploadInput = (event: ChangeEvent<HTMLInputElement>) => {
+        const files = Array.from(event.target.files ?? []).map((file) => ({
+            file,
+            path: file.name,
+        }))
+        // Allow picking the same files again
+        event.target.value = ""
+        handleUpload(currentFolder, files)
+    }
+
     const handleShowEnvironments = () => {
         // Show environments for current folder
         if (currentFolder && onShowEnvironments) {
@@ -215,6 +1063,16 @@ export function WorkspaceViewer({
         }
     }
 
+    const selectedConflict = useMemo(() => {
+        const file = conflictStatus?.files.find(
+            (conflict) =>
+                `${conflictStatus.root}/${conflict.path}` === selectedFile,
+        )
+        return file && conflictStatus
+            ? { folder: conflictStatus.root, path: file.path }
+            : undefined
+    }, [conflictStatus, selectedFile])
+
     const pathSegments = useMemo(
         () => currentFolder.split("/").filter(Boolean),
         [currentFolder],
@@ -307,6 +1165,19 @@ export function WorkspaceViewer({
                                             <ArrowUp className="w-3 h-3" />
                                         </Button>
                                     )}
+                                    <Button
+                                        variant={
+                                            isSearchOpen ? "secondary" : "ghost"
+                                        }
+                                        size="sm"
+                                        onClick={() =>
+                                            setIsSearchOpen((open) => !open)
+                                        }
+                                        title="Search files"
+                                        className="h-7 w-7 p-0"
+                                    >
+                                        <Search className="w-3 h-3" />
+                                    </Button>
                                     <Button
                                         variant="ghost"
                                         size="sm"
@@ -331,6 +1202,55 @@ export function WorkspaceViewer({
                                             </Button>
                                         </DropdownMenuTrigger>
                                         <DropdownMenuContent align="end">
+                                            <DropdownMenuCheckboxItem
+                                                checked={showIgnored}
+                                                onCheckedChange={
+                                                    handleToggleIgnored
+                                                }
+                                                className="cursor-pointer"
+                                            >
+                                                Show ignored files
+                                            </DropdownMenuCheckboxItem>
+                                            <DropdownMenuItem
+                                                onClick={() =>
+                                                    handleCreate("file")
+                                                }
+                                                disabled={!currentFolder}
+                                                className="cursor-pointer"
+                                            >
+                                                <FilePlus className="w-4 h-4 mr-2" />
+                                                New file
+                                            </DropdownMenuItem>
+                                            <DropdownMenuItem
+                                                onClick={() =>
+                                                    handleCreate("folder")
+                                                }
+                                                disabled={!currentFolder}
+                                                className="cursor-pointer"
+                                            >
+                                                <FolderPlus className="w-4 h-4 mr-2" />
+                                                New folder
+                                            </DropdownMenuItem>
+                                            <DropdownMenuItem
+                                                onClick={() =>
+                                                    uploadInputRef.current?.click()
+                                                }
+                                                disabled={!currentFolder}
+                                                className="cursor-pointer"
+                                            >
+                                                <Upload className="w-4 h-4 mr-2" />
+                                                Upload files...
+                                            </DropdownMenuItem>
+                                            <DropdownMenuItem
+                                                onClick={() =>
+                                                    downloadPath(currentFolder)
+                                                }
+                                                disabled={!currentFolder}
+                                                className="cursor-pointer"
+                                            >
+                                                <Download className="w-4 h-4 mr-2" />
+                                                Download folder as zip
+                                            </DropdownMenuItem>
                                             <DropdownMenuItem
                                                 onClick={handleOpenInNewWindow}
                                                 disabled={!currentFolder}
@@ -347,8 +1267,25 @@ export function WorkspaceViewer({
                                                 <Server className="w-4 h-4 mr-2" />
                                                 Show folder environments
                                             </DropdownMenuItem>
+                                            {onOpenTerminal && (
+                                                <DropdownMenuItem
+                                                    onClick={handleOpenTerminal}
+                                                    disabled={!currentFolder}
+                                                    className="cursor-pointer"
+                                                >
+                                                    <Terminal className="w-4 h-4 mr-2" />
+                                                    Open terminal here
+                                                </DropdownMenuItem>
+                                            )}
                                         </DropdownMenuContent>
                                     </DropdownMenu>
+                                    <input
+                                        ref={uploadInputRef}
+                                        type="file"
+                                        multiple
+                                        className="hidden"
+                                        onChange={handleUploadInput}
+                                    />
                                 </div>
                             </div>
 
@@ -415,8 +1352,242 @@ export function WorkspaceViewer({
                             </div>
                         </div>
 
-                        {/* Explorer Content */}
-                        <div className="flex-1 overflow-auto">
+                        {/* Conflict Banner */}
+                        {conflictStatus && (
+                            <div className="px-3 py-2 border-b bg-orange-50 space-y-1">
+                                <div className="flex items-center justify-between gap-2">
+                                    <div className="flex items-center gap-1 text-xs font-medium text-orange-700 min-w-0">
+                                        <GitMerge className="w-3 h-3 flex-shrink-0" />
+                                        <span className="truncate">
+                                            {conflictStatus.operation ??
+                                                "merge"}{" "}
+                                            stopped on conflicts
+                                            {conflictStatus.incoming &&
+                                                ` (${conflictStatus.incoming})`}
+                                        </span>
+                                    </div>
+                                    <div className="flex items-center gap-1 flex-shrink-0">
+                                        {conflictStatus.operation && (
+                                            <Button
+                                                size="sm"
+                                                onClick={() =>
+                                                    handleFinishConflict(
+                                                        "continue",
+                                                    )
+                                                }
+                                                disabled={
+                                                    isFinishingConflict ||
+                                                    conflictStatus.files
+                                                        .length > 0
+                                                }
+                                                className="h-6 px-2 text-xs"
+                                            >
+                                                Continue
+                                            </Button>
+                                        )}
+                                        <Button
+                                            variant="outline"
+                                            size="sm"
+                                            onClick={() =>
+                                                handleFinishConflict("abort")
+                                            }
+                                            disabled={isFinishingConflict}
+                                            className="h-6 px-2 text-xs"
+                                        >
+                                            Abort
+                                        </Button>
+                                    </div>
+                                </div>
+                                {conflictStatus.files.map((file) => (
+                                    <button
+                                        key={file.path}
+                                        type="button"
+                                        className="flex items-center justify-between w-full text-left text-xs px-1 py-0.5 rounded hover:bg-orange-100"
+                                        onClick={() =>
+                                            handleFileClick(
+                                                `${conflictStatus.root}/${file.path}`,
+                                            )
+                                        }
+                                    >
+                                        <span className="truncate">
+                                            {file.path}
+                                        </span>
+                                        <span className="text-orange-600 ml-2 flex-shrink-0">
+                                            {file.conflictType}
+                                        </span>
+                                    </button>
+                                ))}
+                                {conflictStatus.files.length === 0 && (
+                                    <div className="text-xs text-muted-foreground">
+                                        All conflicts resolved
+                                    </div>
+                                )}
+                            </div>
+                        )}
+
+                        {/* Environment Worktrees */}
+                        {worktrees.length > 0 && (
+                            <div className="px-3 py-2 border-b space-y-1">
+                                <div className="text-xs font-semibold text-muted-foreground">
+                                    Worktrees
+                                </div>
+                                {worktrees.map((worktree) => (
+                                    <div
+                                        key={worktree.path}
+                                        className={`flex items-center gap-1 rounded px-1 ${
+                                            currentFolder.startsWith(
+                                                worktree.path,
+                                            )
+                                                ? "bg-primary/10"
+                                                : "hover:bg-muted/50"
+                                        }`}
+                                    >
+                                        <button
+                                            type="button"
+                                            className="flex items-center gap-2 flex-1 min-w-0 py-1 text-left"
+                                            onClick={() =>
+                                                handleFolderClick(
+                                                    worktree.path,
+                                                )
+                                            }
+                                            title={worktree.path}
+                                        >
+                                            <FolderGit2 className="w-4 h-4 text-amber-600 flex-shrink-0" />
+                                            <span className="text-sm truncate">
+                                                {worktree.environmentId ||
+                                                    worktree.path
+                                                        .split("/")
+                                                        .pop()}
+                                            </span>
+                                            {worktree.branch && (
+                                                <span className="text-xs text-muted-foreground truncate">
+                                                    {worktree.branch}
+                                                </span>
+                                            )}
+                                        </button>
+                                        {onOpenTerminal && (
+                                            <Button
+                                                variant="ghost"
+                                                size="sm"
+                                                onClick={() =>
+                                                    onOpenTerminal(
+                                                        worktree.path,
+                                                    )
+                                                }
+                                                title="Open terminal in worktree"
+                                                className="h-6 w-6 p-0"
+                                            >
+                                                <Terminal className="w-3 h-3" />
+                                            </Button>
+                                        )}
+                                        {worktree.managed && (
+                                            <Button
+                                                variant="ghost"
+                                                size="sm"
+                                                onClick={() =>
+                                                    handleRemoveWorktree(
+                                                        worktree.path,
+                                                    )
+                                                }
+                                                title="Remove worktree"
+                                                className="h-6 w-6 p-0 hover:text-destructive"
+                                            >
+                                                <Trash2 className="w-3 h-3" />
+                                            </Button>
+                                        )}
+                                    </div>
+                                ))}
+                            </div>
+                        )}
+
+                        {/* File Operation Result */}
+                        {operationStatus && (
+                            <div
+                                className={`px-3 py-1.5 border-b flex items-center justify-between gap-2 text-xs ${
+                                    operationStatus.isError
+                                        ? "bg-destructive/10 text-destructive"
+                                        : "bg-muted/30 text-muted-foreground"
+                                }`}
+                            >
+                                <span className="truncate">
+                                    {operationStatus.message}
+                                </span>
+                                <div className="flex items-center gap-1 flex-shrink-0">
+                                    {operationStatus.trashId && (
+                                        <Button
+                                            variant="ghost"
+                                            size="sm"
+                                            onClick={() =>
+                                                operationStatus.trashId &&
+                                                handleRestore(
+                                                    operationStatus.trashId,
+                                                )
+                                            }
+                                            className="h-6 px-2 text-xs"
+                                        >
+                                            Undo
+                                        </Button>
+                                    )}
+                                    <Button
+                                        variant="ghost"
+                                        size="sm"
+                                        onClick={() => setOperationStatus(null)}
+                                        className="h-6 px-2 text-xs"
+                                    >
+                                        Dismiss
+                                    </Button>
+                                </div>
+                            </div>
+                        )}
+
+                        {/* Search, searching the folder being browsed */}
+                        {isSearchOpen && (
+                            <SearchPanel
+                                root={currentFolder}
+                                onOpen={handleOpenSearchHit}
+                                onClose={() => setIsSearchOpen(false)}
+                            />
+                        )}
+
+                        {/* Explorer Content, also a drop zone for uploads */}
+                        <div
+                            className={`flex-1 overflow-auto ${isSearchOpen ? "hidden" : ""} ${
+                                isDraggingFiles
+                                    ? "outline-2 outline-dashed outline-primary -outline-offset-2"
+                                    : ""
+                            }`}
+                            onDragOver={(event) => {
+                                if (
+                                    currentFolder &&
+                                    event.dataTransfer.types.includes("Files")
+                                ) {
+                                    event.preventDefault()
+                                    setIsDraggingFiles(true)
+                                }
+                            }}
+                            onDragLeave={(event) => {
+                                // Moving over a child also leaves the parent
+                                if (
+                                    !event.currentTarget.contains(
+                                        event.relatedTarget as Node | null,
+                                    )
+                                ) {
+                                    setIsDraggingFiles(false)
+                                }
+                            }}
+                            onDrop={(event) => {
+                                if (
+                                    !event.dataTransfer.types.includes("Files")
+                                ) {
+                                    return
+                                }
+                                event.preventDefault()
+                                handleDropFiles(
+                                    currentFolder,
+                                    event.dataTransfer,
+                                )
+                            }}
+                        >
                             {isLoading && (
                                 <div className="flex items-center justify-center h-32">
                                     <div className="text-sm text-muted-foreground">
@@ -436,31 +1607,35 @@ export function WorkspaceViewer({
 
                             {currentFolderData && !isLoading && !error && (
                                 <div className="space-y-0">
-                                    {currentFolderData.items
-                                        .sort((a, b) => {
-                                            // Sort directories first, then files
-                                            if (a.type !== b.type) {
-                                                return a.type === "folder"
-                                                    ? -1
-                                                    : 1
-                                            }
-                                            return a.name.localeCompare(b.name)
-                                        })
-                                        .map((entry) => (
-                                            <FileTree
-                                                key={entry.path}
-                                                entry={entry}
-                                                level={0}
-                                                onFolderClick={
-                                                    handleFolderClick
-                                                }
-                                                onFileClick={handleFileClick}
-                                                currentFolder={currentFolder}
-                                                selectedFile={selectedFile}
-                                            />
-                                        ))}
+                                    {visibleEntries.map(({ entry, level }) => (
+                                        <FileTree
+                                            key={entry.path}
+                                            entry={entry}
+                                            level={level}
+                                            onFolderClick={handleToggleFolder}
+                                            onFolderOpen={handleFolderClick}
+                                            onFileClick={handleFileClick}
+                                            onAction={handleFileAction}
+                                            onDropFiles={handleDropFiles}
+                                            currentFolder={currentFolder}
+                                            selectedFile={selectedFile}
+                                            isExpanded={expandedFolders.has(
+                                                entry.path,
+                                            )}
+                                            isLoading={loadingFolders.has(
+                                                entry.path,
+                                            )}
+                                        />
+                                    ))}
+
+                                    {currentFolderData.truncated && (
+                                        <div className="px-3 py-2 text-xs text-muted-foreground">
+                                            Some folders are too large to list
+                                            at once and load when expanded
+                                        </div>
+                                    )}
 
-                                    {currentFolderData.items.length === 0 && (
+                                    {currentFolderData.entries.length === 0 && (
                                         <div className="p-4 text-center">
                                             <div className="text-sm text-muted-foreground">
                                                 This folder is empty
@@ -481,6 +1656,9 @@ export function WorkspaceViewer({
                         <FileEditor
                             filePath={selectedFile || undefined}
                             onOpenInVSCode={handleOpenInVSCode}
+                            conflict={selectedConflict}
+                            onConflictResolved={handleConflictResolved}
+                            reveal={revealPosition}
                         />
                     </div>
                 </ResizablePanel>
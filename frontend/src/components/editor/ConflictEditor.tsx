@@ -0,0 +1,257 @@
+import { AlertTriangle, Check, GitCompare, GitMerge } from "lucide-react"
+import { lazy, Suspense, useCallback, useEffect, useState } from "react"
+import { DefaultService, type GetApiV1GitConflictsFileResponse } from "@/client"
+import { Button } from "@/components/ui/button"
+
+// Lazy load Monaco Editor
+const Editor = lazy(() =>
+    import("@monaco-editor/react").then((module) => ({
+        default: module.Editor,
+    })),
+)
+const DiffEditor = lazy(() =>
+    import("@monaco-editor/react").then((module) => ({
+        default: module.DiffEditor,
+    })),
+)
+
+// Loading component for Monaco Editor
+const EditorLoader = () => (
+    <div className="flex items-center justify-center h-full">
+        <div className="animate-spin rounded-full h-8 w-8 border-b-2 border-gray-900"></div>
+    </div>
+)
+
+const editorOptions = {
+    minimap: { enabled: false },
+    scrollBeyondLastLine: false,
+    automaticLayout: true,
+    wordWrap: "on" as const,
+    lineNumbers: "on" as const,
+    folding: true,
+    lineNumbersMinChars: 3,
+    fontSize: 13,
+    fontFamily: 'Monaco, Menlo, "Ubuntu Mono", monospace',
+}
+
+// Conflict markers left by git in the working tree file
+const CONFLICT_MARKER_PATTERN = /^(<{7}|={7}|>{7})( |$)/m
+
+type ConflictVersions = GetApiV1GitConflictsFileResponse["data"]
+
+export interface ConflictTarget {
+    // Repository root the conflicted path is relative to
+    folder: string
+    path: string
+}
+
+interface ConflictEditorProps {
+    conflict: ConflictTarget
+    language: string
+    onResolved?: () => void
+}
+
+export function ConflictEditor({
+    conflict,
+    language,
+    onResolved,
+}: ConflictEditorProps) {
+    const [versions, setVersions] = useState<ConflictVersions | null>(null)
+    const [result, setResult] = useState("")
+    const [compareWithBase, setCompareWithBase] = useState(false)
+    const [isLoading, setIsLoading] = useState(false)
+    const [isResolving, setIsResolving] = useState(false)
+    const [error, setError] = useState<string | null>(null)
+
+    const loadVersions = useCallback(async () => {
+        setIsLoading(true)
+        setError(null)
+
+        try {
+            const response = await DefaultService.getApiV1GitConflictsFile({
+                folder: conflict.folder,
+                path: conflict.path,
+            })
+            setVersions(response.data)
+            setResult(response.data.merged ?? "")
+        } catch (err) {
+            console.error("Failed to load conflict versions:", err)
+            setError("Failed to load conflict versions")
+        } finally {
+            setIsLoading(false)
+        }
+    }, [conflict.folder, conflict.path])
+
+    useEffect(() => {
+        loadVersions()
+    }, [loadVersions])
+
+    const handleResolve = useCallback(
+        async (resolution: "ours" | "theirs" | "content") => {
+            setIsResolving(true)
+            setError(null)
+
+            try {
+                await DefaultService.postApiV1GitConflictsResolve({
+                    folder: conflict.folder,
+                    requestBody: {
+                        path: conflict.path,
+                        resolution,
+                        ...(resolution === "content" && { content: result }),
+                    },
+                })
+                onResolved?.()
+            } catch (err) {
+                console.error("Failed to resolve conflict:", err)
+                setError("Failed to resolve conflict")
+            } finally {
+                setIsResolving(false)
+            }
+        },
+        [conflict.folder, conflict.path, result, onResolved],
+    )
+
+    if (isLoading || !versions) {
+        return (
+            <div className="h-full flex items-center justify-center">
+                <div className="text-sm text-muted-foreground">
+                    {error || "Loading conflict..."}
+                </div>
+            </div>
+        )
+    }
+
+    const hasMarkers = CONFLICT_MARKER_PATTERN.test(result)
+
+    const renderSide = (label: string, content?: string) => (
+        <div className="flex-1 min-w-0 flex flex-col border-r">
+            <div className="px-2 py-1 text-xs font-medium bg-muted/30 border-b">
+                {label}
+                {content === undefined && (
+                    <span className="ml-1 text-muted-foreground">
+                        (deleted)
+                    </span>
+                )}
+            </div>
+            <div className="flex-1 min-h-0">
+                <Suspense fallback={<EditorLoader />}>
+                    {compareWithBase ? (
+                        <DiffEditor
+                            height="100%"
+                            language={language}
+                            original={versions.base ?? ""}
+                            modified={content ?? ""}
+                            theme="light"
+                            options={{
+                                ...editorOptions,
+                                readOnly: true,
+                                renderSideBySide: false,
+                            }}
+                        />
+                    ) : (
+                        <Editor
+                            height="100%"
+                            language={language}
+                            value={content ?? ""}
+                            theme="light"
+                            options={{ ...editorOptions, readOnly: true }}
+                        />
+                    )}
+                </Suspense>
+            </div>
+        </div>
+    )
+
+    return (
+        <div className="h-full flex flex-col">
+            {/* Conflict Actions */}
+            <div className="px-3 py-2 border-b bg-orange-50 flex items-center justify-between gap-2">
+                <div className="flex items-center gap-2 text-xs text-orange-700 min-w-0">
+                    <GitMerge className="w-4 h-4 flex-shrink-0" />
+                    <span className="truncate">
+                        Resolve the conflict in the middle pane, or accept
+                        one side
+                    </span>
+                </div>
+                <div className="flex items-center gap-1 flex-shrink-0">
+                    <Button
+                        variant={compareWithBase ? "default" : "outline"}
+                        size="sm"
+                        onClick={() => setCompareWithBase((prev) => !prev)}
+                        className="h-7 text-xs"
+                        title="Show each side as a diff against the common ancestor"
+                    >
+                        <GitCompare className="w-3 h-3 mr-1" />
+                        Base
+                    </Button>
+                    <Button
+                        variant="outline"
+                        size="sm"
+                        onClick={() => handleResolve("ours")}
+                        disabled={isResolving}
+                        className="h-7 text-xs"
+                    >
+                        Accept Ours
+                    </Button>
+                    <Button
+                        variant="outline"
+                        size="sm"
+                        onClick={() => handleResolve("theirs")}
+                        disabled={isResolving}
+                        className="h-7 text-xs"
+                    >
+                        Accept Theirs
+                    </Button>
+                    <Button
+                        size="sm"
+                        onClick={() => handleResolve("content")}
+                        disabled={isResolving || hasMarkers}
+                        className="h-7 text-xs"
+                        title={
+                            hasMarkers
+                                ? "Remove all conflict markers first"
+                                : "Save the result and mark as resolved"
+                        }
+                    >
+                        <Check className="w-3 h-3 mr-1" />
+                        Mark Resolved
+                    </Button>
+                </div>
+            </div>
+            {error && (
+                <div className="px-3 py-1 text-xs text-destructive flex items-center gap-1 border-b">
+                    <AlertTriangle className="w-3 h-3" />
+                    {error}
+                </div>
+            )}
+
+            {/* Three-way view: ours | result | theirs */}
+            <div className="flex-1 min-h-0 flex">
+                {renderSide("Ours (current)", versions.ours)}
+                <div className="flex-1 min-w-0 flex flex-col border-r">
+                    <div className="px-2 py-1 text-xs font-medium bg-muted/30 border-b">
+                        Result
+                        {hasMarkers && (
+                            <span className="ml-1 text-orange-600">
+                                (conflict markers remaining)
+                            </span>
+                        )}
+                    </div>
+                    <div className="flex-1 min-h-0">
+                        <Suspense fallback={<EditorLoader />}>
+                            <Editor
+                                height="100%"
+                                language={language}
+                                value={result}
+                                onChange={(value) => setResult(value ?? "")}
+                                theme="light"
+                                options={editorOptions}
+                            />
+                        </Suspense>
+                    </div>
+                </div>
+                {renderSide("Theirs (incoming)", versions.theirs)}
+            </div>
+        </div>
+    )
+}
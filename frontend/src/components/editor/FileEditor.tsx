@@ -1,6 +1,30 @@
-import { ExternalLink, FileIcon, RefreshCw } from "lucide-react"
+import {
+    AlertTriangle,
+    Code,
+    ExternalLink,
+    Eye,
+    FileIcon,
+    Pencil,
+    RefreshCw,
+    Save,
+    X,
+} from "lucide-react"
+import type { OnMount } from "@monaco-editor/react"
 import { lazy, Suspense, useCallback, useEffect, useRef, useState } from "react"
+import {
+    ApiError,
+    DefaultService,
+    type FileContent,
+    type FileVersion,
+} from "@/client"
+import {
+    ConflictEditor,
+    type ConflictTarget,
+} from "@/components/editor/ConflictEditor"
+import { FilePreview, getPreviewKind } from "@/components/editor/FilePreview"
+import { HexViewer } from "@/components/editor/HexViewer"
 import { Button } from "@/components/ui/button"
+import { watchFile } from "@/lib/file-watch"
 
 // Lazy load Monaco Editor
 const Editor = lazy(() =>
@@ -16,27 +40,75 @@ const EditorLoader = () => (
     </div>
 )
 
+// First read of a file, enough for the hex view of a binary file. A multiple
+// of 3 so base64 pages can be concatenated
+const PROBE_SIZE = 48 * 1024
+
+const formatSize = (bytes: number) => {
+    if (bytes < 1024) return `${bytes} B`
+    if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`
+    return `${(bytes / (1024 * 1024)).toFixed(1)} MB`
+}
+
 interface FileEditorProps {
     filePath?: string
     onOpenInVSCode?: (filePath: string) => void
+    // When set, the file has merge conflicts and is shown in a three-way view
+    conflict?: ConflictTarget
+    onConflictResolved?: () => void
+    // Line to scroll to and select, e.g. a search hit. A new object reveals
+    // the position again
+    reveal?: { line: number; column?: number; length?: number }
 }
 
-interface FileWatchMessage {
-    type: "content" | "error" | "deleted"
-    filePath: string
-    content?: string
-    error?: string
-    timestamp: string
+// Content on disk that arrived while there were unsaved edits
+interface DiskChange {
+    content: string | null
+    version: Pick<FileVersion, "modified" | "hash"> | null
 }
 
-export function FileEditor({ filePath, onOpenInVSCode }: FileEditorProps) {
+export function FileEditor({
+    filePath,
+    onOpenInVSCode,
+    conflict,
+    onConflictResolved,
+    reveal,
+}: FileEditorProps) {
     const [content, setContent] = useState<string>("")
     const [isLoading, setIsLoading] = useState(false)
     const [error, setError] = useState<string | null>(null)
     const [isConnected, setIsConnected] = useState(false)
     const [isUpdating, setIsUpdating] = useState(false)
-    const wsRef = useRef<WebSocket | null>(null)
+    // Metadata of the last range read through /files/content
+    const [fileInfo, setFileInfo] = useState<FileContent | null>(null)
+    const [isLoadingMore, setIsLoadingMore] = useState(false)
+    // Editing: the last content known to be on disk and its version
+    const [isEditing, setIsEditing] = useState(false)
+    const [savedContent, setSavedContent] = useState("")
+    const [version, setVersion] = useState<Pick<
+        FileVersion,
+        "modified" | "hash"
+    > | null>(null)
+    const [isSaving, setIsSaving] = useState(false)
+    const [saveError, setSaveError] = useState<string | null>(null)
+    const [diskChange, setDiskChange] = useState<DiskChange | null>(null)
+    // Files with a preview open in it, the source is a click away
+    const [showSource, setShowSource] = useState(false)
+    const unwatchRef = useRef<(() => void) | null>(null)
     const updateTimeoutRef = useRef<NodeJS.Timeout | null>(null)
+    // Read by the watch listener, which outlives renders
+    const isDirtyRef = useRef(false)
+    const contentRef = useRef("")
+    const editorRef = useRef<Parameters<OnMount>[0] | null>(null)
+    // Position to reveal once the file content has been loaded
+    const pendingRevealRef = useRef<FileEditorProps["reveal"]>(undefined)
+
+    const isDirty = isEditing && content !== savedContent
+
+    useEffect(() => {
+        isDirtyRef.current = isDirty
+        contentRef.current = content
+    }, [isDirty, content])
 
     // Get file extension for language detection
     const getLanguageFromPath = useCallback((path: string): string => {
@@ -76,96 +148,162 @@ export function FileEditor({ filePath, onOpenInVSCode }: FileEditorProps) {
         return languageMap[extension || ""] || "plaintext"
     }, [])
 
-    // Connect to file watching WebSocket
-    const connectToFile = useCallback((path: string) => {
-        if (wsRef.current) {
-            wsRef.current.close()
-        }
-
-        setIsLoading(true)
-        setError(null)
+    const stopWatching = useCallback(() => {
+        unwatchRef.current?.()
+        unwatchRef.current = null
         setIsConnected(false)
+    }, [])
 
-        try {
-            const wsUrl = `ws://localhost:8000/api/v1/files/watch?path=${encodeURIComponent(path)}`
-            const ws = new WebSocket(wsUrl)
-
-            ws.onopen = () => {
-                console.log("File watch WebSocket connected")
-                setIsConnected(true)
-                setIsLoading(false)
-            }
-
-            ws.onmessage = (event) => {
-                try {
-                    const message: FileWatchMessage = JSON.parse(event.data)
-
-                    switch (message.type) {
-                        case "content":
-                            // Show update indicator
-                            setIsUpdating(true)
-
-                            // Clear any existing timeout
-                            if (updateTimeoutRef.current) {
-                                clearTimeout(updateTimeoutRef.current)
-                            }
-
-                            // Update content
-                            setContent(message.content || "")
-                            setError(null)
-
-                            // Hide update indicator after a short delay
-                            updateTimeoutRef.current = setTimeout(() => {
-                                setIsUpdating(false)
-                            }, 1000)
-                            break
-                        case "error":
-                            setError(message.error || "Unknown error")
-                            setIsLoading(false)
-                            setIsUpdating(false)
+    // Follow changes to the file through the shared watch connection
+    const connectToFile = useCallback(
+        (path: string) => {
+            stopWatching()
+
+            unwatchRef.current = watchFile(path, (update) => {
+                switch (update.type) {
+                    case "status":
+                        setIsConnected(update.connected)
+                        break
+                    case "content":
+                        // Keep unsaved edits and let the user decide
+                        if (
+                            isDirtyRef.current &&
+                            update.content !== contentRef.current
+                        ) {
+                            setDiskChange({
+                                content: update.content,
+                                version: {
+                                    modified: update.modified,
+                                    hash: update.hash,
+                                },
+                            })
                             break
-                        case "deleted":
-                            setError("File was deleted")
-                            setContent("")
+                        }
+
+                        // Show update indicator
+                        setIsUpdating(true)
+
+                        // Clear any existing timeout
+                        if (updateTimeoutRef.current) {
+                            clearTimeout(updateTimeoutRef.current)
+                        }
+
+                        // Update content
+                        setContent(update.content)
+                        setSavedContent(update.content)
+                        setVersion({
+                            modified: update.modified,
+                            hash: update.hash,
+                        })
+                        setDiskChange(null)
+                        setError(null)
+
+                        // Hide update indicator after a short delay
+                        updateTimeoutRef.current = setTimeout(() => {
                             setIsUpdating(false)
+                        }, 1000)
+                        break
+                    case "error":
+                        setError(update.error)
+                        setIsUpdating(false)
+                        break
+                    case "deleted":
+                        // Unsaved edits can still be saved as a new file
+                        if (isDirtyRef.current) {
+                            setDiskChange({ content: null, version: null })
                             break
-                    }
-                } catch (err) {
-                    console.error("Failed to parse WebSocket message:", err)
-                    setError("Failed to parse file content")
-                    setIsUpdating(false)
+                        }
+                        setError("File was deleted")
+                        setContent("")
+                        setIsUpdating(false)
+                        break
                 }
-            }
+            })
+        },
+        [stopWatching],
+    )
 
-            ws.onclose = () => {
-                console.log("File watch WebSocket disconnected")
-                setIsConnected(false)
-            }
+    // Read the file over REST first, so binary and large files are never
+    // streamed whole; only complete text files get live updates
+    const loadFile = useCallback(
+        async (path: string) => {
+            stopWatching()
 
-            ws.onerror = (err) => {
-                console.error("File watch WebSocket error:", err)
-                setError("Failed to connect to file watcher")
+            setIsLoading(true)
+            setError(null)
+            setFileInfo(null)
+            setIsEditing(false)
+            setSaveError(null)
+            setDiskChange(null)
+            setVersion(null)
+            setShowSource(false)
+
+            try {
+                let file = await DefaultService.getApiV1FilesContent({
+                    path,
+                    length: PROBE_SIZE,
+                })
+                if (!file.binary && file.truncated) {
+                    file = await DefaultService.getApiV1FilesContent({ path })
+                }
+
+                setFileInfo(file)
+                setContent(file.binary ? "" : file.content)
+                setSavedContent(file.binary ? "" : file.content)
+                if (file.hash) {
+                    setVersion({ modified: file.modified, hash: file.hash })
+                }
+                setIsLoading(false)
+                if (!file.binary && !file.truncated) {
+                    connectToFile(path)
+                }
+            } catch (err) {
+                console.error("Failed to read file:", err)
+                setError(
+                    err instanceof ApiError && err.status === 403
+                        ? "File is outside the folders cuweb may access"
+                        : "Failed to read file",
+                )
                 setIsLoading(false)
-                setIsConnected(false)
             }
+        },
+        [connectToFile, stopWatching],
+    )
+
+    // Append the next range of a truncated file
+    const handleLoadMore = useCallback(async () => {
+        if (!filePath || !fileInfo) return
 
-            wsRef.current = ws
+        setIsLoadingMore(true)
+        try {
+            const next = await DefaultService.getApiV1FilesContent({
+                path: filePath,
+                offset: fileInfo.offset + fileInfo.length,
+                ...(fileInfo.binary && { length: PROBE_SIZE }),
+            })
+            setFileInfo({
+                ...next,
+                content: fileInfo.content + next.content,
+                offset: 0,
+                length: fileInfo.length + next.length,
+            })
+            if (!next.binary) {
+                setContent((prev) => prev + next.content)
+            }
         } catch (err) {
-            console.error("Failed to create WebSocket:", err)
-            setError("Failed to create WebSocket connection")
-            setIsLoading(false)
+            console.error("Failed to read more of the file:", err)
+            setError("Failed to read file")
+        } finally {
+            setIsLoadingMore(false)
         }
-    }, [])
+    }, [filePath, fileInfo])
 
     // Handle file path changes
     useEffect(() => {
         if (filePath) {
-            connectToFile(filePath)
+            loadFile(filePath)
         } else {
-            if (wsRef.current) {
-                wsRef.current.close()
-                wsRef.current = null
-            }
+            stopWatching()
 
             // Clear update timeout
             if (updateTimeoutRef.current) {
@@ -175,16 +313,13 @@ export function FileEditor({ filePath, onOpenInVSCode }: FileEditorProps) {
 
             setContent("")
             setError(null)
-            setIsConnected(false)
             setIsUpdating(false)
+            setFileInfo(null)
         }
 
         // Cleanup on unmount
         return () => {
-            if (wsRef.current) {
-                wsRef.current.close()
-                wsRef.current = null
-            }
+            stopWatching()
 
             // Clear update timeout
             if (updateTimeoutRef.current) {
@@ -192,7 +327,136 @@ export function FileEditor({ filePath, onOpenInVSCode }: FileEditorProps) {
                 updateTimeoutRef.current = null
             }
         }
-    }, [filePath, connectToFile])
+    }, [filePath, loadFile, stopWatching])
+
+    // Save the buffer, unless the file changed on disk since it was loaded.
+    // With overwrite the version check is skipped
+    const handleSave = useCallback(
+        async (overwrite = false) => {
+            if (!filePath || !isEditing || isSaving) return
+
+            setIsSaving(true)
+            setSaveError(null)
+            try {
+                const response = await DefaultService.putApiV1FilesContent({
+                    requestBody: {
+                        path: filePath,
+                        content,
+                        ...(!overwrite &&
+                            version && { expectedHash: version.hash }),
+                    },
+                })
+                setSavedContent(content)
+                setVersion({
+                    modified: response.data.modified,
+                    hash: response.data.hash,
+                })
+                setDiskChange(null)
+            } catch (err) {
+                if (err instanceof ApiError && err.status === 409) {
+                    const body = err.body as {
+                        current: FileVersion | null
+                    }
+                    setDiskChange(
+                        (prev) =>
+                            prev ?? {
+                                // Content arrives with the next watch update
+                                content: null,
+                                version: body.current,
+                            },
+                    )
+                    setSaveError("File changed on disk since it was loaded")
+                } else {
+                    console.error("Failed to save file:", err)
+                    setSaveError("Failed to save file")
+                }
+            } finally {
+                setIsSaving(false)
+            }
+        },
+        [filePath, isEditing, isSaving, content, version],
+    )
+
+    // Drop unsaved edits and show what is on disk
+    const handleDiscard = useCallback(() => {
+        if (!filePath) return
+        if (diskChange?.content != null) {
+            setContent(diskChange.content)
+            setSavedContent(diskChange.content)
+            setVersion(diskChange.version)
+            setDiskChange(null)
+            setSaveError(null)
+        } else {
+            loadFile(filePath)
+        }
+    }, [filePath, diskChange, loadFile])
+
+    // Monaco handles Ctrl/Cmd+S itself, so the latest save is kept in a ref
+    const saveRef = useRef(handleSave)
+    useEffect(() => {
+        saveRef.current = handleSave
+    }, [handleSave])
+
+    // Scroll to and select the pending position if its line is loaded
+    const applyReveal = useCallback(() => {
+        const editor = editorRef.current
+        const target = pendingRevealRef.current
+        const model = editor?.getModel()
+        if (!editor || !target || !model) return
+        if (model.getLineCount() < target.line) return
+
+        const column = target.column ?? 1
+        editor.revealLineInCenter(target.line)
+        editor.setSelection({
+            startLineNumber: target.line,
+            startColumn: column,
+            endLineNumber: target.line,
+            endColumn: column + (target.length ?? 0),
+        })
+        pendingRevealRef.current = undefined
+    }, [])
+
+    useEffect(() => {
+        pendingRevealRef.current = reveal
+    }, [reveal])
+
+    // Wait until the editor shows the selected file, not the previous one
+    useEffect(() => {
+        if (reveal && content && fileInfo?.path === filePath) {
+            applyReveal()
+        }
+    }, [content, reveal, fileInfo, filePath, applyReveal])
+
+    const handleEditorMount: OnMount = useCallback(
+        (editor, monaco) => {
+            editorRef.current = editor
+            editor.addCommand(monaco.KeyMod.CtrlCmd | monaco.KeyCode.KeyS, () =>
+                saveRef.current(),
+            )
+            applyReveal()
+        },
+        [applyReveal],
+    )
+
+    // Leave edit mode, dropping unsaved edits after confirmation
+    const handleStopEditing = useCallback(() => {
+        if (isDirty && !window.confirm("Discard unsaved changes?")) return
+        setContent(savedContent)
+        setIsEditing(false)
+        setSaveError(null)
+        setDiskChange(null)
+    }, [isDirty, savedContent])
+
+    // Warn before leaving the page with unsaved edits
+    useEffect(() => {
+        if (!isDirty) return
+        const handleBeforeUnload = (event: BeforeUnloadEvent) => {
+            event.preventDefault()
+        }
+        window.addEventListener("beforeunload", handleBeforeUnload)
+        return () =>
+            window.removeEventListener("beforeunload", handleBeforeUnload)
+    }, [isDirty])
 
     const handleOpenInVSCode = useCallback(() => {
         if (filePath && onOpenInVSCode) {
@@ -235,7 +499,7 @@ export function FileEditor({ filePath, onOpenInVSCode }: FileEditorProps) {
                     <Button
                         variant="outline"
                         size="sm"
-                        onClick={() => filePath && connectToFile(filePath)}
+                        onClick={() => filePath && loadFile(filePath)}
                     >
                         Try Again
                     </Button>
@@ -245,7 +509,19 @@ export function FileEditor({ filePath, onOpenInVSCode }: FileEditorProps) {
     }
 
     const fileName = filePath.split("/").pop() || "Unknown File"
+    // Only complete text files can be edited, merge conflicts have their own
+    // editor
+    const isEditable =
+        !!fileInfo && !fileInfo.binary && !fileInfo.truncated && !conflict
     const language = getLanguageFromPath(filePath)
+    // Images are previewed from their raw bytes, other previews need the
+    // text content
+    const previewKind = conflict ? undefined : getPreviewKind(filePath)
+    const canPreview =
+        !!fileInfo &&
+        !!previewKind &&
+        (previewKind === "image" || !fileInfo.binary)
+    const isPreviewing = canPreview && !showSource && !isEditing
 
     return (
         <div className="h-full flex flex-col">
@@ -258,6 +534,14 @@ export function FileEditor({ filePath, onOpenInVSCode }: FileEditorProps) {
                         title={filePath}
                     >
                         {fileName}
+                        {isDirty && (
+                            <span
+                                className="ml-1 text-orange-500"
+                                title="Unsaved changes"
+                            >
+                                ●
+                            </span>
+                        )}
                     </span>
                     <div className="flex items-center gap-1">
                         <div
@@ -278,48 +562,197 @@ export function FileEditor({ filePath, onOpenInVSCode }: FileEditorProps) {
                             </div>
                         )}
                     </div>
+                    {fileInfo && (
+                        <span className="text-xs text-muted-foreground flex-shrink-0">
+                            {formatSize(fileInfo.size)} · {fileInfo.mimeType}
+                        </span>
+                    )}
+                </div>
+                <div className="flex items-center gap-1 flex-shrink-0">
+                    {canPreview && !isEditing && (
+                        <div className="flex items-center gap-1 mr-1">
+                            <Button
+                                variant={isPreviewing ? "secondary" : "ghost"}
+                                size="sm"
+                                onClick={() => setShowSource(false)}
+                                className="flex items-center gap-1 text-xs h-7"
+                            >
+                                <Eye className="w-3 h-3" />
+                                Preview
+                            </Button>
+                            <Button
+                                variant={isPreviewing ? "ghost" : "secondary"}
+                                size="sm"
+                                onClick={() => setShowSource(true)}
+                                className="flex items-center gap-1 text-xs h-7"
+                            >
+                                <Code className="w-3 h-3" />
+                                Source
+                            </Button>
+                        </div>
+                    )}
+                    {isEditable &&
+                        (isEditing ? (
+                            <>
+                                <Button
+                                    size="sm"
+                                    onClick={() => handleSave()}
+                                    disabled={!isDirty || isSaving}
+                                    className="flex items-center gap-1 text-xs h-7"
+                                    title="Save (Ctrl+S)"
+                                >
+                                    <Save className="w-3 h-3" />
+                                    {isSaving ? "Saving..." : "Save"}
+                                </Button>
+                                <Button
+                                    variant="outline"
+                                    size="sm"
+                                    onClick={handleStopEditing}
+                                    className="flex items-center gap-1 text-xs h-7"
+                                >
+                                    <X className="w-3 h-3" />
+                                    Done
+                                </Button>
+                            </>
+                        ) : (
+                            <Button
+                                variant="outline"
+                                size="sm"
+                                onClick={() => setIsEditing(true)}
+                                className="flex items-center gap-1 text-xs h-7"
+                            >
+                                <Pencil className="w-3 h-3" />
+                                Edit
+                            </Button>
+                        ))}
+                    <Button
+                        variant="outline"
+                        size="sm"
+                        onClick={handleOpenInVSCode}
+                        className="flex items-center gap-1 text-xs h-7"
+                    >
+                        <ExternalLink className="w-3 h-3" />
+                        Edit in VS Code
+                    </Button>
                 </div>
-                <Button
-                    variant="outline"
-                    size="sm"
-                    onClick={handleOpenInVSCode}
-                    className="flex items-center gap-1 text-xs h-7"
-                >
-                    <ExternalLink className="w-3 h-3" />
-                    Edit in VS Code
-                </Button>
             </div>
 
+            {(diskChange || saveError) && (
+                <div className="px-3 py-1 border-b bg-orange-50 text-xs text-orange-800 flex items-center justify-between gap-2">
+                    <span className="flex items-center gap-1 min-w-0">
+                        <AlertTriangle className="w-3 h-3 flex-shrink-0" />
+                        <span className="truncate">
+                            {diskChange
+                                ? diskChange.content === null &&
+                                  !diskChange.version
+                                    ? "File was deleted on disk, your edits are not saved"
+                                    : "File changed on disk, your edits are not saved"
+                                : saveError}
+                        </span>
+                    </span>
+                    {diskChange && (
+                        <span className="flex items-center gap-1 flex-shrink-0">
+                            {diskChange.version && (
+                                <Button
+                                    variant="outline"
+                                    size="sm"
+                                    onClick={handleDiscard}
+                                    className="h-6 text-xs"
+                                >
+                                    Reload from Disk
+                                </Button>
+                            )}
+                            <Button
+                                variant="outline"
+                                size="sm"
+                                onClick={() => handleSave(true)}
+                                disabled={isSaving}
+                                className="h-6 text-xs"
+                            >
+                                {diskChange.version
+                                    ? "Overwrite"
+                                    : "Save as New File"}
+                            </Button>
+                        </span>
+                    )}
+                </div>
+            )}
+
+            {fileInfo?.truncated && !fileInfo.binary && !conflict && (
+                <div className="px-3 py-1 border-b bg-amber-50 text-xs text-amber-800 flex items-center justify-between gap-2">
+                    <span>
+                        Large file, showing the first{" "}
+                        {formatSize(fileInfo.length)} of{" "}
+                        {formatSize(fileInfo.size)} without live updates
+                    </span>
+                    <Button
+                        variant="outline"
+                        size="sm"
+                        onClick={handleLoadMore}
+                        disabled={isLoadingMore}
+                        className="h-6 text-xs"
+                    >
+                        {isLoadingMore ? "Loading..." : "Load more"}
+                    </Button>
+                </div>
+            )}
+
             {/* Editor Content */}
             <div className="flex-1 min-h-0">
-                <Suspense fallback={<EditorLoader />}>
-                    <Editor
-                        height="100%"
+                {conflict ? (
+                    <ConflictEditor
+                        conflict={conflict}
                         language={language}
-                        value={content}
-                        theme="light"
-                        options={{
-                            readOnly: true,
-                            minimap: { enabled: false },
-                            scrollBeyondLastLine: false,
-                            automaticLayout: true,
-                            wordWrap: "on",
-                            lineNumbers: "on",
-                            glyphMargin: false,
-                            folding: true,
-                            lineDecorationsWidth: 10,
-                            lineNumbersMinChars: 3,
-                            renderLineHighlight: "line",
-                            selectOnLineNumbers: true,
-                            roundedSelection: false,
-                            cursorStyle: "line",
-                            cursorWidth: 2,
-                            fontSize: 14,
-                            fontFamily:
-                                'Monaco, Menlo, "Ubuntu Mono", monospace',
-                        }}
+                        onResolved={onConflictResolved}
                     />
-                </Suspense>
+                ) : isPreviewing && previewKind ? (
+                    <FilePreview
+                        kind={previewKind}
+                        filePath={filePath}
+                        content={content}
+                        version={version?.hash ?? fileInfo?.modified}
+                    />
+                ) : fileInfo?.binary ? (
+                    <HexViewer
+                        data={fileInfo.content}
+                        size={fileInfo.size}
+                        onLoadMore={handleLoadMore}
+                        isLoadingMore={isLoadingMore}
+                    />
+                ) : (
+                    <Suspense fallback={<EditorLoader />}>
+                        <Editor
+                            height="100%"
+                            language={language}
+                            value={content}
+                            onChange={(value) =>
+                                isEditing && setContent(value ?? "")
+                            }
+                            onMount={handleEditorMount}
+                            theme="light"
+                            options={{
+                                readOnly: !isEditing,
+                                minimap: { enabled: false },
+                                scrollBeyondLastLine: false,
+                                automaticLayout: true,
+                                wordWrap: "on",
+                                lineNumbers: "on",
+                                glyphMargin: false,
+                                folding: true,
+                                lineDecorationsWidth: 10,
+                                lineNumbersMinChars: 3,
+                                renderLineHighlight: "line",
+                                selectOnLineNumbers: true,
+                                roundedSelection: false,
+                                cursorStyle: "line",
+                                cursorWidth: 2,
+                                fontSize: 14,
+                                fontFamily:
+                                    'Monaco, Menlo, "Ubuntu Mono", monospace',
+                            }}
+                        />
+                    </Suspense>
+                )}
             </div>
         </div>
     )
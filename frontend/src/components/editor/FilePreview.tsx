@@ -0,0 +1,92 @@
+import { useState } from "react"
+import { JsonTree } from "@/components/editor/JsonTree"
+import { MarkdownPreview } from "@/components/editor/MarkdownPreview"
+import { TablePreview } from "@/components/editor/TablePreview"
+import { rawFileUrl } from "@/lib/raw-file"
+
+export type PreviewKind = "markdown" | "image" | "csv" | "tsv" | "json"
+
+const PREVIEW_KINDS: Record<string, PreviewKind> = {
+    md: "markdown",
+    markdown: "markdown",
+    png: "image",
+    jpg: "image",
+    jpeg: "image",
+    gif: "image",
+    webp: "image",
+    bmp: "image",
+    ico: "image",
+    svg: "image",
+    csv: "csv",
+    tsv: "tsv",
+    json: "json",
+}
+
+/**
+ * Kind of preview a file gets from its extension, if any
+ */
+export function getPreviewKind(path: string): PreviewKind | undefined {
+    const extension = path.split(".").pop()?.toLowerCase()
+    return PREVIEW_KINDS[extension || ""]
+}
+
+function ImagePreview({ src, name }: { src: string; name: string }) {
+    const [hasError, setHasError] = useState(false)
+
+    if (hasError) {
+        return (
+            <div className="h-full flex items-center justify-center text-sm text-muted-foreground">
+                This image can't be shown
+            </div>
+        )
+    }
+
+    return (
+        // Checkerboard behind transparent images
+        <div className="h-full overflow-auto flex items-center justify-center p-4 bg-[repeating-conic-gradient(#f1f1f1_0_25%,#fff_0_50%)] bg-[length:16px_16px]">
+            <img
+                src={src}
+                alt={name}
+                className="max-w-full max-h-full object-contain"
+                onError={() => setHasError(true)}
+            />
+        </div>
+    )
+}
+
+interface FilePreviewProps {
+    kind: PreviewKind
+    filePath: string
+    // Text content, unused for images
+    content: string
+    // Changes when the file changes, to reload images
+    version?: string
+}
+
+export function FilePreview({
+    kind,
+    filePath,
+    content,
+    version,
+}: FilePreviewProps) {
+    switch (kind) {
+        case "markdown":
+            return <MarkdownPreview content={content} filePath={filePath} />
+        case "image": {
+            const src = rawFileUrl(filePath, version)
+            return (
+                <ImagePreview
+                    key={src}
+                    src={src}
+                    name={filePath.split("/").pop() || filePath}
+                />
+            )
+        }
+        case "csv":
+            return <TablePreview content={content} delimiter="," />
+        case "tsv":
+            return <TablePreview content={content} delimiter={"\t"} />
+        case "json":
+            return <JsonTree content={content} />
+    }
+}
@@ -0,0 +1,88 @@
+import { useMemo } from "react"
+import { Button } from "@/components/ui/button"
+
+const BYTES_PER_ROW = 16
+
+interface HexViewerProps {
+    // Base64 encoded bytes, starting at offset 0 of the file
+    data: string
+    size: number
+    onLoadMore?: () => void
+    isLoadingMore?: boolean
+}
+
+// Decode base64 into bytes without going through a string per byte
+function decodeBase64(data: string): Uint8Array {
+    const binary = atob(data)
+    const bytes = new Uint8Array(binary.length)
+    for (let i = 0; i < binary.length; i++) {
+        bytes[i] = binary.charCodeAt(i)
+    }
+    return bytes
+}
+
+export function HexViewer({
+    data,
+    size,
+    onLoadMore,
+    isLoadingMore,
+}: HexViewerProps) {
+    const bytes = useMemo(() => decodeBase64(data), [data])
+
+    const rows = useMemo(() => {
+        const result: Array<{ offset: string; hex: string; ascii: string }> =
+            []
+        for (let start = 0; start < bytes.length; start += BYTES_PER_ROW) {
+            const row = bytes.subarray(start, start + BYTES_PER_ROW)
+            const hex = Array.from(row, (byte) =>
+                byte.toString(16).padStart(2, "0"),
+            )
+            result.push({
+                offset: start.toString(16).padStart(8, "0"),
+                // Extra gap after 8 bytes, like hexdump -C
+                hex: [hex.slice(0, 8).join(" "), hex.slice(8).join(" ")]
+                    .join("  ")
+                    .padEnd(BYTES_PER_ROW * 3, " "),
+                ascii: Array.from(row, (byte) =>
+                    byte >= 32 && byte < 127 ? String.fromCharCode(byte) : ".",
+                ).join(""),
+            })
+        }
+        return result
+    }, [bytes])
+
+    return (
+        <div className="h-full overflow-auto bg-white">
+            <div className="px-3 py-2 text-xs text-muted-foreground border-b bg-muted/20">
+                Binary file, showing {bytes.length.toLocaleString()} of{" "}
+                {size.toLocaleString()} bytes
+            </div>
+            <pre className="p-3 text-xs font-mono leading-5">
+                {rows.map((row) => (
+                    <div key={row.offset}>
+                        <span className="text-muted-foreground">
+                            {row.offset}
+                        </span>
+                        {"  "}
+                        {row.hex}
+                        {"  "}
+                        <span className="text-blue-700">|{row.ascii}|</span>
+                    </div>
+                ))}
+            </pre>
+            {bytes.length < size && onLoadMore && (
+                <div className="px-3 pb-3">
+                    <Button
+                        variant="outline"
+                        size="sm"
+                        onClick={onLoadMore}
+                        disabled={isLoadingMore}
+                        className="h-7 text-xs"
+                    >
+                        {isLoadingMore ? "Loading..." : "Load more"}
+                    </Button>
+                </div>
+            )}
+        </div>
+    )
+}
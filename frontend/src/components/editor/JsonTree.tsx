@@ -0,0 +1,167 @@
+import { ChevronRight } from "lucide-react"
+import { useMemo, useState } from "react"
+
+type JsonValue =
+    | null
+    | boolean
+    | number
+    | string
+    | JsonValue[]
+    | { [key: string]: JsonValue }
+
+// Levels open when the tree is first shown
+const EXPANDED_DEPTH = 2
+// Children shown per click on large arrays and objects
+const CHILDREN_PAGE = 200
+
+interface JsonNodeProps {
+    name?: string
+    // Array indexes are shown without quotes
+    isIndex?: boolean
+    value: JsonValue
+    depth: number
+}
+
+function JsonPrimitive({ value }: { value: JsonValue }) {
+    if (typeof value === "string") {
+        return (
+            <span className="text-green-700 break-all">
+                {JSON.stringify(value)}
+            </span>
+        )
+    }
+    if (typeof value === "number") {
+        return <span className="text-blue-700">{value}</span>
+    }
+    return <span className="text-orange-700">{String(value)}</span>
+}
+
+function JsonNode({ name, isIndex, value, depth }: JsonNodeProps) {
+    const [isExpanded, setIsExpanded] = useState(depth < EXPANDED_DEPTH)
+    const [limit, setLimit] = useState(CHILDREN_PAGE)
+
+    const label = name !== undefined && (
+        <>
+            <span
+                className={
+                    isIndex ? "text-muted-foreground" : "text-purple-700"
+                }
+            >
+                {isIndex ? name : JSON.stringify(name)}
+            </span>
+            {": "}
+        </>
+    )
+
+    if (value === null || typeof value !== "object") {
+        return (
+            <div className="pl-4">
+                {label}
+                <JsonPrimitive value={value} />
+            </div>
+        )
+    }
+
+    const isArray = Array.isArray(value)
+    const entries = Object.entries(value)
+    const [open, close] = isArray ? ["[", "]"] : ["{", "}"]
+    const summary = isArray
+        ? `${entries.length} ${entries.length === 1 ? "item" : "items"}`
+        : `${entries.length} ${entries.length === 1 ? "key" : "keys"}`
+
+    return (
+        <div>
+            <button
+                type="button"
+                className="flex items-center w-full text-left hover:bg-muted/50"
+                onClick={() => setIsExpanded((expanded) => !expanded)}
+            >
+                <ChevronRight
+                    className={`w-3 h-3 mr-1 flex-shrink-0 text-muted-foreground transition-transform ${
+                        isExpanded ? "rotate-90" : ""
+                    }`}
+                />
+                <span>
+                    {label}
+                    {open}
+                    {!isExpanded && (
+                        <>
+                            <span className="text-muted-foreground">
+                                {" "}
+                                {summary}{" "}
+                            </span>
+                            {close}
+                        </>
+                    )}
+                </span>
+            </button>
+            {isExpanded && (
+                <>
+                    <div className="ml-1.5 pl-2 border-l">
+                        {entries.slice(0, limit).map(([key, child]) => (
+                            <JsonNode
+                                key={key}
+                                name={key}
+                                isIndex={isArray}
+                                value={child}
+                                depth={depth + 1}
+                            />
+                        ))}
+                        {entries.length > limit && (
+                            <button
+                                type="button"
+                                className="pl-4 text-blue-600 hover:underline"
+                                onClick={() =>
+                                    setLimit((shown) => shown + CHILDREN_PAGE)
+                                }
+                            >
+                                Show{" "}
+                                {Math.min(
+                                    CHILDREN_PAGE,
+                                    entries.length - limit,
+                                )}{" "}
+                                more of {entries.length - limit}
+                            </button>
+                        )}
+                    </div>
+                    <div className="pl-4">{close}</div>
+                </>
+            )}
+        </div>
+    )
+}
+
+interface JsonTreeProps {
+    content: string
+}
+
+export function JsonTree({ content }: JsonTreeProps) {
+    const parsed = useMemo(() => {
+        try {
+            return { value: JSON.parse(content) as JsonValue }
+        } catch (err) {
+            return {
+                error: err instanceof Error ? err.message : "Invalid JSON",
+            }
+        }
+    }, [content])
+
+    if ("error" in parsed) {
+        return (
+            <div className="h-full flex items-center justify-center p-4">
+                <div className="text-sm text-muted-foreground text-center">
+                    This file is not valid JSON, open the source to see it
+                    <div className="mt-1 text-xs text-destructive">
+                        {parsed.error}
+                    </div>
+                </div>
+            </div>
+        )
+    }
+
+    return (
+        <div className="h-full overflow-auto bg-white p-3 font-mono text-xs leading-5">
+            <JsonNode value={parsed.value} depth={0} />
+        </div>
+    )
+}
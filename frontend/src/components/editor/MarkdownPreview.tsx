@@ -0,0 +1,175 @@
+import {
+    type ComponentProps,
+    type ElementType,
+    type JSX,
+    useMemo,
+} from "react"
+import Markdown, { type Components, type ExtraProps } from "react-markdown"
+import remarkGfm from "remark-gfm"
+import { rawFileUrl, resolveFilePath } from "@/lib/raw-file"
+
+// Markdown is rendered to React elements, never to HTML strings, so a file
+// can't inject markup or scripts. Raw HTML in the file is shown as text.
+
+// Link or image target, null for targets that are not safe to follow
+function resolveUrl(
+    url: string,
+    kind: "link" | "image",
+    folder: string,
+): string | null {
+    if (/^(?:https?:|mailto:)/i.test(url)) {
+        return kind === "image" && url.startsWith("mailto:") ? null : url
+    }
+    if (kind === "image" && /^data:image\/(?:png|jpeg|gif|webp);/i.test(url)) {
+        return url
+    }
+    // Other schemes, e.g. javascript:, and in-page anchors
+    if (/^[a-z][a-z0-9+.-]*:/i.test(url) || url.startsWith("//")) {
+        return null
+    }
+    const filePath = url.split(/[?#]/)[0]
+    if (!filePath) {
+        return null
+    }
+    try {
+        return rawFileUrl(resolveFilePath(folder, decodeURIComponent(filePath)))
+    } catch {
+        return null
+    }
+}
+
+/**
+ * Component rendering an element with the preview's classes
+ *
+ * The node prop react-markdown adds is left out, it isn't a DOM attribute.
+ */
+function styled<Tag extends keyof JSX.IntrinsicElements>(
+    tag: Tag,
+    className: string,
+) {
+    const Element = tag as ElementType
+    return ({ node: _node, ...props }: ComponentProps<Tag> & ExtraProps) => (
+        <Element {...props} className={className} />
+    )
+}
+
+const COMPONENTS: Components = {
+    h1: styled("h1", "text-2xl font-semibold mt-6 mb-4 pb-2 border-b"),
+    h2: styled("h2", "text-xl font-semibold mt-6 mb-3 pb-1 border-b"),
+    h3: styled("h3", "text-lg font-semibold mt-5 mb-2"),
+    h4: styled("h4", "text-base font-semibold mt-4 mb-2"),
+    h5: styled("h5", "text-sm font-semibold mt-4 mb-2"),
+    h6: styled("h6", "text-sm font-semibold mt-4 mb-2 text-muted-foreground"),
+    p: styled("p", "my-3 leading-relaxed"),
+    pre: styled(
+        "pre",
+        "my-3 p-3 rounded bg-muted text-xs font-mono overflow-x-auto [&>code]:p-0 [&>code]:bg-transparent [&>code]:text-xs",
+    ),
+    code: styled(
+        "code",
+        "px-1 py-0.5 rounded bg-muted font-mono text-[0.85em]",
+    ),
+    blockquote: styled(
+        "blockquote",
+        "my-3 pl-4 border-l-4 text-muted-foreground",
+    ),
+    // remark-gfm marks lists with checkboxes
+    ul: ({ node: _node, className, ...props }) => (
+        <ul
+            {...props}
+            className={
+                className?.includes("contains-task-list")
+                    ? "my-3 pl-1 space-y-1 list-none [&_p]:inline"
+                    : "my-3 pl-6 space-y-1 list-disc"
+            }
+        />
+    ),
+    ol: styled("ol", "my-3 pl-6 space-y-1 list-decimal"),
+    li: styled("li", "[&>p]:my-1"),
+    input: styled("input", "mr-2 align-middle"),
+    table: ({ node: _node, ...props }) => (
+        <div className="my-3 overflow-x-auto">
+            <table {...props} className="text-sm border-collapse" />
+        </div>
+    ),
+    th: styled("th", "px-3 py-1.5 border bg-muted/50 font-semibold"),
+    td: styled("td", "px-3 py-1.5 border"),
+    hr: styled("hr", "my-6 border-t"),
+    // Unsafe targets were dropped by urlTransform
+    a: ({ href, title, children }) =>
+        href ? (
+            <a
+                href={href}
+                title={title}
+                target="_blank"
+                rel="noopener noreferrer"
+                className="text-blue-600 hover:underline"
+            >
+                {children}
+            </a>
+        ) : (
+            <span>{children}</span>
+        ),
+    img: ({ src, alt, title }) =>
+        src ? (
+            <img
+                src={src}
+                alt={alt}
+                title={title}
+                className="inline max-w-full"
+            />
+        ) : (
+            alt
+        ),
+}
+
+interface MarkdownPreviewProps {
+    content: string
+    filePath: string
+}
+
+/**
+ * Render Markdown with GitHub tables, task lists, strikethrough and
+ * autolinks
+ */
+export function MarkdownPreview({ content, filePath }: MarkdownPreviewProps) {
+    const folder = filePath.split("/").slice(0, -1).join("/") || "/"
+
+    // YAML front matter is shown as a code block
+    const { frontMatter, body } = useMemo(() => {
+        const match = /^---\r?\n([\s\S]*?)\r?\n(?:---|\.\.\.)(?:\r?\n|$)/.exec(
+            content,
+        )
+        return match
+            ? { frontMatter: match[1], body: content.slice(match[0].length) }
+            : { frontMatter: null, body: content }
+    }, [content])
+
+    return (
+        <div className="h-full overflow-auto bg-white">
+            <article className="max-w-3xl mx-auto px-6 py-4 text-sm break-words">
+                {frontMatter !== null && (
+                    <pre
+                        className="my-3 p-3 rounded bg-muted text-xs font-mono overflow-x-auto"
+                        title="yaml"
+                    >
+                        <code>{frontMatter}</code>
+                    </pre>
+                )}
+                <Markdown
+                    remarkPlugins={[remarkGfm]}
+                    components={COMPONENTS}
+                    urlTransform={(url, key) =>
+                        resolveUrl(
+                            url,
+                            key === "src" ? "image" : "link",
+                            folder,
+                        )
+                    }
+                >
+                    {body}
+                </Markdown>
+            </article>
+        </div>
+    )
+}
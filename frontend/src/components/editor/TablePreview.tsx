@@ -0,0 +1,132 @@
+import { useMemo, useState } from "react"
+import { Button } from "@/components/ui/button"
+
+// Rows rendered at once, larger files are cut off with a notice
+const MAX_ROWS = 1000
+
+interface TablePreviewProps {
+    content: string
+    // "," for CSV, "\t" for TSV
+    delimiter: string
+}
+
+/**
+ * Parse delimited text as in RFC 4180
+ *
+ * Quoted fields may contain the delimiter, line breaks and doubled quotes.
+ */
+function parseDelimited(content: string, delimiter: string): string[][] {
+    const rows: string[][] = []
+    let row: string[] = []
+    let field = ""
+    let inQuotes = false
+
+    for (let i = 0; i < content.length; i++) {
+        const char = content[i]
+        if (inQuotes) {
+            if (char === '"' && content[i + 1] === '"') {
+                field += '"'
+                i++
+            } else if (char === '"') {
+                inQuotes = false
+            } else {
+                field += char
+            }
+        } else if (char === '"' && field === "") {
+            inQuotes = true
+        } else if (char === delimiter) {
+            row.push(field)
+            field = ""
+        } else if (char === "\n" || char === "\r") {
+            if (char === "\r" && content[i + 1] === "\n") i++
+            row.push(field)
+            rows.push(row)
+            row = []
+            field = ""
+        } else {
+            field += char
+        }
+    }
+    // The last line may not end with a line break
+    if (field || row.length > 0) {
+        row.push(field)
+        rows.push(row)
+    }
+    return rows
+}
+
+export function TablePreview({ content, delimiter }: TablePreviewProps) {
+    const [hasHeader, setHasHeader] = useState(true)
+
+    const table = useMemo(() => {
+        const rows = parseDelimited(content, delimiter)
+        const width = rows.reduce((max, row) => Math.max(max, row.length), 0)
+        // Ids keep React keys stable without using indexes
+        const columns = Array.from({ length: width }, (_, id) => ({ id }))
+        return {
+            columns,
+            rows: rows.map((cells, id) => ({ id, cells })),
+        }
+    }, [content, delimiter])
+
+    const header = hasHeader ? table.rows[0] : undefined
+    const body = hasHeader ? table.rows.slice(1) : table.rows
+    const shown = body.slice(0, MAX_ROWS)
+
+    return (
+        <div className="h-full flex flex-col bg-white">
+            <div className="px-3 py-1 border-b bg-muted/20 text-xs text-muted-foreground flex items-center justify-between gap-2">
+                <span>
+                    {body.length.toLocaleString()} rows,{" "}
+                    {table.columns.length} columns
+                    {body.length > shown.length &&
+                        `, showing the first ${shown.length.toLocaleString()}`}
+                </span>
+                <Button
+                    variant={hasHeader ? "secondary" : "ghost"}
+                    size="sm"
+                    onClick={() => setHasHeader((value) => !value)}
+                    className="h-6 px-2 text-xs"
+                >
+                    First row is header
+                </Button>
+            </div>
+            <div className="flex-1 overflow-auto">
+                <table className="text-xs border-collapse">
+                    {header && (
+                        <thead className="sticky top-0 bg-muted">
+                            <tr>
+                                <th className="px-2 py-1 border text-muted-foreground" />
+                                {table.columns.map((column) => (
+                                    <th
+                                        key={column.id}
+                                        className="px-2 py-1 border text-left font-semibold whitespace-nowrap"
+                                    >
+                                        {header.cells[column.id]}
+                                    </th>
+                                ))}
+                            </tr>
+                        </thead>
+                    )}
+                    <tbody>
+                        {shown.map((row) => (
+                            <tr key={row.id} className="hover:bg-muted/30">
+                                <td className="px-2 py-1 border text-right text-muted-foreground">
+                                    {row.id + (hasHeader ? 0 : 1)}
+                                </td>
+                                {table.columns.map((column) => (
+                                    <td
+                                        key={column.id}
+                                        className="px-2 py-1 border whitespace-pre-wrap max-w-md"
+                                    >
+                                        {row.cells[column.id]}
+                                    </td>
+                                ))}
+                            </tr>
+                        ))}
+                    </tbody>
+                </table>
+            </div>
+        </div>
+    )
+}
@@ -0,0 +1,13 @@
+import { ApiError } from "@/client"
+
+/**
+ * Message of a failed API call, preferring the backend's error message over
+ * the generic HTTP status text
+ */
+export function apiErrorMessage(err: unknown): string {
+    if (err instanceof ApiError) {
+        const body = err.body as { error?: string } | undefined
+        return body?.error || err.message
+    }
+    return err instanceof Error ? err.message : "Unknown error"
+}
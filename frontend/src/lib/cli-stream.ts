@@ -0,0 +1,49 @@
+// Streams the log or diff of an environment over a WebSocket. In follow mode
+// the server keeps sending what changed, e.g. new log entries as the agent
+// commits, so nothing needs to poll.
+
+export type CLIStreamMessage =
+    | { type: "output"; data: string }
+    | { type: "update"; data: string; at: "start" | "end" }
+    | { type: "reset"; data: string }
+    | { type: "end"; code: number; timestamp: string }
+    | { type: "error"; error: string }
+
+interface CLIStreamOptions {
+    folder?: string
+    cli?: string
+    follow?: boolean
+}
+
+export function cliStreamUrl(
+    kind: "logs" | "diff",
+    environmentId: string,
+    { folder, cli, follow }: CLIStreamOptions,
+): string {
+    const params = new URLSearchParams()
+    if (folder) params.append("folder", folder)
+    if (cli) params.append("cli", cli)
+    if (follow) params.append("follow", "true")
+    const url = `ws://localhost:8000/api/v1/environments/${environmentId}/${kind}/stream`
+    const query = params.toString()
+    return query ? `${url}?${query}` : url
+}
+
+// Apply a message to the output received so far
+export function applyCLIStreamMessage(
+    output: string,
+    message: CLIStreamMessage,
+): string {
+    switch (message.type) {
+        case "output":
+            return output + message.data
+        case "update":
+            return message.at === "start"
+                ? message.data + output
+                : output + message.data
+        case "reset":
+            return message.data
+        default:
+            return output
+    }
+}
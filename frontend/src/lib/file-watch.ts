@@ -0,0 +1,174 @@
+// Watches files over a single WebSocket shared by the whole page. The server
+// sends the content of a file once, then patches that are applied here, so
+// every listener still gets the whole content on each change.
+
+export type FileWatchUpdate =
+    | { type: "content"; content: string; modified: string; hash: string }
+    | { type: "deleted" }
+    | { type: "error"; error: string }
+    | { type: "status"; connected: boolean }
+
+type FileWatchListener = (update: FileWatchUpdate) => void
+
+interface TextPatch {
+    offset: number
+    deleteCount: number
+    text: string
+}
+
+type FileWatchMessage = { path: string } & (
+    | { type: "content"; content: string; modified: string; hash: string }
+    | {
+          type: "patch"
+          baseHash: string
+          patch: TextPatch
+          modified: string
+          hash: string
+      }
+    | { type: "deleted" }
+    | { type: "error"; error: string }
+)
+
+interface WatchedFile {
+    listeners: Set<FileWatchListener>
+    // Content the patches apply to, null until it arrives or once deleted
+    snapshot: { content: string; modified: string; hash: string } | null
+}
+
+const FILE_WATCH_URL = "ws://localhost:8000/api/v1/files/watch"
+const RECONNECT_DELAY = 2000
+
+const watchedFiles = new Map<string, WatchedFile>()
+let socket: WebSocket | null = null
+let reconnectTimeout: ReturnType<typeof setTimeout> | null = null
+
+function send(message: { type: string; path: string }) {
+    if (socket?.readyState === WebSocket.OPEN) {
+        socket.send(JSON.stringify(message))
+    }
+}
+
+function notify(file: WatchedFile, update: FileWatchUpdate) {
+    for (const listener of file.listeners) {
+        listener(update)
+    }
+}
+
+function notifyStatus(connected: boolean) {
+    for (const file of watchedFiles.values()) {
+        notify(file, { type: "status", connected })
+    }
+}
+
+function handleMessage(message: FileWatchMessage) {
+    const file = watchedFiles.get(message.path)
+    if (!file) return
+
+    switch (message.type) {
+        case "content":
+            file.snapshot = {
+                content: message.content,
+                modified: message.modified,
+                hash: message.hash,
+            }
+            notify(file, { type: "content", ...file.snapshot })
+            break
+        case "patch": {
+            // A patch for other content means an update was missed
+            if (file.snapshot?.hash !== message.baseHash) {
+                send({ type: "resync", path: message.path })
+                break
+            }
+            const { content } = file.snapshot
+            const { offset, deleteCount, text } = message.patch
+            file.snapshot = {
+                content:
+                    content.slice(0, offset) +
+                    text +
+                    content.slice(offset + deleteCount),
+                modified: message.modified,
+                hash: message.hash,
+            }
+            notify(file, { type: "content", ...file.snapshot })
+            break
+        }
+        case "deleted":
+            file.snapshot = null
+            notify(file, { type: "deleted" })
+            break
+        case "error":
+            notify(file, { type: "error", error: message.error })
+            break
+    }
+}
+
+function connect() {
+    if (socket || watchedFiles.size === 0) return
+
+    const websocket = new WebSocket(FILE_WATCH_URL)
+    socket = websocket
+
+    websocket.onopen = () => {
+        // Also restores the subscriptions after a reconnect
+        for (const path of watchedFiles.keys()) {
+            send({ type: "subscribe", path })
+        }
+        notifyStatus(true)
+    }
+    websocket.onmessage = (event) => {
+        try {
+            handleMessage(JSON.parse(event.data))
+        } catch (err) {
+            console.error("Failed to parse file watch message:", err)
+        }
+    }
+    websocket.onclose = () => {
+        socket = null
+        notifyStatus(false)
+        if (watchedFiles.size > 0 && !reconnectTimeout) {
+            reconnectTimeout = setTimeout(() => {
+                reconnectTimeout = null
+                connect()
+            }, RECONNECT_DELAY)
+        }
+    }
+    websocket.onerror = (err) => {
+        console.error("File watch WebSocket error:", err)
+    }
+}
+
+/**
+ * Watch a file for changes until the returned function is called
+ *
+ * The listener gets the whole content when watching starts and after every
+ * change, and the state of the shared connection. Files watched by several
+ * listeners are only subscribed to once.
+ */
+export function watchFile(
+    path: string,
+    listener: FileWatchListener,
+): () => void {
+    let file = watchedFiles.get(path)
+    if (!file) {
+        file = { listeners: new Set(), snapshot: null }
+        watchedFiles.set(path, file)
+        send({ type: "subscribe", path })
+    } else if (file.snapshot) {
+        listener({ type: "content", ...file.snapshot })
+    }
+    file.listeners.add(listener)
+
+    if (socket?.readyState === WebSocket.OPEN) {
+        listener({ type: "status", connected: true })
+    }
+    connect()
+
+    const watched = file
+    return () => {
+        watched.listeners.delete(listener)
+        if (watched.listeners.size === 0) {
+            watchedFiles.delete(path)
+            send({ type: "unsubscribe", path })
+        }
+    }
+}
@@ -0,0 +1,32 @@
+import { OpenAPI } from "@/client"
+
+/**
+ * URL serving a file's raw bytes with its content type, e.g. for <img>
+ *
+ * The version, such as the file's hash, changes the URL so the browser
+ * loads a changed file again.
+ */
+export function rawFileUrl(path: string, version?: string): string {
+    const query = new URLSearchParams({ path })
+    if (version) {
+        query.set("v", version)
+    }
+    return `${OpenAPI.BASE}/api/v1/files/raw?${query}`
+}
+
+/**
+ * Resolve a path relative to a folder, like a link in a README
+ *
+ * Absolute paths are returned as they are.
+ */
+export function resolveFilePath(folder: string, relativePath: string): string {
+    const parts = relativePath.startsWith("/") ? [""] : folder.split("/")
+    for (const part of relativePath.split("/")) {
+        if (part === "..") {
+            if (parts.length > 1) parts.pop()
+        } else if (part && part !== ".") {
+            parts.push(part)
+        }
+    }
+    return parts.join("/") || "/"
+}
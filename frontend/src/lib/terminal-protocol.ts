@@ -0,0 +1,124 @@
+import type { TerminalParticipant, TerminalSignalRequest } from "@/client"
+
+// Terminal WebSockets speak a versioned subprotocol. Binary frames carry the
+// terminal input and output as UTF-8, text frames carry the JSON messages
+// below. handleTerminal in the backend documents them.
+
+export const TERMINAL_PROTOCOL = "cuweb.terminal.v1"
+
+// Time between pings, the connection is dropped when a ping is still
+// unanswered at the next one
+const HEARTBEAT_INTERVAL = 30000
+
+export type TerminalControlAction = "request" | "grant" | "deny" | "take"
+
+/**
+ * Who is attached to a session, sent whenever it changes
+ */
+export interface TerminalPresence {
+    type: "presence"
+    // Participant ID of this connection
+    you: string
+    // Secret of this connection, proves it is the driver to the REST API
+    token: string
+    participants: TerminalParticipant[]
+    // Participants that asked the driver for control
+    requests: string[]
+}
+
+export interface TerminalExit {
+    type: "exit"
+    code: number
+    // Name of the signal that ended the terminal, e.g. "SIGTERM"
+    signal: string | null
+}
+
+export type TerminalServerMessage =
+    | TerminalPresence
+    | TerminalExit
+    | { type: "pong"; id?: string | number }
+    | { type: "error"; error: string }
+
+export type TerminalClientMessage =
+    | { type: "resize"; cols: number; rows: number }
+    | { type: "signal"; signal: TerminalSignalRequest["signal"] }
+    | { type: "ping"; id?: string | number }
+    | { type: "control"; action: TerminalControlAction; clientId?: string }
+    | { type: "rename"; name: string }
+
+const encoder = new TextEncoder()
+
+/**
+ * Open a WebSocket to a terminal endpoint
+ */
+export function openTerminalSocket(url: string): WebSocket {
+    const websocket = new WebSocket(url, TERMINAL_PROTOCOL)
+    websocket.binaryType = "arraybuffer"
+    return websocket
+}
+
+/**
+ * Parse a JSON message, or return null if it isn't valid JSON
+ */
+export function parseServerMessage(
+    data: string,
+): TerminalServerMessage | null {
+    try {
+        return JSON.parse(data)
+    } catch {
+        return null
+    }
+}
+
+export function sendTerminalMessage(
+    websocket: WebSocket | null,
+    message: TerminalClientMessage,
+) {
+    if (websocket?.readyState === WebSocket.OPEN) {
+        websocket.send(JSON.stringify(message))
+    }
+}
+
+/**
+ * Send input typed into the terminal, in a binary frame
+ */
+export function sendTerminalInput(websocket: WebSocket | null, data: string) {
+    if (websocket?.readyState === WebSocket.OPEN) {
+        websocket.send(encoder.encode(data))
+    }
+}
+
+/**
+ * Line written to the terminal when its session ends
+ */
+export function formatExit(label: string, exit: TerminalExit): string {
+    const reason = exit.signal
+        ? `was killed by ${exit.signal}`
+        : `ended with exit code ${exit.code}`
+    return `\r\n\x1b[31m${label} ${reason}\x1b[0m\r\n`
+}
+
+/**
+ * Ping the server regularly and close the WebSocket when it stops
+ * answering, so a dead connection is noticed and replaced
+ *
+ * Call onPong for every pong received and stop once the WebSocket closes.
+ */
+export function startHeartbeat(websocket: WebSocket) {
+    let isWaiting = false
+    const interval = setInterval(() => {
+        if (isWaiting) {
+            websocket.close(4000, "The server stopped answering")
+            return
+        }
+        isWaiting = true
+        sendTerminalMessage(websocket, { type: "ping" })
+    }, HEARTBEAT_INTERVAL)
+
+    return {
+        onPong: () => {
+            isWaiting = false
+        },
+        stop: () => clearInterval(interval),
+    }
+}
@@ -0,0 +1,76 @@
+import { OpenAPI } from "@/client"
+
+// Terminal recordings are asciicast v2 files, one JSON header line then one
+// [time, code, data] line per event. See
+// https://docs.asciinema.org/manual/asciicast/v2/
+
+export interface CastEvent {
+    // Seconds since the start, after pauses are shortened
+    time: number
+    // "o" for output, "r" for a resize to "COLSxROWS"
+    code: string
+    data: string
+}
+
+export interface Cast {
+    width: number
+    height: number
+    title?: string
+    events: CastEvent[]
+    duration: number
+}
+
+/**
+ * URL of a recording's .cast file
+ */
+export function recordingUrl(id: string): string {
+    return `${OpenAPI.BASE}/api/v1/terminal/recordings/${encodeURIComponent(id)}`
+}
+
+/**
+ * Parse an asciicast v2 recording
+ *
+ * Pauses are shortened to maxPause seconds, or to the idle_time_limit of
+ * the header if it is shorter, like asciinema's own player does.
+ */
+export function parseCast(text: string, maxPause = Infinity): Cast {
+    const lines = text.split("\n")
+    const header = JSON.parse(lines[0])
+    if (header.version !== 2) {
+        throw new Error("Only asciicast v2 recordings can be played")
+    }
+    const pauseLimit = Math.min(
+        maxPause,
+        typeof header.idle_time_limit === "number"
+            ? header.idle_time_limit
+            : Infinity,
+    )
+
+    const events: CastEvent[] = []
+    let previous = 0
+    let skipped = 0
+    for (const line of lines.slice(1)) {
+        if (!line.trim()) continue
+        let event: unknown
+        try {
+            event = JSON.parse(line)
+        } catch {
+            // The last line may be cut off while the session is recorded
+            continue
+        }
+        if (!Array.isArray(event) || typeof event[0] !== "number") continue
+
+        const [time, code, data] = event
+        skipped += Math.max(0, time - previous - pauseLimit)
+        previous = time
+        events.push({ time: time - skipped, code, data: String(data) })
+    }
+
+    return {
+        width: header.width,
+        height: header.height,
+        title: header.title,
+        events,
+        duration: events.length > 0 ? events[events.length - 1].time : 0,
+    }
+}
@@ -0,0 +1,58 @@
+// Terminal sessions keep running on the server when their WebSocket closes.
+// Their IDs are kept for the browser tab, so reloading the page or the
+// terminal reattaches to the same session and replays its output.
+
+const STORAGE_PREFIX = "cuweb.terminal-session."
+const NAME_STORAGE_KEY = "cuweb.terminal-name"
+
+/**
+ * ID of the session for a terminal, created the first time it's opened
+ */
+export function getTerminalSessionId(key: string): string {
+    const storageKey = STORAGE_PREFIX + key
+    const existing = sessionStorage.getItem(storageKey)
+    if (existing) return existing
+
+    const id = crypto.randomUUID()
+    sessionStorage.setItem(storageKey, id)
+    return id
+}
+
+/**
+ * Switch a terminal to another session, e.g. one shared by someone else
+ */
+export function setTerminalSessionId(key: string, id: string) {
+    sessionStorage.setItem(STORAGE_PREFIX + key, id)
+}
+
+/**
+ * Name shown to the others in a shared terminal, kept across tabs
+ */
+export function getTerminalClientName(): string {
+    const existing = localStorage.getItem(NAME_STORAGE_KEY)
+    if (existing) return existing
+
+    const name = `Guest ${Math.floor(1000 + Math.random() * 9000)}`
+    localStorage.setItem(NAME_STORAGE_KEY, name)
+    return name
+}
+
+export function setTerminalClientName(name: string) {
+    localStorage.setItem(NAME_STORAGE_KEY, name)
+}
+
+// Tokens proving this tab drives a session, from its presence messages. They
+// belong to the WebSocket, so they are dropped with the page.
+const driverTokens = new Map<string, string>()
+
+export function setTerminalDriverToken(sessionId: string, token: string) {
+    driverTokens.set(sessionId, token)
+}
+
+/**
+ * Token to kill or signal a session through the REST API, if this tab is
+ * attached to it
+ */
+export function getTerminalDriverToken(sessionId: string): string | undefined {
+    return driverTokens.get(sessionId)
+}
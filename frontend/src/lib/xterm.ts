@@ -0,0 +1,19 @@
+import type { ITerminalOptions } from "@xterm/xterm"
+
+// Look of every terminal in the dashboard, live or replayed
+export const TERMINAL_OPTIONS: ITerminalOptions = {
+    cursorBlink: true,
+    theme: {
+        background: "#000000",
+        foreground: "#ffffff",
+        cursor: "#ffffff",
+        cursorAccent: "#000000",
+        selectionBackground: "rgba(255, 255, 255, 0.3)",
+    },
+    fontSize: 12,
+    fontFamily:
+        '"Cascadia Code", "Fira Code", "JetBrains Mono", "SF Mono", Consolas, "Liberation Mono", Menlo, Monaco, monospace',
+    allowTransparency: true,
+    scrollback: 1000,
+    convertEol: true, // Convert \n to \r\n
+}
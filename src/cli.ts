@@ -1,7 +1,7 @@
 import { spawn } from "node:child_process";
 import { readFileSync } from "node:fs";
 import { homedir } from "node:os";
-import { dirname, join, resolve } from "node:path";
+import { delimiter, dirname, join, resolve } from "node:path";
 import { fileURLToPath } from "node:url";
 import { Command } from "commander";
 import open from "open";
@@ -47,16 +47,113 @@ program
 		"Path to the container-use binary",
 		"container-use",
 	)
+	.option(
+		"--max-file-size <BYTES>",
+		"Largest file range the file viewer reads at once",
+		"5242880",
+	)
+	.option(
+		"--terminal-scrollback <CHARS>",
+		"Terminal output kept per session and replayed on reconnect",
+		"262144",
+	)
+	.option(
+		"--terminal-idle-timeout <SECONDS>",
+		"How long a terminal session lives with no browser attached",
+		"600",
+	)
+	.option(
+		"--terminal-output-rate <CHARS>",
+		"Terminal output sent per second before the terminal is paused, 0 for no limit",
+		"1048576",
+	)
+	.option(
+		"--terminal-ping-interval <SECONDS>",
+		"How often terminal browsers are pinged, unresponsive ones are dropped",
+		"30",
+	)
+	.option(
+		"--terminal-login-shell",
+		"Run container-use in terminals through a login shell, to load your profile",
+	)
+	.option(
+		"--terminal-shell <SHELL>",
+		"Shell plain terminals run (default: $SHELL)",
+	)
+	.option(
+		"--terminal-shell-args <ARGS>",
+		'Arguments of the terminal shell, separated by spaces (default: "-l")',
+	)
+	.option(
+		"--terminal-env <NAME=VALUE...>",
+		"Extra environment variables of plain terminals",
+		[],
+	)
+	.option(
+		"--terminal-size <COLSxROWS>",
+		"Size plain terminals start at, until the browser resizes them",
+	)
+	.option(
+		"--config <FILE>",
+		"Config file with terminal settings (default: ~/.cuweb/config.json)",
+	)
+	.option(
+		"--recordings-dir <DIR>",
+		"Folder terminal recordings are saved to (default: ~/.cuweb/recordings)",
+	)
+	.option(
+		"-a, --allow <DIR...>",
+		"Additional folders the file browser may access",
+		[],
+	)
 	.option("-n, --no-open", "Do not open the browser automatically")
 	.action(async (options) => {
-		const { host, port, dir, bin, open: shouldOpen } = options;
+		const {
+			host,
+			port,
+			dir,
+			bin,
+			maxFileSize,
+			terminalScrollback,
+			terminalIdleTimeout,
+			terminalOutputRate,
+			terminalPingInterval,
+			terminalLoginShell,
+			terminalShell,
+			terminalShellArgs,
+			terminalEnv,
+			terminalSize,
+			config,
+			recordingsDir,
+			allow,
+			open: shouldOpen,
+		} = options;
 
 		// Resolve the working directory
 		const workingDir = resolveDirectory(dir);
+		const allowedRoots = (allow as string[]).map(resolveDirectory);
+
+		// Passed to the server as a JSON object of strings
+		const shellEnv: Record<string, string> = {};
+		for (const entry of terminalEnv as string[]) {
+			const separator = entry.indexOf("=");
+			if (separator <= 0) {
+				program.error(`error: --terminal-env expects NAME=VALUE: ${entry}`);
+			}
+			shellEnv[entry.slice(0, separator)] = entry.slice(separator + 1);
+		}
+		if (terminalSize && !/^\d+x\d+$/.test(terminalSize)) {
+			program.error(
+				`error: --terminal-size expects COLSxROWS, e.g. 120x30: ${terminalSize}`,
+			);
+		}
 
 		console.log(`🚀 Starting Container Use Web on http://${host}:${port}`);
 		console.log(`📁 Working directory: ${workingDir}`);
 		console.log(`🔧 Container-use binary: ${bin}`);
+		if (allowedRoots.length > 0) {
+			console.log(`🔒 Additional allowed folders: ${allowedRoots.join(", ")}`);
+		}
 
 		// Start the backend server
 		const backendPath = join(__dirname, "..", "backend", "dist", "index.js");
@@ -70,6 +167,19 @@ program
 				HOST: host,
 				CUWEB_WORKING_DIR: workingDir,
 				CUWEB_CLI_BINARY: bin,
+				CUWEB_MAX_FILE_SIZE: maxFileSize,
+				CUWEB_TERMINAL_SCROLLBACK: terminalScrollback,
+				CUWEB_TERMINAL_IDLE_TIMEOUT: terminalIdleTimeout,
+				CUWEB_TERMINAL_OUTPUT_RATE: terminalOutputRate,
+				CUWEB_TERMINAL_PING_INTERVAL: terminalPingInterval,
+				CUWEB_TERMINAL_LOGIN_SHELL: terminalLoginShell ? "true" : "false",
+				CUWEB_TERMINAL_SHELL: terminalShell,
+				CUWEB_TERMINAL_SHELL_ARGS: terminalShellArgs,
+				CUWEB_TERMINAL_ENV: JSON.stringify(shellEnv),
+				CUWEB_TERMINAL_SIZE: terminalSize,
+				CUWEB_CONFIG: config && resolve(config),
+				CUWEB_RECORDINGS_DIR: recordingsDir && resolve(recordingsDir),
+				CUWEB_ALLOWED_ROOTS: allowedRoots.join(delimiter),
 				CUWEB_FRONTEND_DIST: frontendDist,
 			},
 		});